@@ -0,0 +1,521 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// BarType define how a BarBuilder closes its bars
+type BarType string
+
+// Global enums
+const (
+	BarTypeTime   BarType = "TIME"
+	BarTypeTick   BarType = "TICK"
+	BarTypeVolume BarType = "VOLUME"
+	BarTypeDollar BarType = "DOLLAR"
+)
+
+// Bar define a candle built from trades
+type Bar struct {
+	Symbol string  `json:"symbol"`
+	Type   BarType `json:"type"`
+	// OpenTime and CloseTime are the bounds of the interval for time bars,
+	// the times of the first and last trades otherwise
+	OpenTime            int64  `json:"openTime"`
+	CloseTime           int64  `json:"closeTime"`
+	Open                string `json:"open"`
+	High                string `json:"high"`
+	Low                 string `json:"low"`
+	Close               string `json:"close"`
+	Volume              string `json:"volume"`
+	QuoteVolume         string `json:"quoteVolume"`
+	VWAP                string `json:"vwap"`
+	TakerBuyVolume      string `json:"takerBuyVolume"`
+	TakerBuyQuoteVolume string `json:"takerBuyQuoteVolume"`
+	TradeNum            int64  `json:"tradeNum"`
+	// FirstTradeID and LastTradeID are 0 for bars seeded from klines
+	FirstTradeID int64 `json:"firstTradeId"`
+	LastTradeID  int64 `json:"lastTradeId"`
+}
+
+// BarTrade define a trade consumed by a BarBuilder, the ids of the trades of
+// a builder must all come from the same stream, trades or aggTrades
+type BarTrade struct {
+	ID           int64
+	Price        string
+	Quantity     string
+	Time         int64
+	IsBuyerMaker bool
+}
+
+// BarHandler handle a closed bar
+type BarHandler func(bar *Bar)
+
+// BarBuilder build bars from a trade or aggTrade stream. Trades may arrive
+// out of order within the allowed lateness, later ones and duplicates are
+// dropped. Time bars are only emitted for intervals with trades.
+type BarBuilder struct {
+	mu        sync.Mutex
+	symbol    string
+	barType   BarType
+	interval  int64
+	offset    int64
+	threshold decimal.Decimal
+	handler   BarHandler
+	lateness  int64
+	onDrop    func(trade *BarTrade)
+
+	// trades waiting for the watermark, in id order
+	pending     []*BarTrade
+	maxTime     int64
+	lastID      int64
+	current     *barState
+	seeding     bool
+	seedBacklog []*BarTrade
+}
+
+type barState struct {
+	bar           *Bar
+	open          decimal.Decimal
+	high          decimal.Decimal
+	low           decimal.Decimal
+	last          decimal.Decimal
+	volume        decimal.Decimal
+	quote         decimal.Decimal
+	takerBuy      decimal.Decimal
+	takerBuyQuote decimal.Decimal
+}
+
+func newBarBuilder(symbol string, barType BarType, handler BarHandler) *BarBuilder {
+	return &BarBuilder{
+		symbol:  symbol,
+		barType: barType,
+		handler: handler,
+		maxTime: math.MinInt64,
+		lastID:  -1,
+	}
+}
+
+// NewTimeBarBuilder init a builder of bars covering interval, e.g. 5 * time.Second,
+// the bars of whole weeks open on mondays like the weekly klines
+func NewTimeBarBuilder(symbol string, interval time.Duration, handler BarHandler) *BarBuilder {
+	b := newBarBuilder(symbol, BarTypeTime, handler)
+	b.interval = interval.Milliseconds()
+	if week := 7 * 24 * time.Hour; interval >= week && interval%week == 0 {
+		// the epoch is a thursday
+		b.offset = (4 * 24 * time.Hour).Milliseconds()
+	}
+	return b
+}
+
+// NewTickBarBuilder init a builder of bars of the given number of trades
+func NewTickBarBuilder(symbol string, trades int, handler BarHandler) *BarBuilder {
+	b := newBarBuilder(symbol, BarTypeTick, handler)
+	b.threshold = decimal.NewFromInt(int64(trades))
+	return b
+}
+
+// NewVolumeBarBuilder init a builder of bars closed once their base asset
+// volume reaches volume, the trade crossing the threshold closes the bar
+func NewVolumeBarBuilder(symbol, volume string, handler BarHandler) *BarBuilder {
+	b := newBarBuilder(symbol, BarTypeVolume, handler)
+	b.threshold = decimalOrZero(volume)
+	return b
+}
+
+// NewDollarBarBuilder init a builder of bars closed once their quote asset
+// volume reaches quoteVolume, the trade crossing the threshold closes the bar
+func NewDollarBarBuilder(symbol, quoteVolume string, handler BarHandler) *BarBuilder {
+	b := newBarBuilder(symbol, BarTypeDollar, handler)
+	b.threshold = decimalOrZero(quoteVolume)
+	return b
+}
+
+// AllowedLateness hold the trades for d before building bars, so that trades
+// arriving out of order within d are still counted in time order
+func (b *BarBuilder) AllowedLateness(d time.Duration) *BarBuilder {
+	b.lateness = d.Milliseconds()
+	return b
+}
+
+// OnDrop call f for every trade dropped as a duplicate or as too late
+func (b *BarBuilder) OnDrop(f func(trade *BarTrade)) *BarBuilder {
+	b.onDrop = f
+	return b
+}
+
+// HandleAggTradeEvent add a spot aggregate trade, it can be used as a WsAggTradeHandler
+func (b *BarBuilder) HandleAggTradeEvent(event *WsAggTradeEvent) {
+	b.AddTrade(&BarTrade{
+		ID:           event.AggTradeID,
+		Price:        event.Price,
+		Quantity:     event.Quantity,
+		Time:         event.TradeTime,
+		IsBuyerMaker: event.IsBuyerMaker,
+	})
+}
+
+// HandleTradeEvent add a spot trade, it can be used as a WsTradeHandler
+func (b *BarBuilder) HandleTradeEvent(event *WsTradeEvent) {
+	b.AddTrade(&BarTrade{
+		ID:           event.TradeID,
+		Price:        event.Price,
+		Quantity:     event.Quantity,
+		Time:         event.TradeTime,
+		IsBuyerMaker: event.IsBuyerMaker,
+	})
+}
+
+// HandleFuturesAggTradeEvent add a USD-M futures aggregate trade, it can be used as a futures.WsAggTradeHandler
+func (b *BarBuilder) HandleFuturesAggTradeEvent(event *futures.WsAggTradeEvent) {
+	b.AddTrade(&BarTrade{
+		ID:           event.AggregateTradeID,
+		Price:        event.Price,
+		Quantity:     event.Quantity,
+		Time:         event.TradeTime,
+		IsBuyerMaker: event.Maker,
+	})
+}
+
+// AddTrade add a trade, the bars it closes are passed to the handler
+func (b *BarBuilder) AddTrade(trade *BarTrade) {
+	b.mu.Lock()
+	if b.seeding {
+		b.seedBacklog = append(b.seedBacklog, trade)
+		b.mu.Unlock()
+		return
+	}
+	bars, dropped := b.add(trade)
+	b.mu.Unlock()
+	b.emit(bars, dropped)
+}
+
+// AdvanceTo move the clock to t in milliseconds, e.g. on a timer or from the
+// event time of the stream, so that the time bars ended by t are closed
+// without waiting for the next trade
+func (b *BarBuilder) AdvanceTo(t int64) {
+	b.mu.Lock()
+	var bars []*Bar
+	if !b.seeding && t > b.maxTime {
+		b.maxTime = t
+		bars = b.release()
+		watermark := b.maxTime - b.lateness
+		if b.barType == BarTypeTime && b.current != nil && b.current.bar.CloseTime < watermark {
+			bars = append(bars, b.current.finish())
+			b.current = nil
+		}
+	}
+	b.mu.Unlock()
+	b.emit(bars, nil)
+}
+
+// Flush build the pending trades and close the current bar
+func (b *BarBuilder) Flush() {
+	b.mu.Lock()
+	maxTime := b.maxTime
+	b.maxTime = math.MaxInt64
+	bars := b.release()
+	b.maxTime = maxTime
+	if b.current != nil {
+		bars = append(bars, b.current.finish())
+		b.current = nil
+	}
+	b.mu.Unlock()
+	b.emit(bars, nil)
+}
+
+// errSeedCaughtUp stop the trade download once it reaches the live stream
+var errSeedCaughtUp = errors.New("seed caught up with the stream")
+
+// Seed emit the bars since startTime before the live ones, so that there is
+// no gap at startup. Subscribe to the stream first, the live trades are held
+// while seeding. Time bars whose interval is a kline interval are seeded from
+// the closed klines fetched by klines, the remaining trades are replayed from
+// trades, whose ids must match the stream. klines may be nil.
+func (b *BarBuilder) Seed(ctx context.Context, klines KlinePageFetcher, trades *TradeSource, startTime int64) error {
+	b.mu.Lock()
+	b.seeding = true
+	b.mu.Unlock()
+
+	var seeded []*Bar
+	tradeFrom := startTime
+	err := func() error {
+		if interval := b.klineInterval(); klines != nil && b.barType == BarTypeTime && interval != "" {
+			var all []*Kline
+			now := time.Now().UnixMilli()
+			err := NewKlineBackfill(klines, interval, startTime, now).
+				Do(ctx, func(k *Kline) error {
+					all = append(all, k)
+					return nil
+				})
+			if err != nil {
+				return err
+			}
+			// the last kline is still open, its trades are replayed
+			if len(all) > 0 {
+				tradeFrom = all[len(all)-1].OpenTime
+				for _, k := range all[:len(all)-1] {
+					seeded = append(seeded, b.klineBar(k))
+				}
+			}
+		}
+		b.mu.Lock()
+		firstLive := int64(math.MaxInt64)
+		for _, t := range b.seedBacklog {
+			if t.ID < firstLive {
+				firstLive = t.ID
+			}
+		}
+		b.mu.Unlock()
+		if trades == nil {
+			return nil
+		}
+		err := NewTradeDownloader(trades, tradeFrom, math.MaxInt64).Do(ctx, func(t *TradeRecord) error {
+			if t.ID >= firstLive {
+				return errSeedCaughtUp
+			}
+			b.mu.Lock()
+			b.seedBacklog = append(b.seedBacklog, &BarTrade{
+				ID:           t.ID,
+				Price:        t.Price,
+				Quantity:     t.Quantity,
+				Time:         t.Time,
+				IsBuyerMaker: t.IsBuyerMaker,
+			})
+			b.mu.Unlock()
+			return nil
+		})
+		if err == errSeedCaughtUp {
+			return nil
+		}
+		return err
+	}()
+
+	b.mu.Lock()
+	backlog := b.seedBacklog
+	b.seeding = false
+	b.seedBacklog = nil
+	var dropped []*BarTrade
+	if err == nil {
+		for _, bar := range seeded {
+			if bar.CloseTime > b.maxTime {
+				b.maxTime = bar.CloseTime
+			}
+		}
+	} else {
+		seeded = nil
+	}
+	sort.SliceStable(backlog, func(i, j int) bool { return backlog[i].ID < backlog[j].ID })
+	bars := seeded
+	for _, t := range backlog {
+		closed, d := b.add(t)
+		bars = append(bars, closed...)
+		dropped = append(dropped, d...)
+	}
+	b.mu.Unlock()
+	b.emit(bars, dropped)
+	return err
+}
+
+// add queue a trade and return the bars closed and the trades dropped, the lock must be held
+func (b *BarBuilder) add(trade *BarTrade) ([]*Bar, []*BarTrade) {
+	if trade.ID <= b.lastID {
+		return nil, []*BarTrade{trade}
+	}
+	i := sort.Search(len(b.pending), func(i int) bool { return b.pending[i].ID >= trade.ID })
+	if i < len(b.pending) && b.pending[i].ID == trade.ID {
+		return nil, []*BarTrade{trade}
+	}
+	b.pending = append(b.pending, nil)
+	copy(b.pending[i+1:], b.pending[i:])
+	b.pending[i] = trade
+	if trade.Time > b.maxTime {
+		b.maxTime = trade.Time
+	}
+	return b.release(), nil
+}
+
+// release build the pending trades older than the watermark, the lock must be held
+func (b *BarBuilder) release() []*Bar {
+	var bars []*Bar
+	watermark := b.maxTime - b.lateness
+	if b.maxTime == math.MaxInt64 {
+		watermark = b.maxTime
+	}
+	n := 0
+	for ; n < len(b.pending); n++ {
+		t := b.pending[n]
+		if t.Time > watermark {
+			break
+		}
+		if bar := b.apply(t); bar != nil {
+			bars = append(bars, bar)
+		}
+		b.lastID = t.ID
+	}
+	b.pending = b.pending[n:]
+	return bars
+}
+
+// apply add a trade to the current bar and return the bar it closes, if any
+func (b *BarBuilder) apply(t *BarTrade) *Bar {
+	var closed *Bar
+	if b.barType == BarTypeTime {
+		openTime := t.Time - ((t.Time-b.offset)%b.interval+b.interval)%b.interval
+		if b.current != nil && b.current.bar.OpenTime != openTime {
+			closed = b.current.finish()
+			b.current = nil
+		}
+		if b.current == nil {
+			b.current = b.newState(openTime, openTime+b.interval-1)
+		}
+		b.current.add(t)
+		return closed
+	}
+	if b.current == nil {
+		b.current = b.newState(t.Time, t.Time)
+	}
+	s := b.current
+	s.add(t)
+	s.bar.CloseTime = t.Time
+	var size decimal.Decimal
+	switch b.barType {
+	case BarTypeTick:
+		size = decimal.NewFromInt(s.bar.TradeNum)
+	case BarTypeVolume:
+		size = s.volume
+	case BarTypeDollar:
+		size = s.quote
+	}
+	if size.GreaterThanOrEqual(b.threshold) {
+		closed = s.finish()
+		b.current = nil
+	}
+	return closed
+}
+
+func (b *BarBuilder) newState(openTime, closeTime int64) *barState {
+	return &barState{bar: &Bar{
+		Symbol:    b.symbol,
+		Type:      b.barType,
+		OpenTime:  openTime,
+		CloseTime: closeTime,
+	}}
+}
+
+func (b *BarBuilder) emit(bars []*Bar, dropped []*BarTrade) {
+	if b.onDrop != nil {
+		for _, t := range dropped {
+			b.onDrop(t)
+		}
+	}
+	if b.handler != nil {
+		for _, bar := range bars {
+			b.handler(bar)
+		}
+	}
+}
+
+// klineIntervals are the intervals of the klines served by Binance
+var klineIntervals = map[string]bool{
+	"1s": true, "1m": true, "3m": true, "5m": true, "15m": true, "30m": true, "1h": true, "2h": true,
+	"4h": true, "6h": true, "8h": true, "12h": true, "1d": true, "3d": true, "1w": true, "1M": true,
+}
+
+// klineInterval return the kline interval matching the bar interval, empty
+// when there is none
+func (b *BarBuilder) klineInterval() string {
+	d := time.Duration(b.interval) * time.Millisecond
+	for _, u := range []struct {
+		unit   time.Duration
+		suffix string
+	}{
+		{7 * 24 * time.Hour, "w"},
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+	} {
+		if d >= u.unit && d%u.unit == 0 {
+			interval := strconv.FormatInt(int64(d/u.unit), 10) + u.suffix
+			if klineIntervals[interval] {
+				return interval
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+func (b *BarBuilder) klineBar(k *Kline) *Bar {
+	bar := &Bar{
+		Symbol:              b.symbol,
+		Type:                b.barType,
+		OpenTime:            k.OpenTime,
+		CloseTime:           k.CloseTime,
+		Open:                k.Open,
+		High:                k.High,
+		Low:                 k.Low,
+		Close:               k.Close,
+		Volume:              k.Volume,
+		QuoteVolume:         k.QuoteAssetVolume,
+		TakerBuyVolume:      k.TakerBuyBaseAssetVolume,
+		TakerBuyQuoteVolume: k.TakerBuyQuoteAssetVolume,
+		TradeNum:            k.TradeNum,
+	}
+	if volume := decimalOrZero(k.Volume); volume.IsPositive() {
+		bar.VWAP = decimalOrZero(k.QuoteAssetVolume).Div(volume).String()
+	}
+	return bar
+}
+
+func (s *barState) add(t *BarTrade) {
+	price := decimalOrZero(t.Price)
+	qty := decimalOrZero(t.Quantity)
+	quote := price.Mul(qty)
+	if s.bar.TradeNum == 0 {
+		s.open, s.high, s.low = price, price, price
+		s.bar.FirstTradeID = t.ID
+	}
+	if price.GreaterThan(s.high) {
+		s.high = price
+	}
+	if price.LessThan(s.low) {
+		s.low = price
+	}
+	s.last = price
+	s.volume = s.volume.Add(qty)
+	s.quote = s.quote.Add(quote)
+	if !t.IsBuyerMaker {
+		s.takerBuy = s.takerBuy.Add(qty)
+		s.takerBuyQuote = s.takerBuyQuote.Add(quote)
+	}
+	s.bar.TradeNum++
+	s.bar.LastTradeID = t.ID
+}
+
+func (s *barState) finish() *Bar {
+	bar := s.bar
+	bar.Open = s.open.String()
+	bar.High = s.high.String()
+	bar.Low = s.low.String()
+	bar.Close = s.last.String()
+	bar.Volume = s.volume.String()
+	bar.QuoteVolume = s.quote.String()
+	bar.TakerBuyVolume = s.takerBuy.String()
+	bar.TakerBuyQuoteVolume = s.takerBuyQuote.String()
+	if s.volume.IsPositive() {
+		bar.VWAP = s.quote.Div(s.volume).String()
+	}
+	return bar
+}
@@ -0,0 +1,177 @@
+package binance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type barBuilderTestSuite struct {
+	baseTestSuite
+}
+
+func TestBarBuilder(t *testing.T) {
+	suite.Run(t, new(barBuilderTestSuite))
+}
+
+func (s *barBuilderTestSuite) TestTimeBars() {
+	var bars []*Bar
+	var dropped []int64
+	b := NewTimeBarBuilder("BTCUSDT", 5*time.Second, func(bar *Bar) { bars = append(bars, bar) }).
+		AllowedLateness(time.Second).
+		OnDrop(func(trade *BarTrade) { dropped = append(dropped, trade.ID) })
+
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 1, Price: "10", Quantity: "1", TradeTime: 1000, IsBuyerMaker: false})
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 3, Price: "12", Quantity: "2", TradeTime: 4000, IsBuyerMaker: true})
+	// out of order but within the lateness
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 2, Price: "9", Quantity: "1", TradeTime: 3500, IsBuyerMaker: false})
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 3, Price: "12", Quantity: "2", TradeTime: 4000, IsBuyerMaker: true})
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 4, Price: "11", Quantity: "1", TradeTime: 6000, IsBuyerMaker: false})
+	r := s.r()
+	r.Empty(bars)
+
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 5, Price: "11", Quantity: "1", TradeTime: 7500, IsBuyerMaker: false})
+	r.Len(bars, 1)
+	r.Equal(&Bar{
+		Symbol:              "BTCUSDT",
+		Type:                BarTypeTime,
+		OpenTime:            0,
+		CloseTime:           4999,
+		Open:                "10",
+		High:                "12",
+		Low:                 "9",
+		Close:               "12",
+		Volume:              "4",
+		QuoteVolume:         "43",
+		VWAP:                "10.75",
+		TakerBuyVolume:      "2",
+		TakerBuyQuoteVolume: "19",
+		TradeNum:            3,
+		FirstTradeID:        1,
+		LastTradeID:         3,
+	}, bars[0])
+	r.Equal([]int64{3}, dropped)
+
+	// too late
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 2, Price: "9", Quantity: "1", TradeTime: 3500})
+	r.Equal([]int64{3, 2}, dropped)
+
+	b.AdvanceTo(11000)
+	r.Len(bars, 2)
+	r.Equal(int64(5000), bars[1].OpenTime)
+	r.Equal(int64(2), bars[1].TradeNum)
+
+	// quiet intervals have no bar
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 6, Price: "11", Quantity: "1", TradeTime: 21000})
+	b.Flush()
+	r.Len(bars, 3)
+	r.Equal(int64(20000), bars[2].OpenTime)
+}
+
+func (s *barBuilderTestSuite) TestThresholdBars() {
+	var ticks, volumes, dollars []*Bar
+	tick := NewTickBarBuilder("BTCUSDT", 2, func(bar *Bar) { ticks = append(ticks, bar) })
+	volume := NewVolumeBarBuilder("BTCUSDT", "3", func(bar *Bar) { volumes = append(volumes, bar) })
+	dollar := NewDollarBarBuilder("BTCUSDT", "50", func(bar *Bar) { dollars = append(dollars, bar) })
+	for i, qty := range []string{"1", "1", "2", "1", "4"} {
+		event := &futures.WsAggTradeEvent{AggregateTradeID: int64(i + 1), Price: "10", Quantity: qty, TradeTime: int64(i+1) * 100, Maker: true}
+		tick.HandleFuturesAggTradeEvent(event)
+		volume.HandleFuturesAggTradeEvent(event)
+		dollar.HandleFuturesAggTradeEvent(event)
+	}
+	r := s.r()
+	r.Len(ticks, 2)
+	r.Equal(int64(100), ticks[0].OpenTime)
+	r.Equal(int64(200), ticks[0].CloseTime)
+	r.Equal("0", ticks[0].TakerBuyVolume)
+
+	r.Len(volumes, 2)
+	r.Equal("4", volumes[0].Volume)
+	r.Equal(int64(3), volumes[0].LastTradeID)
+	r.Equal("5", volumes[1].Volume)
+
+	r.Len(dollars, 1)
+	r.Equal("50", dollars[0].QuoteVolume)
+
+	tick.Flush()
+	r.Len(ticks, 3)
+	r.Equal(int64(1), ticks[2].TradeNum)
+}
+
+func (s *barBuilderTestSuite) TestWeekBars() {
+	var bars []*Bar
+	b := NewTimeBarBuilder("BTCUSDT", 7*24*time.Hour, func(bar *Bar) { bars = append(bars, bar) })
+	wednesday := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC).UnixMilli()
+	nextMonday := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC).UnixMilli()
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 1, Price: "10", Quantity: "1", TradeTime: wednesday})
+	b.HandleAggTradeEvent(&WsAggTradeEvent{AggTradeID: 2, Price: "11", Quantity: "1", TradeTime: nextMonday})
+	r := s.r()
+	r.Len(bars, 1)
+	// like the weekly klines, the bars open on mondays
+	r.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC).UnixMilli(), bars[0].OpenTime)
+	r.Equal(nextMonday-1, bars[0].CloseTime)
+}
+
+func (s *barBuilderTestSuite) TestSeed() {
+	minute := int64(60000)
+	klines := func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		var res []*Kline
+		for _, openTime := range []int64{0, minute, 2 * minute} {
+			if openTime >= startTime {
+				res = append(res, &Kline{OpenTime: openTime, CloseTime: openTime + minute - 1, Close: "10", Volume: "2", QuoteAssetVolume: "20", TradeNum: 2})
+			}
+		}
+		return res, nil
+	}
+	var calls int
+	// trades of the open kline, some already received live
+	trades := fakeTradeSource([]int64{12000, 12001, 12002, 12003, 12004}, &calls)
+
+	var bars []*Bar
+	b := NewTimeBarBuilder("BTCUSDT", time.Minute, func(bar *Bar) { bars = append(bars, bar) })
+	trades.Locate = func(ctx context.Context, startTime, endTime int64) (int64, bool, error) {
+		// the live stream starts while the backfill runs
+		b.HandleTradeEvent(&WsTradeEvent{TradeID: 12003, Price: "1", Quantity: "2", TradeTime: 120030})
+		b.HandleTradeEvent(&WsTradeEvent{TradeID: 12004, Price: "1", Quantity: "2", TradeTime: 120040})
+		r := s.r()
+		r.Equal(2*minute, startTime)
+		return 12000, true, nil
+	}
+	r := s.r()
+	r.NoError(b.Seed(newContext(), klines, trades, 0))
+	r.Len(bars, 2)
+	r.Equal("10", bars[1].VWAP)
+
+	b.HandleTradeEvent(&WsTradeEvent{TradeID: 12005, Price: "1", Quantity: "1", TradeTime: 3 * minute})
+	r.Len(bars, 3)
+	r.Equal(2*minute, bars[2].OpenTime)
+	r.Equal(int64(5), bars[2].TradeNum)
+	r.Equal("10", bars[2].Volume)
+}
+
+func (s *barBuilderTestSuite) TestSeedSeconds() {
+	klines := func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		s.T().Error("5s bars can't be seeded from klines")
+		return nil, nil
+	}
+	var calls int
+	trades := fakeTradeSource([]int64{100, 600, 1100}, &calls)
+
+	var bars []*Bar
+	b := NewTimeBarBuilder("BTCUSDT", 5*time.Second, func(bar *Bar) { bars = append(bars, bar) })
+	trades.Locate = func(ctx context.Context, startTime, endTime int64) (int64, bool, error) {
+		b.HandleTradeEvent(&WsTradeEvent{TradeID: 1100, Price: "1", Quantity: "2", TradeTime: 11000})
+		s.r().Equal(int64(0), startTime)
+		return 100, true, nil
+	}
+	r := s.r()
+	r.NoError(b.Seed(newContext(), klines, trades, 0))
+	r.Len(bars, 2)
+	r.Equal(int64(0), bars[0].OpenTime)
+	r.Equal(int64(5000), bars[1].OpenTime)
+	r.Equal(int64(1), bars[1].TradeNum)
+}
@@ -0,0 +1,283 @@
+package binancetest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+func (s *Server) futuresRoutes() {
+	s.route(http.MethodGet, "/fapi/v1/ping", secTypeNone, func(c *call) (interface{}, *apiError) {
+		return struct{}{}, nil
+	})
+	s.route(http.MethodGet, "/fapi/v1/time", secTypeNone, func(c *call) (interface{}, *apiError) {
+		return map[string]int64{"serverTime": c.now}, nil
+	})
+	s.route(http.MethodGet, "/fapi/v1/depth", secTypeNone, func(c *call) (interface{}, *apiError) {
+		return s.depth(c, MarketFutures)
+	})
+	s.route(http.MethodPost, "/fapi/v1/order", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketFutures)
+		if err != nil {
+			return nil, err
+		}
+		o, err := s.newOrder(c, sym)
+		if err != nil {
+			return nil, err
+		}
+		o.reduceOnly = c.params.Get("reduceOnly") == "true"
+		if err := s.futuresPosition(c, o); err != nil {
+			return nil, err
+		}
+		if err := s.checkMargin(o); err != nil {
+			return nil, err
+		}
+		if err := s.place(o, c.now); err != nil {
+			return nil, err
+		}
+		return futuresOrder(o), nil
+	})
+	s.route(http.MethodGet, "/fapi/v1/order", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketFutures)
+		if err != nil {
+			return nil, err
+		}
+		o, err := c.findOrder(sym)
+		if err != nil {
+			return nil, err
+		}
+		return futuresOrder(o), nil
+	})
+	s.route(http.MethodDelete, "/fapi/v1/order", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketFutures)
+		if err != nil {
+			return nil, err
+		}
+		o, err := c.findOrder(sym)
+		if err == errUnknownOrder {
+			return nil, errCancelUnknown
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := s.cancel(sym, o, c.now); err != nil {
+			return nil, err
+		}
+		return futuresOrder(o), nil
+	})
+	s.route(http.MethodGet, "/fapi/v1/openOrders", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		symbols, err := s.symbolsParam(c, MarketFutures)
+		if err != nil {
+			return nil, err
+		}
+		res := []map[string]interface{}{}
+		for _, o := range c.openOrders(symbols) {
+			res = append(res, futuresOrder(o))
+		}
+		return res, nil
+	})
+	s.route(http.MethodDelete, "/fapi/v1/allOpenOrders", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketFutures)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range c.openOrders([]*symbol{sym}) {
+			s.cancel(sym, o, c.now)
+		}
+		return map[string]interface{}{"code": 200, "msg": "The operation of cancel all open order is done."}, nil
+	})
+	s.route(http.MethodGet, "/fapi/v2/positionRisk", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		symbols, err := s.symbolsParam(c, MarketFutures)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(symbols, func(i, j int) bool { return symbols[i].name < symbols[j].name })
+		sides := []string{"BOTH"}
+		if c.account.dualSide {
+			sides = []string{"LONG", "SHORT"}
+		}
+		res := []map[string]interface{}{}
+		for _, sym := range symbols {
+			for _, side := range sides {
+				p, ok := c.account.positions[positionKey{sym.name, side}]
+				if !ok {
+					p = &position{}
+				}
+				markPrice := sym.markPrice(p)
+				res = append(res, map[string]interface{}{
+					"symbol": sym.name, "positionAmt": p.amount.String(), "entryPrice": p.entryPrice.String(),
+					"breakEvenPrice": p.entryPrice.String(), "markPrice": markPrice.String(),
+					"unRealizedProfit": markPrice.Sub(p.entryPrice).Mul(p.amount).String(),
+					"liquidationPrice": "0", "leverage": strconv.FormatInt(c.account.symbolLeverage(sym.name), 10),
+					"maxNotionalValue": "0", "marginType": "cross", "isolatedMargin": "0", "isAutoAddMargin": "false",
+					"positionSide": side, "notional": markPrice.Mul(p.amount).String(), "isolatedWallet": "0",
+					"updateTime": c.now,
+				})
+			}
+		}
+		return res, nil
+	})
+	s.route(http.MethodPost, "/fapi/v1/leverage", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketFutures)
+		if err != nil {
+			return nil, err
+		}
+		leverage, ok := c.int64("leverage")
+		if !ok || leverage < 1 || leverage > maxLeverage {
+			return nil, newAPIError(http.StatusBadRequest, -4028, "Leverage %s is not valid", c.params.Get("leverage"))
+		}
+		c.account.leverage[sym.name] = leverage
+		return map[string]interface{}{"leverage": leverage, "maxNotionalValue": "0", "symbol": sym.name}, nil
+	})
+	s.route(http.MethodGet, "/fapi/v1/positionSide/dual", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		return map[string]bool{"dualSidePosition": c.account.dualSide}, nil
+	})
+	s.route(http.MethodPost, "/fapi/v1/positionSide/dual", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		dualSide, err := c.required("dualSidePosition")
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range c.account.positions {
+			if !p.amount.IsZero() {
+				return nil, newAPIError(http.StatusBadRequest, -4068, "Position side cannot be changed if there exists position.")
+			}
+		}
+		if len(c.openOrders(s.futuresSymbols())) > 0 {
+			return nil, newAPIError(http.StatusBadRequest, -4067, "Position side cannot be changed if there exists open orders.")
+		}
+		c.account.dualSide = dualSide == "true"
+		return map[string]interface{}{"code": 200, "msg": "success"}, nil
+	})
+	s.route(http.MethodGet, "/fapi/v1/commissionRate", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketFutures)
+		if err != nil {
+			return nil, err
+		}
+		rates := c.account.fees[feeKey{MarketFutures, sym.name}]
+		return map[string]string{
+			"symbol": sym.name, "makerCommissionRate": rates.maker.String(), "takerCommissionRate": rates.taker.String(),
+		}, nil
+	})
+	s.route(http.MethodGet, "/fapi/v3/balance", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		assets := make([]string, 0, len(c.account.wallets))
+		for asset := range c.account.wallets {
+			assets = append(assets, asset)
+		}
+		sort.Strings(assets)
+		res := []map[string]interface{}{}
+		for _, asset := range assets {
+			wallet := c.account.wallets[asset].String()
+			res = append(res, map[string]interface{}{
+				"accountAlias": "fake", "asset": asset, "balance": wallet, "crossWalletBalance": wallet,
+				"crossUnPnl": c.account.unrealized(asset).String(), "availableBalance": c.account.availableMargin(asset).String(),
+				"maxWithdrawAmount": c.account.availableMargin(asset).String(), "marginAvailable": true, "updateTime": c.now,
+			})
+		}
+		return res, nil
+	})
+	s.listenKeyRoutes("/fapi/v1/listenKey", MarketFutures)
+}
+
+func futuresOrder(o *order) map[string]interface{} {
+	return map[string]interface{}{
+		"symbol": o.sym.name, "orderId": o.id, "clientOrderId": o.clientID, "price": o.price.String(),
+		"avgPrice": o.avgPrice().String(), "origQty": o.qty.String(), "executedQty": o.executed.String(),
+		"cumQty": o.executed.String(), "cumQuote": o.cumQuote.String(), "status": o.status,
+		"timeInForce": o.tif, "type": o.typ, "origType": o.typ, "side": o.side, "reduceOnly": o.reduceOnly,
+		"closePosition": false, "positionSide": o.positionSide, "stopPrice": "0", "workingType": "CONTRACT_PRICE",
+		"priceProtect": false, "priceMatch": "NONE", "selfTradePreventionMode": "NONE",
+		"time": o.time, "updateTime": o.updateTime,
+	}
+}
+
+// futuresPosition check the positionSide parameter against the position mode
+func (s *Server) futuresPosition(c *call, o *order) *apiError {
+	o.positionSide = c.params.Get("positionSide")
+	if o.positionSide == "" {
+		o.positionSide = "BOTH"
+	}
+	if c.account.dualSide != (o.positionSide != "BOTH") || (o.positionSide != "BOTH" && o.positionSide != "LONG" && o.positionSide != "SHORT") {
+		return newAPIError(http.StatusBadRequest, -4061, "Order's position side does not match user's setting.")
+	}
+	if c.account.dualSide && o.reduceOnly {
+		return newAPIError(http.StatusBadRequest, -1106, "Parameter 'reduceonly' sent when not required.")
+	}
+	return nil
+}
+
+// checkMargin reject the orders opening more than the available margin allows
+func (s *Server) checkMargin(o *order) *apiError {
+	if o.reduceOnly {
+		return nil
+	}
+	price := o.price
+	if o.typ == "MARKET" {
+		book := o.sym.opposite(o)
+		if len(book) == 0 {
+			return nil
+		}
+		price = book[0].price
+	}
+	opening := o.qty
+	if p, ok := o.account.positions[positionKey{o.sym.name, o.positionSide}]; ok && !p.amount.IsZero() && p.amount.IsPositive() != (o.side == sideBuy) {
+		opening = decimal.Max(decimal.Zero, o.qty.Sub(p.amount.Abs()))
+	}
+	required := opening.Mul(price).Div(decimal.NewFromInt(o.account.symbolLeverage(o.sym.name)))
+	if required.GreaterThan(o.account.availableMargin(o.sym.quote)) {
+		return newAPIError(http.StatusBadRequest, -2019, "Margin is insufficient.")
+	}
+	return nil
+}
+
+func (s *Server) futuresSymbols() []*symbol {
+	var res []*symbol
+	for _, sym := range s.symbols[MarketFutures] {
+		res = append(res, sym)
+	}
+	return res
+}
+
+// markPrice return the last trade price, or the entry price of p before any trade
+func (sym *symbol) markPrice(p *position) decimal.Decimal {
+	if sym.lastPrice.IsZero() {
+		return p.entryPrice
+	}
+	return sym.lastPrice
+}
+
+// unrealized return the profit of the open positions margined in asset
+func (a *Account) unrealized(asset string) decimal.Decimal {
+	res := decimal.Zero
+	for k, p := range a.positions {
+		sym, ok := a.s.symbols[MarketFutures][k.symbol]
+		if !ok || sym.quote != asset {
+			continue
+		}
+		res = res.Add(sym.markPrice(p).Sub(p.entryPrice).Mul(p.amount))
+	}
+	return res
+}
+
+// availableMargin return the wallet balance of asset not used as the initial
+// margin of the positions and open orders
+func (a *Account) availableMargin(asset string) decimal.Decimal {
+	res := a.wallets[asset]
+	for k, p := range a.positions {
+		if sym, ok := a.s.symbols[MarketFutures][k.symbol]; ok && sym.quote == asset {
+			res = res.Sub(p.amount.Abs().Mul(p.entryPrice).Div(decimal.NewFromInt(a.symbolLeverage(k.symbol))))
+		}
+	}
+	for _, sym := range a.s.symbols[MarketFutures] {
+		if sym.quote != asset {
+			continue
+		}
+		for _, o := range sym.orders {
+			if o.account == a && o.isOpen() {
+				res = res.Sub(o.remaining().Mul(o.price).Div(decimal.NewFromInt(a.symbolLeverage(sym.name))))
+			}
+		}
+	}
+	return res
+}
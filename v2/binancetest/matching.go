@@ -0,0 +1,596 @@
+package binancetest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Order statuses
+const (
+	statusNew             = "NEW"
+	statusPartiallyFilled = "PARTIALLY_FILLED"
+	statusFilled          = "FILLED"
+	statusCanceled        = "CANCELED"
+	statusExpired         = "EXPIRED"
+)
+
+const (
+	sideBuy  = "BUY"
+	sideSell = "SELL"
+)
+
+// symbol define a market of the fake exchange and its order book
+type symbol struct {
+	market Market
+	name   string
+	base   string
+	quote  string
+
+	// resting orders, best price first then oldest first
+	bids []*order
+	asks []*order
+
+	orders      map[int64]*order
+	fills       []*fill
+	nextTradeID int64
+	updateID    int64
+	lastPrice   decimal.Decimal
+}
+
+type order struct {
+	sym      *symbol
+	account  *Account
+	id       int64
+	clientID string
+	side     string
+	typ      string
+	tif      string
+	price    decimal.Decimal
+	qty      decimal.Decimal
+	// quoteQty is the quote amount of a market order placed with quoteOrderQty
+	quoteQty     decimal.Decimal
+	executed     decimal.Decimal
+	cumQuote     decimal.Decimal
+	reserved     decimal.Decimal
+	status       string
+	reduceOnly   bool
+	positionSide string
+	time         int64
+	updateTime   int64
+}
+
+// fill define the side of a trade belonging to an account order
+type fill struct {
+	tradeID         int64
+	order           *order
+	price           decimal.Decimal
+	qty             decimal.Decimal
+	quote           decimal.Decimal
+	isMaker         bool
+	time            int64
+	commission      decimal.Decimal
+	commissionAsset string
+	// realized is the futures profit of the position the fill reduced
+	realized decimal.Decimal
+}
+
+// AddSymbol list a symbol on a market
+func (s *Server) AddSymbol(market Market, name, base, quote string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.symbols[market] == nil {
+		s.symbols[market] = make(map[string]*symbol)
+	}
+	s.symbols[market][name] = &symbol{
+		market:      market,
+		name:        name,
+		base:        base,
+		quote:       quote,
+		orders:      make(map[int64]*order),
+		nextTradeID: 1,
+	}
+}
+
+// SetOrderBook replace the liquidity not owned by any account, levels are
+// [price, quantity] pairs. The orders of the accounts stay in the book, those
+// the new liquidity crosses are filled at their price as makers, as if the
+// market traded through them.
+func (s *Server) SetOrderBook(market Market, name string, bids, asks [][2]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sym, ok := s.symbols[market][name]
+	if !ok {
+		return fmt.Errorf("unknown %s symbol %s", market, name)
+	}
+	keep := func(orders []*order) []*order {
+		var res []*order
+		for _, o := range orders {
+			if o.account != nil {
+				res = append(res, o)
+			}
+		}
+		return res
+	}
+	sym.bids, sym.asks = keep(sym.bids), keep(sym.asks)
+	now := s.Now().UnixMilli()
+	for _, levels := range []struct {
+		side   string
+		levels [][2]string
+	}{{sideBuy, bids}, {sideSell, asks}} {
+		for _, l := range levels.levels {
+			price, err := decimal.NewFromString(l[0])
+			if err != nil {
+				return err
+			}
+			qty, err := decimal.NewFromString(l[1])
+			if err != nil {
+				return err
+			}
+			s.nextID++
+			sym.rest(&order{sym: sym, id: s.nextID, side: levels.side, typ: "LIMIT", tif: "GTC", price: price, qty: qty, status: statusNew, time: now})
+		}
+	}
+	s.uncross(sym, now)
+	sym.updateID++
+	s.accountEvents(sym, now)
+	return nil
+}
+
+// uncross fill the resting orders of the accounts crossed by the liquidity
+// of no account
+func (s *Server) uncross(sym *symbol, now int64) {
+	for len(sym.bids) > 0 && len(sym.asks) > 0 {
+		bid, ask := sym.bids[0], sym.asks[0]
+		if bid.price.LessThan(ask.price) {
+			return
+		}
+		maker, taker := bid, ask
+		if bid.account == nil {
+			maker, taker = ask, bid
+		}
+		if maker.account == nil || taker.account != nil || !s.trade(taker, maker, now) {
+			return
+		}
+	}
+}
+
+func (o *order) remaining() decimal.Decimal {
+	return o.qty.Sub(o.executed)
+}
+
+func (o *order) isOpen() bool {
+	return o.status == statusNew || o.status == statusPartiallyFilled
+}
+
+func (o *order) avgPrice() decimal.Decimal {
+	if o.executed.IsZero() {
+		return decimal.Zero
+	}
+	return o.cumQuote.DivRound(o.executed, 8)
+}
+
+// crosses tell whether o can trade against a resting order at price
+func (o *order) crosses(price decimal.Decimal) bool {
+	if o.typ == "MARKET" {
+		return true
+	}
+	if o.side == sideBuy {
+		return o.price.GreaterThanOrEqual(price)
+	}
+	return o.price.LessThanOrEqual(price)
+}
+
+// rest add an order to its side of the book
+func (sym *symbol) rest(o *order) {
+	sym.orders[o.id] = o
+	book := &sym.asks
+	better := func(a, b *order) bool { return a.price.LessThan(b.price) }
+	if o.side == sideBuy {
+		book = &sym.bids
+		better = func(a, b *order) bool { return a.price.GreaterThan(b.price) }
+	}
+	i := sort.Search(len(*book), func(i int) bool { return better(o, (*book)[i]) })
+	*book = append(*book, nil)
+	copy((*book)[i+1:], (*book)[i:])
+	(*book)[i] = o
+}
+
+// unrest remove an order from the book
+func (sym *symbol) unrest(o *order) {
+	for _, book := range []*[]*order{&sym.bids, &sym.asks} {
+		for i, r := range *book {
+			if r == o {
+				*book = append((*book)[:i], (*book)[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// opposite return the resting orders o would trade against
+func (sym *symbol) opposite(o *order) []*order {
+	if o.side == sideBuy {
+		return sym.asks
+	}
+	return sym.bids
+}
+
+// simulate return the quantity and quote amount o would fill right now
+func (sym *symbol) simulate(o *order) (qty, quote decimal.Decimal) {
+	for _, m := range sym.opposite(o) {
+		if !o.crosses(m.price) {
+			break
+		}
+		q := m.remaining()
+		if o.quoteQty.IsPositive() {
+			q = decimal.Min(q, o.quoteQty.Sub(quote).Div(m.price).Truncate(8))
+		} else {
+			q = decimal.Min(q, o.qty.Sub(qty))
+		}
+		if !q.IsPositive() {
+			break
+		}
+		qty = qty.Add(q)
+		quote = quote.Add(q.Mul(m.price))
+	}
+	return qty, quote
+}
+
+// levels aggregate a side of the book by price
+func levels(orders []*order, limit int) [][2]string {
+	var res [][2]string
+	var price, qty decimal.Decimal
+	for _, o := range orders {
+		if len(res) > 0 && o.price.Equal(price) {
+			qty = qty.Add(o.remaining())
+			res[len(res)-1][1] = qty.String()
+			continue
+		}
+		if len(res) == limit {
+			break
+		}
+		price, qty = o.price, o.remaining()
+		res = append(res, [2]string{price.String(), qty.String()})
+	}
+	if res == nil {
+		res = [][2]string{}
+	}
+	return res
+}
+
+// place validate, reserve and match a new order, the lock must be held
+func (s *Server) place(o *order, now int64) *apiError {
+	sym := o.sym
+	if o.reduceOnly {
+		p := o.account.positions[positionKey{sym.name, o.positionSide}]
+		if p == nil || p.amount.IsZero() || (p.amount.IsPositive() == (o.side == sideBuy)) {
+			return newAPIError(http.StatusBadRequest, -2022, "ReduceOnly Order is rejected.")
+		}
+	}
+	crosses := false
+	if book := sym.opposite(o); len(book) > 0 {
+		crosses = o.crosses(book[0].price)
+	}
+	if o.typ == "LIMIT_MAKER" && crosses {
+		return newAPIError(http.StatusBadRequest, -2010, "Order would immediately match and take.")
+	}
+	if o.quoteQty.IsPositive() {
+		// the quantity of a quote order is what the book can fill
+		o.qty, _ = sym.simulate(o)
+	}
+	if sym.market == MarketSpot {
+		if err := s.reserve(o); err != nil {
+			return err
+		}
+	}
+	s.nextID++
+	o.id = s.nextID
+	if o.clientID == "" {
+		o.clientID = fmt.Sprintf("fake-%d", o.id)
+	}
+	o.status = statusNew
+	o.time, o.updateTime = now, now
+	sym.orders[o.id] = o
+	s.orderEvent(o, nil, now)
+
+	if o.tif == "GTX" && crosses {
+		// futures post only orders expire instead of taking
+		s.finish(o, statusExpired, now)
+		return nil
+	}
+	if o.tif == "FOK" {
+		if qty, _ := sym.simulate(o); qty.LessThan(o.qty) {
+			s.finish(o, statusExpired, now)
+			return nil
+		}
+	}
+	s.match(o, now)
+	switch {
+	case !o.isOpen():
+	case o.typ == "MARKET" || o.tif == "IOC" || o.tif == "FOK":
+		s.finish(o, statusExpired, now)
+	default:
+		sym.rest(o)
+	}
+	sym.updateID++
+	s.accountEvents(o.sym, now)
+	return nil
+}
+
+// reserve lock the balance a spot order may spend
+func (s *Server) reserve(o *order) *apiError {
+	asset, amount := o.sym.base, o.qty
+	switch {
+	case o.side == sideBuy && o.typ == "MARKET":
+		_, quote := o.sym.simulate(o)
+		asset, amount = o.sym.quote, quote
+	case o.side == sideBuy:
+		asset, amount = o.sym.quote, o.price.Mul(o.qty)
+	}
+	b := o.account.balance(asset)
+	if b.free.LessThan(amount) {
+		return errInsufficient
+	}
+	b.free = b.free.Sub(amount)
+	b.locked = b.locked.Add(amount)
+	o.reserved = amount
+	s.touched[o.account] = true
+	return nil
+}
+
+// match trade o against the book
+func (s *Server) match(o *order, now int64) {
+	for {
+		book := o.sym.opposite(o)
+		if len(book) == 0 || !o.crosses(book[0].price) || !s.trade(o, book[0], now) {
+			return
+		}
+	}
+}
+
+// trade fill a taker order against a resting maker order at the maker price,
+// it return false when nothing could be traded
+func (s *Server) trade(taker, maker *order, now int64) bool {
+	sym := taker.sym
+	qty := decimal.Min(taker.remaining(), maker.remaining())
+	if !qty.IsPositive() {
+		return false
+	}
+	tradeID := sym.nextTradeID
+	sym.nextTradeID++
+	price := maker.price
+	sym.lastPrice = price
+	for _, side := range []struct {
+		o       *order
+		isMaker bool
+	}{{maker, true}, {taker, false}} {
+		f := &fill{tradeID: tradeID, order: side.o, price: price, qty: qty, quote: qty.Mul(price), isMaker: side.isMaker, time: now}
+		side.o.executed = side.o.executed.Add(qty)
+		side.o.cumQuote = side.o.cumQuote.Add(f.quote)
+		side.o.updateTime = now
+		side.o.status = statusPartiallyFilled
+		if !side.o.remaining().IsPositive() {
+			side.o.status = statusFilled
+			sym.unrest(side.o)
+		}
+		if side.o.account == nil {
+			continue
+		}
+		sym.fills = append(sym.fills, f)
+		s.settle(f)
+		s.orderEvent(side.o, f, now)
+		if side.o.status == statusFilled {
+			s.release(side.o)
+		}
+	}
+	s.publishTrade(sym, tradeID, price, qty, taker.side == sideSell, now)
+	return true
+}
+
+// settle move the balances or the position of a fill and charge its commission
+func (s *Server) settle(f *fill) {
+	o, a, sym := f.order, f.order.account, f.order.sym
+	rates := a.fees[feeKey{sym.market, sym.name}]
+	rate := rates.taker
+	if f.isMaker {
+		rate = rates.maker
+	}
+	if sym.market == MarketFutures {
+		p := a.position(sym.name, o.positionSide)
+		qty := f.qty
+		if o.side == sideSell {
+			qty = qty.Neg()
+		}
+		amount := p.amount.Add(qty)
+		switch {
+		case p.amount.IsZero() || p.amount.IsPositive() == qty.IsPositive():
+			// the position grows
+			p.entryPrice = p.amount.Abs().Mul(p.entryPrice).Add(f.quote).DivRound(amount.Abs(), 8)
+		default:
+			closed := decimal.Min(qty.Abs(), p.amount.Abs())
+			f.realized = f.price.Sub(p.entryPrice).Mul(closed)
+			if p.amount.IsNegative() {
+				f.realized = f.realized.Neg()
+			}
+			if !amount.IsZero() && amount.IsPositive() != p.amount.IsPositive() {
+				// the position flips
+				p.entryPrice = f.price
+			}
+		}
+		p.amount = amount
+		if p.amount.IsZero() {
+			p.entryPrice = decimal.Zero
+		}
+		p.realized = p.realized.Add(f.realized)
+		f.commission, f.commissionAsset = f.quote.Mul(rate), sym.quote
+		a.wallets[sym.quote] = a.wallets[sym.quote].Add(f.realized).Sub(f.commission)
+		return
+	}
+	s.touched[a] = true
+	base, quote := a.balance(sym.base), a.balance(sym.quote)
+	if o.side == sideBuy {
+		spent := f.quote
+		if o.typ != "MARKET" {
+			spent = f.qty.Mul(o.price)
+		}
+		// the commission is taken from the asset received
+		f.commission, f.commissionAsset = f.qty.Mul(rate), sym.base
+		quote.locked = quote.locked.Sub(spent)
+		quote.free = quote.free.Add(spent.Sub(f.quote))
+		o.reserved = o.reserved.Sub(spent)
+		base.free = base.free.Add(f.qty).Sub(f.commission)
+		return
+	}
+	f.commission, f.commissionAsset = f.quote.Mul(rate), sym.quote
+	base.locked = base.locked.Sub(f.qty)
+	o.reserved = o.reserved.Sub(f.qty)
+	quote.free = quote.free.Add(f.quote).Sub(f.commission)
+}
+
+// release unlock what is left of the reservation of an order
+func (s *Server) release(o *order) {
+	if o.account == nil || !o.reserved.IsPositive() {
+		return
+	}
+	asset := o.sym.base
+	if o.side == sideBuy {
+		asset = o.sym.quote
+	}
+	b := o.account.balance(asset)
+	b.locked = b.locked.Sub(o.reserved)
+	b.free = b.free.Add(o.reserved)
+	o.reserved = decimal.Zero
+	s.touched[o.account] = true
+}
+
+// finish end an open order
+func (s *Server) finish(o *order, status string, now int64) {
+	o.status = status
+	o.updateTime = now
+	o.sym.unrest(o)
+	s.release(o)
+	s.orderEvent(o, nil, now)
+}
+
+// cancel cancel an open order of the account
+func (s *Server) cancel(sym *symbol, o *order, now int64) *apiError {
+	if !o.isOpen() {
+		return errCancelUnknown
+	}
+	s.finish(o, statusCanceled, now)
+	sym.updateID++
+	s.accountEvents(sym, now)
+	return nil
+}
+
+// findOrder look an order of the account up by orderId or origClientOrderId
+func (c *call) findOrder(sym *symbol) (*order, *apiError) {
+	if id, ok := c.int64("orderId"); ok {
+		if o, ok := sym.orders[id]; ok && o.account == c.account {
+			return o, nil
+		}
+		return nil, errUnknownOrder
+	}
+	clientID := c.params.Get("origClientOrderId")
+	if clientID == "" {
+		return nil, newAPIError(http.StatusBadRequest, -1102, "Param 'origClientOrderId' or 'orderId' must be sent, but both were empty/null!")
+	}
+	// the latest order wins when a client id was reused
+	var found *order
+	for _, o := range sym.orders {
+		if o.account == c.account && o.clientID == clientID && (found == nil || o.id > found.id) {
+			found = o
+		}
+	}
+	if found == nil {
+		return nil, errUnknownOrder
+	}
+	return found, nil
+}
+
+// openOrders return the open orders of the account in id order
+func (c *call) openOrders(symbols []*symbol) []*order {
+	var res []*order
+	for _, sym := range symbols {
+		for _, o := range sym.orders {
+			if o.account == c.account && o.isOpen() {
+				res = append(res, o)
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].id < res[j].id })
+	return res
+}
+
+// symbol return the symbol named by the symbol parameter
+func (s *Server) symbol(c *call, market Market) (*symbol, *apiError) {
+	name, err := c.required("symbol")
+	if err != nil {
+		return nil, err
+	}
+	sym, ok := s.symbols[market][name]
+	if !ok {
+		return nil, errInvalidSymbol
+	}
+	return sym, nil
+}
+
+// newOrder read the common parameters of a new order
+func (s *Server) newOrder(c *call, sym *symbol) (*order, *apiError) {
+	o := &order{sym: sym, account: c.account, clientID: c.params.Get("newClientOrderId")}
+	var err *apiError
+	if o.side, err = c.required("side"); err != nil {
+		return nil, err
+	}
+	if o.side != sideBuy && o.side != sideSell {
+		return nil, newAPIError(http.StatusBadRequest, -1117, "Invalid side.")
+	}
+	if o.typ, err = c.required("type"); err != nil {
+		return nil, err
+	}
+	var ok bool
+	if o.qty, ok, err = c.decimal("quantity"); err != nil {
+		return nil, err
+	}
+	if !ok && o.typ == "MARKET" && sym.market == MarketSpot {
+		if o.quoteQty, ok, err = c.decimal("quoteOrderQty"); err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, newAPIError(http.StatusBadRequest, -1102, "Mandatory parameter 'quantity' was not sent, was empty/null, or malformed.")
+	}
+	switch o.typ {
+	case "MARKET":
+	case "LIMIT", "LIMIT_MAKER":
+		if o.price, ok, err = c.decimal("price"); err != nil {
+			return nil, err
+		}
+		if !ok || !o.price.IsPositive() {
+			return nil, newAPIError(http.StatusBadRequest, -1102, "Mandatory parameter 'price' was not sent, was empty/null, or malformed.")
+		}
+		if o.typ == "LIMIT" {
+			if o.tif, err = c.required("timeInForce"); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, newAPIError(http.StatusBadRequest, -1116, "Invalid orderType.")
+	}
+	switch o.tif {
+	case "", "GTC", "IOC", "FOK":
+	case "GTX":
+		if sym.market != MarketFutures {
+			return nil, newAPIError(http.StatusBadRequest, -1115, "Invalid timeInForce.")
+		}
+	default:
+		return nil, newAPIError(http.StatusBadRequest, -1115, "Invalid timeInForce.")
+	}
+	if o.quoteQty.IsZero() && !o.qty.IsPositive() {
+		return nil, newAPIError(http.StatusBadRequest, -1013, "Invalid quantity.")
+	}
+	return o, nil
+}
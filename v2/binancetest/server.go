@@ -0,0 +1,583 @@
+// Package binancetest provides an in-process fake Binance exchange for
+// integration tests. It serves the REST API over httptest and the market and
+// user data streams over websocket, so that requests go through the real
+// signing, query encoding and websocket framing of the clients.
+//
+// The spot and USD-M futures order endpoints are simulated with simple order
+// books, other endpoints answer a -1000 error. Futures positions are cross
+// margined on a futures wallet, at the leverage and in the position mode of
+// the account.
+//
+// Only the spot and USD-M futures clients are served over REST, see
+// NewClient and NewFuturesClient. The COIN-M futures market only serves the
+// streams with the events passed to Publish, and the options and portfolio
+// margin APIs aren't simulated.
+package binancetest
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// Market define a trading venue of the fake exchange
+type Market string
+
+// Global enums
+const (
+	MarketSpot    Market = "SPOT"
+	MarketFutures Market = "FUTURES"
+	// MarketDelivery only serves the COIN-M futures streams, with the events passed to Publish
+	MarketDelivery Market = "DELIVERY"
+)
+
+const (
+	defaultRecvWindow = int64(5000)
+	maxRecvWindow     = int64(60000)
+	// maxClockAhead is how far in the future a request timestamp may be
+	maxClockAhead   = int64(1000)
+	defaultLeverage = int64(20)
+	maxLeverage     = int64(125)
+)
+
+type secType int
+
+const (
+	secTypeNone secType = iota
+	secTypeAPIKey
+	secTypeSigned
+)
+
+// apiError define an error answered with its http status
+type apiError struct {
+	status int
+	code   int64
+	msg    string
+}
+
+func newAPIError(status int, code int64, format string, a ...interface{}) *apiError {
+	return &apiError{status: status, code: code, msg: fmt.Sprintf(format, a...)}
+}
+
+var (
+	errUnknownOrder  = newAPIError(http.StatusBadRequest, -2013, "Order does not exist.")
+	errCancelUnknown = newAPIError(http.StatusBadRequest, -2011, "Unknown order sent.")
+	errInsufficient  = newAPIError(http.StatusBadRequest, -2010, "Account has insufficient balance for requested action.")
+	errInvalidSymbol = newAPIError(http.StatusBadRequest, -1121, "Invalid symbol.")
+	errBadSignature  = newAPIError(http.StatusBadRequest, -1022, "Signature for this request is not valid.")
+	errInvalidAPIKey = newAPIError(http.StatusUnauthorized, -2015, "Invalid API-key, IP, or permissions for action.")
+	errAPIKeyFormat  = newAPIError(http.StatusUnauthorized, -2014, "API-key format invalid.")
+	errListenKey     = newAPIError(http.StatusBadRequest, -1125, "This listenKey does not exist.")
+)
+
+// call define a request being served
+type call struct {
+	account *Account
+	params  url.Values
+	now     int64
+}
+
+type endpoint struct {
+	sec    secType
+	handle func(c *call) (interface{}, *apiError)
+}
+
+// Account define a user of the fake exchange
+type Account struct {
+	s         *Server
+	apiKey    string
+	keyType   string
+	secretKey string
+	publicKey crypto.PublicKey
+
+	balances  map[string]*balance
+	positions map[positionKey]*position
+	// wallets are the futures wallet balances
+	wallets  map[string]decimal.Decimal
+	leverage map[string]int64
+	dualSide bool
+	fees     map[feeKey]fee
+}
+
+type feeKey struct {
+	market Market
+	symbol string
+}
+
+// fee define the maker and taker commission rates of a symbol
+type fee struct {
+	maker decimal.Decimal
+	taker decimal.Decimal
+}
+
+type positionKey struct {
+	symbol string
+	side   string
+}
+
+type balance struct {
+	free   decimal.Decimal
+	locked decimal.Decimal
+}
+
+type position struct {
+	amount     decimal.Decimal
+	entryPrice decimal.Decimal
+	realized   decimal.Decimal
+}
+
+// listenKey define a user data stream
+type listenKey struct {
+	account *Account
+	market  Market
+}
+
+// Server define a fake exchange
+type Server struct {
+	// Now return the server time, requests are checked against it
+	Now func() time.Time
+
+	mu         sync.Mutex
+	ts         *httptest.Server
+	routes     map[string]endpoint
+	accounts   map[string]*Account
+	symbols    map[Market]map[string]*symbol
+	listenKeys map[string]*listenKey
+	conns      map[*wsConn]bool
+	upstreams  map[Market]string
+	proxied    map[*websocket.Conn]bool
+	nextID     int64
+	// touched are the accounts whose spot balances changed since the last account events
+	touched map[*Account]bool
+}
+
+// NewServer start a fake exchange, close it with Close
+func NewServer() *Server {
+	s := &Server{
+		Now:        time.Now,
+		accounts:   make(map[string]*Account),
+		symbols:    map[Market]map[string]*symbol{MarketSpot: {}, MarketFutures: {}},
+		listenKeys: make(map[string]*listenKey),
+		conns:      make(map[*wsConn]bool),
+		upstreams:  make(map[Market]string),
+		proxied:    make(map[*websocket.Conn]bool),
+		touched:    make(map[*Account]bool),
+	}
+	s.routes = make(map[string]endpoint)
+	s.spotRoutes()
+	s.futuresRoutes()
+	s.ts = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close stop the server and close the websocket connections
+func (s *Server) Close() {
+	s.mu.Lock()
+	for c := range s.conns {
+		c.close()
+	}
+	for conn := range s.proxied {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.ts.Close()
+}
+
+// URL return the base url of the REST API, to be set as the BaseURL of the
+// spot and USD-M futures clients
+func (s *Server) URL() string {
+	return s.ts.URL
+}
+
+// WsURL return the websocket endpoint of a market, the equivalent of
+// binance.BaseWsMainURL or futures.BaseWsMainUrl
+func (s *Server) WsURL(market Market) string {
+	return "ws" + strings.TrimPrefix(s.ts.URL, "http") + wsPrefix(market) + "/ws"
+}
+
+// CombinedURL return the combined stream endpoint of a market, the
+// equivalent of binance.BaseCombinedMainURL
+func (s *Server) CombinedURL(market Market) string {
+	return "ws" + strings.TrimPrefix(s.ts.URL, "http") + wsPrefix(market) + "/stream?streams="
+}
+
+// UseWsEndpoints point the websocket endpoint variables of the spot, USD-M
+// and COIN-M futures clients to the server, the returned function restores them
+func (s *Server) UseWsEndpoints() (restore func()) {
+	vars := []struct {
+		v     *string
+		value string
+	}{
+		{&binance.BaseWsMainURL, s.WsURL(MarketSpot)},
+		{&binance.BaseCombinedMainURL, s.CombinedURL(MarketSpot)},
+		{&futures.BaseWsMainUrl, s.WsURL(MarketFutures)},
+		{&futures.BaseCombinedMainURL, s.CombinedURL(MarketFutures)},
+		{&delivery.BaseWsMainUrl, s.WsURL(MarketDelivery)},
+	}
+	saved := make([]string, len(vars))
+	for i, v := range vars {
+		saved[i] = *v.v
+		*v.v = v.value
+	}
+	return func() {
+		for i, v := range vars {
+			*v.v = saved[i]
+		}
+	}
+}
+
+// NewClient return a spot client of the account pointing at the server
+func (s *Server) NewClient(a *Account) *binance.Client {
+	c := binance.NewClient(a.apiKey, a.secretKey)
+	c.KeyType = a.keyType
+	c.BaseURL = s.URL()
+	return c
+}
+
+// NewFuturesClient return a USD-M futures client of the account pointing at the server
+func (s *Server) NewFuturesClient(a *Account) *futures.Client {
+	c := futures.NewClient(a.apiKey, a.secretKey)
+	c.KeyType = a.keyType
+	c.BaseURL = s.URL()
+	return c
+}
+
+// AddAccount register an account with a HMAC secret key
+func (s *Server) AddAccount(apiKey, secretKey string) *Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.newAccount(apiKey, common.KeyTypeHmac)
+	a.secretKey = secretKey
+	return a
+}
+
+// AddKeyPairAccount register an account with a RSA or Ed25519 key, privateKey
+// is the PKCS8 PEM the clients sign with, the server only keeps its public key
+func (s *Server) AddKeyPairAccount(apiKey, keyType, privateKey string) (*Account, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid pem private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	var publicKey crypto.PublicKey
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if keyType != common.KeyTypeRsa {
+			return nil, fmt.Errorf("unexpected RSA key for keyType=%s", keyType)
+		}
+		publicKey = &k.PublicKey
+	case ed25519.PrivateKey:
+		if keyType != common.KeyTypeEd25519 {
+			return nil, fmt.Errorf("unexpected Ed25519 key for keyType=%s", keyType)
+		}
+		publicKey = k.Public()
+	default:
+		return nil, fmt.Errorf("unsupported private key %T", key)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.newAccount(apiKey, keyType)
+	// the clients need the private key, only publicKey is used to verify
+	a.secretKey = privateKey
+	a.publicKey = publicKey
+	return a, nil
+}
+
+func (s *Server) newAccount(apiKey, keyType string) *Account {
+	a := &Account{
+		s:         s,
+		apiKey:    apiKey,
+		keyType:   keyType,
+		balances:  make(map[string]*balance),
+		positions: make(map[positionKey]*position),
+		wallets:   make(map[string]decimal.Decimal),
+		leverage:  make(map[string]int64),
+		fees:      make(map[feeKey]fee),
+	}
+	s.accounts[apiKey] = a
+	return a
+}
+
+// SetBalance set the free spot balance of an asset
+func (a *Account) SetBalance(asset, free string) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	a.balance(asset).free = decimal.RequireFromString(free)
+}
+
+// Balance return the free and locked spot balance of an asset
+func (a *Account) Balance(asset string) (free, locked string) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	b := a.balance(asset)
+	return b.free.String(), b.locked.String()
+}
+
+// SetFuturesBalance set the futures wallet balance of an asset
+func (a *Account) SetFuturesBalance(asset, amount string) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	a.wallets[asset] = decimal.RequireFromString(amount)
+}
+
+// FuturesBalance return the futures wallet balance of an asset, realized
+// profits and commissions included
+func (a *Account) FuturesBalance(asset string) string {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	return a.wallets[asset].String()
+}
+
+// SetCommission set the maker and taker commission rates of a symbol, e.g.
+// "0.001" for 0.1%, they are zero by default
+func (a *Account) SetCommission(market Market, symbol, maker, taker string) {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	a.fees[feeKey{market, symbol}] = fee{maker: decimal.RequireFromString(maker), taker: decimal.RequireFromString(taker)}
+}
+
+// Position return the net futures position amount of a symbol, negative when
+// short, of both sides in hedge mode
+func (a *Account) Position(symbol string) string {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+	amount := decimal.Zero
+	for k, p := range a.positions {
+		if k.symbol == symbol {
+			amount = amount.Add(p.amount)
+		}
+	}
+	return amount.String()
+}
+
+func (a *Account) position(symbol, side string) *position {
+	k := positionKey{symbol, side}
+	p, ok := a.positions[k]
+	if !ok {
+		p = &position{}
+		a.positions[k] = p
+	}
+	return p
+}
+
+func (a *Account) symbolLeverage(symbol string) int64 {
+	if l, ok := a.leverage[symbol]; ok {
+		return l
+	}
+	return defaultLeverage
+}
+
+func (a *Account) balance(asset string) *balance {
+	b, ok := a.balances[asset]
+	if !ok {
+		b = &balance{}
+		a.balances[asset] = b
+	}
+	return b
+}
+
+func (s *Server) route(method, path string, sec secType, handle func(c *call) (interface{}, *apiError)) {
+	s.routes[method+" "+path] = endpoint{sec: sec, handle: handle}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upgrade") == "websocket" {
+		s.serveWs(w, r)
+		return
+	}
+	res, apiErr := s.serveAPI(r)
+	w.Header().Set("Content-Type", "application/json")
+	if apiErr != nil {
+		w.WriteHeader(apiErr.status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": apiErr.code, "msg": apiErr.msg})
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s *Server) serveAPI(r *http.Request) (interface{}, *apiError) {
+	e, ok := s.routes[r.Method+" "+r.URL.Path]
+	if !ok {
+		return nil, newAPIError(http.StatusNotFound, -1000, "%s %s is not supported by the fake exchange", r.Method, r.URL.Path)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, -1000, "%v", err)
+	}
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, -1100, "Illegal characters found in a parameter.")
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, -1100, "Illegal characters found in a parameter.")
+	}
+	for k, v := range form {
+		params[k] = append(params[k], v...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &call{params: params, now: s.Now().UnixMilli()}
+	if e.sec != secTypeNone {
+		apiKey := r.Header.Get("X-MBX-APIKEY")
+		if apiKey == "" {
+			return nil, errAPIKeyFormat
+		}
+		if c.account, ok = s.accounts[apiKey]; !ok {
+			return nil, errInvalidAPIKey
+		}
+	}
+	if e.sec == secTypeSigned {
+		if apiErr := c.verify(r.URL.RawQuery, string(body)); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+	return e.handle(c)
+}
+
+// verify check the signature, timestamp and recvWindow of a signed request
+func (c *call) verify(rawQuery, body string) *apiError {
+	signature := c.params.Get("signature")
+	if signature == "" {
+		return newAPIError(http.StatusBadRequest, -1102, "Mandatory parameter 'signature' was not sent, was empty/null, or malformed.")
+	}
+	// the signature is the last query parameter, it signs everything before it
+	payload := rawQuery
+	if i := strings.Index(payload, "signature="); i >= 0 {
+		payload = strings.TrimSuffix(payload[:i], "&")
+	}
+	payload += body
+	if !c.account.verifySignature(payload, signature) {
+		return errBadSignature
+	}
+
+	timestamp, err := strconv.ParseInt(c.params.Get("timestamp"), 10, 64)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, -1102, "Mandatory parameter 'timestamp' was not sent, was empty/null, or malformed.")
+	}
+	recvWindow := defaultRecvWindow
+	if v := c.params.Get("recvWindow"); v != "" {
+		if recvWindow, err = strconv.ParseInt(v, 10, 64); err != nil || recvWindow <= 0 || recvWindow > maxRecvWindow {
+			return newAPIError(http.StatusBadRequest, -1131, "recvWindow must be less than %d.", maxRecvWindow)
+		}
+	}
+	if timestamp > c.now+maxClockAhead {
+		return newAPIError(http.StatusBadRequest, -1021, "Timestamp for this request was %dms ahead of the server's time.", maxClockAhead)
+	}
+	if c.now-timestamp > recvWindow {
+		return newAPIError(http.StatusBadRequest, -1021, "Timestamp for this request is outside of the recvWindow.")
+	}
+	return nil
+}
+
+func (a *Account) verifySignature(payload, signature string) bool {
+	switch a.keyType {
+	case common.KeyTypeHmac:
+		mac := hmac.New(sha256.New, []byte(a.secretKey))
+		mac.Write([]byte(payload))
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(expected), []byte(signature))
+	case common.KeyTypeRsa:
+		sig, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			return false
+		}
+		hashed := sha256.Sum256([]byte(payload))
+		return rsa.VerifyPKCS1v15(a.publicKey.(*rsa.PublicKey), crypto.SHA256, hashed[:], sig) == nil
+	case common.KeyTypeEd25519:
+		sig, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			return false
+		}
+		return ed25519.Verify(a.publicKey.(ed25519.PublicKey), []byte(payload), sig)
+	}
+	return false
+}
+
+func (c *call) required(name string) (string, *apiError) {
+	v := c.params.Get(name)
+	if v == "" {
+		return "", newAPIError(http.StatusBadRequest, -1102, "Mandatory parameter '%s' was not sent, was empty/null, or malformed.", name)
+	}
+	return v, nil
+}
+
+func (c *call) decimal(name string) (decimal.Decimal, bool, *apiError) {
+	v := c.params.Get(name)
+	if v == "" {
+		return decimal.Zero, false, nil
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil || d.IsNegative() {
+		return decimal.Zero, false, newAPIError(http.StatusBadRequest, -1100, "Illegal characters found in parameter '%s'; legal range is '^([0-9]{1,20})(\\.[0-9]{1,20})?$'.", name)
+	}
+	return d, true, nil
+}
+
+func (c *call) int64(name string) (int64, bool) {
+	v, err := strconv.ParseInt(c.params.Get(name), 10, 64)
+	return v, err == nil
+}
+
+// newListenKey open a user data stream, the lock must be held
+func (s *Server) newListenKey(a *Account, market Market) string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	key := hex.EncodeToString(b)
+	s.listenKeys[key] = &listenKey{account: a, market: market}
+	return key
+}
+
+func (s *Server) listenKeyRoutes(path string, market Market) {
+	s.route(http.MethodPost, path, secTypeAPIKey, func(c *call) (interface{}, *apiError) {
+		return map[string]string{"listenKey": s.newListenKey(c.account, market)}, nil
+	})
+	s.route(http.MethodPut, path, secTypeAPIKey, func(c *call) (interface{}, *apiError) {
+		if lk, ok := s.listenKeys[c.params.Get("listenKey")]; !ok || lk.account != c.account {
+			return nil, errListenKey
+		}
+		return struct{}{}, nil
+	})
+	s.route(http.MethodDelete, path, secTypeAPIKey, func(c *call) (interface{}, *apiError) {
+		key := c.params.Get("listenKey")
+		if lk, ok := s.listenKeys[key]; !ok || lk.account != c.account {
+			return nil, errListenKey
+		}
+		delete(s.listenKeys, key)
+		for conn := range s.conns {
+			if conn.streams[key] {
+				conn.close()
+			}
+		}
+		return struct{}{}, nil
+	})
+}
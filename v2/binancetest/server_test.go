@@ -0,0 +1,414 @@
+package binancetest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type serverTestSuite struct {
+	suite.Suite
+	server  *Server
+	restore func()
+}
+
+func TestServer(t *testing.T) {
+	suite.Run(t, new(serverTestSuite))
+}
+
+func (s *serverTestSuite) SetupTest() {
+	s.server = NewServer()
+	s.restore = s.server.UseWsEndpoints()
+	s.server.AddSymbol(MarketSpot, "BTCUSDT", "BTC", "USDT")
+	s.server.AddSymbol(MarketFutures, "BTCUSDT", "BTC", "USDT")
+}
+
+func (s *serverTestSuite) TearDownTest() {
+	s.restore()
+	s.server.Close()
+}
+
+func (s *serverTestSuite) apiErrorCode(err error) int64 {
+	s.Require().True(common.IsAPIError(err), "%v", err)
+	return err.(*common.APIError).Code
+}
+
+func (s *serverTestSuite) TestSpotTrading() {
+	r := s.Require()
+	ctx := context.Background()
+	seller := s.server.AddAccount("seller", "seller-secret")
+	seller.SetBalance("BTC", "2")
+	buyer := s.server.AddAccount("buyer", "buyer-secret")
+	buyer.SetBalance("USDT", "1000")
+	sellerClient, buyerClient := s.server.NewClient(seller), s.server.NewClient(buyer)
+
+	listenKey, err := buyerClient.NewStartUserStreamService().Do(ctx)
+	r.NoError(err)
+	eventC := make(chan *binance.WsUserDataEvent, 16)
+	doneC, stopC, err := binance.WsUserDataServe(listenKey, func(event *binance.WsUserDataEvent) {
+		eventC <- event
+	}, func(err error) {})
+	r.NoError(err)
+	tradeC := make(chan *binance.WsTradeEvent, 16)
+	tradeDoneC, tradeStopC, err := binance.WsTradeServe("BTCUSDT", func(event *binance.WsTradeEvent) {
+		tradeC <- event
+	}, func(err error) {})
+	r.NoError(err)
+
+	ask, err := sellerClient.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeSell).
+		Type(binance.OrderTypeLimit).TimeInForce(binance.TimeInForceTypeGTC).
+		Quantity("1.5").Price("100").Do(ctx)
+	r.NoError(err)
+	r.Equal(binance.OrderStatusTypeNew, ask.Status)
+	free, locked := seller.Balance("BTC")
+	r.Equal("0.5", free)
+	r.Equal("1.5", locked)
+
+	depth, err := buyerClient.NewDepthService().Symbol("BTCUSDT").Do(ctx)
+	r.NoError(err)
+	r.Len(depth.Asks, 1)
+	r.Equal("1.5", depth.Asks[0].Quantity)
+
+	bid, err := buyerClient.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeBuy).
+		Type(binance.OrderTypeMarket).Quantity("1").Do(ctx)
+	r.NoError(err)
+	r.Equal(binance.OrderStatusTypeFilled, bid.Status)
+	r.Len(bid.Fills, 1)
+	r.Equal("100", bid.Fills[0].Price)
+
+	account, err := buyerClient.NewGetAccountService().Do(ctx)
+	r.NoError(err)
+	r.Len(account.Balances, 2)
+	r.Equal(binance.Balance{Asset: "BTC", Free: "1", Locked: "0"}, account.Balances[0])
+	r.Equal(binance.Balance{Asset: "USDT", Free: "900", Locked: "0"}, account.Balances[1])
+
+	order, err := sellerClient.NewGetOrderService().Symbol("BTCUSDT").OrderID(ask.OrderID).Do(ctx)
+	r.NoError(err)
+	r.Equal(binance.OrderStatusTypePartiallyFilled, order.Status)
+	r.Equal("1", order.ExecutedQuantity)
+
+	trades, err := buyerClient.NewListTradesService().Symbol("BTCUSDT").Do(ctx)
+	r.NoError(err)
+	r.Len(trades, 1)
+	r.True(trades[0].IsBuyer)
+	r.False(trades[0].IsMaker)
+
+	_, err = buyerClient.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeBuy).
+		Type(binance.OrderTypeLimit).TimeInForce(binance.TimeInForceTypeGTC).
+		Quantity("10").Price("100").Do(ctx)
+	r.Equal(int64(-2010), s.apiErrorCode(err))
+
+	canceled, err := sellerClient.NewCancelOrderService().Symbol("BTCUSDT").OrderID(ask.OrderID).Do(ctx)
+	r.NoError(err)
+	r.Equal(binance.OrderStatusTypeCanceled, canceled.Status)
+	free, locked = seller.Balance("BTC")
+	r.Equal("1", free)
+	r.Equal("0", locked)
+	_, err = sellerClient.NewCancelOrderService().Symbol("BTCUSDT").OrderID(ask.OrderID).Do(ctx)
+	r.Equal(int64(-2011), s.apiErrorCode(err))
+
+	// the buyer gets its order updates then its balances
+	var executions []string
+	for len(executions) < 2 {
+		event := s.receive(eventC)
+		if event.Event == binance.UserDataEventTypeExecutionReport {
+			executions = append(executions, event.OrderUpdate.ExecutionType)
+		}
+	}
+	r.Equal([]string{"NEW", "TRADE"}, executions)
+	event := s.receive(eventC)
+	r.Equal(binance.UserDataEventTypeOutboundAccountPosition, event.Event)
+	r.Len(event.AccountUpdate.WsAccountUpdates, 2)
+
+	trade := <-tradeC
+	r.Equal("100", trade.Price)
+	r.Equal("1", trade.Quantity)
+	r.False(trade.IsBuyerMaker)
+
+	close(stopC)
+	close(tradeStopC)
+	<-doneC
+	<-tradeDoneC
+}
+
+func (s *serverTestSuite) receive(eventC chan *binance.WsUserDataEvent) *binance.WsUserDataEvent {
+	select {
+	case event := <-eventC:
+		return event
+	case <-time.After(time.Second):
+		s.FailNow("no user data event")
+		return nil
+	}
+}
+
+func (s *serverTestSuite) TestAuthentication() {
+	r := s.Require()
+	ctx := context.Background()
+	account := s.server.AddAccount("key", "secret")
+
+	_, err := s.server.NewClient(account).NewGetAccountService().Do(ctx)
+	r.NoError(err)
+
+	c := s.server.NewClient(account)
+	c.SecretKey = "wrong"
+	_, err = c.NewGetAccountService().Do(ctx)
+	r.Equal(int64(-1022), s.apiErrorCode(err))
+
+	c = s.server.NewClient(account)
+	c.APIKey = "unknown"
+	_, err = c.NewGetAccountService().Do(ctx)
+	r.Equal(int64(-2015), s.apiErrorCode(err))
+
+	// the server clock is ahead of the client one
+	s.server.Now = func() time.Time { return time.Now().Add(10 * time.Second) }
+	_, err = s.server.NewClient(account).NewGetAccountService().Do(ctx)
+	r.Equal(int64(-1021), s.apiErrorCode(err))
+	_, err = s.server.NewClient(account).NewGetAccountService().Do(ctx, binance.WithRecvWindow(20000))
+	r.NoError(err)
+	_, err = s.server.NewClient(account).NewGetAccountService().Do(ctx, binance.WithRecvWindow(70000))
+	r.Equal(int64(-1131), s.apiErrorCode(err))
+	s.server.Now = time.Now
+
+	// unsigned endpoints need no key
+	c = binance.NewClient("", "")
+	c.BaseURL = s.server.URL()
+	r.NoError(c.NewPingService().Do(ctx))
+	_, err = c.NewGetAccountService().Do(ctx)
+	r.Equal(int64(-2014), s.apiErrorCode(err))
+	_, err = c.NewGetAPIKeyPermission().Do(ctx)
+	r.Equal(int64(-1000), s.apiErrorCode(err))
+}
+
+func (s *serverTestSuite) TestKeyPairs() {
+	r := s.Require()
+	_, rsaKey, err := generatePEM(func() (interface{}, error) { return rsa.GenerateKey(rand.Reader, 2048) })
+	r.NoError(err)
+	_, edKey, err := generatePEM(func() (interface{}, error) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	})
+	r.NoError(err)
+
+	for _, c := range []struct {
+		keyType string
+		key     string
+	}{{common.KeyTypeRsa, rsaKey}, {common.KeyTypeEd25519, edKey}} {
+		account, err := s.server.AddKeyPairAccount(c.keyType, c.keyType, c.key)
+		r.NoError(err)
+		_, err = s.server.NewClient(account).NewGetAccountService().Do(context.Background())
+		r.NoError(err, c.keyType)
+	}
+	_, err = s.server.AddKeyPairAccount("mismatch", common.KeyTypeRsa, edKey)
+	r.Error(err)
+}
+
+func generatePEM(generate func() (interface{}, error)) (interface{}, string, error) {
+	key, err := generate()
+	if err != nil {
+		return nil, "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func (s *serverTestSuite) TestFuturesTrading() {
+	r := s.Require()
+	ctx := context.Background()
+	r.NoError(s.server.SetOrderBook(MarketFutures, "BTCUSDT",
+		[][2]string{{"99", "1"}, {"98", "2"}},
+		[][2]string{{"101", "1"}, {"102", "2"}}))
+	account := s.server.AddAccount("trader", "secret")
+	account.SetFuturesBalance("USDT", "1000")
+	c := s.server.NewFuturesClient(account)
+
+	listenKey, err := c.NewStartUserStreamService().Do(ctx)
+	r.NoError(err)
+	eventC := make(chan *futures.WsUserDataEvent, 16)
+	doneC, stopC, err := futures.WsUserDataServe(listenKey, func(event *futures.WsUserDataEvent) {
+		eventC <- event
+	}, func(err error) {})
+	r.NoError(err)
+
+	res, err := c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeBuy).
+		Type(futures.OrderTypeMarket).Quantity("2").Do(ctx)
+	r.NoError(err)
+	r.Equal(futures.OrderStatusTypeFilled, res.Status)
+	r.Equal("101.5", res.AvgPrice)
+	r.Equal("2", account.Position("BTCUSDT"))
+
+	// post only orders crossing the book expire
+	res, err = c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeSell).
+		Type(futures.OrderTypeLimit).TimeInForce(futures.TimeInForceTypeGTX).Quantity("1").Price("99").Do(ctx)
+	r.NoError(err)
+	r.Equal(futures.OrderStatusTypeExpired, res.Status)
+
+	_, err = c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeBuy).
+		Type(futures.OrderTypeMarket).Quantity("1").ReduceOnly(true).Do(ctx)
+	r.Equal(int64(-2022), s.apiErrorCode(err))
+
+	positions, err := c.NewGetPositionRiskService().Symbol("BTCUSDT").Do(ctx)
+	r.NoError(err)
+	r.Len(positions, 1)
+	r.Equal("101.5", positions[0].EntryPrice)
+	r.Equal("1", positions[0].UnRealizedProfit)
+
+	var statuses []futures.OrderStatusType
+	for len(statuses) < 3 {
+		select {
+		case event := <-eventC:
+			if event.Event == futures.UserDataEventTypeOrderTradeUpdate {
+				statuses = append(statuses, event.OrderTradeUpdate.Status)
+			}
+		case <-time.After(time.Second):
+			s.FailNow("no user data event")
+		}
+	}
+	r.Equal([]futures.OrderStatusType{futures.OrderStatusTypeNew, futures.OrderStatusTypePartiallyFilled, futures.OrderStatusTypeFilled}, statuses)
+	close(stopC)
+	<-doneC
+}
+
+func (s *serverTestSuite) TestCommissions() {
+	r := s.Require()
+	ctx := context.Background()
+	r.NoError(s.server.SetOrderBook(MarketSpot, "BTCUSDT", nil, [][2]string{{"100", "5"}}))
+	account := s.server.AddAccount("trader", "secret")
+	account.SetBalance("USDT", "1000")
+	account.SetCommission(MarketSpot, "BTCUSDT", "0.001", "0.002")
+	c := s.server.NewClient(account)
+
+	fees, err := c.NewTradeFeeService().Symbol("BTCUSDT").Do(ctx)
+	r.NoError(err)
+	r.Equal([]*binance.TradeFeeDetails{{Symbol: "BTCUSDT", MakerCommission: "0.001", TakerCommission: "0.002"}}, fees)
+
+	res, err := c.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeBuy).
+		Type(binance.OrderTypeMarket).Quantity("2").Do(ctx)
+	r.NoError(err)
+	r.Equal("0.004", res.Fills[0].Commission)
+	r.Equal("BTC", res.Fills[0].CommissionAsset)
+	free, _ := account.Balance("BTC")
+	r.Equal("1.996", free)
+
+	// a resting sell filled by the market moving through it pays the maker rate
+	_, err = c.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeSell).
+		Type(binance.OrderTypeLimit).TimeInForce(binance.TimeInForceTypeGTC).Quantity("1").Price("110").Do(ctx)
+	r.NoError(err)
+	r.NoError(s.server.SetOrderBook(MarketSpot, "BTCUSDT", [][2]string{{"111", "3"}}, [][2]string{{"112", "1"}}))
+	free, locked := account.Balance("USDT")
+	r.Equal("909.89", free)
+	r.Equal("0", locked)
+	trades, err := c.NewListTradesService().Symbol("BTCUSDT").Do(context.Background())
+	r.NoError(err)
+	r.Len(trades, 2)
+	r.True(trades[1].IsMaker)
+	r.Equal("110", trades[1].Price)
+	r.Equal("0.11", trades[1].Commission)
+}
+
+func (s *serverTestSuite) TestFuturesPositions() {
+	r := s.Require()
+	ctx := context.Background()
+	r.NoError(s.server.SetOrderBook(MarketFutures, "BTCUSDT", [][2]string{{"99", "10"}}, [][2]string{{"100", "10"}}))
+	account := s.server.AddAccount("trader", "secret")
+	account.SetFuturesBalance("USDT", "100")
+	account.SetCommission(MarketFutures, "BTCUSDT", "0.0002", "0.0005")
+	c := s.server.NewFuturesClient(account)
+
+	// 5 at 100 with the default 20x leverage needs 25 of margin, 30 needs 150
+	_, err := c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeBuy).
+		Type(futures.OrderTypeMarket).Quantity("30").Do(ctx)
+	r.Equal(int64(-2019), s.apiErrorCode(err))
+	leverage, err := c.NewChangeLeverageService().Symbol("BTCUSDT").Leverage(50).Do(ctx)
+	r.NoError(err)
+	r.Equal(50, leverage.Leverage)
+	_, err = c.NewChangeLeverageService().Symbol("BTCUSDT").Leverage(200).Do(ctx)
+	r.Equal(int64(-4028), s.apiErrorCode(err))
+
+	r.NoError(c.NewChangePositionModeService().DualSide(true).Do(ctx))
+	mode, err := c.NewGetPositionModeService().Do(ctx)
+	r.NoError(err)
+	r.True(mode.DualSidePosition)
+	_, err = c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeBuy).
+		Type(futures.OrderTypeMarket).Quantity("1").Do(ctx)
+	r.Equal(int64(-4061), s.apiErrorCode(err))
+
+	_, err = c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeBuy).PositionSide(futures.PositionSideTypeLong).
+		Type(futures.OrderTypeMarket).Quantity("10").Do(ctx)
+	r.NoError(err)
+	_, err = c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeSell).PositionSide(futures.PositionSideTypeShort).
+		Type(futures.OrderTypeMarket).Quantity("2").Do(ctx)
+	r.NoError(err)
+	r.Equal("8", account.Position("BTCUSDT"))
+	err = c.NewChangePositionModeService().DualSide(false).Do(ctx)
+	r.Equal(int64(-4068), s.apiErrorCode(err))
+
+	// the long is closed at 110
+	r.NoError(s.server.SetOrderBook(MarketFutures, "BTCUSDT", [][2]string{{"110", "10"}}, [][2]string{{"111", "10"}}))
+	res, err := c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeSell).PositionSide(futures.PositionSideTypeLong).
+		Type(futures.OrderTypeMarket).Quantity("10").Do(ctx)
+	r.NoError(err)
+	r.Equal(futures.PositionSideTypeLong, res.PositionSide)
+	// 100 - 0.5 - 0.099 of commissions + 100 of profit - 0.55 of commission
+	r.Equal("198.851", account.FuturesBalance("USDT"))
+
+	positions, err := c.NewGetPositionRiskService().Symbol("BTCUSDT").Do(ctx)
+	r.NoError(err)
+	r.Len(positions, 2)
+	r.Equal("LONG", positions[0].PositionSide)
+	r.Equal("0", positions[0].PositionAmt)
+	r.Equal("SHORT", positions[1].PositionSide)
+	r.Equal("-2", positions[1].PositionAmt)
+	r.Equal("-22", positions[1].UnRealizedProfit)
+	r.Equal("50", positions[1].Leverage)
+
+	rate, err := c.NewCommissionRateService().Symbol("BTCUSDT").Do(ctx)
+	r.NoError(err)
+	r.Equal("0.0005", rate.TakerCommissionRate)
+	balances, err := c.NewGetBalanceService().Do(ctx)
+	r.NoError(err)
+	r.Len(balances, 1)
+	r.Equal("198.851", balances[0].Balance)
+	r.Equal("-22", balances[0].CrossUnPnl)
+}
+
+func (s *serverTestSuite) TestUpstream() {
+	r := s.Require()
+	live := NewServer()
+	defer live.Close()
+	s.server.Upstream(MarketSpot, strings.TrimSuffix(live.WsURL(MarketSpot), "/ws"))
+
+	tickerC := make(chan *binance.WsBookTickerEvent, 1)
+	doneC, stopC, err := binance.WsBookTickerServe("BTCUSDT", func(event *binance.WsBookTickerEvent) {
+		tickerC <- event
+	}, func(err error) {})
+	r.NoError(err)
+	// the subscription reaches the live server asynchronously
+	deadline := time.Now().Add(time.Second)
+	for {
+		live.Publish(MarketSpot, "btcusdt@bookTicker", map[string]interface{}{"u": 1, "s": "BTCUSDT", "b": "10"})
+		select {
+		case event := <-tickerC:
+			r.Equal("10", event.BestBidPrice)
+			close(stopC)
+			<-doneC
+			return
+		case <-time.After(10 * time.Millisecond):
+			r.True(time.Now().Before(deadline), "no proxied event")
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package binancetest
+
+import (
+	"net/http"
+	"sort"
+)
+
+func (s *Server) spotRoutes() {
+	s.route(http.MethodGet, "/api/v3/ping", secTypeNone, func(c *call) (interface{}, *apiError) {
+		return struct{}{}, nil
+	})
+	s.route(http.MethodGet, "/api/v3/time", secTypeNone, func(c *call) (interface{}, *apiError) {
+		return map[string]int64{"serverTime": c.now}, nil
+	})
+	s.route(http.MethodGet, "/api/v3/exchangeInfo", secTypeNone, s.spotExchangeInfo)
+	s.route(http.MethodGet, "/api/v3/depth", secTypeNone, func(c *call) (interface{}, *apiError) {
+		return s.depth(c, MarketSpot)
+	})
+	s.route(http.MethodPost, "/api/v3/order", secTypeSigned, s.spotNewOrder)
+	s.route(http.MethodPost, "/api/v3/order/test", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketSpot)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.newOrder(c, sym); err != nil {
+			return nil, err
+		}
+		return struct{}{}, nil
+	})
+	s.route(http.MethodGet, "/api/v3/order", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketSpot)
+		if err != nil {
+			return nil, err
+		}
+		o, err := c.findOrder(sym)
+		if err != nil {
+			return nil, err
+		}
+		return spotOrder(o), nil
+	})
+	s.route(http.MethodDelete, "/api/v3/order", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketSpot)
+		if err != nil {
+			return nil, err
+		}
+		o, err := c.findOrder(sym)
+		if err == errUnknownOrder {
+			return nil, errCancelUnknown
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := s.cancel(sym, o, c.now); err != nil {
+			return nil, err
+		}
+		return spotCancelResponse(o, c.now), nil
+	})
+	s.route(http.MethodGet, "/api/v3/openOrders", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		symbols, err := s.symbolsParam(c, MarketSpot)
+		if err != nil {
+			return nil, err
+		}
+		res := []map[string]interface{}{}
+		for _, o := range c.openOrders(symbols) {
+			res = append(res, spotOrder(o))
+		}
+		return res, nil
+	})
+	s.route(http.MethodDelete, "/api/v3/openOrders", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketSpot)
+		if err != nil {
+			return nil, err
+		}
+		orders := c.openOrders([]*symbol{sym})
+		if len(orders) == 0 {
+			return nil, errCancelUnknown
+		}
+		var res []map[string]interface{}
+		for _, o := range orders {
+			s.cancel(sym, o, c.now)
+			res = append(res, spotCancelResponse(o, c.now))
+		}
+		return res, nil
+	})
+	s.route(http.MethodGet, "/api/v3/allOrders", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketSpot)
+		if err != nil {
+			return nil, err
+		}
+		var orders []*order
+		for _, o := range sym.orders {
+			if o.account == c.account {
+				orders = append(orders, o)
+			}
+		}
+		sort.Slice(orders, func(i, j int) bool { return orders[i].id < orders[j].id })
+		res := []map[string]interface{}{}
+		for _, o := range orders {
+			res = append(res, spotOrder(o))
+		}
+		return res, nil
+	})
+	s.route(http.MethodGet, "/api/v3/account", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		assets := make([]string, 0, len(c.account.balances))
+		for asset := range c.account.balances {
+			assets = append(assets, asset)
+		}
+		sort.Strings(assets)
+		balances := []map[string]string{}
+		for _, asset := range assets {
+			b := c.account.balances[asset]
+			balances = append(balances, map[string]string{"asset": asset, "free": b.free.String(), "locked": b.locked.String()})
+		}
+		return map[string]interface{}{
+			"makerCommission": 0, "takerCommission": 0, "buyerCommission": 0, "sellerCommission": 0,
+			"canTrade": true, "canWithdraw": true, "canDeposit": true, "updateTime": c.now,
+			"accountType": "SPOT", "balances": balances, "permissions": []string{"SPOT"},
+		}, nil
+	})
+	s.route(http.MethodGet, "/api/v3/myTrades", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		sym, err := s.symbol(c, MarketSpot)
+		if err != nil {
+			return nil, err
+		}
+		fromID, hasFromID := c.int64("fromId")
+		startTime, hasStart := c.int64("startTime")
+		endTime, hasEnd := c.int64("endTime")
+		limit, ok := c.int64("limit")
+		if !ok {
+			limit = 500
+		}
+		res := []map[string]interface{}{}
+		for _, f := range sym.fills {
+			switch {
+			case f.order.account != c.account,
+				hasFromID && f.tradeID < fromID,
+				hasStart && f.time < startTime,
+				hasEnd && f.time > endTime:
+				continue
+			}
+			if int64(len(res)) == limit {
+				break
+			}
+			res = append(res, map[string]interface{}{
+				"symbol": sym.name, "id": f.tradeID, "orderId": f.order.id, "orderListId": -1,
+				"price": f.price.String(), "qty": f.qty.String(), "quoteQty": f.quote.String(),
+				"commission": f.commission.String(), "commissionAsset": f.commissionAsset, "time": f.time,
+				"isBuyer": f.order.side == sideBuy, "isMaker": f.isMaker, "isBestMatch": true,
+			})
+		}
+		return res, nil
+	})
+	s.route(http.MethodGet, "/sapi/v1/asset/tradeFee", secTypeSigned, func(c *call) (interface{}, *apiError) {
+		symbols, err := s.symbolsParam(c, MarketSpot)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(symbols, func(i, j int) bool { return symbols[i].name < symbols[j].name })
+		res := []map[string]string{}
+		for _, sym := range symbols {
+			rates := c.account.fees[feeKey{MarketSpot, sym.name}]
+			res = append(res, map[string]string{
+				"symbol": sym.name, "makerCommission": rates.maker.String(), "takerCommission": rates.taker.String(),
+			})
+		}
+		return res, nil
+	})
+	s.listenKeyRoutes("/api/v3/userDataStream", MarketSpot)
+}
+
+func (s *Server) spotExchangeInfo(c *call) (interface{}, *apiError) {
+	names := make([]string, 0, len(s.symbols[MarketSpot]))
+	for name := range s.symbols[MarketSpot] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	symbols := []map[string]interface{}{}
+	for _, name := range names {
+		sym := s.symbols[MarketSpot][name]
+		symbols = append(symbols, map[string]interface{}{
+			"symbol": sym.name, "status": "TRADING", "baseAsset": sym.base, "quoteAsset": sym.quote,
+			"baseAssetPrecision": 8, "quotePrecision": 8, "quoteAssetPrecision": 8,
+			"orderTypes":                 []string{"LIMIT", "LIMIT_MAKER", "MARKET"},
+			"isSpotTradingAllowed":       true,
+			"quoteOrderQtyMarketAllowed": true,
+			"permissions":                []string{"SPOT"},
+			"filters":                    []interface{}{},
+		})
+	}
+	return map[string]interface{}{
+		"timezone": "UTC", "serverTime": c.now, "rateLimits": []interface{}{}, "symbols": symbols,
+	}, nil
+}
+
+func (s *Server) spotNewOrder(c *call) (interface{}, *apiError) {
+	sym, err := s.symbol(c, MarketSpot)
+	if err != nil {
+		return nil, err
+	}
+	o, err := s.newOrder(c, sym)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.place(o, c.now); err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{
+		"symbol": sym.name, "orderId": o.id, "orderListId": -1, "clientOrderId": o.clientID, "transactTime": c.now,
+	}
+	respType := c.params.Get("newOrderRespType")
+	if respType == "ACK" {
+		return res, nil
+	}
+	for k, v := range spotOrder(o) {
+		res[k] = v
+	}
+	if respType == "RESULT" {
+		return res, nil
+	}
+	fills := []map[string]interface{}{}
+	for _, f := range sym.fills {
+		if f.order == o {
+			fills = append(fills, map[string]interface{}{
+				"price": f.price.String(), "qty": f.qty.String(), "commission": f.commission.String(),
+				"commissionAsset": f.commissionAsset, "tradeId": f.tradeID,
+			})
+		}
+	}
+	res["fills"] = fills
+	return res, nil
+}
+
+func (s *Server) depth(c *call, market Market) (interface{}, *apiError) {
+	sym, err := s.symbol(c, market)
+	if err != nil {
+		return nil, err
+	}
+	limit, ok := c.int64("limit")
+	if !ok {
+		limit = 100
+	}
+	res := map[string]interface{}{
+		"lastUpdateId": sym.updateID,
+		"bids":         levels(sym.bids, int(limit)),
+		"asks":         levels(sym.asks, int(limit)),
+	}
+	if market == MarketFutures {
+		res["E"], res["T"] = c.now, c.now
+	}
+	return res, nil
+}
+
+// symbolsParam return the symbol named by the optional symbol parameter, or all of them
+func (s *Server) symbolsParam(c *call, market Market) ([]*symbol, *apiError) {
+	if c.params.Get("symbol") != "" {
+		sym, err := s.symbol(c, market)
+		if err != nil {
+			return nil, err
+		}
+		return []*symbol{sym}, nil
+	}
+	var res []*symbol
+	for _, sym := range s.symbols[market] {
+		res = append(res, sym)
+	}
+	return res, nil
+}
+
+func spotOrder(o *order) map[string]interface{} {
+	return map[string]interface{}{
+		"symbol": o.sym.name, "orderId": o.id, "orderListId": -1, "clientOrderId": o.clientID,
+		"price": o.price.String(), "origQty": o.qty.String(), "executedQty": o.executed.String(),
+		"cummulativeQuoteQty": o.cumQuote.String(), "status": o.status, "timeInForce": o.tif,
+		"type": o.typ, "side": o.side, "stopPrice": "0", "icebergQty": "0", "time": o.time,
+		"updateTime": o.updateTime, "isWorking": o.isOpen(), "workingTime": o.time,
+		"origQuoteOrderQty": o.quoteQty.String(), "selfTradePreventionMode": "NONE",
+	}
+}
+
+func spotCancelResponse(o *order, now int64) map[string]interface{} {
+	return map[string]interface{}{
+		"symbol": o.sym.name, "origClientOrderId": o.clientID, "orderId": o.id, "orderListId": -1,
+		"clientOrderId": o.clientID, "transactTime": now, "price": o.price.String(),
+		"origQty": o.qty.String(), "executedQty": o.executed.String(),
+		"cummulativeQuoteQty": o.cumQuote.String(), "status": o.status, "timeInForce": o.tif,
+		"type": o.typ, "side": o.side, "origQuoteOrderQty": o.quoteQty.String(),
+		"selfTradePreventionMode": "NONE",
+	}
+}
@@ -0,0 +1,343 @@
+package binancetest
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// wsSendBuffer is the number of messages queued for a slow websocket client
+const wsSendBuffer = 1024
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsConn define a websocket client and the streams it listens to
+type wsConn struct {
+	market   Market
+	streams  map[string]bool
+	combined bool
+	sendC    chan interface{}
+
+	closeOnce sync.Once
+	doneC     chan struct{}
+}
+
+func (c *wsConn) send(stream string, data interface{}) {
+	var msg interface{} = data
+	if c.combined {
+		msg = map[string]interface{}{"stream": stream, "data": data}
+	}
+	select {
+	case c.sendC <- msg:
+	case <-c.doneC:
+	default:
+		// the client doesn't read, drop it like the exchange would
+		c.close()
+	}
+}
+
+func (c *wsConn) close() {
+	c.closeOnce.Do(func() { close(c.doneC) })
+}
+
+func wsPrefix(market Market) string {
+	switch market {
+	case MarketSpot:
+		return ""
+	case MarketFutures:
+		return "/futures"
+	}
+	return "/" + strings.ToLower(string(market))
+}
+
+// serveWs serve the raw streams of /ws/<stream> and the combined ones of /stream?streams=
+func (s *Server) serveWs(w http.ResponseWriter, r *http.Request) {
+	c := &wsConn{
+		streams: make(map[string]bool),
+		sendC:   make(chan interface{}, wsSendBuffer),
+		doneC:   make(chan struct{}),
+	}
+	path := r.URL.Path
+	c.market = MarketSpot
+	for _, m := range []Market{MarketFutures, MarketDelivery} {
+		if prefix := wsPrefix(m); strings.HasPrefix(path, prefix+"/") {
+			c.market = m
+			path = strings.TrimPrefix(path, prefix)
+		}
+	}
+	var streams []string
+	switch {
+	case strings.HasPrefix(path, "/ws/"):
+		streams = []string{strings.TrimPrefix(path, "/ws/")}
+	case path == "/stream":
+		c.combined = true
+		streams = strings.Split(r.URL.Query().Get("streams"), "/")
+	default:
+		http.Error(w, "unknown stream path", http.StatusNotFound)
+		return
+	}
+
+	// subscribe before upgrading so that no event is missed once the client is connected
+	s.mu.Lock()
+	if upstream, ok := s.upstreams[c.market]; ok && !s.hasListenKey(streams) {
+		s.mu.Unlock()
+		target := upstream + path
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		s.proxyWs(w, r, target)
+		return
+	}
+	for _, stream := range streams {
+		if lk, ok := s.listenKeys[stream]; ok {
+			if lk.market != c.market {
+				s.mu.Unlock()
+				http.Error(w, "listenKey of another market", http.StatusBadRequest)
+				return
+			}
+			c.streams[stream] = true
+			continue
+		}
+		c.streams[strings.ToLower(stream)] = true
+	}
+	s.conns[c] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.close()
+	}()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	go func() {
+		// read the control frames and notice the client leaving
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				c.close()
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case msg := <-c.sendC:
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-c.doneC:
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
+
+// Upstream serve the streams of a market other than the user data streams of
+// the server from another endpoint, e.g. "wss://stream.binance.com:9443" for
+// the live spot market data. A connection can't mix both.
+func (s *Server) Upstream(market Market, endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.upstreams[market] = strings.TrimSuffix(endpoint, "/")
+}
+
+func (s *Server) hasListenKey(streams []string) bool {
+	for _, stream := range streams {
+		if _, ok := s.listenKeys[stream]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyWs pass the messages of a websocket connection to and from target
+func (s *Server) proxyWs(w http.ResponseWriter, r *http.Request, target string) {
+	upstream, _, err := websocket.DefaultDialer.Dial(target, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.proxied[conn] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.proxied, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	// the pings of the exchange are answered and forwarded for the keepalive of the client
+	upstream.SetPingHandler(func(data string) error {
+		conn.WriteControl(websocket.PingMessage, []byte(data), time.Now().Add(time.Second))
+		return upstream.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	doneC := make(chan struct{}, 2)
+	pipe := func(from, to *websocket.Conn) {
+		defer func() { doneC <- struct{}{} }()
+		for {
+			typ, data, err := from.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := to.WriteMessage(typ, data); err != nil {
+				return
+			}
+		}
+	}
+	go pipe(upstream, conn)
+	go pipe(conn, upstream)
+	<-doneC
+}
+
+// Publish send an event on a market stream, e.g. "btcusdt@depth", to the
+// connected clients. Trades of the fake exchange are published on their own.
+func (s *Server) Publish(market Market, stream string, event interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.push(market, strings.ToLower(stream), event)
+}
+
+// push send an event to the clients of a stream, the lock must be held
+func (s *Server) push(market Market, stream string, event interface{}) {
+	for c := range s.conns {
+		if c.market == market && c.streams[stream] {
+			c.send(stream, event)
+		}
+	}
+}
+
+// pushUser send a user data event to the streams of the account, the lock must be held
+func (s *Server) pushUser(a *Account, market Market, event interface{}) {
+	for key, lk := range s.listenKeys {
+		if lk.account == a && lk.market == market {
+			s.push(market, key, event)
+		}
+	}
+}
+
+// publishTrade send a trade on the trade and aggTrade streams of its symbol
+func (s *Server) publishTrade(sym *symbol, tradeID int64, price, qty decimal.Decimal, isBuyerMaker bool, now int64) {
+	name := strings.ToLower(sym.name)
+	trade := map[string]interface{}{
+		"e": "trade", "E": now, "T": now, "s": sym.name, "t": tradeID,
+		"p": price.String(), "q": qty.String(), "m": isBuyerMaker,
+	}
+	aggTrade := map[string]interface{}{
+		"e": "aggTrade", "E": now, "T": now, "s": sym.name, "a": tradeID, "f": tradeID, "l": tradeID,
+		"p": price.String(), "q": qty.String(), "m": isBuyerMaker,
+	}
+	if sym.market == MarketSpot {
+		// the spot events have an ignored "M" field, futures ones would decode it as "m"
+		trade["M"], aggTrade["M"] = true, true
+	}
+	s.push(sym.market, name+"@trade", trade)
+	s.push(sym.market, name+"@aggTrade", aggTrade)
+}
+
+// orderEvent send the order update of an account order
+func (s *Server) orderEvent(o *order, f *fill, now int64) {
+	if o.account == nil {
+		return
+	}
+	execType := o.status
+	switch {
+	case f != nil:
+		execType = "TRADE"
+	case o.status == statusPartiallyFilled:
+		execType = statusNew
+	}
+	var lastQty, lastPrice, lastQuote, commission, realized = "0", "0", "0", "0", "0"
+	var commissionAsset interface{}
+	var tradeID int64 = -1
+	var isMaker bool
+	if f != nil {
+		lastQty, lastPrice, lastQuote = f.qty.String(), f.price.String(), f.quote.String()
+		commission, commissionAsset, realized = f.commission.String(), f.commissionAsset, f.realized.String()
+		tradeID, isMaker = f.tradeID, f.isMaker
+	}
+	if o.sym.market == MarketFutures {
+		s.pushUser(o.account, MarketFutures, map[string]interface{}{
+			"e": "ORDER_TRADE_UPDATE",
+			"E": now,
+			"T": now,
+			"o": map[string]interface{}{
+				"s": o.sym.name, "c": o.clientID, "S": o.side, "o": o.typ, "f": o.tif,
+				"q": o.qty.String(), "p": o.price.String(), "ap": o.avgPrice().String(), "sp": "0",
+				"x": execType, "X": o.status, "i": o.id, "l": lastQty, "z": o.executed.String(), "L": lastPrice,
+				"N": o.sym.quote, "n": commission, "T": now, "t": tradeID, "b": "0", "a": "0", "m": isMaker,
+				"R": o.reduceOnly, "wt": "CONTRACT_PRICE", "ot": o.typ, "ps": o.positionSide, "cp": false, "rp": realized,
+			},
+		})
+		if f != nil {
+			s.positionEvent(o, now)
+		}
+		return
+	}
+	s.pushUser(o.account, MarketSpot, map[string]interface{}{
+		"e": "executionReport", "E": now, "s": o.sym.name, "c": o.clientID, "S": o.side, "o": o.typ,
+		"f": o.tif, "q": o.qty.String(), "p": o.price.String(), "P": "0", "F": "0", "g": -1, "C": "",
+		"x": execType, "X": o.status, "r": "NONE", "i": o.id, "l": lastQty, "z": o.executed.String(),
+		"L": lastPrice, "n": commission, "N": commissionAsset, "T": now, "t": tradeID, "I": 0, "w": o.isOpen(), "m": isMaker,
+		"M": false, "O": o.time, "Z": o.cumQuote.String(), "Y": lastQuote, "Q": o.quoteQty.String(),
+		"V": "NONE",
+	})
+}
+
+// accountEvents send the spot balances of the accounts whose balances changed
+func (s *Server) accountEvents(sym *symbol, now int64) {
+	if sym.market != MarketSpot {
+		return
+	}
+	for a := range s.touched {
+		base, quote := a.balances[sym.base], a.balances[sym.quote]
+		var balances []map[string]string
+		for _, b := range []struct {
+			asset string
+			b     *balance
+		}{{sym.base, base}, {sym.quote, quote}} {
+			if b.b != nil {
+				balances = append(balances, map[string]string{"a": b.asset, "f": b.b.free.String(), "l": b.b.locked.String()})
+			}
+		}
+		s.pushUser(a, MarketSpot, map[string]interface{}{
+			"e": "outboundAccountPosition", "E": now, "u": now, "B": balances,
+		})
+	}
+	s.touched = make(map[*Account]bool)
+}
+
+// positionEvent send the futures wallet and position an order fill changed
+func (s *Server) positionEvent(o *order, now int64) {
+	a, sym := o.account, o.sym
+	p := a.position(sym.name, o.positionSide)
+	wallet := a.wallets[sym.quote].String()
+	s.pushUser(a, MarketFutures, map[string]interface{}{
+		"e": "ACCOUNT_UPDATE",
+		"E": now,
+		"T": now,
+		"a": map[string]interface{}{
+			"m": "ORDER",
+			"B": []map[string]string{{"a": sym.quote, "wb": wallet, "cw": wallet, "bc": "0"}},
+			"P": []map[string]string{{
+				"s": sym.name, "pa": p.amount.String(), "ep": p.entryPrice.String(), "cr": p.realized.String(),
+				"up": sym.markPrice(p).Sub(p.entryPrice).Mul(p.amount).String(), "mt": "cross", "iw": "0",
+				"ps": o.positionSide,
+			}},
+		},
+	})
+}
@@ -0,0 +1,310 @@
+// Package cassette records the traffic between the clients and the exchange
+// once, with the secrets scrubbed, and replays it in tests.
+//
+// The HTTP traffic goes through the Recorder used as the transport of a
+// client, the websocket traffic goes through a local server the websocket
+// endpoint variables are pointed at:
+//
+//	rec, err := cassette.New("testdata/order.json", cassette.ModeReplay)
+//	...
+//	defer rec.Stop()
+//	restore := rec.UseWsEndpoints()
+//	defer restore()
+//	client := binance.NewClient(apiKey, secretKey)
+//	client.HTTPClient = rec.HTTPClient()
+//
+// The websocket dialers of the clients can be routed through the recorder
+// instead, e.g. for the websocket API connections, with UseWsDialers.
+//
+// Requests are matched on their method, URL, parameters and body, ignoring
+// the parameters that change on every call such as the timestamp and the
+// signature. The listen keys of the user data streams are replaced by
+// placeholders, which the replayed responses hand to the clients.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Redacted replace the scrubbed values in the cassettes
+const Redacted = "REDACTED"
+
+// RedactedListenKey is the prefix of the placeholders of the listen keys, they
+// are numbered in the order the keys were seen
+const RedactedListenKey = "REDACTED_LISTEN_KEY_"
+
+var (
+	// IgnoredParams are the parameters left out when matching a request, the
+	// client order ids are generated by the clients when not given
+	IgnoredParams = []string{"timestamp", "signature", "newClientOrderId"}
+	// ScrubbedParams are the parameters redacted from the recorded requests and
+	// websocket frames, they are left out when matching too
+	ScrubbedParams = []string{"signature", "apiKey"}
+	// ScrubbedHeaders are the headers redacted from the recorded requests
+	ScrubbedHeaders = []string{"X-MBX-APIKEY"}
+)
+
+// Cassette define the recorded traffic
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+	WsSessions   []*WsSession   `json:"wsSessions"`
+}
+
+// Interaction define a recorded HTTP request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request define a recorded HTTP request
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response define a recorded HTTP response
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// WsSession define a recorded websocket connection
+type WsSession struct {
+	URL    string   `json:"url"`
+	Frames []*Frame `json:"frames"`
+}
+
+// Frame define a websocket message, Sent is set for the messages written by
+// the client
+type Frame struct {
+	Sent bool   `json:"sent,omitempty"`
+	Type int    `json:"type"`
+	Data string `json:"data"`
+}
+
+// Load read a cassette file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Cassette)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save write the cassette to a file
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func isIgnored(param string) bool {
+	return contains(IgnoredParams, param) || contains(ScrubbedParams, param)
+}
+
+// scrubValues redact the scrubbed parameters, it return the encoding
+// unchanged when there is none
+func scrubValues(encoded string) string {
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		return encoded
+	}
+	scrubbed := false
+	for _, p := range ScrubbedParams {
+		if _, ok := values[p]; ok {
+			values.Set(p, Redacted)
+			scrubbed = true
+		}
+	}
+	if !scrubbed {
+		return encoded
+	}
+	return values.Encode()
+}
+
+func scrubHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, h := range ScrubbedHeaders {
+		if header.Get(h) != "" {
+			header.Set(h, Redacted)
+		}
+	}
+	return header
+}
+
+// sameValues compare two encoded parameter lists regardless of their order
+// and of the ignored parameters
+func sameValues(a, b string) bool {
+	va, errA := url.ParseQuery(a)
+	vb, errB := url.ParseQuery(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	for k := range va {
+		if isIgnored(k) {
+			delete(va, k)
+		}
+	}
+	for k := range vb {
+		if isIgnored(k) {
+			delete(vb, k)
+		}
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// matchRequest tell whether a recorded request match a live one
+func matchRequest(rec *Request, method string, u *url.URL, body string) bool {
+	if rec.Method != method {
+		return false
+	}
+	ru, err := url.Parse(rec.URL)
+	if err != nil {
+		return false
+	}
+	return ru.Scheme == u.Scheme && ru.Host == u.Host && ru.Path == u.Path &&
+		sameValues(ru.RawQuery, u.RawQuery) && sameValues(rec.Body, body)
+}
+
+// decodeMessage decode a JSON websocket message keeping the numbers as they are
+func decodeMessage(data []byte) (map[string]interface{}, bool) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var m map[string]interface{}
+	if err := d.Decode(&m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// scrubMessage redact the scrubbed parameters of a websocket API request
+func scrubMessage(data []byte) []byte {
+	m, ok := decodeMessage(data)
+	if !ok {
+		return data
+	}
+	params, ok := m["params"].(map[string]interface{})
+	if !ok {
+		return data
+	}
+	scrubbed := false
+	for _, p := range ScrubbedParams {
+		if _, ok := params[p]; ok {
+			params[p] = Redacted
+			scrubbed = true
+		}
+	}
+	if !scrubbed {
+		return data
+	}
+	res, err := json.Marshal(m)
+	if err != nil {
+		return data
+	}
+	return res
+}
+
+// matchMessage tell whether a recorded websocket message match a live one,
+// the request ids and the ignored parameters are left out
+func matchMessage(rec string, data []byte) bool {
+	a, okA := decodeMessage([]byte(rec))
+	b, okB := decodeMessage(data)
+	if !okA || !okB {
+		return rec == string(data)
+	}
+	for _, m := range []map[string]interface{}{a, b} {
+		delete(m, "id")
+		if params, ok := m["params"].(map[string]interface{}); ok {
+			for k := range params {
+				if isIgnored(k) {
+					delete(params, k)
+				}
+			}
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// listenKeys replace the listen keys seen in the recorded traffic by placeholders
+type listenKeys struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+// learn register the listen keys of a JSON payload, at any depth, or of
+// encoded parameters
+func (l *listenKeys) learn(data string) {
+	var found []string
+	if m, ok := decodeMessage([]byte(data)); ok {
+		found = findListenKeys(m, found)
+	} else if values, err := url.ParseQuery(data); err == nil && values.Get("listenKey") != "" {
+		found = append(found, values.Get("listenKey"))
+	}
+	if len(found) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.keys == nil {
+		l.keys = make(map[string]string)
+	}
+	for _, key := range found {
+		if _, ok := l.keys[key]; !ok && !strings.HasPrefix(key, RedactedListenKey) {
+			l.keys[key] = RedactedListenKey + strconv.Itoa(len(l.keys)+1)
+		}
+	}
+}
+
+func findListenKeys(v interface{}, found []string) []string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if key, ok := value.(string); ok && k == "listenKey" && key != "" {
+				found = append(found, key)
+				continue
+			}
+			found = findListenKeys(value, found)
+		}
+	case []interface{}:
+		for _, value := range v {
+			found = findListenKeys(value, found)
+		}
+	}
+	return found
+}
+
+// scrub replace the known listen keys of s by their placeholders
+func (l *listenKeys) scrub(s string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, placeholder := range l.keys {
+		s = strings.ReplaceAll(s, key, placeholder)
+	}
+	return s
+}
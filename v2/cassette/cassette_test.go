@@ -0,0 +1,247 @@
+package cassette
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/suite"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/binancetest"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type cassetteTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func TestCassette(t *testing.T) {
+	suite.Run(t, new(cassetteTestSuite))
+}
+
+func (s *cassetteTestSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "cassette.json")
+}
+
+// trade place an order against the book and listen to the trade
+func (s *cassetteTestSuite) trade(c *binance.Client) (*binance.CreateOrderResponse, *binance.WsTradeEvent) {
+	r := s.Require()
+	tradeC := make(chan *binance.WsTradeEvent, 1)
+	doneC, stopC, err := binance.WsTradeServe("BTCUSDT", func(event *binance.WsTradeEvent) {
+		tradeC <- event
+	}, func(err error) {})
+	r.NoError(err)
+	defer func() {
+		close(stopC)
+		<-doneC
+	}()
+
+	r.NoError(c.NewPingService().Do(context.Background()))
+	res, err := c.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeBuy).
+		Type(binance.OrderTypeMarket).Quantity("1").Do(context.Background())
+	r.NoError(err)
+	select {
+	case trade := <-tradeC:
+		return res, trade
+	case <-time.After(time.Second):
+		s.FailNow("no trade event")
+		return nil, nil
+	}
+}
+
+func (s *cassetteTestSuite) TestRecordAndReplay() {
+	r := s.Require()
+	server := binancetest.NewServer()
+	restoreServer := server.UseWsEndpoints()
+	defer restoreServer()
+	server.AddSymbol(binancetest.MarketSpot, "BTCUSDT", "BTC", "USDT")
+	r.NoError(server.SetOrderBook(binancetest.MarketSpot, "BTCUSDT", nil, [][2]string{{"100", "5"}}))
+	account := server.AddAccount("key", "secret")
+	account.SetBalance("USDT", "1000")
+
+	rec, err := New(s.path, ModeRecord)
+	r.NoError(err)
+	restore := rec.UseWsEndpoints()
+	c := server.NewClient(account)
+	c.HTTPClient = rec.HTTPClient()
+	recorded, recordedTrade := s.trade(c)
+	restore()
+	r.NoError(rec.Stop())
+	server.Close()
+
+	data, err := os.ReadFile(s.path)
+	r.NoError(err)
+	r.NotContains(string(data), "secret")
+	order := rec.Cassette().Interactions[1].Request
+	r.Equal(Redacted, order.Header.Get("X-MBX-APIKEY"))
+	r.Contains(order.URL, "signature=REDACTED")
+
+	// the exchange is gone, the cassette answers with other timestamps and keys
+	rec, err = New(s.path, ModeReplay)
+	r.NoError(err)
+	restore = rec.UseWsEndpoints()
+	defer restore()
+	c = binance.NewClient("other", "other-secret")
+	c.BaseURL = server.URL()
+	c.HTTPClient = rec.HTTPClient()
+	replayed, replayedTrade := s.trade(c)
+	r.Equal(recorded, replayed)
+	r.Equal(recordedTrade, replayedTrade)
+
+	_, err = c.NewGetAccountService().Do(context.Background())
+	r.Error(err)
+	r.Contains(err.Error(), "no recorded interaction for GET")
+	r.NoError(rec.Stop())
+}
+
+func (s *cassetteTestSuite) TestWsApi() {
+	r := s.Require()
+	// the upstream answers the requests with their id
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			m, _ := decodeMessage(data)
+			conn.WriteJSON(map[string]interface{}{"id": m["id"], "status": 200, "result": m["params"]})
+		}
+	}))
+	defer upstream.Close()
+	endpoint := "ws" + strings.TrimPrefix(upstream.URL, "http") + "/ws-api/v3"
+
+	call := func(rec *Recorder, id string, timestamp int64) map[string]interface{} {
+		conn, _, err := websocket.DefaultDialer.Dial(rec.WsEndpoint(endpoint), nil)
+		r.NoError(err)
+		defer conn.Close()
+		r.NoError(conn.WriteJSON(map[string]interface{}{
+			"id": id, "method": "order.status",
+			"params": map[string]interface{}{
+				"symbol": "BTCUSDT", "orderId": 1, "apiKey": "key", "timestamp": timestamp, "signature": id,
+			},
+		}))
+		var res map[string]interface{}
+		r.NoError(conn.ReadJSON(&res))
+		return res
+	}
+
+	rec, err := New(s.path, ModeRecord)
+	r.NoError(err)
+	res := call(rec, "first", 1)
+	r.Equal("first", res["id"])
+	r.NoError(rec.Stop())
+	r.Len(rec.Cassette().WsSessions, 1)
+	frames := rec.Cassette().WsSessions[0].Frames
+	r.Len(frames, 2)
+	r.True(frames[0].Sent)
+	r.NotContains(frames[0].Data, `"key"`)
+	r.NotContains(frames[0].Data, `"first"}`)
+
+	upstream.Close()
+	rec, err = New(s.path, ModeReplay)
+	r.NoError(err)
+	res = call(rec, "second", 2)
+	r.Equal("second", res["id"])
+	r.Equal("BTCUSDT", res["result"].(map[string]interface{})["symbol"])
+	r.NoError(rec.Stop())
+
+	// another request doesn't match the cassette
+	rec, err = New(s.path, ModeReplay)
+	r.NoError(err)
+	conn, _, err := websocket.DefaultDialer.Dial(rec.WsEndpoint(endpoint), nil)
+	r.NoError(err)
+	r.NoError(conn.WriteJSON(map[string]interface{}{"id": "third", "method": "order.cancel"}))
+	_, _, err = conn.ReadMessage()
+	r.True(websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+	conn.Close()
+	r.Error(rec.Stop())
+}
+
+func (s *cassetteTestSuite) TestListenKeys() {
+	r := s.Require()
+	const liveKey = "pqia91ma19a5s61cv6a81va65sdf19v8a65a1a5s61cv6a81va65sdf19v8a65a1"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodPost && req.URL.Path == "/fapi/v1/listenKey":
+			w.Write([]byte(`{"listenKey": "` + liveKey + `"}`))
+		case req.Method == http.MethodPut && req.URL.Path == "/fapi/v1/listenKey":
+			w.Write([]byte(`{}`))
+		case req.URL.Path == "/ws/"+liveKey:
+			conn, err := upgrader.Upgrade(w, req, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"e": "listenKeyExpired", "E": 1, "listenKey": "`+liveKey+`"}`))
+			conn.ReadMessage()
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer upstream.Close()
+	origEndpoint := futures.BaseWsMainUrl
+	futures.BaseWsMainUrl = "ws" + strings.TrimPrefix(upstream.URL, "http") + "/ws"
+	defer func() { futures.BaseWsMainUrl = origEndpoint }()
+
+	stream := func(rec *Recorder) (string, *futures.WsUserDataEvent) {
+		c := futures.NewClient("key", "secret")
+		c.BaseURL = upstream.URL
+		c.HTTPClient = rec.HTTPClient()
+		listenKey, err := c.NewStartUserStreamService().Do(context.Background())
+		r.NoError(err)
+		r.NoError(c.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(context.Background()))
+		eventC := make(chan *futures.WsUserDataEvent, 1)
+		doneC, stopC, err := futures.WsUserDataServe(listenKey, func(event *futures.WsUserDataEvent) {
+			eventC <- event
+		}, func(err error) {})
+		r.NoError(err)
+		defer func() {
+			close(stopC)
+			<-doneC
+		}()
+		select {
+		case event := <-eventC:
+			return listenKey, event
+		case <-time.After(time.Second):
+			s.FailNow("no user data event")
+			return "", nil
+		}
+	}
+
+	rec, err := New(s.path, ModeRecord)
+	r.NoError(err)
+	restore := rec.UseWsDialers()
+	listenKey, event := stream(rec)
+	restore()
+	r.Equal(liveKey, listenKey)
+	r.Equal(futures.UserDataEventTypeListenKeyExpired, event.Event)
+	r.NoError(rec.Stop())
+	data, err := os.ReadFile(s.path)
+	r.NoError(err)
+	r.NotContains(string(data), liveKey)
+	r.True(strings.HasSuffix(rec.Cassette().WsSessions[0].URL, "/ws/"+RedactedListenKey+"1"))
+
+	// the clients get the placeholder and use it to replay the stream
+	upstream.Close()
+	rec, err = New(s.path, ModeReplay)
+	r.NoError(err)
+	restore = rec.UseWsDialers()
+	defer restore()
+	listenKey, event = stream(rec)
+	r.Equal(RedactedListenKey+"1", listenKey)
+	r.Equal(futures.UserDataEventTypeListenKeyExpired, event.Event)
+	r.NoError(rec.Stop())
+}
@@ -0,0 +1,196 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Mode define whether a recorder records or replays the traffic
+type Mode int
+
+// Recorder modes
+const (
+	ModeReplay Mode = iota
+	ModeRecord
+)
+
+// Recorder record the traffic of the clients to a cassette file or replay it.
+// It is the HTTP transport of the clients and serves their websocket
+// connections, see WsEndpoint.
+type Recorder struct {
+	// Transport send the recorded requests, http.DefaultTransport by default
+	Transport http.RoundTripper
+	// Dialer open the recorded websocket connections
+	Dialer *websocket.Dialer
+
+	mode       Mode
+	path       string
+	cassette   *Cassette
+	ts         *httptest.Server
+	listenKeys listenKeys
+
+	mu        sync.Mutex
+	used      map[interface{}]bool
+	upstreams []string
+	conns     map[*websocket.Conn]bool
+	wg        sync.WaitGroup
+	err       error
+}
+
+// New return a recorder of the cassette file at path. The file is loaded in
+// replay mode and written by Stop in record mode.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		Dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+		},
+		mode:     mode,
+		path:     path,
+		cassette: new(Cassette),
+		used:     make(map[interface{}]bool),
+		conns:    make(map[*websocket.Conn]bool),
+	}
+	if mode == ModeReplay {
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = c
+	}
+	r.ts = httptest.NewServer(http.HandlerFunc(r.serveWs))
+	return r, nil
+}
+
+// Cassette return the recorded traffic
+func (r *Recorder) Cassette() *Cassette {
+	return r.cassette
+}
+
+// HTTPClient return an HTTP client going through the recorder
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Stop close the websocket connections and, in record mode, write the
+// cassette. In replay mode it return the first websocket message that didn't
+// match the cassette.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.mu.Unlock()
+	r.ts.Close()
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mode == ModeRecord {
+		return r.cassette.Save(r.path)
+	}
+	return r.err
+}
+
+// fail keep the first replay error
+func (r *Recorder) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// RoundTrip implement http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, string(body))
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	u := *req.URL
+	u.RawQuery = scrubValues(u.RawQuery)
+	r.listenKeys.learn(u.RawQuery)
+	r.listenKeys.learn(string(body))
+	r.listenKeys.learn(string(data))
+	interaction := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    r.listenKeys.scrub(u.String()),
+			Header: scrubHeader(req.Header),
+			Body:   r.listenKeys.scrub(scrubValues(string(body))),
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+			Body:       r.listenKeys.scrub(string(data)),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+	return res, nil
+}
+
+// replay return the response of the first unused interaction matching the request
+func (r *Recorder) replay(req *http.Request, body string) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, i := range r.cassette.Interactions {
+		if r.used[i] || !matchRequest(&i.Request, req.Method, req.URL, body) {
+			continue
+		}
+		r.used[i] = true
+		header := i.Response.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+			StatusCode:    i.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(i.Response.Body)),
+			ContentLength: int64(len(i.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette: no recorded interaction for %s %s", req.Method, req.URL)
+}
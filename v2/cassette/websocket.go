@@ -0,0 +1,284 @@
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/adshao/go-binance/v2/portfolio"
+)
+
+// controlTimeout bound the writes of the forwarded control frames
+const controlTimeout = 10 * time.Second
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// WsEndpoint return the local endpoint standing for a websocket endpoint, e.g.
+// binance.BaseWsMainURL. The paths and streams appended to it by the clients
+// are kept, the connections are recorded to the upstream endpoint or replayed.
+func (r *Recorder) WsEndpoint(upstream string) string {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return upstream
+	}
+	r.mu.Lock()
+	i := len(r.upstreams)
+	for j, v := range r.upstreams {
+		if v == upstream {
+			i = j
+		}
+	}
+	if i == len(r.upstreams) {
+		r.upstreams = append(r.upstreams, upstream)
+	}
+	r.mu.Unlock()
+	endpoint := "ws" + strings.TrimPrefix(r.ts.URL, "http") + "/" + strconv.Itoa(i) + u.Path
+	if u.RawQuery != "" || u.ForceQuery {
+		endpoint += "?" + u.RawQuery
+	}
+	return endpoint
+}
+
+// UseWsEndpoints point the websocket endpoint variables of the clients to the
+// recorder, the returned function restores them
+func (r *Recorder) UseWsEndpoints() (restore func()) {
+	vars := []*string{
+		&binance.BaseWsMainURL,
+		&binance.BaseCombinedMainURL,
+		&binance.BaseWsApiMainURL,
+		&futures.BaseWsMainUrl,
+		&futures.BaseCombinedMainURL,
+		&futures.BaseWsApiMainURL,
+		&delivery.BaseWsMainUrl,
+		&portfolio.BaseWsMainUrl,
+	}
+	saved := make([]string, len(vars))
+	for i, v := range vars {
+		saved[i] = *v
+		*v = r.WsEndpoint(*v)
+	}
+	return func() {
+		for i, v := range vars {
+			*v = saved[i]
+		}
+	}
+}
+
+// Dial open a websocket connection to endpoint through the recorder, it is
+// the dialer hook of the clients set by UseWsDialers
+func (r *Recorder) Dial(dialer *websocket.Dialer, endpoint string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	local := r.WsEndpoint(u.Scheme+"://"+u.Host) + u.Path
+	if u.RawQuery != "" || u.ForceQuery {
+		local += "?" + u.RawQuery
+	}
+	d := *dialer
+	// the recorder is local, it goes through the proxy itself when recording
+	d.Proxy = nil
+	return d.Dial(local, header)
+}
+
+// UseWsDialers route the websocket connections of the clients, streams and
+// websocket API, through the recorder, the returned function restores the
+// dialers
+func (r *Recorder) UseWsDialers() (restore func()) {
+	spot, usdm, coinm, pm := binance.WsDial, futures.WsDial, delivery.WsDial, portfolio.WsDial
+	binance.WsDial, futures.WsDial, delivery.WsDial, portfolio.WsDial = r.Dial, r.Dial, r.Dial, r.Dial
+	return func() {
+		binance.WsDial, futures.WsDial, delivery.WsDial, portfolio.WsDial = spot, usdm, coinm, pm
+	}
+}
+
+// upstreamURL return the upstream URL of a local websocket request
+func (r *Recorder) upstreamURL(req *http.Request) (string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 2)
+	i, err := strconv.Atoi(parts[0])
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil || i < 0 || i >= len(r.upstreams) {
+		return "", false
+	}
+	u, err := url.Parse(r.upstreams[i])
+	if err != nil {
+		return "", false
+	}
+	target := u.Scheme + "://" + u.Host
+	if len(parts) == 2 {
+		target += "/" + parts[1]
+	}
+	if req.URL.RawQuery != "" || req.URL.ForceQuery {
+		target += "?" + req.URL.RawQuery
+	}
+	return target, true
+}
+
+func (r *Recorder) serveWs(w http.ResponseWriter, req *http.Request) {
+	target, ok := r.upstreamURL(req)
+	if !ok {
+		http.Error(w, "unknown websocket endpoint", http.StatusNotFound)
+		return
+	}
+	r.wg.Add(1)
+	defer r.wg.Done()
+	if r.mode == ModeReplay {
+		r.replayWs(w, req, target)
+		return
+	}
+	r.recordWs(w, req, target)
+}
+
+// track register a connection closed by Stop, the returned function
+// unregisters it
+func (r *Recorder) track(conn *websocket.Conn) func() {
+	r.mu.Lock()
+	r.conns[conn] = true
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		delete(r.conns, conn)
+		r.mu.Unlock()
+		conn.Close()
+	}
+}
+
+// recordWs proxy a websocket connection to its upstream and record the messages
+func (r *Recorder) recordWs(w http.ResponseWriter, req *http.Request, target string) {
+	upstream, _, err := r.Dialer.Dial(target, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer r.track(upstream)()
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	defer r.track(conn)()
+
+	session := &WsSession{URL: r.listenKeys.scrub(target)}
+	r.mu.Lock()
+	r.cassette.WsSessions = append(r.cassette.WsSessions, session)
+	r.mu.Unlock()
+
+	// the exchange pings are answered and forwarded for the keepalive of the client
+	upstream.SetPingHandler(func(data string) error {
+		conn.WriteControl(websocket.PingMessage, []byte(data), time.Now().Add(controlTimeout))
+		return upstream.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(controlTimeout))
+	})
+	doneC := make(chan struct{}, 2)
+	pipe := func(from, to *websocket.Conn, sent bool) {
+		defer func() { doneC <- struct{}{} }()
+		for {
+			typ, data, err := from.ReadMessage()
+			if err != nil {
+				return
+			}
+			recorded := data
+			if sent {
+				recorded = scrubMessage(data)
+			}
+			r.listenKeys.learn(string(recorded))
+			frame := &Frame{Sent: sent, Type: typ, Data: r.listenKeys.scrub(string(recorded))}
+			r.mu.Lock()
+			session.Frames = append(session.Frames, frame)
+			r.mu.Unlock()
+			if err := to.WriteMessage(typ, data); err != nil {
+				return
+			}
+		}
+	}
+	go pipe(upstream, conn, false)
+	go pipe(conn, upstream, true)
+	<-doneC
+}
+
+// replayWs play the messages of the first unused session of the URL, the
+// messages of the client are checked against the recorded ones
+func (r *Recorder) replayWs(w http.ResponseWriter, req *http.Request, target string) {
+	var session *WsSession
+	r.mu.Lock()
+	for _, s := range r.cassette.WsSessions {
+		if !r.used[s] && s.URL == target {
+			session = s
+			r.used[s] = true
+			break
+		}
+	}
+	r.mu.Unlock()
+	if session == nil {
+		r.fail(fmt.Errorf("cassette: no recorded websocket session for %s", target))
+		http.Error(w, "no recorded websocket session", http.StatusNotFound)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	defer r.track(conn)()
+
+	// the responses of the websocket API carry the id of the live requests
+	ids := make(map[string]interface{})
+	for _, f := range session.Frames {
+		if f.Sent {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if !matchMessage(f.Data, data) {
+				r.fail(fmt.Errorf("cassette: unexpected websocket message on %s: %s", target, data))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "unexpected message"))
+				return
+			}
+			if rec, ok := decodeMessage([]byte(f.Data)); ok {
+				if live, ok := decodeMessage(data); ok {
+					ids[fmt.Sprint(rec["id"])] = live["id"]
+				}
+			}
+			continue
+		}
+		if err := conn.WriteMessage(f.Type, replaceID([]byte(f.Data), ids)); err != nil {
+			return
+		}
+	}
+	// keep the connection open until the client leaves
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// replaceID set the id of the live request in a recorded response
+func replaceID(data []byte, ids map[string]interface{}) []byte {
+	if len(ids) == 0 {
+		return data
+	}
+	m, ok := decodeMessage(data)
+	if !ok || m["id"] == nil {
+		return data
+	}
+	id, ok := ids[fmt.Sprint(m["id"])]
+	if !ok {
+		return data
+	}
+	m["id"] = id
+	res, err := json.Marshal(m)
+	if err != nil {
+		return data
+	}
+	return res
+}
@@ -193,6 +193,12 @@ const (
 	UserDataEventTypeBalanceUpdate           UserDataEventType = "balanceUpdate"
 	UserDataEventTypeExecutionReport         UserDataEventType = "executionReport"
 	UserDataEventTypeListStatus              UserDataEventType = "ListStatus"
+	UserDataEventTypeExternalLockUpdate      UserDataEventType = "externalLockUpdate"
+	UserDataEventTypeListenKeyExpired        UserDataEventType = "listenKeyExpired"
+	UserDataEventTypeEventStreamTerminated   UserDataEventType = "eventStreamTerminated"
+	// margin user data stream events
+	UserDataEventTypeUserLiabilityChange     UserDataEventType = "USER_LIABILITY_CHANGE"
+	UserDataEventTypeMarginLevelStatusChange UserDataEventType = "MARGIN_LEVEL_STATUS_CHANGE"
 
 	MarginTransferTypeToMargin MarginTransferType = 1
 	MarginTransferTypeToMain   MarginTransferType = 2
@@ -368,13 +374,14 @@ func getAPIEndpoint() string {
 // Services will be created by the form client.NewXXXService().
 func NewClient(apiKey, secretKey string) *Client {
 	return &Client{
-		APIKey:     apiKey,
-		SecretKey:  secretKey,
-		KeyType:    common.KeyTypeHmac,
-		BaseURL:    getAPIEndpoint(),
-		UserAgent:  "Binance/golang",
-		HTTPClient: http.DefaultClient,
-		Logger:     log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		APIKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		BaseURL:                getAPIEndpoint(),
+		UserAgent:              "Binance/golang",
+		HTTPClient:             http.DefaultClient,
+		Logger:                 log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX),
 	}
 }
 
@@ -397,7 +404,8 @@ func NewProxiedClient(apiKey, secretKey, proxyUrl string) *Client {
 		HTTPClient: &http.Client{
 			Transport: tr,
 		},
-		Logger: log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		Logger:                 log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX),
 	}
 }
 
@@ -433,6 +441,22 @@ type Client struct {
 
 	UsedWeight common.UsedWeight
 	OrderCount common.OrderCount
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed without one
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
+}
+
+// newClientOrderID return a client order id for an order placed without one
+func (c *Client) newClientOrderID() string {
+	return newSpotClientOrderID(c.ClientOrderIDGenerator)
+}
+
+// newSpotClientOrderID return an id made by ids, or a broker id when ids is nil
+func newSpotClientOrderID(ids *common.ClientOrderIDGenerator) string {
+	if ids == nil {
+		return common.GenerateSpotId()
+	}
+	return ids.NewID()
 }
 
 func (c *Client) debug(format string, v ...interface{}) {
@@ -1456,27 +1480,47 @@ func (c *Client) NewDualInvestmentService() *DualInvestmentService {
 
 // NewOrderCreateWsService init order creation websocket service
 func (c *Client) NewOrderCreateWsService() (*OrderCreateWsService, error) {
-	return NewOrderCreateWsService(c.APIKey, c.SecretKey)
+	s, err := NewOrderCreateWsService(c.APIKey, c.SecretKey)
+	if err == nil && c.ClientOrderIDGenerator != nil {
+		s.ClientOrderIDGenerator = c.ClientOrderIDGenerator
+	}
+	return s, err
 }
 
 // NewOrderListCreateWsService init order list creation websocket service (OCO)
 func (c *Client) NewOrderListCreateWsService() (*OrderListCreateWsService, error) {
-	return NewOrderListCreateWsService(c.APIKey, c.SecretKey)
+	s, err := NewOrderListCreateWsService(c.APIKey, c.SecretKey)
+	if err == nil && c.ClientOrderIDGenerator != nil {
+		s.ClientOrderIDGenerator = c.ClientOrderIDGenerator
+	}
+	return s, err
 }
 
 // NewOrderListPlaceWsService init order list placement websocket service (deprecated OCO)
 func (c *Client) NewOrderListPlaceWsService() (*OrderListPlaceWsService, error) {
-	return NewOrderListPlaceWsService(c.APIKey, c.SecretKey)
+	s, err := NewOrderListPlaceWsService(c.APIKey, c.SecretKey)
+	if err == nil && c.ClientOrderIDGenerator != nil {
+		s.ClientOrderIDGenerator = c.ClientOrderIDGenerator
+	}
+	return s, err
 }
 
 // NewOrderListPlaceOtoWsService init order list placement websocket service (OTO)
 func (c *Client) NewOrderListPlaceOtoWsService() (*OrderListPlaceOtoWsService, error) {
-	return NewOrderListPlaceOtoWsService(c.APIKey, c.SecretKey)
+	s, err := NewOrderListPlaceOtoWsService(c.APIKey, c.SecretKey)
+	if err == nil && c.ClientOrderIDGenerator != nil {
+		s.ClientOrderIDGenerator = c.ClientOrderIDGenerator
+	}
+	return s, err
 }
 
 // NewOrderListPlaceOtocoWsService init order list placement websocket service (OTOCO)
 func (c *Client) NewOrderListPlaceOtocoWsService() (*OrderListPlaceOtocoWsService, error) {
-	return NewOrderListPlaceOtocoWsService(c.APIKey, c.SecretKey)
+	s, err := NewOrderListPlaceOtocoWsService(c.APIKey, c.SecretKey)
+	if err == nil && c.ClientOrderIDGenerator != nil {
+		s.ClientOrderIDGenerator = c.ClientOrderIDGenerator
+	}
+	return s, err
 }
 
 // NewOrderListCancelWsService init order list cancellation websocket service
@@ -1486,10 +1530,29 @@ func (c *Client) NewOrderListCancelWsService() (*OrderListCancelWsService, error
 
 // NewSorOrderPlaceWsService init SOR order placement websocket service
 func (c *Client) NewSorOrderPlaceWsService() (*SorOrderPlaceWsService, error) {
-	return NewSorOrderPlaceWsService(c.APIKey, c.SecretKey)
+	s, err := NewSorOrderPlaceWsService(c.APIKey, c.SecretKey)
+	if err == nil && c.ClientOrderIDGenerator != nil {
+		s.ClientOrderIDGenerator = c.ClientOrderIDGenerator
+	}
+	return s, err
 }
 
 // NewSorOrderTestWsService init SOR order testing websocket service
 func (c *Client) NewSorOrderTestWsService() (*SorOrderTestWsService, error) {
 	return NewSorOrderTestWsService(c.APIKey, c.SecretKey)
 }
+
+// NewUnifiedAccountService init unified cross-product account service
+func (c *Client) NewUnifiedAccountService() *UnifiedAccountService {
+	return &UnifiedAccountService{c: c}
+}
+
+// NewMarginManager init a cross margin manager, use IsolatedSymbol to manage
+// an isolated margin account instead
+func (c *Client) NewMarginManager(errHandler ErrHandler) *MarginManager {
+	return &MarginManager{
+		c:           c,
+		errHandler:  errHandler,
+		borrowRatio: decimalOrZero("0.9"),
+	}
+}
@@ -146,6 +146,56 @@ func (m *mockedClient) do(req *http.Request) (*http.Response, error) {
 	return args.Get(0).(*http.Response), args.Error(1)
 }
 
+// routedResponses serves canned JSON bodies keyed by URL path, for helpers
+// that call several endpoints or several clients in one go. A key can be
+// narrowed with the method, "POST /path", or with query parameters,
+// "/path?type=REPAY".
+type routedResponses map[string]string
+
+func (rr routedResponses) do(req *http.Request) (*http.Response, error) {
+	data, ok := rr[req.Method+" "+req.URL.Path]
+	if !ok {
+		data, ok = rr.matchQuery(req)
+	}
+	if !ok {
+		data, ok = rr[req.URL.Path]
+	}
+	if !ok {
+		return newHTTPResponse([]byte(`{"code":-1,"msg":"unexpected path `+req.URL.Path+`"}`), http.StatusNotFound), nil
+	}
+	return newHTTPResponse([]byte(data), http.StatusOK), nil
+}
+
+func (rr routedResponses) matchQuery(req *http.Request) (string, bool) {
+	query := req.URL.Query()
+	for key, data := range rr {
+		u, err := url.Parse(key)
+		if err != nil || u.Path != req.URL.Path || u.RawQuery == "" {
+			continue
+		}
+		matched := true
+		for k := range u.Query() {
+			if query.Get(k) != u.Query().Get(k) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return data, true
+		}
+	}
+	return "", false
+}
+
+// RoundTrip lets other packages' clients be routed through HTTPClient.Transport
+func (rr routedResponses) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rr.do(req)
+}
+
+func (s *baseTestSuite) mockRoutes(routes routedResponses) {
+	s.client.Client.do = routes.do
+}
+
 func (s *baseTestSuite) assertTradeV3Equal(e, a *TradeV3) {
 	r := s.r()
 	r.Equal(e.ID, a.ID, "ID")
@@ -0,0 +1,179 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MaxClientOrderIDLength is the max length of the client order ids accepted by Binance
+const MaxClientOrderIDLength = 36
+
+const clientOrderIDSeparator = "_"
+
+// clientOrderIDEntropy is the number of random base 36 digits of the default sessions
+const clientOrderIDEntropy = 4
+
+// clientOrderIDSequenceRoom is the number of base 36 digits of the sequence
+// the sessions leave room for, about 1.6M ids
+const clientOrderIDSequenceRoom = 4
+
+// ClientOrderIDGenerator generate client order ids made of a prefix, a tag,
+// a session and a sequence number, e.g. x-B3AUXNYVgrid_lq3x9k2a4f0z_1f. The
+// default session is the start time of the generator in base 36 followed by
+// random digits, so that the ids stay unique across restarts and across the
+// generators started in the same millisecond, the sequence is increased by
+// every id.
+type ClientOrderIDGenerator struct {
+	prefix  string
+	tag     string
+	session string
+	seq     uint64
+}
+
+// ClientOrderIDMeta define the metadata encoded in a client order id
+type ClientOrderIDMeta struct {
+	Prefix   string
+	Tag      string
+	Session  string
+	Sequence uint64
+}
+
+// NewClientOrderIDGenerator init a generator of client order ids starting with prefix
+func NewClientOrderIDGenerator(prefix string) *ClientOrderIDGenerator {
+	g := &ClientOrderIDGenerator{prefix: prefix}
+	g.session = g.fitSession(strconv.FormatInt(time.Now().UnixMilli(), 36) + randomBase36(clientOrderIDEntropy))
+	return g
+}
+
+// fitSession drop the first characters of session so that the ids with an
+// empty tag fit in MaxClientOrderIDLength, at least one character is kept
+func (g *ClientOrderIDGenerator) fitSession(session string) string {
+	max := MaxClientOrderIDLength - len(g.prefix) - 2*len(clientOrderIDSeparator) - clientOrderIDSequenceRoom
+	if max < 1 {
+		max = 1
+	}
+	if len(session) > max {
+		session = session[len(session)-max:]
+	}
+	return session
+}
+
+// randomBase36 return n random base 36 digits
+func randomBase36(n int) string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// fall back to the clock, the ids are still unique within the process
+		binary.BigEndian.PutUint64(b[:], uint64(time.Now().UnixNano()))
+	}
+	digits := strconv.FormatUint(binary.BigEndian.Uint64(b[:]), 36)
+	for len(digits) < n {
+		digits = "0" + digits
+	}
+	return digits[len(digits)-n:]
+}
+
+// Tag set the default tag of the ids, e.g. the name of a strategy
+func (g *ClientOrderIDGenerator) Tag(tag string) *ClientOrderIDGenerator {
+	g.tag = sanitizeClientOrderIDTag(tag)
+	return g
+}
+
+// Session set the session of the ids, e.g. to resume a saved session with
+// Sequence. The first characters of a session too long for the prefix are dropped.
+func (g *ClientOrderIDGenerator) Session(session string) *ClientOrderIDGenerator {
+	g.session = g.fitSession(strings.ReplaceAll(sanitizeClientOrderIDTag(session), clientOrderIDSeparator, ""))
+	return g
+}
+
+// Sequence set the last sequence number used, the next id uses seq+1
+func (g *ClientOrderIDGenerator) Sequence(seq uint64) *ClientOrderIDGenerator {
+	atomic.StoreUint64(&g.seq, seq)
+	return g
+}
+
+// Prefix return the prefix of the ids
+func (g *ClientOrderIDGenerator) Prefix() string {
+	return g.prefix
+}
+
+// CurrentSession return the session of the ids
+func (g *ClientOrderIDGenerator) CurrentSession() string {
+	return g.session
+}
+
+// CurrentSequence return the last sequence number used
+func (g *ClientOrderIDGenerator) CurrentSequence() uint64 {
+	return atomic.LoadUint64(&g.seq)
+}
+
+// NewID return a new id with the default tag
+func (g *ClientOrderIDGenerator) NewID() string {
+	return g.NewTaggedID(g.tag)
+}
+
+// NewTaggedID return a new id with tag, the tag is truncated when the id
+// would be longer than MaxClientOrderIDLength. The ids are only longer when
+// the prefix alone is too long, Binance then rejects the orders.
+func (g *ClientOrderIDGenerator) NewTaggedID(tag string) string {
+	seq := strconv.FormatUint(atomic.AddUint64(&g.seq, 1), 36)
+	tag = sanitizeClientOrderIDTag(tag)
+	session := g.session
+	room := MaxClientOrderIDLength - len(g.prefix) - len(session) - len(seq) - 2*len(clientOrderIDSeparator)
+	if room < 0 && len(session) > 1 {
+		// the sequence outgrew the room left by the session
+		cut := -room
+		if cut > len(session)-1 {
+			cut = len(session) - 1
+		}
+		session, room = session[cut:], room+cut
+	}
+	if room < 0 {
+		room = 0
+	}
+	if len(tag) > room {
+		tag = tag[:room]
+	}
+	return g.prefix + tag + clientOrderIDSeparator + session + clientOrderIDSeparator + seq
+}
+
+// Decode return the metadata of an id made by a generator with the same
+// prefix, false if id wasn't made by such a generator
+func (g *ClientOrderIDGenerator) Decode(id string) (*ClientOrderIDMeta, bool) {
+	if !strings.HasPrefix(id, g.prefix) {
+		return nil, false
+	}
+	rest := id[len(g.prefix):]
+	i := strings.LastIndex(rest, clientOrderIDSeparator)
+	if i < 0 {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(rest[i+1:], 36, 64)
+	if err != nil {
+		return nil, false
+	}
+	rest = rest[:i]
+	j := strings.LastIndex(rest, clientOrderIDSeparator)
+	if j < 0 || j == len(rest)-1 {
+		return nil, false
+	}
+	return &ClientOrderIDMeta{
+		Prefix:   g.prefix,
+		Tag:      rest[:j],
+		Session:  rest[j+1:],
+		Sequence: seq,
+	}, true
+}
+
+// sanitizeClientOrderIDTag drop the characters not allowed in client order ids
+func sanitizeClientOrderIDTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return -1
+	}, tag)
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientOrderIDGenerator(t *testing.T) {
+	assert := assert.New(t)
+	g := NewClientOrderIDGenerator(SPOT_ORDER_PREFIX).Session("lq3x9k2a").Tag("grid")
+
+	id := g.NewID()
+	assert.Equal("x-B3AUXNYVgrid_lq3x9k2a_1", id)
+	assert.Equal("x-B3AUXNYVgrid_lq3x9k2a_2", g.NewID())
+	assert.Equal("x-B3AUXNYVdca_v2_lq3x9k2a_3", g.NewTaggedID("dca_v2!"))
+	assert.Equal(uint64(3), g.CurrentSequence())
+
+	meta, ok := g.Decode("x-B3AUXNYVdca_v2_lq3x9k2a_3")
+	assert.True(ok)
+	assert.Equal(&ClientOrderIDMeta{Prefix: SPOT_ORDER_PREFIX, Tag: "dca_v2", Session: "lq3x9k2a", Sequence: 3}, meta)
+
+	g.Sequence(35)
+	assert.Equal("x-B3AUXNYVgrid_lq3x9k2a_10", g.NewID())
+	meta, ok = g.Decode("x-B3AUXNYV_lq3x9k2a_10")
+	assert.True(ok)
+	assert.Equal("", meta.Tag)
+	assert.Equal(uint64(36), meta.Sequence)
+
+	for _, id := range []string{"x-ftGmvgANgrid_lq3x9k2a_1", "x-B3AUXNYV" + Uuid22(), "x-B3AUXNYVgrid_1", "x-B3AUXNYVgrid_s_!"} {
+		_, ok = g.Decode(id)
+		assert.False(ok, id)
+	}
+}
+
+func TestClientOrderIDGeneratorLength(t *testing.T) {
+	assert := assert.New(t)
+	g := NewClientOrderIDGenerator(CONTRACT_ORDER_PREFIX)
+	first := g.NewID()
+	assert.True(strings.HasPrefix(first, CONTRACT_ORDER_PREFIX+"_"))
+
+	id := g.NewTaggedID(strings.Repeat("t", 40))
+	assert.Len(id, MaxClientOrderIDLength)
+	meta, ok := g.Decode(id)
+	assert.True(ok)
+	assert.Equal(g.CurrentSession(), meta.Session)
+	assert.Equal(uint64(2), meta.Sequence)
+
+	// a new generator never repeats the ids of a previous one
+	assert.NotEqual(g.CurrentSession(), "")
+	assert.NotEqual(first, NewClientOrderIDGenerator(CONTRACT_ORDER_PREFIX).Session("other").NewID())
+
+	// nor do generators started in the same millisecond
+	sessions := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		sessions[NewClientOrderIDGenerator(CONTRACT_ORDER_PREFIX).CurrentSession()] = true
+	}
+	assert.Len(sessions, 100)
+}
+
+func TestClientOrderIDGeneratorSession(t *testing.T) {
+	assert := assert.New(t)
+	g := NewClientOrderIDGenerator(SPOT_ORDER_PREFIX).Session("s 1_é!")
+	assert.Equal("s1", g.CurrentSession())
+	assert.Equal("x-B3AUXNYV_s1_1", g.NewID())
+
+	// the sessions too long for the prefix are shortened
+	g.Session(strings.Repeat("s", 29) + "t")
+	id := g.NewID()
+	assert.LessOrEqual(len(id), MaxClientOrderIDLength)
+	assert.Equal(g.CurrentSession(), id[len(SPOT_ORDER_PREFIX)+1:len(id)-2])
+	assert.True(strings.HasSuffix(g.CurrentSession(), "t"))
+	g = NewClientOrderIDGenerator(strings.Repeat("p", 30))
+	assert.Len(g.CurrentSession(), 1)
+	assert.NotPanics(func() { g.NewID() })
+
+	// the sequence outgrowing its room shortens the session
+	g = NewClientOrderIDGenerator(SPOT_ORDER_PREFIX).Sequence(1 << 40)
+	id = g.NewID()
+	assert.LessOrEqual(len(id), MaxClientOrderIDLength)
+	meta, ok := g.Decode(id)
+	assert.True(ok)
+	assert.Equal(uint64(1<<40+1), meta.Sequence)
+}
@@ -0,0 +1,871 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/adshao/go-binance/v2/options"
+)
+
+// ContingencyType define how the orders of a ContingentGroup are linked
+type ContingencyType string
+
+// Global enums
+const (
+	ContingencyTypeOCO          ContingencyType = "OCO"
+	ContingencyTypeOTO          ContingencyType = "OTO"
+	ContingencyTypeTrailingStop ContingencyType = "TRAILING_STOP"
+)
+
+// contingentActionTimeout is the timeout of the orders placed and canceled on events
+const contingentActionTimeout = 10 * time.Second
+
+// ContingentOrder define an order of a ContingentGroup and its state. The
+// types, time in force and working types are those of the market.
+type ContingentOrder struct {
+	Symbol       string   `json:"symbol"`
+	Side         SideType `json:"side"`
+	PositionSide string   `json:"positionSide,omitempty"`
+	Type         string   `json:"type"`
+	TimeInForce  string   `json:"timeInForce,omitempty"`
+	// Quantity may be empty for the pending orders of an OTO, they are then
+	// placed with the executed quantity of the working order
+	Quantity      string `json:"quantity,omitempty"`
+	Price         string `json:"price,omitempty"`
+	StopPrice     string `json:"stopPrice,omitempty"`
+	WorkingType   string `json:"workingType,omitempty"`
+	ReduceOnly    bool   `json:"reduceOnly,omitempty"`
+	ClientOrderID string `json:"clientOrderId,omitempty"`
+
+	OrderID          int64  `json:"orderId,omitempty"`
+	Status           string `json:"status,omitempty"`
+	ExecutedQuantity string `json:"executedQuantity,omitempty"`
+	AvgPrice         string `json:"avgPrice,omitempty"`
+
+	canceling bool
+}
+
+func (o *ContingentOrder) isFinal() bool {
+	return orderStatusRank(o.Status) == orderStatusRankFinal
+}
+
+func (o *ContingentOrder) isFilled() bool {
+	return decimalOrZero(o.ExecutedQuantity).IsPositive()
+}
+
+// merge apply a status unless it's older than the current one
+func (o *ContingentOrder) merge(status *ChildOrderStatus) bool {
+	if o.Status != "" && orderStatusRank(status.Status) < orderStatusRank(o.Status) {
+		return false
+	}
+	if decimalOrZero(status.ExecutedQuantity).LessThan(decimalOrZero(o.ExecutedQuantity)) {
+		return false
+	}
+	o.Status = status.Status
+	o.ExecutedQuantity = status.ExecutedQuantity
+	if status.OrderID != 0 {
+		o.OrderID = status.OrderID
+	}
+	if status.AvgPrice != "" {
+		o.AvgPrice = status.AvgPrice
+	}
+	return true
+}
+
+// TrailingCallback return the stop price of a trailing stop from the best
+// price seen since its activation, the highest for a sell stop and the lowest
+// for a buy stop
+type TrailingCallback func(side SideType, best decimal.Decimal) decimal.Decimal
+
+// TrailingCallbackRate return a callback trailing the best price by rate, e.g. 0.01 for 1%
+func TrailingCallbackRate(rate string) TrailingCallback {
+	r := decimalOrZero(rate)
+	return func(side SideType, best decimal.Decimal) decimal.Decimal {
+		if side == SideTypeSell {
+			return best.Mul(decimal.NewFromInt(1).Sub(r))
+		}
+		return best.Mul(decimal.NewFromInt(1).Add(r))
+	}
+}
+
+// TrailingStop define a client-side trailing stop, its order is placed once
+// the price crosses the stop price
+type TrailingStop struct {
+	// ActivationPrice is the price to reach before trailing, the current price when empty
+	ActivationPrice string `json:"activationPrice,omitempty"`
+	// CallbackRate is used when Callback is nil, e.g. 0.01 for 1%
+	CallbackRate string           `json:"callbackRate,omitempty"`
+	Callback     TrailingCallback `json:"-"`
+
+	Activated bool   `json:"activated"`
+	BestPrice string `json:"bestPrice,omitempty"`
+	StopPrice string `json:"stopPrice,omitempty"`
+	Triggered bool   `json:"triggered"`
+}
+
+// ContingentGroup define orders linked by the client
+type ContingentGroup struct {
+	ID   string          `json:"id"`
+	Type ContingencyType `json:"type"`
+	// Orders are the legs of an OCO, the working order of an OTO or the
+	// order of a trailing stop
+	Orders []*ContingentOrder `json:"orders"`
+	// Pending are the orders of an OTO placed once its working order is
+	// done, they are then linked as an OCO
+	Pending  []*ContingentOrder `json:"pending,omitempty"`
+	Trailing *TrailingStop      `json:"trailing,omitempty"`
+	Done     bool               `json:"done"`
+}
+
+func (g *ContingentGroup) copy() *ContingentGroup {
+	res := *g
+	copyOrders := func(orders []*ContingentOrder) []*ContingentOrder {
+		if orders == nil {
+			return nil
+		}
+		res := make([]*ContingentOrder, len(orders))
+		for i, o := range orders {
+			order := *o
+			res[i] = &order
+		}
+		return res
+	}
+	res.Orders = copyOrders(g.Orders)
+	res.Pending = copyOrders(g.Pending)
+	if g.Trailing != nil {
+		trailing := *g.Trailing
+		res.Trailing = &trailing
+	}
+	return &res
+}
+
+// ContingentVenue place, query and cancel the orders of contingent groups on a market
+type ContingentVenue interface {
+	PlaceOrder(ctx context.Context, order *ContingentOrder) (*ChildOrderStatus, error)
+	GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error)
+	CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error)
+}
+
+type futuresContingentVenue struct {
+	futuresExecutionVenue
+}
+
+// FuturesContingentVenue return a venue placing the contingent orders on USD-M futures
+func FuturesContingentVenue(c *futures.Client) ContingentVenue {
+	return &futuresContingentVenue{futuresExecutionVenue{c: c}}
+}
+
+func (v *futuresContingentVenue) PlaceOrder(ctx context.Context, order *ContingentOrder) (*ChildOrderStatus, error) {
+	s := v.c.NewCreateOrderService().Symbol(order.Symbol).Side(futures.SideType(order.Side)).
+		Type(futures.OrderType(order.Type)).Quantity(order.Quantity)
+	if order.ClientOrderID != "" {
+		s.NewClientOrderID(order.ClientOrderID)
+	}
+	if order.PositionSide != "" {
+		s.PositionSide(futures.PositionSideType(order.PositionSide))
+	}
+	if order.TimeInForce != "" {
+		s.TimeInForce(futures.TimeInForceType(order.TimeInForce))
+	}
+	if order.Price != "" {
+		s.Price(order.Price)
+	}
+	if order.StopPrice != "" {
+		s.StopPrice(order.StopPrice)
+	}
+	if order.WorkingType != "" {
+		s.WorkingType(futures.WorkingType(order.WorkingType))
+	}
+	if order.ReduceOnly {
+		s.ReduceOnly(true)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         res.AvgPrice,
+	}, nil
+}
+
+type deliveryContingentVenue struct {
+	deliveryExecutionVenue
+}
+
+// DeliveryContingentVenue return a venue placing the contingent orders on COIN-M futures
+func DeliveryContingentVenue(c *delivery.Client) ContingentVenue {
+	return &deliveryContingentVenue{deliveryExecutionVenue{c: c}}
+}
+
+func (v *deliveryContingentVenue) PlaceOrder(ctx context.Context, order *ContingentOrder) (*ChildOrderStatus, error) {
+	s := v.c.NewCreateOrderService().Symbol(order.Symbol).Side(delivery.SideType(order.Side)).
+		Type(delivery.OrderType(order.Type)).Quantity(order.Quantity)
+	if order.ClientOrderID != "" {
+		s.NewClientOrderID(order.ClientOrderID)
+	}
+	if order.PositionSide != "" {
+		s.PositionSide(delivery.PositionSideType(order.PositionSide))
+	}
+	if order.TimeInForce != "" {
+		s.TimeInForce(delivery.TimeInForceType(order.TimeInForce))
+	}
+	if order.Price != "" {
+		s.Price(order.Price)
+	}
+	if order.StopPrice != "" {
+		s.StopPrice(order.StopPrice)
+	}
+	if order.WorkingType != "" {
+		s.WorkingType(delivery.WorkingType(order.WorkingType))
+	}
+	if order.ReduceOnly {
+		s.ReduceOnly(true)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         res.AvgPrice,
+	}, nil
+}
+
+type optionsContingentVenue struct {
+	c *options.Client
+}
+
+// OptionsContingentVenue return a venue placing the contingent orders on
+// options, which only have limit orders
+func OptionsContingentVenue(c *options.Client) ContingentVenue {
+	return &optionsContingentVenue{c: c}
+}
+
+func optionsChildOrderStatus(res *options.Order) *ChildOrderStatus {
+	return &ChildOrderStatus{
+		OrderID:          res.OrderId,
+		ClientOrderID:    res.ClientOrderId,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQty,
+		AvgPrice:         res.AvgPrice,
+	}
+}
+
+func (v *optionsContingentVenue) PlaceOrder(ctx context.Context, order *ContingentOrder) (*ChildOrderStatus, error) {
+	if order.StopPrice != "" {
+		return nil, errors.New("options don't support stop orders")
+	}
+	s := v.c.NewCreateOrderService().Symbol(order.Symbol).Side(options.SideType(order.Side)).
+		Type(options.OrderType(order.Type)).Quantity(order.Quantity).ClientOrderId(order.ClientOrderID)
+	if order.TimeInForce != "" {
+		s.TimeInForce(options.TimeInForceType(order.TimeInForce))
+	}
+	if order.Price != "" {
+		s.Price(order.Price)
+	}
+	if order.ReduceOnly {
+		s.ReduceOnly(true)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return optionsChildOrderStatus(res), nil
+}
+
+func (v *optionsContingentVenue) GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewGetOrderService().Symbol(symbol).ClientOrderId(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return optionsChildOrderStatus(res), nil
+}
+
+func (v *optionsContingentVenue) CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewCancelOrderService().Symbol(symbol).ClientOrderId(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return optionsChildOrderStatus(res), nil
+}
+
+// contingentAction define an order to place or cancel
+type contingentAction struct {
+	group  *ContingentGroup
+	order  *ContingentOrder
+	cancel bool
+}
+
+// ContingentOrderManager link orders on the markets without order lists,
+// USD-M futures, COIN-M futures and options. The unfilled legs of an OCO are
+// canceled as soon as one of them is filled, even partially, or canceled. The pending
+// orders of an OTO are placed once its working order is done with a fill.
+// Trailing stops follow the prices fed to the manager and place their order
+// when the price crosses the stop.
+//
+// The manager follows the ORDER_TRADE_UPDATE events of the user data stream
+// of its market, the orders are placed and canceled by the event handlers.
+// OnChange is called with every change of a group so that it can be saved,
+// Restore resumes the saved groups after a restart.
+type ContingentOrderManager struct {
+	// OnError is called with the errors of the orders placed and canceled by the handlers
+	OnError func(err error)
+	// OnChange is called with a copy of a group every time it changes
+	OnChange func(group *ContingentGroup)
+	// ClientOrderIDGenerator generate the client order ids of the orders
+	// without one, the generator of the client of the venue by default
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
+
+	venue      ContingentVenue
+	prefix     string
+	indexedIDs bool
+	mu         sync.Mutex
+	seq        int
+	groups     map[string]*ContingentGroup
+	orders     map[string]*ContingentGroup
+}
+
+// NewContingentOrderManager init a manager placing its orders on venue
+func NewContingentOrderManager(venue ContingentVenue) *ContingentOrderManager {
+	return &ContingentOrderManager{
+		ClientOrderIDGenerator: venueClientOrderIDs(venue),
+		venue:                  venue,
+		prefix:                 "c" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		groups:                 make(map[string]*ContingentGroup),
+		orders:                 make(map[string]*ContingentGroup),
+	}
+}
+
+// ClientOrderIDPrefix set the prefix of the group ids, the client order ids
+// of the orders without one are then the group id followed by their index
+// instead of the ids of ClientOrderIDGenerator
+func (m *ContingentOrderManager) ClientOrderIDPrefix(prefix string) *ContingentOrderManager {
+	m.prefix = prefix
+	m.indexedIDs = true
+	return m
+}
+
+// add register a new group and assign the missing client order ids, the lock must be held
+func (m *ContingentOrderManager) add(g *ContingentGroup) {
+	if g.ID == "" {
+		m.seq++
+		g.ID = fmt.Sprintf("%s-%d", m.prefix, m.seq)
+	}
+	for i, o := range append(append([]*ContingentOrder{}, g.Orders...), g.Pending...) {
+		if o.ClientOrderID == "" {
+			if m.indexedIDs {
+				o.ClientOrderID = fmt.Sprintf("%s-%d", g.ID, i)
+			} else {
+				o.ClientOrderID = m.ClientOrderIDGenerator.NewID()
+			}
+		}
+		m.orders[o.ClientOrderID] = g
+	}
+	m.groups[g.ID] = g
+}
+
+// PlaceOCO place orders canceling each other, e.g. a take profit and a stop loss
+func (m *ContingentOrderManager) PlaceOCO(ctx context.Context, orders ...*ContingentOrder) (*ContingentGroup, error) {
+	if len(orders) < 2 {
+		return nil, errors.New("an OCO needs at least 2 orders")
+	}
+	return m.place(ctx, &ContingentGroup{Type: ContingencyTypeOCO, Orders: orders})
+}
+
+// PlaceOTO place a working order and hold the pending orders until it's
+// done, e.g. an entry and its take profit and stop loss
+func (m *ContingentOrderManager) PlaceOTO(ctx context.Context, working *ContingentOrder, pending ...*ContingentOrder) (*ContingentGroup, error) {
+	if len(pending) == 0 {
+		return nil, errors.New("an OTO needs pending orders")
+	}
+	return m.place(ctx, &ContingentGroup{Type: ContingencyTypeOTO, Orders: []*ContingentOrder{working}, Pending: pending})
+}
+
+func (m *ContingentOrderManager) place(ctx context.Context, g *ContingentGroup) (*ContingentGroup, error) {
+	m.mu.Lock()
+	m.add(g)
+	orders := append([]*ContingentOrder{}, g.Orders...)
+	m.mu.Unlock()
+	// the events of the orders may come before their responses
+	for _, o := range orders {
+		m.mu.Lock()
+		skip := g.Done || o.Status != ""
+		m.mu.Unlock()
+		if skip {
+			continue
+		}
+		if unplaced, err := m.placeOrder(ctx, g, o); err != nil {
+			if unplaced {
+				m.mu.Lock()
+				o.Status = string(OrderStatusTypeRejected)
+				m.mu.Unlock()
+				m.run(m.advance(g))
+			}
+			return g.copy(), err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return g.copy(), nil
+}
+
+// AddTrailingStop place order once the price fed to the manager for its
+// symbol crosses the trailing stop
+func (m *ContingentOrderManager) AddTrailingStop(order *ContingentOrder, trailing *TrailingStop) *ContingentGroup {
+	g := &ContingentGroup{Type: ContingencyTypeTrailingStop, Orders: []*ContingentOrder{order}, Trailing: trailing}
+	m.mu.Lock()
+	m.add(g)
+	res := g.copy()
+	m.mu.Unlock()
+	m.changed(g)
+	return res
+}
+
+// SetTrailingCallback set the callback of a restored trailing stop
+func (m *ContingentOrderManager) SetTrailingCallback(id string, callback TrailingCallback) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if g, ok := m.groups[id]; ok && g.Trailing != nil {
+		g.Trailing.Callback = callback
+	}
+}
+
+// Group return a copy of a group, nil if unknown
+func (m *ContingentOrderManager) Group(id string) *ContingentGroup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if g, ok := m.groups[id]; ok {
+		return g.copy()
+	}
+	return nil
+}
+
+// Groups return a copy of the groups that are not done, to be saved
+func (m *ContingentOrderManager) Groups() []*ContingentGroup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]*ContingentGroup, 0, len(m.groups))
+	for _, g := range m.groups {
+		if !g.Done {
+			res = append(res, g.copy())
+		}
+	}
+	return res
+}
+
+// Cancel cancel the open orders of a group and drop its pending orders
+func (m *ContingentOrderManager) Cancel(ctx context.Context, id string) error {
+	m.mu.Lock()
+	g, ok := m.groups[id]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("unknown contingent group %s", id)
+	}
+	g.Done = true
+	var actions []*contingentAction
+	for _, o := range g.Orders {
+		if o.Status != "" && !o.isFinal() && !o.canceling {
+			o.canceling = true
+			actions = append(actions, &contingentAction{group: g, order: o, cancel: true})
+		}
+	}
+	m.forget(g)
+	m.mu.Unlock()
+	m.changed(g)
+	var errs []error
+	for _, a := range actions {
+		if err := m.cancelOrder(ctx, a); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// Restore resume saved groups, the orders are checked to apply what
+// happened while the manager was stopped. Orders whose placement wasn't
+// recorded are placed if they are still needed once all the orders of
+// their group are checked.
+func (m *ContingentOrderManager) Restore(ctx context.Context, groups []*ContingentGroup) error {
+	for _, g := range groups {
+		if g.Done {
+			continue
+		}
+		if len(g.Orders) == 0 {
+			return fmt.Errorf("group %s has no orders", g.ID)
+		}
+		for _, orders := range [][]*ContingentOrder{g.Orders, g.Pending} {
+			for _, o := range orders {
+				if o == nil {
+					return fmt.Errorf("group %s has a nil order", g.ID)
+				}
+			}
+		}
+	}
+	for _, saved := range groups {
+		g := saved.copy()
+		if g.Done {
+			continue
+		}
+		m.mu.Lock()
+		m.add(g)
+		m.mu.Unlock()
+		var unplaced []*ContingentOrder
+		for _, o := range g.Orders {
+			if o.isFinal() || (g.Trailing != nil && !g.Trailing.Triggered) {
+				continue
+			}
+			status, err := m.venue.GetOrder(ctx, o.Symbol, o.ClientOrderID)
+			if err == nil {
+				m.apply(g, o, status)
+				continue
+			}
+			if !isUnknownOrder(err) || o.Status != "" {
+				return err
+			}
+			unplaced = append(unplaced, o)
+		}
+		m.run(m.advance(g))
+		var actions []*contingentAction
+		m.mu.Lock()
+		for _, o := range unplaced {
+			// the siblings may have filled or canceled it meanwhile
+			if !g.Done && o.Status == "" {
+				actions = append(actions, &contingentAction{group: g, order: o})
+			}
+		}
+		m.mu.Unlock()
+		m.run(actions)
+	}
+	return nil
+}
+
+// HandleFuturesUserDataEvent apply the ORDER_TRADE_UPDATE events of USD-M futures
+func (m *ContingentOrderManager) HandleFuturesUserDataEvent(event *futures.WsUserDataEvent) {
+	if event.Event != futures.UserDataEventTypeOrderTradeUpdate {
+		return
+	}
+	u := event.OrderTradeUpdate
+	m.HandleOrderUpdate(&ChildOrderStatus{
+		OrderID:          u.ID,
+		ClientOrderID:    u.ClientOrderID,
+		Status:           string(u.Status),
+		ExecutedQuantity: u.AccumulatedFilledQty,
+		AvgPrice:         u.AveragePrice,
+	})
+}
+
+// HandleDeliveryUserDataEvent apply the ORDER_TRADE_UPDATE events of COIN-M futures
+func (m *ContingentOrderManager) HandleDeliveryUserDataEvent(event *delivery.WsUserDataEvent) {
+	if event.Event != delivery.UserDataEventTypeOrderTradeUpdate {
+		return
+	}
+	u := event.OrderTradeUpdate
+	m.HandleOrderUpdate(&ChildOrderStatus{
+		OrderID:          u.ID,
+		ClientOrderID:    u.ClientOrderID,
+		Status:           string(u.Status),
+		ExecutedQuantity: u.AccumulatedFilledQty,
+		AvgPrice:         u.AveragePrice,
+	})
+}
+
+// HandleOptionsUserDataEvent apply the ORDER_TRADE_UPDATE events of options
+func (m *ContingentOrderManager) HandleOptionsUserDataEvent(event *options.WsUserDataEvent) {
+	if event.Event != options.UserDataEventTypeOrderTradeUpdate {
+		return
+	}
+	for _, u := range event.OTU {
+		orderID, _ := strconv.ParseInt(u.OrderId, 10, 64)
+		m.HandleOrderUpdate(&ChildOrderStatus{
+			OrderID:          orderID,
+			ClientOrderID:    u.ClientOrderID,
+			Status:           u.Status,
+			ExecutedQuantity: u.ExecutedQty,
+			AvgPrice:         avgPriceOf(u.ExecutedQty, u.ExecutedCost),
+		})
+	}
+}
+
+// HandleOrderUpdate apply the status of an order of any market
+func (m *ContingentOrderManager) HandleOrderUpdate(status *ChildOrderStatus) {
+	m.mu.Lock()
+	g, ok := m.orders[status.ClientOrderID]
+	var order *ContingentOrder
+	if ok {
+		for _, o := range g.Orders {
+			if o.ClientOrderID == status.ClientOrderID {
+				order = o
+			}
+		}
+	}
+	m.mu.Unlock()
+	if order != nil {
+		m.apply(g, order, status)
+	}
+}
+
+// HandleFuturesMarkPrice feed the USD-M futures mark price to the trailing stops
+func (m *ContingentOrderManager) HandleFuturesMarkPrice(event *futures.WsMarkPriceEvent) {
+	m.HandlePrice(event.Symbol, event.MarkPrice)
+}
+
+// HandleDeliveryMarkPrice feed the COIN-M futures mark price to the trailing stops
+func (m *ContingentOrderManager) HandleDeliveryMarkPrice(event *delivery.WsMarkPriceEvent) {
+	m.HandlePrice(event.Symbol, event.MarkPrice)
+}
+
+// HandleOptionsMarkPrice feed the options mark prices to the trailing stops
+func (m *ContingentOrderManager) HandleOptionsMarkPrice(events []*options.WsMarkPriceEvent) {
+	for _, event := range events {
+		m.HandlePrice(event.Symbol, event.MarkPrice)
+	}
+}
+
+// HandlePrice feed the price of a symbol to the trailing stops, e.g. a last or mark price
+func (m *ContingentOrderManager) HandlePrice(symbol, price string) {
+	p := decimalOrZero(price)
+	if !p.IsPositive() {
+		return
+	}
+	var actions []*contingentAction
+	var changed []*ContingentGroup
+	m.mu.Lock()
+	for _, g := range m.groups {
+		t := g.Trailing
+		o := g.Orders[0]
+		if g.Done || t == nil || t.Triggered || o.Symbol != symbol {
+			continue
+		}
+		if m.trail(g, p) {
+			changed = append(changed, g)
+		}
+		if t.Triggered {
+			actions = append(actions, &contingentAction{group: g, order: o})
+		}
+	}
+	m.mu.Unlock()
+	for _, g := range changed {
+		m.changed(g)
+	}
+	m.run(actions)
+}
+
+// trail move a trailing stop with a price and return whether it changed, the lock must be held
+func (m *ContingentOrderManager) trail(g *ContingentGroup, price decimal.Decimal) bool {
+	t := g.Trailing
+	side := g.Orders[0].Side
+	if !t.Activated {
+		if t.ActivationPrice != "" {
+			activation := decimalOrZero(t.ActivationPrice)
+			if (side == SideTypeSell && price.LessThan(activation)) || (side == SideTypeBuy && price.GreaterThan(activation)) {
+				return false
+			}
+		}
+		t.Activated = true
+		t.BestPrice = ""
+	}
+	best := decimalOrZero(t.BestPrice)
+	if t.BestPrice == "" || (side == SideTypeSell && price.GreaterThan(best)) || (side == SideTypeBuy && price.LessThan(best)) {
+		callback := t.Callback
+		if callback == nil {
+			callback = TrailingCallbackRate(t.CallbackRate)
+		}
+		t.BestPrice = price.String()
+		t.StopPrice = callback(side, price).String()
+		return true
+	}
+	stop := decimalOrZero(t.StopPrice)
+	if (side == SideTypeSell && price.LessThanOrEqual(stop)) || (side == SideTypeBuy && price.GreaterThanOrEqual(stop)) {
+		t.Triggered = true
+		return true
+	}
+	return false
+}
+
+// apply merge the status of an order of a group and run what it triggers
+func (m *ContingentOrderManager) apply(g *ContingentGroup, o *ContingentOrder, status *ChildOrderStatus) {
+	m.mu.Lock()
+	merged := o.merge(status)
+	m.mu.Unlock()
+	if !merged {
+		return
+	}
+	m.changed(g)
+	m.run(m.advance(g))
+}
+
+// advance return the orders to place or cancel after a change of a group
+func (m *ContingentOrderManager) advance(g *ContingentGroup) []*contingentAction {
+	m.mu.Lock()
+	var actions []*contingentAction
+	changed := false
+	switch {
+	case g.Done:
+	case g.Type == ContingencyTypeOTO:
+		working := g.Orders[0]
+		if !working.isFinal() {
+			break
+		}
+		changed = true
+		if !working.isFilled() {
+			g.Done = true
+			break
+		}
+		for _, o := range g.Pending {
+			if o.Quantity == "" {
+				o.Quantity = working.ExecutedQuantity
+			}
+			actions = append(actions, &contingentAction{group: g, order: o})
+		}
+		g.Type = ContingencyTypeOCO
+		g.Orders, g.Pending = g.Pending, nil
+	default:
+		triggered, done := false, true
+		for _, o := range g.Orders {
+			triggered = triggered || o.isFilled() || o.isFinal()
+			done = done && o.isFinal()
+		}
+		if done {
+			g.Done, changed = true, true
+			break
+		}
+		if !triggered || g.Type != ContingencyTypeOCO {
+			break
+		}
+		for _, o := range g.Orders {
+			if o.Status == "" {
+				// not placed yet
+				o.Status, changed = string(OrderStatusTypeCanceled), true
+			} else if !o.isFinal() && !o.isFilled() && !o.canceling {
+				o.canceling = true
+				actions = append(actions, &contingentAction{group: g, order: o, cancel: true})
+			}
+		}
+		done = true
+		for _, o := range g.Orders {
+			done = done && o.isFinal()
+		}
+		g.Done = done
+	}
+	if g.Done {
+		m.forget(g)
+	}
+	m.mu.Unlock()
+	if changed {
+		m.changed(g)
+	}
+	return actions
+}
+
+// forget drop a done group, the lock must be held
+func (m *ContingentOrderManager) forget(g *ContingentGroup) {
+	delete(m.groups, g.ID)
+	for id, og := range m.orders {
+		if og == g {
+			delete(m.orders, id)
+		}
+	}
+}
+
+func (m *ContingentOrderManager) changed(g *ContingentGroup) {
+	if m.OnChange == nil {
+		return
+	}
+	m.mu.Lock()
+	res := g.copy()
+	m.mu.Unlock()
+	m.OnChange(res)
+}
+
+func (m *ContingentOrderManager) handleError(err error) {
+	if m.OnError != nil {
+		m.OnError(err)
+	}
+}
+
+// run place and cancel orders and apply their results
+func (m *ContingentOrderManager) run(actions []*contingentAction) {
+	for _, a := range actions {
+		ctx, cancel := context.WithTimeout(context.Background(), contingentActionTimeout)
+		var err error
+		if a.cancel {
+			err = m.cancelOrder(ctx, a)
+		} else {
+			var unplaced bool
+			if unplaced, err = m.placeOrder(ctx, a.group, a.order); unplaced {
+				m.unplaced(a.group, a.order, err)
+			}
+		}
+		cancel()
+		if err != nil {
+			m.handleError(fmt.Errorf("contingent group %s, order %s: %w", a.group.ID, a.order.ClientOrderID, err))
+		}
+	}
+}
+
+// placeOrder place an order of a group and return the error and whether the
+// order is known not to be placed. An order failing to be placed is checked,
+// it may have been placed anyway, e.g. after a timeout.
+func (m *ContingentOrderManager) placeOrder(ctx context.Context, g *ContingentGroup, o *ContingentOrder) (bool, error) {
+	status, err := m.venue.PlaceOrder(ctx, o)
+	if err != nil {
+		var getErr error
+		if status, getErr = m.venue.GetOrder(ctx, o.Symbol, o.ClientOrderID); getErr != nil {
+			return isUnknownOrder(getErr), err
+		}
+	}
+	m.apply(g, o, status)
+	return false, nil
+}
+
+// unplaced handle an order of a trailing stop which couldn't be placed, the
+// stop triggers again after a transient error and is done after a rejection
+func (m *ContingentOrderManager) unplaced(g *ContingentGroup, o *ContingentOrder, err error) {
+	m.mu.Lock()
+	if g.Done || g.Trailing == nil {
+		m.mu.Unlock()
+		return
+	}
+	if isTransientError(err) {
+		g.Trailing.Triggered = false
+	} else {
+		o.Status = string(OrderStatusTypeRejected)
+	}
+	m.mu.Unlock()
+	m.changed(g)
+	m.run(m.advance(g))
+}
+
+// cancelOrder cancel an order, or check it when it can't be canceled anymore
+func (m *ContingentOrderManager) cancelOrder(ctx context.Context, a *contingentAction) error {
+	status, err := m.venue.CancelOrder(ctx, a.order.Symbol, a.order.ClientOrderID)
+	if err != nil {
+		if status, err = m.venue.GetOrder(ctx, a.order.Symbol, a.order.ClientOrderID); err != nil {
+			m.mu.Lock()
+			a.order.canceling = false
+			m.mu.Unlock()
+			return err
+		}
+	}
+	m.apply(a.group, a.order, status)
+	return nil
+}
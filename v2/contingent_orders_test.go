@@ -0,0 +1,395 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type contingentOrderTestSuite struct {
+	baseTestSuite
+}
+
+func TestContingentOrder(t *testing.T) {
+	suite.Run(t, new(contingentOrderTestSuite))
+}
+
+// fakeContingentVenue rest the placed orders, market orders are filled
+type fakeContingentVenue struct {
+	mu       sync.Mutex
+	placed   []*ContingentOrder
+	canceled []string
+	statuses map[string]*ChildOrderStatus
+	// err fails the placements, the orders are placed anyway when accept is set
+	err    error
+	accept bool
+}
+
+func newFakeContingentVenue() *fakeContingentVenue {
+	return &fakeContingentVenue{statuses: make(map[string]*ChildOrderStatus)}
+}
+
+func (v *fakeContingentVenue) PlaceOrder(ctx context.Context, order *ContingentOrder) (*ChildOrderStatus, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.err != nil && !v.accept {
+		return nil, v.err
+	}
+	placed := *order
+	v.placed = append(v.placed, &placed)
+	status := &ChildOrderStatus{OrderID: int64(len(v.placed)), ClientOrderID: order.ClientOrderID, Status: "NEW", ExecutedQuantity: "0"}
+	if order.Type == "MARKET" {
+		status.Status, status.ExecutedQuantity, status.AvgPrice = "FILLED", order.Quantity, "100"
+	}
+	v.statuses[order.ClientOrderID] = status
+	if v.err != nil {
+		return nil, v.err
+	}
+	res := *status
+	return &res, nil
+}
+
+func (v *fakeContingentVenue) GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	status, ok := v.statuses[clientOrderID]
+	if !ok {
+		return nil, &common.APIError{Code: errCodeUnknownOrder, Message: "Order does not exist."}
+	}
+	res := *status
+	return &res, nil
+}
+
+func (v *fakeContingentVenue) CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.canceled = append(v.canceled, clientOrderID)
+	status := v.statuses[clientOrderID]
+	if status.Status == "FILLED" {
+		return nil, &common.APIError{Code: errCodeUnknownOrder, Message: "Unknown order sent."}
+	}
+	status.Status = "CANCELED"
+	res := *status
+	return &res, nil
+}
+
+func (v *fakeContingentVenue) set(clientOrderID, status, executed string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.statuses[clientOrderID].Status = status
+	v.statuses[clientOrderID].ExecutedQuantity = executed
+}
+
+func takeProfit() *ContingentOrder {
+	return &ContingentOrder{Symbol: "BTCUSDT", Side: SideTypeSell, Type: "LIMIT", TimeInForce: "GTC", Price: "110", ReduceOnly: true}
+}
+
+func stopLoss() *ContingentOrder {
+	return &ContingentOrder{Symbol: "BTCUSDT", Side: SideTypeSell, Type: "STOP_MARKET", StopPrice: "90", ReduceOnly: true}
+}
+
+func (s *contingentOrderTestSuite) TestOCO() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	var changes []*ContingentGroup
+	m.OnChange = func(group *ContingentGroup) {
+		changes = append(changes, group)
+	}
+	tp, sl := takeProfit(), stopLoss()
+	tp.Quantity, sl.Quantity = "1", "1"
+	g, err := m.PlaceOCO(newContext(), tp, sl)
+	r := s.r()
+	r.NoError(err)
+	r.Equal("t-1", g.ID)
+	r.Equal("t-1-0", g.Orders[0].ClientOrderID)
+	r.Equal("t-1-1", g.Orders[1].ClientOrderID)
+	r.Equal("NEW", g.Orders[1].Status)
+	r.Len(m.Groups(), 1)
+
+	venue.set("t-1-0", "PARTIALLY_FILLED", "0.4")
+	m.HandleFuturesUserDataEvent(&futures.WsUserDataEvent{
+		Event: futures.UserDataEventTypeOrderTradeUpdate,
+		WsUserDataOrderTradeUpdate: futures.WsUserDataOrderTradeUpdate{
+			OrderTradeUpdate: futures.WsOrderTradeUpdate{
+				ClientOrderID: "t-1-0", ID: 1, Status: futures.OrderStatusTypePartiallyFilled, AccumulatedFilledQty: "0.4",
+			},
+		},
+	})
+	r.Equal([]string{"t-1-1"}, venue.canceled)
+	g = m.Group("t-1")
+	r.Equal("CANCELED", g.Orders[1].Status)
+	r.False(g.Done)
+
+	// events older than the state are ignored
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-1", Status: "NEW", ExecutedQuantity: "0"})
+	r.Equal("CANCELED", m.Group("t-1").Orders[1].Status)
+
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-0", Status: "FILLED", ExecutedQuantity: "1"})
+	r.Nil(m.Group("t-1"))
+	r.Len(m.Groups(), 0)
+	r.True(changes[len(changes)-1].Done)
+}
+
+func (s *contingentOrderTestSuite) TestOCOCancelFilled() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	tp, sl := takeProfit(), stopLoss()
+	tp.Quantity, sl.Quantity = "1", "1"
+	_, err := m.PlaceOCO(newContext(), tp, sl)
+	r := s.r()
+	r.NoError(err)
+
+	// both legs fill before the events come
+	venue.set("t-1-0", "FILLED", "1")
+	venue.set("t-1-1", "FILLED", "1")
+	var errs []error
+	m.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-0", Status: "FILLED", ExecutedQuantity: "1"})
+	r.Empty(errs)
+	r.Nil(m.Group("t-1"))
+}
+
+func (s *contingentOrderTestSuite) TestOTO() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	entry := &ContingentOrder{Symbol: "BTCUSDT", Side: SideTypeBuy, Type: "LIMIT", TimeInForce: "GTC", Price: "100", Quantity: "2"}
+	g, err := m.PlaceOTO(newContext(), entry, takeProfit(), stopLoss())
+	r := s.r()
+	r.NoError(err)
+	r.Equal(ContingencyTypeOTO, g.Type)
+	r.Len(venue.placed, 1)
+	r.Len(g.Pending, 2)
+
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-0", Status: "PARTIALLY_FILLED", ExecutedQuantity: "1"})
+	r.Len(venue.placed, 1)
+
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-0", Status: "CANCELED", ExecutedQuantity: "1.5"})
+	r.Len(venue.placed, 3)
+	r.Equal("1.5", venue.placed[1].Quantity)
+	r.Equal("t-1-1", venue.placed[1].ClientOrderID)
+	r.Equal("1.5", venue.placed[2].Quantity)
+	g = m.Group("t-1")
+	r.Equal(ContingencyTypeOCO, g.Type)
+	r.Len(g.Orders, 2)
+	r.Empty(g.Pending)
+
+	venue.set("t-1-2", "FILLED", "1.5")
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-2", Status: "FILLED", ExecutedQuantity: "1.5"})
+	r.Equal([]string{"t-1-1"}, venue.canceled)
+	r.Nil(m.Group("t-1"))
+}
+
+func (s *contingentOrderTestSuite) TestOTONotFilled() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	entry := &ContingentOrder{Symbol: "BTCUSDT", Side: SideTypeBuy, Type: "LIMIT", TimeInForce: "GTC", Price: "100", Quantity: "2"}
+	_, err := m.PlaceOTO(newContext(), entry, takeProfit())
+	r := s.r()
+	r.NoError(err)
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-0", Status: "EXPIRED", ExecutedQuantity: "0"})
+	r.Len(venue.placed, 1)
+	r.Nil(m.Group("t-1"))
+}
+
+func (s *contingentOrderTestSuite) TestTrailingStop() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	order := &ContingentOrder{Symbol: "BTCUSDT", Side: SideTypeSell, Type: "MARKET", Quantity: "1", ReduceOnly: true}
+	g := m.AddTrailingStop(order, &TrailingStop{ActivationPrice: "105", CallbackRate: "0.1"})
+	r := s.r()
+
+	m.HandlePrice("BTCUSDT", "100")
+	r.False(m.Group(g.ID).Trailing.Activated)
+	m.HandlePrice("BTCUSDT", "110")
+	m.HandlePrice("ETHUSDT", "1")
+	m.HandlePrice("BTCUSDT", "120")
+	m.HandlePrice("BTCUSDT", "115")
+	t := m.Group(g.ID).Trailing
+	r.True(t.Activated)
+	r.Equal("120", t.BestPrice)
+	r.Equal("108", t.StopPrice)
+	r.Empty(venue.placed)
+
+	m.HandleFuturesMarkPrice(&futures.WsMarkPriceEvent{Symbol: "BTCUSDT", MarkPrice: "107.5"})
+	r.Len(venue.placed, 1)
+	r.Nil(m.Group(g.ID))
+}
+
+func (s *contingentOrderTestSuite) TestTrailingCallback() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	order := &ContingentOrder{Symbol: "BTCUSDT", Side: SideTypeBuy, Type: "MARKET", Quantity: "1"}
+	g := m.AddTrailingStop(order, &TrailingStop{Callback: func(side SideType, best decimal.Decimal) decimal.Decimal {
+		return best.Add(decimal.NewFromInt(5))
+	}})
+	r := s.r()
+	m.HandlePrice("BTCUSDT", "100")
+	m.HandlePrice("BTCUSDT", "90")
+	r.Equal("95", m.Group(g.ID).Trailing.StopPrice)
+	m.HandlePrice("BTCUSDT", "94")
+	r.Empty(venue.placed)
+	m.HandlePrice("BTCUSDT", "95")
+	r.Len(venue.placed, 1)
+}
+
+func (s *contingentOrderTestSuite) TestCancel() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	tp, sl := takeProfit(), stopLoss()
+	tp.Quantity, sl.Quantity = "1", "1"
+	_, err := m.PlaceOCO(newContext(), tp, sl)
+	r := s.r()
+	r.NoError(err)
+	r.NoError(m.Cancel(newContext(), "t-1"))
+	r.ElementsMatch([]string{"t-1-0", "t-1-1"}, venue.canceled)
+	r.Nil(m.Group("t-1"))
+	r.Error(m.Cancel(newContext(), "t-1"))
+}
+
+func (s *contingentOrderTestSuite) TestRestore() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	var saved []byte
+	m.OnChange = func(group *ContingentGroup) {
+		saved, _ = json.Marshal([]*ContingentGroup{group})
+	}
+	tp, sl := takeProfit(), stopLoss()
+	tp.Quantity, sl.Quantity = "1", "1"
+	_, err := m.PlaceOCO(newContext(), tp, sl)
+	r := s.r()
+	r.NoError(err)
+
+	// the take profit fills while stopped
+	venue.set("t-1-0", "FILLED", "1")
+	var groups []*ContingentGroup
+	r.NoError(json.Unmarshal(saved, &groups))
+	restored := NewContingentOrderManager(venue)
+	r.NoError(restored.Restore(newContext(), groups))
+	r.Equal([]string{"t-1-1"}, venue.canceled)
+	r.Empty(restored.Groups())
+}
+
+func (s *contingentOrderTestSuite) TestRestoreUnplaced() {
+	venue := newFakeContingentVenue()
+	groups := []*ContingentGroup{{
+		ID:   "t-1",
+		Type: ContingencyTypeTrailingStop,
+		Orders: []*ContingentOrder{{
+			Symbol: "BTCUSDT", Side: SideTypeSell, Type: "LIMIT", TimeInForce: "GTC", Price: "100", Quantity: "1", ClientOrderID: "t-1-0",
+		}},
+		Trailing: &TrailingStop{CallbackRate: "0.01", Activated: true, BestPrice: "110", StopPrice: "108.9", Triggered: true},
+	}}
+	m := NewContingentOrderManager(venue)
+	r := s.r()
+	r.NoError(m.Restore(newContext(), groups))
+	r.Len(venue.placed, 1)
+	g := m.Group("t-1")
+	r.Equal("NEW", g.Orders[0].Status)
+
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-0", Status: "FILLED", ExecutedQuantity: "1"})
+	r.Nil(m.Group("t-1"))
+}
+
+func (s *contingentOrderTestSuite) TestRestoreUnplacedSibling() {
+	venue := newFakeContingentVenue()
+	venue.statuses["t-1-1"] = &ChildOrderStatus{OrderID: 1, ClientOrderID: "t-1-1", Status: "FILLED", ExecutedQuantity: "1"}
+	tp, sl := takeProfit(), stopLoss()
+	tp.Quantity, tp.ClientOrderID = "1", "t-1-0"
+	sl.Quantity, sl.ClientOrderID, sl.Status = "1", "t-1-1", "NEW"
+	m := NewContingentOrderManager(venue)
+	r := s.r()
+	r.NoError(m.Restore(newContext(), []*ContingentGroup{{ID: "t-1", Type: ContingencyTypeOCO, Orders: []*ContingentOrder{tp, sl}}}))
+	// the stop loss filled while stopped, the take profit isn't placed
+	r.Empty(venue.placed)
+	r.Empty(venue.canceled)
+	r.Nil(m.Group("t-1"))
+}
+
+func (s *contingentOrderTestSuite) TestRestoreInvalid() {
+	m := NewContingentOrderManager(newFakeContingentVenue())
+	r := s.r()
+	r.Error(m.Restore(newContext(), []*ContingentGroup{{ID: "t-1", Type: ContingencyTypeTrailingStop, Trailing: &TrailingStop{CallbackRate: "0.01"}}}))
+	r.Empty(m.Groups())
+	m.HandlePrice("BTCUSDT", "100")
+}
+
+func (s *contingentOrderTestSuite) TestClientOrderIDs() {
+	venue := newFakeContingentVenue()
+	m := NewContingentOrderManager(venue)
+	ids := common.NewClientOrderIDGenerator("x-TEST").Tag("oco")
+	m.ClientOrderIDGenerator = ids
+	tp, sl := takeProfit(), stopLoss()
+	tp.Quantity, sl.Quantity = "1", "1"
+	_, err := m.PlaceOCO(newContext(), tp, sl)
+	r := s.r()
+	r.NoError(err)
+	r.Len(venue.placed, 2)
+	for _, o := range venue.placed {
+		meta, ok := ids.Decode(o.ClientOrderID)
+		r.True(ok)
+		r.Equal("oco", meta.Tag)
+	}
+
+	// the venues of the clients use the client generator
+	c := futures.NewClient("", "")
+	r.Same(c.ClientOrderIDGenerator, NewContingentOrderManager(FuturesContingentVenue(c)).ClientOrderIDGenerator)
+}
+
+func (s *contingentOrderTestSuite) TestPlaceAcceptedDespiteError() {
+	venue := newFakeContingentVenue()
+	venue.err, venue.accept = errors.New("timeout"), true
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	tp, sl := takeProfit(), stopLoss()
+	tp.Quantity, sl.Quantity = "1", "1"
+	g, err := m.PlaceOCO(newContext(), tp, sl)
+	r := s.r()
+	r.NoError(err)
+	r.Equal("NEW", g.Orders[0].Status)
+	r.Equal("NEW", g.Orders[1].Status)
+
+	// the events of the orders are still followed
+	m.HandleOrderUpdate(&ChildOrderStatus{ClientOrderID: "t-1-0", Status: "FILLED", ExecutedQuantity: "1"})
+	r.Equal([]string{"t-1-1"}, venue.canceled)
+	r.Nil(m.Group("t-1"))
+}
+
+func (s *contingentOrderTestSuite) TestTrailingStopPlaceError() {
+	venue := newFakeContingentVenue()
+	venue.err = errors.New("timeout")
+	m := NewContingentOrderManager(venue).ClientOrderIDPrefix("t")
+	var errs []error
+	m.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+	order := &ContingentOrder{Symbol: "BTCUSDT", Side: SideTypeSell, Type: "MARKET", Quantity: "1"}
+	g := m.AddTrailingStop(order, &TrailingStop{CallbackRate: "0.1"})
+	r := s.r()
+	m.HandlePrice("BTCUSDT", "100")
+	m.HandlePrice("BTCUSDT", "89")
+	r.Len(errs, 1)
+	r.False(m.Group(g.ID).Trailing.Triggered)
+
+	// the stop triggers again
+	venue.err = nil
+	m.HandlePrice("BTCUSDT", "88")
+	r.Len(venue.placed, 1)
+	r.Nil(m.Group(g.ID))
+
+	// a rejected stop is done
+	venue.err = &common.APIError{Code: -2019, Message: "Margin is insufficient."}
+	g = m.AddTrailingStop(&ContingentOrder{Symbol: "BTCUSDT", Side: SideTypeSell, Type: "MARKET", Quantity: "1"}, &TrailingStop{CallbackRate: "0.1"})
+	m.HandlePrice("BTCUSDT", "100")
+	m.HandlePrice("BTCUSDT", "89")
+	r.Len(errs, 2)
+	r.Nil(m.Group(g.ID))
+}
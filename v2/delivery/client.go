@@ -142,6 +142,8 @@ const (
 	UserDataEventTypeAccountUpdate       UserDataEventType = "ACCOUNT_UPDATE"
 	UserDataEventTypeOrderTradeUpdate    UserDataEventType = "ORDER_TRADE_UPDATE"
 	UserDataEventTypeAccountConfigUpdate UserDataEventType = "ACCOUNT_CONFIG_UPDATE"
+	UserDataEventTypeStrategyUpdate      UserDataEventType = "STRATEGY_UPDATE"
+	UserDataEventTypeGridUpdate          UserDataEventType = "GRID_UPDATE"
 
 	UserDataEventReasonTypeDeposit             UserDataEventReasonType = "DEPOSIT"
 	UserDataEventReasonTypeWithdraw            UserDataEventReasonType = "WITHDRAW"
@@ -188,13 +190,14 @@ func getApiEndpoint() string {
 // Services will be created by the form client.NewXXXService().
 func NewClient(apiKey, secretKey string) *Client {
 	return &Client{
-		APIKey:     apiKey,
-		SecretKey:  secretKey,
-		KeyType:    common.KeyTypeHmac,
-		BaseURL:    getApiEndpoint(),
-		UserAgent:  "Binance/golang",
-		HTTPClient: http.DefaultClient,
-		Logger:     log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		APIKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		BaseURL:                getApiEndpoint(),
+		UserAgent:              "Binance/golang",
+		HTTPClient:             http.DefaultClient,
+		Logger:                 log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.CONTRACT_ORDER_PREFIX),
 	}
 }
 
@@ -216,7 +219,8 @@ func NewProxiedClient(apiKey, secretKey, proxyUrl string) *Client {
 		HTTPClient: &http.Client{
 			Transport: tr,
 		},
-		Logger: log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		Logger:                 log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.CONTRACT_ORDER_PREFIX),
 	}
 }
 
@@ -237,6 +241,17 @@ type Client struct {
 
 	UsedWeight common.UsedWeight
 	OrderCount common.OrderCount
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed without one
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
+}
+
+// newClientOrderID return a client order id for an order placed without one
+func (c *Client) newClientOrderID() string {
+	if c.ClientOrderIDGenerator == nil {
+		return common.GenerateSwapId()
+	}
+	return c.ClientOrderIDGenerator.NewID()
 }
 
 func (c *Client) debug(format string, v ...interface{}) {
@@ -472,6 +487,11 @@ func (c *Client) NewGetPositionRiskService() *GetPositionRiskService {
 	return &GetPositionRiskService{c: c}
 }
 
+// NewGetLeverageBracketService init leverage bracket service
+func (c *Client) NewGetLeverageBracketService() *GetLeverageBracketService {
+	return &GetLeverageBracketService{c: c}
+}
+
 // NewChangeLeverageService init change leverage service
 func (c *Client) NewChangeLeverageService() *ChangeLeverageService {
 	return &ChangeLeverageService{c: c}
@@ -506,3 +526,8 @@ func (c *Client) NewGetFundingInfoService() *GetFundingInfoService {
 func (c *Client) NewFundingRateService() *FundingRateService {
 	return &FundingRateService{c: c}
 }
+
+// NewGetIncomeHistoryService init getting income history service
+func (c *Client) NewGetIncomeHistoryService() *GetIncomeHistoryService {
+	return &GetIncomeHistoryService{c: c}
+}
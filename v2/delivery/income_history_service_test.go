@@ -0,0 +1,53 @@
+package delivery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type incomeHistoryServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestIncomeHistoryService(t *testing.T) {
+	suite.Run(t, new(incomeHistoryServiceTestSuite))
+}
+
+func (s *incomeHistoryServiceTestSuite) TestGetIncomeHistory() {
+	data := []byte(`[
+		{
+			"symbol": "BTCUSD_PERP",
+			"incomeType": "FUNDING_FEE",
+			"income": "-0.00000012",
+			"asset": "BTC",
+			"info": "",
+			"time": 1570636800000,
+			"tranId": "4480321991774044580",
+			"tradeId": ""
+		}
+	]`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"symbol":     "BTCUSD_PERP",
+			"incomeType": "FUNDING_FEE",
+			"startTime":  int64(1570000000000),
+			"limit":      int64(100),
+		})
+		s.assertRequestEqual(e, r)
+	})
+	res, err := s.client.NewGetIncomeHistoryService().Symbol("BTCUSD_PERP").IncomeType("FUNDING_FEE").
+		StartTime(1570000000000).Limit(100).Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal([]*IncomeHistory{{
+		Symbol:     "BTCUSD_PERP",
+		IncomeType: "FUNDING_FEE",
+		Income:     "-0.00000012",
+		Asset:      "BTC",
+		Time:       1570636800000,
+		TranID:     "4480321991774044580",
+	}}, res)
+}
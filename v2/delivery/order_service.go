@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-
-	"github.com/adshao/go-binance/v2/common"
 )
 
 // CreateOrderService create order
@@ -157,7 +155,7 @@ func (s *CreateOrderService) createOrder(ctx context.Context, endpoint string, o
 	if s.newClientOrderID != nil {
 		m["newClientOrderId"] = *s.newClientOrderID
 	} else {
-		m["newClientOrderId"] = common.GenerateSwapId()
+		m["newClientOrderId"] = s.c.newClientOrderID()
 	}
 	if s.stopPrice != nil {
 		m["stopPrice"] = *s.stopPrice
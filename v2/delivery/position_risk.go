@@ -55,6 +55,7 @@ type PositionRisk struct {
 	Symbol           string `json:"symbol"`
 	PositionAmt      string `json:"positionAmt"`
 	EntryPrice       string `json:"entryPrice"`
+	BreakEvenPrice   string `json:"breakEvenPrice"`
 	MarkPrice        string `json:"markPrice"`
 	UnRealizedProfit string `json:"unRealizedProfit"`
 	LiquidationPrice string `json:"liquidationPrice"`
@@ -672,6 +672,8 @@ type WsUserDataEvent struct {
 	TransactionTime     int64              `json:"T"`
 	AccountUpdate       WsAccountUpdate    `json:"a"`
 	OrderTradeUpdate    WsOrderTradeUpdate `json:"o"`
+	StrategyUpdate      WsStrategyUpdate   `json:"su"`
+	GridUpdate          WsGridUpdate       `json:"gu"`
 }
 
 func (e *WsUserDataEvent) UnmarshalJSON(data []byte) error {
@@ -684,6 +686,8 @@ func (e *WsUserDataEvent) UnmarshalJSON(data []byte) error {
 		TransactionTime     int64              `json:"T"`
 		AccountUpdate       WsAccountUpdate    `json:"a"`
 		OrderTradeUpdate    WsOrderTradeUpdate `json:"o"`
+		StrategyUpdate      WsStrategyUpdate   `json:"su"`
+		GridUpdate          WsGridUpdate       `json:"gu"`
 	}
 	if err := json.Unmarshal(data, &tmp); err != nil {
 		return err
@@ -708,9 +712,35 @@ func (e *WsUserDataEvent) UnmarshalJSON(data []byte) error {
 	e.TransactionTime = tmp.TransactionTime
 	e.AccountUpdate = tmp.AccountUpdate
 	e.OrderTradeUpdate = tmp.OrderTradeUpdate
+	e.StrategyUpdate = tmp.StrategyUpdate
+	e.GridUpdate = tmp.GridUpdate
 	return nil
 }
 
+// WsStrategyUpdate define strategy update
+type WsStrategyUpdate struct {
+	StrategyID     int64  `json:"si"`
+	StrategyType   string `json:"st"` // GRID
+	StrategyStatus string `json:"ss"` // NEW, WORKING, CANCELLED, EXPIRED
+	Symbol         string `json:"s"`
+	UpdateTime     int64  `json:"ut"`
+	OpCode         int64  `json:"c"`
+}
+
+// WsGridUpdate define grid update
+type WsGridUpdate struct {
+	StrategyID            int64  `json:"si"`
+	StrategyType          string `json:"st"`
+	StrategyStatus        string `json:"ss"`
+	Symbol                string `json:"s"`
+	RealizedPnL           string `json:"r"`
+	UnmatchedAveragePrice string `json:"up"`
+	UnmatchedQty          string `json:"uq"`
+	UnmatchedFee          string `json:"uf"`
+	MatchedPnL            string `json:"mp"`
+	UpdateTime            int64  `json:"ut"`
+}
+
 // WsAccountUpdate define account update
 type WsAccountUpdate struct {
 	Reason    UserDataEventReasonType `json:"m"`
@@ -734,6 +764,7 @@ type WsPosition struct {
 	MarginType                MarginType       `json:"mt"`
 	IsolatedWallet            string           `json:"iw"`
 	EntryPrice                string           `json:"ep"`
+	BreakEvenPrice            string           `json:"bep"`
 	MarkPrice                 string           `json:"mp"`
 	UnrealizedPnL             string           `json:"up"`
 	AccumulatedRealized       string           `json:"cr"`
@@ -1484,6 +1484,74 @@ func (s *websocketServiceTestSuite) TestWsUserDataServeOrderTradeUpdate() {
 	s.testWsUserDataServe(data, expectedEvent)
 }
 
+func (s *websocketServiceTestSuite) TestWsUserDataServeStrategyUpdate() {
+	data := []byte(`{
+		"e":"STRATEGY_UPDATE",
+		"T":1669782450003,
+		"E":1669782450009,
+		"su":{
+			"si":176054594,
+			"st":"GRID",
+			"ss":"NEW",
+			"s":"BTCUSD_PERP",
+			"ut":1669782450003,
+			"c":8007
+		}
+	}`)
+	expectedEvent := &WsUserDataEvent{
+		Event:           "STRATEGY_UPDATE",
+		Time:            1669782450009,
+		TransactionTime: 1669782450003,
+		StrategyUpdate: WsStrategyUpdate{
+			StrategyID:     176054594,
+			StrategyType:   "GRID",
+			StrategyStatus: "NEW",
+			Symbol:         "BTCUSD_PERP",
+			UpdateTime:     1669782450003,
+			OpCode:         8007,
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeGridUpdate() {
+	data := []byte(`{
+		"e":"GRID_UPDATE",
+		"T":1669782450003,
+		"E":1669782450009,
+		"gu":{
+			"si":176057039,
+			"st":"GRID",
+			"ss":"WORKING",
+			"s":"BTCUSD_PERP",
+			"r":"-0.00300716",
+			"up":"16720",
+			"uq":"-0.001",
+			"uf":"-0.00300716",
+			"mp":"0.0",
+			"ut":1669782450003
+		}
+	}`)
+	expectedEvent := &WsUserDataEvent{
+		Event:           "GRID_UPDATE",
+		Time:            1669782450009,
+		TransactionTime: 1669782450003,
+		GridUpdate: WsGridUpdate{
+			StrategyID:            176057039,
+			StrategyType:          "GRID",
+			StrategyStatus:        "WORKING",
+			Symbol:                "BTCUSD_PERP",
+			RealizedPnL:           "-0.00300716",
+			UnmatchedAveragePrice: "16720",
+			UnmatchedQty:          "-0.001",
+			UnmatchedFee:          "-0.00300716",
+			MatchedPnL:            "0.0",
+			UpdateTime:            1669782450003,
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
 func (s *websocketServiceTestSuite) assertUserDataEvent(e, a *WsUserDataEvent) {
 	r := s.r()
 	r.Equal(e.Event, a.Event, "Event")
@@ -1496,6 +1564,8 @@ func (s *websocketServiceTestSuite) assertUserDataEvent(e, a *WsUserDataEvent) {
 	r.Equal(e.TransactionTime, a.TransactionTime, "TransactionTime")
 	s.assertAccountUpdate(e.AccountUpdate, a.AccountUpdate)
 	s.assertOrderTradeUpdate(e.OrderTradeUpdate, a.OrderTradeUpdate)
+	r.Equal(e.StrategyUpdate, a.StrategyUpdate, "StrategyUpdate")
+	r.Equal(e.GridUpdate, a.GridUpdate, "GridUpdate")
 }
 
 func (s *websocketServiceTestSuite) assertPosition(e, a WsPosition) {
@@ -0,0 +1,1042 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// ExecutionAlgo define how an Execution slices its parent order
+type ExecutionAlgo string
+
+// ExecutionState define the state of an Execution
+type ExecutionState string
+
+// Global enums
+const (
+	ExecutionAlgoTWAP    ExecutionAlgo = "TWAP"
+	ExecutionAlgoVWAP    ExecutionAlgo = "VWAP"
+	ExecutionAlgoPOV     ExecutionAlgo = "POV"
+	ExecutionAlgoIceberg ExecutionAlgo = "ICEBERG"
+
+	ExecutionStateNew      ExecutionState = "NEW"
+	ExecutionStateRunning  ExecutionState = "RUNNING"
+	ExecutionStatePaused   ExecutionState = "PAUSED"
+	ExecutionStateCanceled ExecutionState = "CANCELED"
+	ExecutionStateDone     ExecutionState = "DONE"
+)
+
+// defaultExecutionPollInterval is the delay between two status requests of a resting child order
+const defaultExecutionPollInterval = time.Second
+
+// executionCancelTimeout is the timeout of the cancel of the resting child once ctx is done
+const executionCancelTimeout = 10 * time.Second
+
+// ErrExecutionChildUnknown is returned when a child may have been placed but its status can't be checked
+var ErrExecutionChildUnknown = errors.New("execution child status unknown")
+
+// transientErrorCodes are the codes of the errors which may not happen again,
+// the other codes are rejections, e.g. an insufficient balance or a filter failure
+var transientErrorCodes = map[int64]bool{
+	-1000: true, // unknown error
+	-1001: true, // disconnected
+	-1003: true, // too many requests
+	-1006: true, // unexpected response
+	-1007: true, // timeout
+	-1008: true, // server busy
+	-1015: true, // too many orders
+}
+
+// isTransientError return whether a request may succeed if sent again, e.g.
+// after a timeout, a server error or a rate limit
+func isTransientError(err error) bool {
+	var apiErr *common.APIError
+	if !errors.As(err, &apiErr) || !apiErr.IsValid() {
+		return true
+	}
+	return transientErrorCodes[apiErr.Code]
+}
+
+// ChildOrder define a child order placed by an Execution
+type ChildOrder struct {
+	Symbol        string
+	Side          SideType
+	Quantity      string
+	ClientOrderID string
+	// Price is empty for market orders, limit orders are IOC unless Resting
+	Price   string
+	Resting bool
+}
+
+// ChildOrderStatus define the state of a child order on its market
+type ChildOrderStatus struct {
+	OrderID          int64
+	ClientOrderID    string
+	Status           string
+	ExecutedQuantity string
+	AvgPrice         string
+}
+
+// ExecutionVenue place, query and cancel the child orders of an Execution on a market
+type ExecutionVenue interface {
+	PlaceOrder(ctx context.Context, order *ChildOrder) (*ChildOrderStatus, error)
+	GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error)
+	CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error)
+}
+
+// clientOrderIDVenue is implemented by the venues of the clients, the client
+// order ids of the orders placed on them are made by the client generator
+type clientOrderIDVenue interface {
+	clientOrderIDs() *common.ClientOrderIDGenerator
+}
+
+// venueClientOrderIDs return the generator of the client of venue, or a
+// generator of ids without prefix for the other venues
+func venueClientOrderIDs(venue interface{}) *common.ClientOrderIDGenerator {
+	if v, ok := venue.(clientOrderIDVenue); ok {
+		if ids := v.clientOrderIDs(); ids != nil {
+			return ids
+		}
+	}
+	return common.NewClientOrderIDGenerator("")
+}
+
+// avgPriceOf return the average price of an order from its quote quantity
+func avgPriceOf(executed, cumQuote string) string {
+	qty := decimalOrZero(executed)
+	if qty.IsZero() {
+		return ""
+	}
+	return decimalOrZero(cumQuote).Div(qty).String()
+}
+
+type spotExecutionVenue struct {
+	c *Client
+}
+
+// SpotExecutionVenue return a venue placing the child orders on spot
+func SpotExecutionVenue(c *Client) ExecutionVenue {
+	return &spotExecutionVenue{c: c}
+}
+
+func (v *spotExecutionVenue) clientOrderIDs() *common.ClientOrderIDGenerator {
+	return v.c.ClientOrderIDGenerator
+}
+
+func (v *spotExecutionVenue) PlaceOrder(ctx context.Context, order *ChildOrder) (*ChildOrderStatus, error) {
+	s := v.c.NewCreateOrderService().Symbol(order.Symbol).Side(order.Side).
+		Quantity(order.Quantity).NewClientOrderID(order.ClientOrderID).NewOrderRespType(NewOrderRespTypeRESULT)
+	switch {
+	case order.Price == "":
+		s.Type(OrderTypeMarket)
+	case order.Resting:
+		s.Type(OrderTypeLimit).TimeInForce(TimeInForceTypeGTC).Price(order.Price)
+	default:
+		s.Type(OrderTypeLimit).TimeInForce(TimeInForceTypeIOC).Price(order.Price)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CummulativeQuoteQuantity),
+	}, nil
+}
+
+func (v *spotExecutionVenue) GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewGetOrderService().Symbol(symbol).OrigClientOrderID(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CummulativeQuoteQuantity),
+	}, nil
+}
+
+func (v *spotExecutionVenue) CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewCancelOrderService().Symbol(symbol).OrigClientOrderID(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.OrigClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CummulativeQuoteQuantity),
+	}, nil
+}
+
+type marginExecutionVenue struct {
+	c          *Client
+	isIsolated bool
+}
+
+// MarginExecutionVenue return a venue placing the child orders on the cross
+// or isolated margin account
+func MarginExecutionVenue(c *Client, isIsolated bool) ExecutionVenue {
+	return &marginExecutionVenue{c: c, isIsolated: isIsolated}
+}
+
+func (v *marginExecutionVenue) clientOrderIDs() *common.ClientOrderIDGenerator {
+	return v.c.ClientOrderIDGenerator
+}
+
+func (v *marginExecutionVenue) PlaceOrder(ctx context.Context, order *ChildOrder) (*ChildOrderStatus, error) {
+	s := v.c.NewCreateMarginOrderService().Symbol(order.Symbol).IsIsolated(v.isIsolated).Side(order.Side).
+		Quantity(order.Quantity).NewClientOrderID(order.ClientOrderID).NewOrderRespType(NewOrderRespTypeRESULT)
+	switch {
+	case order.Price == "":
+		s.Type(OrderTypeMarket)
+	case order.Resting:
+		s.Type(OrderTypeLimit).TimeInForce(TimeInForceTypeGTC).Price(order.Price)
+	default:
+		s.Type(OrderTypeLimit).TimeInForce(TimeInForceTypeIOC).Price(order.Price)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CummulativeQuoteQuantity),
+	}, nil
+}
+
+func (v *marginExecutionVenue) GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewGetMarginOrderService().Symbol(symbol).IsIsolated(v.isIsolated).OrigClientOrderID(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CummulativeQuoteQuantity),
+	}, nil
+}
+
+func (v *marginExecutionVenue) CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewCancelMarginOrderService().Symbol(symbol).IsIsolated(v.isIsolated).OrigClientOrderID(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orderID, _ := strconv.ParseInt(res.OrderID, 10, 64)
+	return &ChildOrderStatus{
+		OrderID:          orderID,
+		ClientOrderID:    res.OrigClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CummulativeQuoteQuantity),
+	}, nil
+}
+
+type futuresExecutionVenue struct {
+	c *futures.Client
+}
+
+// FuturesExecutionVenue return a venue placing the child orders on USD-M futures
+func FuturesExecutionVenue(c *futures.Client) ExecutionVenue {
+	return &futuresExecutionVenue{c: c}
+}
+
+func (v *futuresExecutionVenue) clientOrderIDs() *common.ClientOrderIDGenerator {
+	return v.c.ClientOrderIDGenerator
+}
+
+func (v *futuresExecutionVenue) PlaceOrder(ctx context.Context, order *ChildOrder) (*ChildOrderStatus, error) {
+	s := v.c.NewCreateOrderService().Symbol(order.Symbol).Side(futures.SideType(order.Side)).
+		Quantity(order.Quantity).NewClientOrderID(order.ClientOrderID).NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+	switch {
+	case order.Price == "":
+		s.Type(futures.OrderTypeMarket)
+	case order.Resting:
+		s.Type(futures.OrderTypeLimit).TimeInForce(futures.TimeInForceTypeGTC).Price(order.Price)
+	default:
+		s.Type(futures.OrderTypeLimit).TimeInForce(futures.TimeInForceTypeIOC).Price(order.Price)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CumQuote),
+	}, nil
+}
+
+func (v *futuresExecutionVenue) GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewGetOrderService().Symbol(symbol).OrigClientOrderID(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CumQuote),
+	}, nil
+}
+
+func (v *futuresExecutionVenue) CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewCancelOrderService().Symbol(symbol).OrigClientOrderID(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         avgPriceOf(res.ExecutedQuantity, res.CumQuote),
+	}, nil
+}
+
+type deliveryExecutionVenue struct {
+	c *delivery.Client
+}
+
+// DeliveryExecutionVenue return a venue placing the child orders on COIN-M
+// futures, the quantities are numbers of contracts
+func DeliveryExecutionVenue(c *delivery.Client) ExecutionVenue {
+	return &deliveryExecutionVenue{c: c}
+}
+
+func (v *deliveryExecutionVenue) clientOrderIDs() *common.ClientOrderIDGenerator {
+	return v.c.ClientOrderIDGenerator
+}
+
+func (v *deliveryExecutionVenue) PlaceOrder(ctx context.Context, order *ChildOrder) (*ChildOrderStatus, error) {
+	s := v.c.NewCreateOrderService().Symbol(order.Symbol).Side(delivery.SideType(order.Side)).
+		Quantity(order.Quantity).NewClientOrderID(order.ClientOrderID).NewOrderResponseType(delivery.NewOrderRespTypeRESULT)
+	switch {
+	case order.Price == "":
+		s.Type(delivery.OrderTypeMarket)
+	case order.Resting:
+		s.Type(delivery.OrderTypeLimit).TimeInForce(delivery.TimeInForceTypeGTC).Price(order.Price)
+	default:
+		s.Type(delivery.OrderTypeLimit).TimeInForce(delivery.TimeInForceTypeIOC).Price(order.Price)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         res.AvgPrice,
+	}, nil
+}
+
+func (v *deliveryExecutionVenue) GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewGetOrderService().Symbol(symbol).OrigClientOrderID(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         res.AvgPrice,
+	}, nil
+}
+
+func (v *deliveryExecutionVenue) CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	res, err := v.c.NewCancelOrderService().Symbol(symbol).OrigClientOrderID(clientOrderID).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ChildOrderStatus{
+		OrderID:          res.OrderID,
+		ClientOrderID:    res.ClientOrderID,
+		Status:           string(res.Status),
+		ExecutedQuantity: res.ExecutedQuantity,
+		AvgPrice:         res.AvgPrice,
+	}, nil
+}
+
+// VolumeProfile define the share of the daily volume traded in each bucket
+// of the UTC day
+type VolumeProfile struct {
+	Bucket time.Duration
+	Shares []float64
+}
+
+// NewVolumeProfile build the volume profile of the last days complete days
+// from the klines of interval, e.g. 15m, which must divide a day
+func NewVolumeProfile(ctx context.Context, fetch KlinePageFetcher, interval string, days int) (*VolumeProfile, error) {
+	next, err := klineIntervalNext(0, interval)
+	if err != nil {
+		return nil, err
+	}
+	day := 24 * time.Hour
+	bucket := time.Duration(next) * time.Millisecond
+	if bucket > day || day%bucket != 0 {
+		return nil, fmt.Errorf("kline interval %q doesn't divide a day", interval)
+	}
+	if days <= 0 {
+		return nil, errors.New("days must be positive")
+	}
+	endTime := time.Now().UTC().Truncate(day)
+	startTime := endTime.Add(-time.Duration(days) * day)
+	volumes := make([]float64, day/bucket)
+	err = NewKlineBackfill(fetch, interval, startTime.UnixMilli(), endTime.UnixMilli()-1).Do(ctx, func(kline *Kline) error {
+		i := time.Duration(kline.OpenTime%day.Milliseconds()) * time.Millisecond / bucket
+		v, _ := decimalOrZero(kline.Volume).Float64()
+		volumes[i] += v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newVolumeProfile(bucket, volumes)
+}
+
+func newVolumeProfile(bucket time.Duration, volumes []float64) (*VolumeProfile, error) {
+	var total float64
+	for _, v := range volumes {
+		total += v
+	}
+	if total <= 0 {
+		return nil, errors.New("no volume to build the profile from")
+	}
+	shares := make([]float64, len(volumes))
+	for i, v := range volumes {
+		shares[i] = v / total
+	}
+	return &VolumeProfile{Bucket: bucket, Shares: shares}, nil
+}
+
+// Weights return the share of the profile volume of each of the slices
+// splitting [start, start+duration)
+func (p *VolumeProfile) Weights(start time.Time, duration time.Duration, slices int) []float64 {
+	weights := make([]float64, slices)
+	if slices <= 0 || len(p.Shares) == 0 {
+		return weights
+	}
+	day := time.Duration(len(p.Shares)) * p.Bucket
+	sliceDuration := duration / time.Duration(slices)
+	for i := range weights {
+		from := start.Add(time.Duration(i) * sliceDuration)
+		to := from.Add(sliceDuration)
+		for t := from; t.Before(to); {
+			offset := time.Duration(t.UnixNano()) % day
+			if offset < 0 {
+				offset += day
+			}
+			b := offset / p.Bucket
+			end := t.Add(p.Bucket - offset%p.Bucket)
+			if end.After(to) {
+				end = to
+			}
+			weights[i] += p.Shares[b] * float64(end.Sub(t)) / float64(p.Bucket)
+			t = end
+		}
+	}
+	return weights
+}
+
+// ExecutionChild define a child order of an Execution and its fills
+type ExecutionChild struct {
+	Index            int
+	ClientOrderID    string
+	OrderID          int64
+	Quantity         string
+	Price            string
+	Status           string
+	ExecutedQuantity string
+	AvgPrice         string
+	Time             int64
+	// Err is the error of the placement of the child
+	Err error
+}
+
+// ExecutionProgress define the progress of an Execution
+type ExecutionProgress struct {
+	Algo     ExecutionAlgo
+	State    ExecutionState
+	Quantity string
+	Executed string
+	// AvgPrice is the quantity weighted average price of the fills, empty before the first fill
+	AvgPrice string
+	Children []*ExecutionChild
+}
+
+// Execution slice a parent order into child orders placed on a venue. TWAP
+// and VWAP place one child per slice of the schedule, the quantity left by a
+// skipped or partially filled slice is spread over the next ones. POV places
+// children to keep up with a share of the market volume. Iceberg keeps a
+// single resting child of the display quantity at the limit price.
+//
+// The children are market orders, or IOC limit orders at the limit price when
+// one is set, iceberg children rest on the book.
+type Execution struct {
+	// OnChild is called every time a child is placed or its fills change
+	OnChild func(child *ExecutionChild)
+	// ClientOrderIDGenerator generate the client order ids of the children,
+	// the generator of the client of the venue by default
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
+
+	venue    ExecutionVenue
+	algo     ExecutionAlgo
+	symbol   string
+	side     SideType
+	quantity decimal.Decimal
+
+	limitPrice   string
+	step         decimal.Decimal
+	minQuantity  decimal.Decimal
+	prefix       string
+	pollInterval time.Duration
+
+	// TWAP and VWAP
+	startTime time.Time
+	duration  time.Duration
+	weights   []float64
+	profile   *VolumeProfile
+
+	// POV
+	participation decimal.Decimal
+	marketVolume  decimal.Decimal
+
+	// Iceberg
+	display decimal.Decimal
+
+	mu       sync.Mutex
+	state    ExecutionState
+	executed decimal.Decimal
+	notional decimal.Decimal
+	children []*ExecutionChild
+	wake     chan struct{}
+}
+
+func newExecution(venue ExecutionVenue, algo ExecutionAlgo, symbol string, side SideType, quantity string) *Execution {
+	return &Execution{
+		ClientOrderIDGenerator: venueClientOrderIDs(venue),
+		venue:                  venue,
+		algo:                   algo,
+		symbol:                 symbol,
+		side:                   side,
+		quantity:               decimalOrZero(quantity),
+		pollInterval:           defaultExecutionPollInterval,
+		state:                  ExecutionStateNew,
+		wake:                   make(chan struct{}, 1),
+	}
+}
+
+// NewTWAPExecution init an execution of quantity in slices equal children over duration
+func NewTWAPExecution(venue ExecutionVenue, symbol string, side SideType, quantity string, duration time.Duration, slices int) *Execution {
+	e := newExecution(venue, ExecutionAlgoTWAP, symbol, side, quantity)
+	e.duration = duration
+	e.weights = make([]float64, slices)
+	for i := range e.weights {
+		e.weights[i] = 1
+	}
+	return e
+}
+
+// NewVWAPExecution init an execution of quantity in slices children over
+// duration, each sized by the share of the volume the profile expects
+func NewVWAPExecution(venue ExecutionVenue, symbol string, side SideType, quantity string, duration time.Duration, slices int, profile *VolumeProfile) *Execution {
+	e := newExecution(venue, ExecutionAlgoVWAP, symbol, side, quantity)
+	e.duration = duration
+	e.weights = make([]float64, slices)
+	e.profile = profile
+	return e
+}
+
+// NewPOVExecution init an execution of quantity following participation,
+// e.g. 0.1, of the market volume fed by the aggTrade handlers
+func NewPOVExecution(venue ExecutionVenue, symbol string, side SideType, quantity, participation string) *Execution {
+	e := newExecution(venue, ExecutionAlgoPOV, symbol, side, quantity)
+	e.participation = decimalOrZero(participation)
+	return e
+}
+
+// NewIcebergExecution init an execution of quantity showing at most display
+// quantity on the book at price
+func NewIcebergExecution(venue ExecutionVenue, symbol string, side SideType, quantity, display, price string) *Execution {
+	e := newExecution(venue, ExecutionAlgoIceberg, symbol, side, quantity)
+	e.display = decimalOrZero(display)
+	e.limitPrice = price
+	return e
+}
+
+// LimitPrice set the worst price of the children, they are then IOC limit orders
+func (e *Execution) LimitPrice(price string) *Execution {
+	e.limitPrice = price
+	return e
+}
+
+// QuantityStep round the quantities of the children down to a multiple of step, e.g. the LOT_SIZE step
+func (e *Execution) QuantityStep(step string) *Execution {
+	e.step = decimalOrZero(step)
+	return e
+}
+
+// MinQuantity set the smallest quantity of a child, smaller children are not placed
+func (e *Execution) MinQuantity(quantity string) *Execution {
+	e.minQuantity = decimalOrZero(quantity)
+	return e
+}
+
+// ClientOrderIDPrefix set the prefix of the client order ids of the children,
+// followed by their index, instead of the ids of ClientOrderIDGenerator
+func (e *Execution) ClientOrderIDPrefix(prefix string) *Execution {
+	e.prefix = prefix
+	return e
+}
+
+// StartTime set the start of the TWAP and VWAP schedules, the start of Run by default
+func (e *Execution) StartTime(startTime time.Time) *Execution {
+	e.startTime = startTime
+	return e
+}
+
+// PollInterval set the delay between two status requests of a resting iceberg child, 1s by default
+func (e *Execution) PollInterval(interval time.Duration) *Execution {
+	e.pollInterval = interval
+	return e
+}
+
+// Pause stop placing children until Resume, the TWAP and VWAP slices due
+// meanwhile are skipped and the market volume isn't followed
+func (e *Execution) Pause() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state == ExecutionStateRunning || e.state == ExecutionStateNew {
+		e.state = ExecutionStatePaused
+	}
+}
+
+// Resume place children again after Pause
+func (e *Execution) Resume() {
+	e.mu.Lock()
+	if e.state == ExecutionStatePaused {
+		e.state = ExecutionStateRunning
+	}
+	e.mu.Unlock()
+	e.signal()
+}
+
+// Cancel stop the execution, Run cancels the resting child and returns
+func (e *Execution) Cancel() {
+	e.mu.Lock()
+	if e.state != ExecutionStateDone {
+		e.state = ExecutionStateCanceled
+	}
+	e.mu.Unlock()
+	e.signal()
+}
+
+func (e *Execution) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Progress return the state, fills and children of the execution
+func (e *Execution) Progress() *ExecutionProgress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	res := &ExecutionProgress{
+		Algo:     e.algo,
+		State:    e.state,
+		Quantity: e.quantity.String(),
+		Executed: e.executed.String(),
+		Children: make([]*ExecutionChild, len(e.children)),
+	}
+	if !e.executed.IsZero() {
+		res.AvgPrice = e.notional.Div(e.executed).String()
+	}
+	for i, c := range e.children {
+		child := *c
+		res.Children[i] = &child
+	}
+	return res
+}
+
+// HandleAggTradeEvent add the volume of a spot aggregate trade to a POV execution
+func (e *Execution) HandleAggTradeEvent(event *WsAggTradeEvent) {
+	e.addMarketVolume(event.Symbol, event.Quantity)
+}
+
+// HandleFuturesAggTradeEvent add the volume of a USD-M futures aggregate trade to a POV execution
+func (e *Execution) HandleFuturesAggTradeEvent(event *futures.WsAggTradeEvent) {
+	e.addMarketVolume(event.Symbol, event.Quantity)
+}
+
+// HandleDeliveryAggTradeEvent add the volume of a COIN-M futures aggregate trade to a POV execution
+func (e *Execution) HandleDeliveryAggTradeEvent(event *delivery.WsAggTradeEvent) {
+	e.addMarketVolume(event.Symbol, event.Quantity)
+}
+
+func (e *Execution) addMarketVolume(symbol, quantity string) {
+	if symbol != e.symbol {
+		return
+	}
+	e.mu.Lock()
+	running := e.state == ExecutionStateRunning
+	if running {
+		e.marketVolume = e.marketVolume.Add(decimalOrZero(quantity))
+	}
+	e.mu.Unlock()
+	if running {
+		e.signal()
+	}
+}
+
+// Run place the children until the parent order is filled, the schedule is
+// over, the execution is canceled or ctx is done. The children failing with
+// transient errors are retried, Run returns and cancels the execution when a
+// child is rejected, when the status of a child can't be checked, or when
+// ctx is done.
+func (e *Execution) Run(ctx context.Context) error {
+	if e.algo == ExecutionAlgoIceberg && e.limitPrice == "" {
+		return errors.New("iceberg execution without price")
+	}
+	e.mu.Lock()
+	switch e.state {
+	case ExecutionStateNew:
+		e.state = ExecutionStateRunning
+	case ExecutionStatePaused:
+	default:
+		e.mu.Unlock()
+		return fmt.Errorf("execution is %s", e.state)
+	}
+	e.mu.Unlock()
+	var err error
+	switch e.algo {
+	case ExecutionAlgoTWAP, ExecutionAlgoVWAP:
+		err = e.runSchedule(ctx)
+	case ExecutionAlgoPOV:
+		err = e.runPOV(ctx)
+	case ExecutionAlgoIceberg:
+		err = e.runIceberg(ctx)
+	}
+	if err != nil {
+		e.Cancel()
+		return err
+	}
+	e.mu.Lock()
+	if e.state != ExecutionStateCanceled {
+		e.state = ExecutionStateDone
+	}
+	e.mu.Unlock()
+	return nil
+}
+
+// status return whether the execution is canceled or paused and the quantity left
+func (e *Execution) status() (canceled, paused bool, remaining decimal.Decimal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state == ExecutionStateCanceled, e.state == ExecutionStatePaused, e.quantity.Sub(e.executed)
+}
+
+// childQuantity round a quantity down to the step, zero below the minimum
+func (e *Execution) childQuantity(quantity decimal.Decimal) decimal.Decimal {
+	if e.step.IsPositive() {
+		quantity = quantity.Div(e.step).Floor().Mul(e.step)
+	}
+	if !quantity.IsPositive() || quantity.LessThan(e.minQuantity) {
+		return decimal.Zero
+	}
+	return quantity
+}
+
+func (e *Execution) runSchedule(ctx context.Context) error {
+	start := e.startTime
+	if start.IsZero() {
+		start = time.Now()
+	}
+	slices := len(e.weights)
+	if slices == 0 {
+		return errors.New("execution without slices")
+	}
+	if e.profile != nil {
+		e.weights = e.profile.Weights(start, e.duration, slices)
+	}
+	interval := e.duration / time.Duration(slices)
+	for i := 0; i < slices; i++ {
+		if err := e.waitUntil(ctx, start.Add(time.Duration(i)*interval)); err != nil {
+			return err
+		}
+		canceled, paused, remaining := e.status()
+		if canceled || !remaining.IsPositive() {
+			return nil
+		}
+		if paused {
+			continue
+		}
+		var left float64
+		for _, w := range e.weights[i:] {
+			left += w
+		}
+		quantity := remaining
+		if left > 0 && i < slices-1 {
+			quantity = remaining.Mul(decimal.NewFromFloat(e.weights[i])).Div(decimal.NewFromFloat(left))
+		}
+		if quantity = e.childQuantity(quantity); quantity.IsPositive() {
+			if _, err := e.place(ctx, quantity, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// waitUntil wait for t, the cancellation of the execution or ctx
+func (e *Execution) waitUntil(ctx context.Context, t time.Time) error {
+	for {
+		d := time.Until(t)
+		if d <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-e.wake:
+			timer.Stop()
+			if canceled, _, _ := e.status(); canceled {
+				return nil
+			}
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+func (e *Execution) runPOV(ctx context.Context) error {
+	for {
+		canceled, paused, remaining := e.status()
+		if canceled || e.childQuantity(remaining).IsZero() {
+			return nil
+		}
+		if !paused {
+			e.mu.Lock()
+			quantity := e.marketVolume.Mul(e.participation).Sub(e.executed)
+			e.mu.Unlock()
+			if quantity.GreaterThan(remaining) {
+				quantity = remaining
+			}
+			// check again after a fill, an unfilled child waits for more volume
+			if quantity = e.childQuantity(quantity); quantity.IsPositive() {
+				filled, err := e.place(ctx, quantity, false)
+				if err != nil {
+					return err
+				}
+				if filled {
+					continue
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.wake:
+		}
+	}
+}
+
+func (e *Execution) runIceberg(ctx context.Context) error {
+	var open *ExecutionChild
+	for {
+		canceled, paused, remaining := e.status()
+		if canceled {
+			if open != nil {
+				return e.cancelChild(ctx, open)
+			}
+			return nil
+		}
+		if open == nil {
+			if e.childQuantity(remaining).IsZero() {
+				return nil
+			}
+			if !paused {
+				quantity := remaining
+				if e.display.IsPositive() && quantity.GreaterThan(e.display) {
+					quantity = e.display
+				}
+				if quantity = e.childQuantity(quantity); quantity.IsPositive() {
+					// a child whose status is unknown is followed until it is known
+					if _, err := e.place(ctx, quantity, true); err != nil && !errors.Is(err, ErrExecutionChildUnknown) {
+						return err
+					}
+					open = e.lastOpenChild()
+				}
+			}
+		} else if status, err := e.venue.GetOrder(ctx, e.symbol, open.ClientOrderID); err == nil {
+			e.update(open, status)
+			if orderStatusRank(status.Status) == orderStatusRankFinal {
+				open = nil
+				continue
+			}
+		} else if isUnknownOrder(err) && e.unplaced(open, err) {
+			open = nil
+			continue
+		}
+		timer := time.NewTimer(e.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if open != nil {
+				// ctx is done, the resting child is canceled with a fresh one
+				cancelCtx, cancel := context.WithTimeout(context.Background(), executionCancelTimeout)
+				defer cancel()
+				if err := e.cancelChild(cancelCtx, open); err != nil {
+					return fmt.Errorf("%w: cancel child %s: %v", ctx.Err(), open.ClientOrderID, err)
+				}
+			}
+			return ctx.Err()
+		case <-e.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// lastOpenChild return the last child if it was placed and is still working
+func (e *Execution) lastOpenChild() *ExecutionChild {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.children) == 0 {
+		return nil
+	}
+	child := e.children[len(e.children)-1]
+	if child.Err != nil || orderStatusRank(child.Status) == orderStatusRankFinal {
+		return nil
+	}
+	return child
+}
+
+func (e *Execution) cancelChild(ctx context.Context, child *ExecutionChild) error {
+	status, err := e.venue.CancelOrder(ctx, e.symbol, child.ClientOrderID)
+	if err != nil {
+		if status, err = e.venue.GetOrder(ctx, e.symbol, child.ClientOrderID); err != nil {
+			if isUnknownOrder(err) && e.unplaced(child, err) {
+				return nil
+			}
+			return err
+		}
+	}
+	e.update(child, status)
+	return nil
+}
+
+// unplaced record that a child whose placement failed doesn't exist, false if
+// the child was placed
+func (e *Execution) unplaced(child *ExecutionChild, err error) bool {
+	e.mu.Lock()
+	if child.Status != "" {
+		e.mu.Unlock()
+		return false
+	}
+	if child.Err == nil {
+		child.Err = err
+	}
+	report := *child
+	e.mu.Unlock()
+	if e.OnChild != nil {
+		e.OnChild(&report)
+	}
+	return true
+}
+
+// place send a child of quantity and return whether it was filled, even
+// partially. A child whose placement failed is checked, it may have been
+// placed anyway, e.g. after a timeout. The error is returned when the child
+// was rejected, and ErrExecutionChildUnknown when it can't be checked.
+func (e *Execution) place(ctx context.Context, quantity decimal.Decimal, resting bool) (bool, error) {
+	e.mu.Lock()
+	id := fmt.Sprintf("%s-%d", e.prefix, len(e.children))
+	if e.prefix == "" {
+		id = e.ClientOrderIDGenerator.NewID()
+	}
+	child := &ExecutionChild{
+		Index:         len(e.children),
+		ClientOrderID: id,
+		Quantity:      quantity.String(),
+		Price:         e.limitPrice,
+		Time:          time.Now().UnixMilli(),
+	}
+	e.children = append(e.children, child)
+	e.mu.Unlock()
+	status, err := e.venue.PlaceOrder(ctx, &ChildOrder{
+		Symbol:        e.symbol,
+		Side:          e.side,
+		Quantity:      child.Quantity,
+		ClientOrderID: child.ClientOrderID,
+		Price:         e.limitPrice,
+		Resting:       resting,
+	})
+	if err != nil {
+		var getErr error
+		if status, getErr = e.venue.GetOrder(ctx, e.symbol, child.ClientOrderID); getErr != nil {
+			if !isUnknownOrder(getErr) {
+				return false, fmt.Errorf("%w: %s: %v", ErrExecutionChildUnknown, child.ClientOrderID, err)
+			}
+			e.unplaced(child, err)
+			if isTransientError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	e.update(child, status)
+	return decimalOrZero(status.ExecutedQuantity).IsPositive(), nil
+}
+
+// update apply the status of a child and report it when its fills changed
+func (e *Execution) update(child *ExecutionChild, status *ChildOrderStatus) {
+	e.mu.Lock()
+	previous := decimalOrZero(child.ExecutedQuantity)
+	executed := decimalOrZero(status.ExecutedQuantity)
+	changed := child.Status != status.Status || !executed.Equal(previous)
+	if executed.GreaterThan(previous) {
+		e.executed = e.executed.Sub(previous).Add(executed)
+		e.notional = e.notional.Sub(previous.Mul(decimalOrZero(child.AvgPrice))).
+			Add(executed.Mul(decimalOrZero(status.AvgPrice)))
+		child.ExecutedQuantity = status.ExecutedQuantity
+		child.AvgPrice = status.AvgPrice
+	} else if child.ExecutedQuantity == "" {
+		child.ExecutedQuantity = status.ExecutedQuantity
+	}
+	child.OrderID = status.OrderID
+	child.Status = status.Status
+	report := *child
+	e.mu.Unlock()
+	if changed && e.OnChild != nil {
+		e.OnChild(&report)
+	}
+}
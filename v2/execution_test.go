@@ -0,0 +1,411 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+type executionTestSuite struct {
+	baseTestSuite
+}
+
+func TestExecution(t *testing.T) {
+	suite.Run(t, new(executionTestSuite))
+}
+
+// fakeExecutionVenue fill the child orders with fill, resting children are
+// filled by their first status request
+type fakeExecutionVenue struct {
+	mu       sync.Mutex
+	orders   []*ChildOrder
+	statuses map[string]*ChildOrderStatus
+	fill     func(order *ChildOrder) (executed, price string)
+	query    func(status *ChildOrderStatus)
+	// err fails the placements, the orders are placed anyway when accept is set
+	err    error
+	accept bool
+	getErr error
+}
+
+func newFakeExecutionVenue() *fakeExecutionVenue {
+	return &fakeExecutionVenue{statuses: make(map[string]*ChildOrderStatus)}
+}
+
+func (v *fakeExecutionVenue) PlaceOrder(ctx context.Context, order *ChildOrder) (*ChildOrderStatus, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.orders = append(v.orders, order)
+	if v.err != nil && !v.accept {
+		return nil, v.err
+	}
+	status := &ChildOrderStatus{OrderID: int64(len(v.orders)), ClientOrderID: order.ClientOrderID, Status: "NEW", ExecutedQuantity: "0"}
+	if !order.Resting {
+		executed, price := order.Quantity, "100"
+		if v.fill != nil {
+			executed, price = v.fill(order)
+		}
+		status.ExecutedQuantity, status.AvgPrice, status.Status = executed, price, "FILLED"
+		if executed != order.Quantity {
+			status.Status = "EXPIRED"
+		}
+	}
+	v.statuses[order.ClientOrderID] = status
+	if v.err != nil {
+		return nil, v.err
+	}
+	res := *status
+	return &res, nil
+}
+
+func (v *fakeExecutionVenue) GetOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.getErr != nil {
+		return nil, v.getErr
+	}
+	status, ok := v.statuses[clientOrderID]
+	if !ok {
+		return nil, &common.APIError{Code: errCodeUnknownOrder, Message: "Order does not exist."}
+	}
+	if v.query != nil {
+		v.query(status)
+	} else {
+		status.Status, status.ExecutedQuantity, status.AvgPrice = "FILLED", v.orders[status.OrderID-1].Quantity, "100"
+	}
+	res := *status
+	return &res, nil
+}
+
+func (v *fakeExecutionVenue) CancelOrder(ctx context.Context, symbol, clientOrderID string) (*ChildOrderStatus, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	status := v.statuses[clientOrderID]
+	status.Status = "CANCELED"
+	res := *status
+	return &res, nil
+}
+
+func (v *fakeExecutionVenue) quantities() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	res := make([]string, len(v.orders))
+	for i, o := range v.orders {
+		res[i] = o.Quantity
+	}
+	return res
+}
+
+func (s *executionTestSuite) TestTWAP() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	venue.fill = func(order *ChildOrder) (string, string) {
+		if order.ClientOrderID == "t-0" {
+			return "0.5", "100"
+		}
+		return order.Quantity, "103"
+	}
+	var reports []*ExecutionChild
+	e := NewTWAPExecution(venue, "BTCUSDT", SideTypeBuy, "3", 30*time.Millisecond, 3).
+		QuantityStep("0.1").ClientOrderIDPrefix("t").LimitPrice("105")
+	e.OnChild = func(child *ExecutionChild) {
+		reports = append(reports, child)
+	}
+	r.NoError(e.Run(context.Background()))
+
+	// the half filled first slice is spread over the next ones
+	r.Equal([]string{"1", "1.2", "1.3"}, venue.quantities())
+	for i, o := range venue.orders {
+		r.Equal("105", o.Price)
+		r.False(o.Resting)
+		r.Equal(SideTypeBuy, o.Side)
+		r.Equal("BTCUSDT", o.Symbol)
+		r.Equal(reports[i].ClientOrderID, o.ClientOrderID)
+	}
+	r.Len(reports, 3)
+	r.Equal("EXPIRED", reports[0].Status)
+	r.Equal("0.5", reports[0].ExecutedQuantity)
+	r.Equal("t-2", reports[2].ClientOrderID)
+
+	progress := e.Progress()
+	r.Equal(ExecutionStateDone, progress.State)
+	r.Equal("3", progress.Executed)
+	r.Equal("102.5", progress.AvgPrice)
+	r.Len(progress.Children, 3)
+	r.EqualError(e.Run(context.Background()), "execution is DONE")
+}
+
+func (s *executionTestSuite) TestTWAPCancel() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	e := NewTWAPExecution(venue, "BTCUSDT", SideTypeSell, "2", time.Hour, 2)
+	e.OnChild = func(child *ExecutionChild) {
+		e.Cancel()
+	}
+	r.NoError(e.Run(context.Background()))
+	r.Equal([]string{"1"}, venue.quantities())
+	progress := e.Progress()
+	r.Equal(ExecutionStateCanceled, progress.State)
+	r.Equal("1", progress.Executed)
+}
+
+func (s *executionTestSuite) TestTWAPError() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	venue.err = errors.New("timeout")
+	var reports []*ExecutionChild
+	e := NewTWAPExecution(venue, "BTCUSDT", SideTypeBuy, "2", 10*time.Millisecond, 2)
+	e.OnChild = func(child *ExecutionChild) {
+		reports = append(reports, child)
+	}
+	// the slices failing with transient errors are skipped
+	r.NoError(e.Run(context.Background()))
+	r.Len(reports, 2)
+	r.EqualError(reports[0].Err, "timeout")
+	r.Equal("2", reports[1].Quantity)
+	r.Equal("0", e.Progress().Executed)
+
+	// a rejection stops the execution
+	rejected := &common.APIError{Code: -2010, Message: "Account has insufficient balance for requested action."}
+	venue.err = rejected
+	e = NewTWAPExecution(venue, "BTCUSDT", SideTypeBuy, "2", 10*time.Millisecond, 2)
+	r.ErrorIs(e.Run(context.Background()), rejected)
+	progress := e.Progress()
+	r.Equal(ExecutionStateCanceled, progress.State)
+	r.Len(progress.Children, 1)
+}
+
+func (s *executionTestSuite) TestTWAPAcceptedDespiteError() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	venue.err, venue.accept = errors.New("timeout"), true
+	e := NewTWAPExecution(venue, "BTCUSDT", SideTypeBuy, "2", 10*time.Millisecond, 2)
+	r.NoError(e.Run(context.Background()))
+	// the first child was placed, it isn't sent again in the next slice
+	r.Equal([]string{"1", "1"}, venue.quantities())
+	progress := e.Progress()
+	r.Equal("2", progress.Executed)
+	r.Nil(progress.Children[0].Err)
+
+	// the status of a child can't be checked
+	venue.getErr = errors.New("timeout")
+	e = NewTWAPExecution(venue, "BTCUSDT", SideTypeBuy, "2", 10*time.Millisecond, 2)
+	r.ErrorIs(e.Run(context.Background()), ErrExecutionChildUnknown)
+	r.Len(e.Progress().Children, 1)
+}
+
+func (s *executionTestSuite) TestVolumeProfileWeights() {
+	r := s.r()
+	volumes := make([]float64, 24)
+	volumes[0], volumes[1] = 1, 3
+	profile, err := newVolumeProfile(time.Hour, volumes)
+	r.NoError(err)
+	start := time.Date(2024, 1, 2, 0, 30, 0, 0, time.UTC)
+	r.Equal([]float64{0.125, 0.375}, profile.Weights(start, time.Hour, 2))
+
+	_, err = newVolumeProfile(time.Hour, make([]float64, 24))
+	r.Error(err)
+}
+
+func (s *executionTestSuite) TestNewVolumeProfile() {
+	r := s.r()
+	fetch := func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		var res []*Kline
+		for t := startTime; t <= endTime && len(res) < limit; t += 12 * time.Hour.Milliseconds() {
+			volume := "1"
+			if t%(24*time.Hour.Milliseconds()) != 0 {
+				volume = "3"
+			}
+			res = append(res, &Kline{OpenTime: t, CloseTime: t + 12*time.Hour.Milliseconds() - 1, Volume: volume})
+		}
+		return res, nil
+	}
+	profile, err := NewVolumeProfile(context.Background(), fetch, "12h", 2)
+	r.NoError(err)
+	r.Equal(12*time.Hour, profile.Bucket)
+	r.Equal([]float64{0.25, 0.75}, profile.Shares)
+
+	_, err = NewVolumeProfile(context.Background(), fetch, "7h", 2)
+	r.EqualError(err, `kline interval "7h" doesn't divide a day`)
+}
+
+func (s *executionTestSuite) TestVWAP() {
+	r := s.r()
+	volumes := make([]float64, 24)
+	volumes[0], volumes[1] = 1, 3
+	profile, err := newVolumeProfile(time.Hour, volumes)
+	r.NoError(err)
+	venue := newFakeExecutionVenue()
+	e := NewVWAPExecution(venue, "BTCUSDT", SideTypeBuy, "4", 2*time.Hour, 2, profile).
+		StartTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	r.NoError(e.Run(context.Background()))
+	r.Equal([]string{"1", "3"}, venue.quantities())
+}
+
+func (s *executionTestSuite) TestPOV() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	children := make(chan *ExecutionChild, 10)
+	e := NewPOVExecution(venue, "BTCUSDT", SideTypeBuy, "1", "0.1").QuantityStep("0.1")
+	e.OnChild = func(child *ExecutionChild) {
+		children <- child
+	}
+	done := make(chan error)
+	go func() {
+		done <- e.Run(context.Background())
+	}()
+	r.Eventually(func() bool { return e.Progress().State == ExecutionStateRunning }, time.Second, time.Millisecond)
+
+	e.HandleAggTradeEvent(&WsAggTradeEvent{Symbol: "BTCUSDT", Quantity: "5"})
+	r.Equal("0.5", (<-children).Quantity)
+	e.HandleAggTradeEvent(&WsAggTradeEvent{Symbol: "ETHUSDT", Quantity: "100"})
+	e.HandleAggTradeEvent(&WsAggTradeEvent{Symbol: "BTCUSDT", Quantity: "3"})
+	r.Equal("0.3", (<-children).Quantity)
+
+	// the volume traded while paused isn't followed
+	e.Pause()
+	e.HandleAggTradeEvent(&WsAggTradeEvent{Symbol: "BTCUSDT", Quantity: "100"})
+	e.Resume()
+	e.HandleAggTradeEvent(&WsAggTradeEvent{Symbol: "BTCUSDT", Quantity: "1"})
+	r.Equal("0.1", (<-children).Quantity)
+	// capped at the quantity left
+	e.HandleAggTradeEvent(&WsAggTradeEvent{Symbol: "BTCUSDT", Quantity: "10"})
+	r.Equal("0.1", (<-children).Quantity)
+	r.NoError(<-done)
+	r.Equal([]string{"0.5", "0.3", "0.1", "0.1"}, venue.quantities())
+	r.Equal("1", e.Progress().Executed)
+}
+
+func (s *executionTestSuite) TestIceberg() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	e := NewIcebergExecution(venue, "BTCUSDT", SideTypeSell, "2.5", "1", "100").PollInterval(time.Millisecond)
+	r.NoError(e.Run(context.Background()))
+	r.Equal([]string{"1", "1", "0.5"}, venue.quantities())
+	for _, o := range venue.orders {
+		r.True(o.Resting)
+		r.Equal("100", o.Price)
+	}
+	progress := e.Progress()
+	r.Equal(ExecutionStateDone, progress.State)
+	r.Equal("2.5", progress.Executed)
+	r.Equal("100", progress.AvgPrice)
+
+	// the execution stays new, it can run once the price is set
+	e = NewIcebergExecution(venue, "BTCUSDT", SideTypeSell, "1", "1", "")
+	r.EqualError(e.Run(context.Background()), "iceberg execution without price")
+	r.Equal(ExecutionStateNew, e.Progress().State)
+}
+
+func (s *executionTestSuite) TestIcebergCancel() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	e := NewIcebergExecution(venue, "BTCUSDT", SideTypeSell, "3", "1", "100").PollInterval(time.Millisecond)
+	venue.query = func(status *ChildOrderStatus) {
+		status.Status, status.ExecutedQuantity, status.AvgPrice = "PARTIALLY_FILLED", "0.4", "100"
+		e.Cancel()
+	}
+	r.NoError(e.Run(context.Background()))
+	r.Equal([]string{"1"}, venue.quantities())
+	progress := e.Progress()
+	r.Equal(ExecutionStateCanceled, progress.State)
+	r.Equal("0.4", progress.Executed)
+	r.Equal("CANCELED", progress.Children[0].Status)
+}
+
+func (s *executionTestSuite) TestIcebergContextDone() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e := NewIcebergExecution(venue, "BTCUSDT", SideTypeSell, "3", "1", "100").PollInterval(time.Millisecond)
+	venue.query = func(status *ChildOrderStatus) {
+		status.Status, status.ExecutedQuantity, status.AvgPrice = "PARTIALLY_FILLED", "0.4", "100"
+		cancel()
+	}
+	r.ErrorIs(e.Run(ctx), context.Canceled)
+	progress := e.Progress()
+	r.Equal(ExecutionStateCanceled, progress.State)
+	r.Equal("CANCELED", progress.Children[0].Status)
+	r.Equal("0.4", progress.Executed)
+}
+
+func (s *executionTestSuite) TestIcebergPlaceError() {
+	r := s.r()
+	venue := newFakeExecutionVenue()
+	venue.err, venue.accept = errors.New("timeout"), true
+	e := NewIcebergExecution(venue, "BTCUSDT", SideTypeSell, "2", "1", "100").PollInterval(time.Millisecond)
+	// the children placed despite the errors are followed
+	r.NoError(e.Run(context.Background()))
+	r.Equal([]string{"1", "1"}, venue.quantities())
+	r.Equal("2", e.Progress().Executed)
+
+	rejected := &common.APIError{Code: -1013, Message: "Filter failure: PRICE_FILTER"}
+	venue.err, venue.accept = rejected, false
+	e = NewIcebergExecution(venue, "BTCUSDT", SideTypeSell, "2", "1", "100").PollInterval(time.Millisecond)
+	r.ErrorIs(e.Run(context.Background()), rejected)
+	r.Equal(ExecutionStateCanceled, e.Progress().State)
+}
+
+func (s *executionTestSuite) TestClientOrderIDs() {
+	r := s.r()
+	ids := common.NewClientOrderIDGenerator("x-TEST").Tag("exec")
+	s.client.Client.ClientOrderIDGenerator = ids
+	e := NewTWAPExecution(SpotExecutionVenue(s.client.Client), "BTCUSDT", SideTypeBuy, "1", time.Hour, 2)
+	r.Same(ids, e.ClientOrderIDGenerator)
+
+	// the other venues get ids without prefix
+	venue := newFakeExecutionVenue()
+	e = NewTWAPExecution(venue, "BTCUSDT", SideTypeBuy, "1", 10*time.Millisecond, 1)
+	e.ClientOrderIDGenerator = ids
+	r.NoError(e.Run(context.Background()))
+	meta, ok := ids.Decode(venue.orders[0].ClientOrderID)
+	r.True(ok)
+	r.Equal("exec", meta.Tag)
+}
+
+func (s *executionTestSuite) TestSpotExecutionVenue() {
+	data := []byte(`{
+		"symbol": "BTCUSDT",
+		"orderId": 28,
+		"clientOrderId": "x-0",
+		"transactTime": 1507725176595,
+		"price": "100",
+		"origQty": "2",
+		"executedQty": "2",
+		"cummulativeQuoteQty": "199",
+		"status": "FILLED",
+		"timeInForce": "IOC",
+		"type": "LIMIT",
+		"side": "BUY"
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setFormParams(params{
+			"symbol":           "BTCUSDT",
+			"side":             SideTypeBuy,
+			"type":             OrderTypeLimit,
+			"timeInForce":      TimeInForceTypeIOC,
+			"quantity":         "2",
+			"price":            "100",
+			"newClientOrderId": "x-0",
+			"newOrderRespType": NewOrderRespTypeRESULT,
+		})
+		s.assertRequestEqual(e, r)
+	})
+	status, err := SpotExecutionVenue(s.client.Client).PlaceOrder(newContext(), &ChildOrder{
+		Symbol: "BTCUSDT", Side: SideTypeBuy, Quantity: "2", Price: "100", ClientOrderID: "x-0",
+	})
+	r := s.r()
+	r.NoError(err)
+	r.Equal(&ChildOrderStatus{
+		OrderID: 28, ClientOrderID: "x-0", Status: "FILLED", ExecutedQuantity: "2", AvgPrice: "99.5",
+	}, status)
+}
@@ -0,0 +1,533 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/alpha"
+)
+
+// fundingFlowPageSize is the page size used to walk the deposit and withdraw histories
+const fundingFlowPageSize = 1000
+
+// fundingFlowTimeLayout is the layout of the apply times of the withdraw histories, in UTC
+const fundingFlowTimeLayout = "2006-01-02 15:04:05"
+
+// FundingFlowKind define whether a funding flow is a deposit or a withdrawal
+type FundingFlowKind string
+
+// FundingFlowState define the normalized state of a deposit or a withdrawal
+type FundingFlowState string
+
+// FundingFlowEventType define the type of a funding flow event
+type FundingFlowEventType string
+
+// Global enums
+const (
+	FundingFlowKindDeposit    FundingFlowKind = "DEPOSIT"
+	FundingFlowKindWithdrawal FundingFlowKind = "WITHDRAWAL"
+
+	FundingFlowStatePending  FundingFlowState = "PENDING"
+	FundingFlowStateCredited FundingFlowState = "CREDITED"
+	FundingFlowStateSuccess  FundingFlowState = "SUCCESS"
+	FundingFlowStateRejected FundingFlowState = "REJECTED"
+
+	FundingFlowEventTypePending      FundingFlowEventType = "PENDING"
+	FundingFlowEventTypeCredited     FundingFlowEventType = "CREDITED"
+	FundingFlowEventTypeSuccess      FundingFlowEventType = "SUCCESS"
+	FundingFlowEventTypeRejected     FundingFlowEventType = "REJECTED"
+	FundingFlowEventTypeConfirmation FundingFlowEventType = "CONFIRMATION"
+)
+
+// FundingFlow define a deposit or a withdrawal of any history
+type FundingFlow struct {
+	Kind       FundingFlowKind  `json:"kind"`
+	ID         string           `json:"id"`
+	Coin       string           `json:"coin"`
+	Network    string           `json:"network"`
+	Address    string           `json:"address"`
+	AddressTag string           `json:"addressTag"`
+	Amount     string           `json:"amount"`
+	TxID       string           `json:"txId"`
+	State      FundingFlowState `json:"state"`
+	// Status is the status of the history
+	Status int `json:"status"`
+	// Confirmations is the number of block confirmations, RequiredConfirmations
+	// the number needed to unlock a deposit, 0 when unknown
+	Confirmations         int    `json:"confirmations"`
+	RequiredConfirmations int    `json:"requiredConfirmations"`
+	Info                  string `json:"info"`
+	// Time is the insert time of a deposit or the apply time of a withdrawal, in milliseconds
+	Time int64 `json:"time"`
+}
+
+// depositFlowState return the state of a deposit status: 0 pending, 6
+// credited but cannot withdraw, 7 wrong deposit, 8 waiting user confirm, 1
+// success, 2 rejected
+func depositFlowState(status int) FundingFlowState {
+	switch status {
+	case 1:
+		return FundingFlowStateSuccess
+	case 6:
+		return FundingFlowStateCredited
+	case 2, 7:
+		return FundingFlowStateRejected
+	}
+	return FundingFlowStatePending
+}
+
+// withdrawalFlowState return the state of a withdrawal status
+func withdrawalFlowState(status int) FundingFlowState {
+	switch status {
+	case WithdrawStatusCompleted:
+		return FundingFlowStateSuccess
+	case WithdrawStatusCancelled, WithdrawStatusRejected, WithdrawStatusFailure:
+		return FundingFlowStateRejected
+	}
+	return FundingFlowStatePending
+}
+
+// parseConfirmTimes return the confirmations of a "12/12" confirm times
+func parseConfirmTimes(confirmTimes string) (confirmations, required int) {
+	parts := strings.SplitN(confirmTimes, "/", 2)
+	confirmations, _ = strconv.Atoi(parts[0])
+	if len(parts) == 2 {
+		required, _ = strconv.Atoi(parts[1])
+	}
+	return confirmations, required
+}
+
+// parseFundingFlowTime return the milliseconds of a withdraw history time
+func parseFundingFlowTime(value string) int64 {
+	t, err := time.Parse(fundingFlowTimeLayout, value)
+	if err != nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// FundingFlowFetcher fetch the flows of a history made between startTime
+// and endTime included, in milliseconds
+type FundingFlowFetcher func(ctx context.Context, startTime, endTime int64) ([]*FundingFlow, error)
+
+// SpotDepositFlows fetch the deposits of an account
+func SpotDepositFlows(c *Client) FundingFlowFetcher {
+	return func(ctx context.Context, startTime, endTime int64) ([]*FundingFlow, error) {
+		var flows []*FundingFlow
+		for offset := 0; ; offset += fundingFlowPageSize {
+			deposits, err := c.NewListDepositsService().StartTime(startTime).EndTime(endTime).
+				Offset(offset).Limit(fundingFlowPageSize).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range deposits {
+				confirmations, required := parseConfirmTimes(d.ConfirmTimes)
+				if d.UnlockConfirm > 0 {
+					required = int(d.UnlockConfirm)
+				}
+				flows = append(flows, &FundingFlow{
+					Kind: FundingFlowKindDeposit, ID: d.ID, Coin: d.Coin, Network: d.Network,
+					Address: d.Address, AddressTag: d.AddressTag, Amount: d.Amount, TxID: d.TxID,
+					State: depositFlowState(d.Status), Status: d.Status,
+					Confirmations: confirmations, RequiredConfirmations: required, Time: d.InsertTime,
+				})
+			}
+			if len(deposits) < fundingFlowPageSize {
+				return flows, nil
+			}
+		}
+	}
+}
+
+// SpotWithdrawalFlows fetch the withdrawals of an account
+func SpotWithdrawalFlows(c *Client) FundingFlowFetcher {
+	return func(ctx context.Context, startTime, endTime int64) ([]*FundingFlow, error) {
+		var flows []*FundingFlow
+		for offset := 0; ; offset += fundingFlowPageSize {
+			withdraws, err := c.NewListWithdrawsService().StartTime(startTime).EndTime(endTime).
+				Offset(offset).Limit(fundingFlowPageSize).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, w := range withdraws {
+				flows = append(flows, &FundingFlow{
+					Kind: FundingFlowKindWithdrawal, ID: w.ID, Coin: w.Coin, Network: w.Network,
+					Address: w.Address, Amount: w.Amount, TxID: w.TxID,
+					State: withdrawalFlowState(w.Status), Status: w.Status,
+					Confirmations: int(w.ConfirmNo), Info: w.Info, Time: parseFundingFlowTime(w.ApplyTime),
+				})
+			}
+			if len(withdraws) < fundingFlowPageSize {
+				return flows, nil
+			}
+		}
+	}
+}
+
+// SubAccountDepositFlows fetch the deposits of a sub-account, with a master account client
+func SubAccountDepositFlows(c *Client, email string) FundingFlowFetcher {
+	return func(ctx context.Context, startTime, endTime int64) ([]*FundingFlow, error) {
+		var flows []*FundingFlow
+		for offset := 0; ; offset += fundingFlowPageSize {
+			deposits, err := c.NewSubAccountDepositRecordService().Email(email).StartTime(startTime).EndTime(endTime).
+				Offset(offset).Limit(fundingFlowPageSize).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range deposits {
+				confirmations, required := parseConfirmTimes(d.ConfirmTimes)
+				if d.UnlockConfirm > 0 {
+					required = int(d.UnlockConfirm)
+				}
+				flows = append(flows, &FundingFlow{
+					Kind: FundingFlowKindDeposit, ID: d.Id, Coin: d.Coin, Network: d.Network,
+					Address: d.Address, AddressTag: d.AddressTag, Amount: d.Amount, TxID: d.TxId,
+					State: depositFlowState(int(d.Status)), Status: int(d.Status),
+					Confirmations: confirmations, RequiredConfirmations: required, Time: d.InsertTime,
+				})
+			}
+			if len(deposits) < fundingFlowPageSize {
+				return flows, nil
+			}
+		}
+	}
+}
+
+// AlphaDepositFlows fetch the deposits of alpha tokens, the coins are alpha ids
+func AlphaDepositFlows(c *alpha.Client) FundingFlowFetcher {
+	return func(ctx context.Context, startTime, endTime int64) ([]*FundingFlow, error) {
+		var flows []*FundingFlow
+		for offset := 0; ; offset += fundingFlowPageSize {
+			deposits, err := c.NewGetAlphaDepositHistoryService().StartTime(startTime).EndTime(endTime).
+				Offset(offset).Limit(fundingFlowPageSize).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range deposits {
+				flows = append(flows, &FundingFlow{
+					Kind: FundingFlowKindDeposit, ID: d.ID, Coin: d.AlphaID, Network: d.Network,
+					Address: d.Address, AddressTag: d.AddressTag, Amount: d.Amount, TxID: d.TxID,
+					State: depositFlowState(d.Status), Status: d.Status,
+					Confirmations: int(d.ConfirmationNo), RequiredConfirmations: d.UnlockConfirm, Time: d.InsertTime,
+				})
+			}
+			if len(deposits) < fundingFlowPageSize {
+				return flows, nil
+			}
+		}
+	}
+}
+
+// AlphaWithdrawalFlows fetch the withdrawals of alpha tokens, the coins are alpha ids
+func AlphaWithdrawalFlows(c *alpha.Client) FundingFlowFetcher {
+	return func(ctx context.Context, startTime, endTime int64) ([]*FundingFlow, error) {
+		var flows []*FundingFlow
+		for offset := 0; ; offset += fundingFlowPageSize {
+			withdraws, err := c.NewGetAlphaWithdrawHistoryService().StartTime(startTime).EndTime(endTime).
+				Offset(offset).Limit(fundingFlowPageSize).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, w := range withdraws {
+				flows = append(flows, &FundingFlow{
+					Kind: FundingFlowKindWithdrawal, ID: w.ID, Coin: w.AlphaID, Network: w.Network,
+					Address: w.Address, AddressTag: w.AddressTag, Amount: w.Amount, TxID: w.TxID,
+					State: withdrawalFlowState(w.Status), Status: w.Status,
+					Confirmations: w.ConfirmNo, Info: w.Info, Time: parseFundingFlowTime(w.ApplyTime),
+				})
+			}
+			if len(withdraws) < fundingFlowPageSize {
+				return flows, nil
+			}
+		}
+	}
+}
+
+// FundingFlowEvent define a change of a funding flow, a new state or new
+// confirmations
+type FundingFlowEvent struct {
+	Type FundingFlowEventType
+	// Source is the name of the history of the flow
+	Source string
+	Flow   *FundingFlow
+	// PreviousState is empty for a flow seen for the first time
+	PreviousState FundingFlowState
+}
+
+// FundingFlowMark define the last state of a flow seen by a watcher
+type FundingFlowMark struct {
+	State         FundingFlowState `json:"state"`
+	Confirmations int              `json:"confirmations"`
+	TxID          string           `json:"txId"`
+	Time          int64            `json:"time"`
+}
+
+func (m *FundingFlowMark) isFinal() bool {
+	return m.State == FundingFlowStateSuccess || m.State == FundingFlowStateRejected
+}
+
+// FundingFlowCursor define where a watcher stopped on a history
+type FundingFlowCursor struct {
+	// StartTime is the start of the next window, in milliseconds
+	StartTime int64 `json:"startTime"`
+	// Flows are the flows not final yet or in the overlap of the windows, by id
+	Flows map[string]*FundingFlowMark `json:"flows"`
+}
+
+// FundingFlowCheckpoint define the cursors of a watcher by source
+type FundingFlowCheckpoint struct {
+	Cursors map[string]*FundingFlowCursor `json:"cursors"`
+}
+
+// FundingFlowStore load and save the checkpoints of a watcher
+type FundingFlowStore interface {
+	// Load return the saved checkpoint, nil when there is none
+	Load(ctx context.Context) (*FundingFlowCheckpoint, error)
+	Save(ctx context.Context, checkpoint *FundingFlowCheckpoint) error
+}
+
+// MemoryFundingFlowStore keep the checkpoint in memory
+type MemoryFundingFlowStore struct {
+	mu         sync.Mutex
+	checkpoint []byte
+}
+
+// Load return the saved checkpoint
+func (s *MemoryFundingFlowStore) Load(ctx context.Context) (*FundingFlowCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checkpoint == nil {
+		return nil, nil
+	}
+	checkpoint := new(FundingFlowCheckpoint)
+	return checkpoint, json.Unmarshal(s.checkpoint, checkpoint)
+}
+
+// Save save a checkpoint
+func (s *MemoryFundingFlowStore) Save(ctx context.Context, checkpoint *FundingFlowCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = data
+	return nil
+}
+
+// FileFundingFlowStore keep the checkpoint in a JSON file
+type FileFundingFlowStore struct {
+	Path string
+}
+
+// Load return the checkpoint of the file, nil when the file doesn't exist
+func (s *FileFundingFlowStore) Load(ctx context.Context) (*FundingFlowCheckpoint, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := new(FundingFlowCheckpoint)
+	return checkpoint, json.Unmarshal(data, checkpoint)
+}
+
+// Save write a checkpoint to a temporary file renamed to the file
+func (s *FileFundingFlowStore) Save(ctx context.Context, checkpoint *FundingFlowCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+type fundingFlowSource struct {
+	name  string
+	fetch FundingFlowFetcher
+}
+
+// FundingFlowWatcher poll deposit and withdraw histories and emit an event
+// every time a flow changes state or gets confirmations. Every history is
+// walked from a cursor by windows, the cursor stays at the oldest flow not
+// final yet. The checkpoint is saved after the events of each history are
+// delivered, so events may be delivered again after a crash, never lost.
+type FundingFlowWatcher struct {
+	// OnEvent is called with the events of the flows
+	OnEvent func(event *FundingFlowEvent)
+	// OnError is called by Run with the errors of the polls
+	OnError func(err error)
+
+	store      FundingFlowStore
+	sources    []*fundingFlowSource
+	interval   time.Duration
+	lookback   time.Duration
+	overlap    time.Duration
+	window     time.Duration
+	now        func() time.Time
+	checkpoint *FundingFlowCheckpoint
+}
+
+// NewFundingFlowWatcher init a watcher saving its checkpoints in store, in memory when store is nil
+func NewFundingFlowWatcher(store FundingFlowStore) *FundingFlowWatcher {
+	if store == nil {
+		store = &MemoryFundingFlowStore{}
+	}
+	return &FundingFlowWatcher{
+		store:    store,
+		interval: time.Minute,
+		lookback: 24 * time.Hour,
+		overlap:  time.Hour,
+		window:   30 * 24 * time.Hour,
+		now:      time.Now,
+	}
+}
+
+// Add add a history to watch, name identify its cursor in the checkpoints
+func (w *FundingFlowWatcher) Add(name string, fetch FundingFlowFetcher) *FundingFlowWatcher {
+	w.sources = append(w.sources, &fundingFlowSource{name: name, fetch: fetch})
+	return w
+}
+
+// Interval set the interval between the polls of Run
+func (w *FundingFlowWatcher) Interval(d time.Duration) *FundingFlowWatcher {
+	w.interval = d
+	return w
+}
+
+// Lookback set how far back a history without checkpoint is walked
+func (w *FundingFlowWatcher) Lookback(d time.Duration) *FundingFlowWatcher {
+	w.lookback = d
+	return w
+}
+
+// Overlap set how far back every poll walks again to catch late records
+func (w *FundingFlowWatcher) Overlap(d time.Duration) *FundingFlowWatcher {
+	w.overlap = d
+	return w
+}
+
+// Window set the max duration of a request, the histories limit it to 90
+// days, a window under a millisecond is ignored
+func (w *FundingFlowWatcher) Window(d time.Duration) *FundingFlowWatcher {
+	if d >= time.Millisecond {
+		w.window = d
+	}
+	return w
+}
+
+// Run poll the histories every interval until ctx is done
+func (w *FundingFlowWatcher) Run(ctx context.Context) error {
+	for {
+		if err := w.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if w.OnError != nil {
+				w.OnError(err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+// Poll walk every history once from its cursor and save the checkpoint
+func (w *FundingFlowWatcher) Poll(ctx context.Context) error {
+	if w.checkpoint == nil {
+		checkpoint, err := w.store.Load(ctx)
+		if err != nil {
+			return err
+		}
+		if checkpoint == nil {
+			checkpoint = &FundingFlowCheckpoint{}
+		}
+		if checkpoint.Cursors == nil {
+			checkpoint.Cursors = make(map[string]*FundingFlowCursor)
+		}
+		w.checkpoint = checkpoint
+	}
+	for _, source := range w.sources {
+		if err := w.poll(ctx, source); err != nil {
+			return err
+		}
+		if err := w.store.Save(ctx, w.checkpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *FundingFlowWatcher) poll(ctx context.Context, source *fundingFlowSource) error {
+	now := w.now()
+	end := now.UnixMilli()
+	cursor, ok := w.checkpoint.Cursors[source.name]
+	if !ok {
+		cursor = &FundingFlowCursor{StartTime: now.Add(-w.lookback).UnixMilli()}
+	}
+	if cursor.Flows == nil {
+		cursor.Flows = make(map[string]*FundingFlowMark)
+	}
+	var flows []*FundingFlow
+	for start := cursor.StartTime; start <= end; start += w.window.Milliseconds() {
+		windowEnd := start + w.window.Milliseconds() - 1
+		if windowEnd > end {
+			windowEnd = end
+		}
+		res, err := source.fetch(ctx, start, windowEnd)
+		if err != nil {
+			return err
+		}
+		flows = append(flows, res...)
+	}
+	for _, f := range flows {
+		key := string(f.Kind) + ":" + f.ID
+		mark, seen := cursor.Flows[key]
+		event := &FundingFlowEvent{Type: FundingFlowEventType(f.State), Source: source.name, Flow: f}
+		switch {
+		case !seen:
+		case mark.State != f.State:
+			event.PreviousState = mark.State
+		case mark.Confirmations != f.Confirmations || mark.TxID != f.TxID:
+			event.Type, event.PreviousState = FundingFlowEventTypeConfirmation, mark.State
+		default:
+			continue
+		}
+		cursor.Flows[key] = &FundingFlowMark{State: f.State, Confirmations: f.Confirmations, TxID: f.TxID, Time: f.Time}
+		if w.OnEvent != nil {
+			w.OnEvent(event)
+		}
+	}
+	// the next window starts at the oldest flow not final, the final flows
+	// before it won't be fetched again
+	next := now.Add(-w.overlap).UnixMilli()
+	for _, mark := range cursor.Flows {
+		if !mark.isFinal() && mark.Time < next {
+			next = mark.Time
+		}
+	}
+	if next < cursor.StartTime {
+		next = cursor.StartTime
+	}
+	for key, mark := range cursor.Flows {
+		if mark.Time < next {
+			delete(cursor.Flows, key)
+		}
+	}
+	cursor.StartTime = next
+	w.checkpoint.Cursors[source.name] = cursor
+	return nil
+}
@@ -0,0 +1,166 @@
+package binance
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type fundingFlowWatcherTestSuite struct {
+	baseTestSuite
+}
+
+func TestFundingFlowWatcher(t *testing.T) {
+	suite.Run(t, new(fundingFlowWatcherTestSuite))
+}
+
+type fakeFundingFlows struct {
+	flows   []*FundingFlow
+	windows [][2]int64
+}
+
+func (f *fakeFundingFlows) fetch(ctx context.Context, startTime, endTime int64) ([]*FundingFlow, error) {
+	f.windows = append(f.windows, [2]int64{startTime, endTime})
+	var res []*FundingFlow
+	for _, flow := range f.flows {
+		if flow.Time >= startTime && flow.Time <= endTime {
+			copied := *flow
+			res = append(res, &copied)
+		}
+	}
+	return res, nil
+}
+
+func (s *fundingFlowWatcherTestSuite) TestFlows() {
+	s.mockRoutes(routedResponses{
+		"/sapi/v1/capital/deposit/hisrec": `[
+			{"id": "d1", "amount": "0.5", "coin": "BTC", "network": "BTC", "status": 0, "address": "addr",
+				"txId": "tx1", "insertTime": 1599621997000, "confirmTimes": "1/2", "unlockConfirm": 3},
+			{"id": "d2", "amount": "1", "coin": "ETH", "network": "ETH", "status": 7, "insertTime": 1599621998000}
+		]`,
+		"/sapi/v1/capital/withdraw/history": `[
+			{"id": "w1", "amount": "0.1", "coin": "BTC", "network": "BTC", "status": 6, "txId": "tx2",
+				"applyTime": "2019-10-12 11:12:02", "confirmNo": 3}
+		]`,
+	})
+	r := s.r()
+	deposits, err := SpotDepositFlows(s.client.Client)(newContext(), 0, 1)
+	r.NoError(err)
+	r.Len(deposits, 2)
+	r.Equal(&FundingFlow{
+		Kind: FundingFlowKindDeposit, ID: "d1", Coin: "BTC", Network: "BTC", Address: "addr", Amount: "0.5",
+		TxID: "tx1", State: FundingFlowStatePending, Confirmations: 1, RequiredConfirmations: 3, Time: 1599621997000,
+	}, deposits[0])
+	r.Equal(FundingFlowStateRejected, deposits[1].State)
+
+	withdrawals, err := SpotWithdrawalFlows(s.client.Client)(newContext(), 0, 1)
+	r.NoError(err)
+	r.Len(withdrawals, 1)
+	r.Equal(FundingFlowStateSuccess, withdrawals[0].State)
+	r.Equal(3, withdrawals[0].Confirmations)
+	r.Equal(int64(1570878722000), withdrawals[0].Time)
+}
+
+func (s *fundingFlowWatcherTestSuite) TestPoll() {
+	now := time.UnixMilli(100 * 24 * 3600 * 1000)
+	hour := time.Hour.Milliseconds()
+	flows := &fakeFundingFlows{flows: []*FundingFlow{
+		{Kind: FundingFlowKindDeposit, ID: "d1", State: FundingFlowStatePending, Time: now.UnixMilli() - 10*hour},
+		{Kind: FundingFlowKindDeposit, ID: "d2", State: FundingFlowStateSuccess, Time: now.UnixMilli() - 5*hour},
+	}}
+	store := &MemoryFundingFlowStore{}
+	var events []*FundingFlowEvent
+	newWatcher := func() *FundingFlowWatcher {
+		w := NewFundingFlowWatcher(store).Add("deposits", flows.fetch)
+		w.now = func() time.Time { return now }
+		w.OnEvent = func(event *FundingFlowEvent) {
+			events = append(events, event)
+		}
+		return w
+	}
+	w := newWatcher()
+	r := s.r()
+	r.NoError(w.Poll(newContext()))
+	r.Len(events, 2)
+	r.Equal(FundingFlowEventTypePending, events[0].Type)
+	r.Equal("deposits", events[0].Source)
+	r.Equal(FundingFlowState(""), events[0].PreviousState)
+	r.Equal(FundingFlowEventTypeSuccess, events[1].Type)
+	r.Equal([2]int64{now.UnixMilli() - 24*hour, now.UnixMilli()}, flows.windows[0])
+
+	// nothing changed
+	events = nil
+	r.NoError(w.Poll(newContext()))
+	r.Empty(events)
+	r.Equal(now.UnixMilli()-10*hour, flows.windows[1][0])
+
+	flows.flows[0].Confirmations = 2
+	flows.flows[0].TxID = "tx1"
+	r.NoError(w.Poll(newContext()))
+	r.Len(events, 1)
+	r.Equal(FundingFlowEventTypeConfirmation, events[0].Type)
+	r.Equal(FundingFlowStatePending, events[0].PreviousState)
+	r.Equal("tx1", events[0].Flow.TxID)
+
+	// a new watcher resumes from the checkpoint
+	events = nil
+	flows.flows[0].State = FundingFlowStateCredited
+	w = newWatcher()
+	r.NoError(w.Poll(newContext()))
+	r.Len(events, 1)
+	r.Equal(FundingFlowEventTypeCredited, events[0].Type)
+	r.Equal(FundingFlowStatePending, events[0].PreviousState)
+
+	// once final, the cursor moves past the flows
+	events = nil
+	flows.flows[0].State = FundingFlowStateSuccess
+	r.NoError(w.Poll(newContext()))
+	r.Len(events, 1)
+	r.Equal(FundingFlowEventTypeSuccess, events[0].Type)
+	r.NoError(w.Poll(newContext()))
+	r.Len(events, 1)
+	checkpoint, err := store.Load(newContext())
+	r.NoError(err)
+	r.Equal(now.UnixMilli()-hour, checkpoint.Cursors["deposits"].StartTime)
+	r.Empty(checkpoint.Cursors["deposits"].Flows)
+}
+
+func (s *fundingFlowWatcherTestSuite) TestWindows() {
+	now := time.UnixMilli(100 * 24 * 3600 * 1000)
+	flows := &fakeFundingFlows{}
+	w := NewFundingFlowWatcher(nil).Add("withdrawals", flows.fetch).Lookback(70 * 24 * time.Hour)
+	w.now = func() time.Time { return now }
+	r := s.r()
+	r.NoError(w.Poll(newContext()))
+	r.Len(flows.windows, 3)
+	r.Equal(now.Add(-70*24*time.Hour).UnixMilli(), flows.windows[0][0])
+	r.Equal(flows.windows[0][1]+1, flows.windows[1][0])
+	r.Equal(now.UnixMilli(), flows.windows[2][1])
+
+	flows.windows = nil
+	w.Window(0).Window(-time.Hour).Window(time.Microsecond)
+	now = now.Add(time.Hour)
+	r.NoError(w.Poll(newContext()))
+	r.Len(flows.windows, 1)
+}
+
+func (s *fundingFlowWatcherTestSuite) TestFileStore() {
+	store := &FileFundingFlowStore{Path: filepath.Join(s.T().TempDir(), "checkpoint.json")}
+	r := s.r()
+	checkpoint, err := store.Load(newContext())
+	r.NoError(err)
+	r.Nil(checkpoint)
+
+	saved := &FundingFlowCheckpoint{Cursors: map[string]*FundingFlowCursor{
+		"deposits": {StartTime: 1, Flows: map[string]*FundingFlowMark{
+			"DEPOSIT:d1": {State: FundingFlowStatePending, Confirmations: 1, TxID: "tx1", Time: 2},
+		}},
+	}}
+	r.NoError(store.Save(newContext(), saved))
+	checkpoint, err = store.Load(newContext())
+	r.NoError(err)
+	r.Equal(saved, checkpoint)
+}
@@ -0,0 +1,399 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// fundingRatePageSize is the page size used to backfill the funding history
+const fundingRatePageSize = 1000
+
+// incomeTypeFundingFee is the income type of the funding payments
+const incomeTypeFundingFee = "FUNDING_FEE"
+
+// FundingState define the current and predicted funding of a perpetual
+type FundingState struct {
+	Symbol          string `json:"symbol"`
+	MarkPrice       string `json:"markPrice"`
+	IndexPrice      string `json:"indexPrice"`
+	FundingRate     string `json:"fundingRate"` // predicted rate of the next funding
+	NextFundingTime int64  `json:"nextFundingTime"`
+	LastFundingRate string `json:"lastFundingRate"` // rate of the last funding, empty until known
+	LastFundingTime int64  `json:"lastFundingTime"`
+	Time            int64  `json:"time"`
+}
+
+// FundingRecord define a settled funding
+type FundingRecord struct {
+	Symbol    string `json:"symbol"`
+	Rate      string `json:"rate"`
+	Time      int64  `json:"time"`
+	MarkPrice string `json:"markPrice"`
+}
+
+// FundingRatePageFetcher fetch at most limit fundings settled between
+// startTime and endTime included, in milliseconds
+type FundingRatePageFetcher func(ctx context.Context, startTime, endTime int64, limit int) ([]*FundingRecord, error)
+
+// FuturesFundingRatePages fetch the fundings of a USD-M perpetual
+func FuturesFundingRatePages(c *futures.Client, symbol string) FundingRatePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*FundingRecord, error) {
+		res, err := c.NewFundingRateService().Symbol(symbol).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		records := make([]*FundingRecord, len(res))
+		for i, f := range res {
+			records[i] = &FundingRecord{Symbol: f.Symbol, Rate: f.FundingRate, Time: f.FundingTime, MarkPrice: f.MarkPrice}
+		}
+		return records, nil
+	}
+}
+
+// DeliveryFundingRatePages fetch the fundings of a COIN-M perpetual
+func DeliveryFundingRatePages(c *delivery.Client, symbol string) FundingRatePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*FundingRecord, error) {
+		res, err := c.NewFundingRateService().Symbol(symbol).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		records := make([]*FundingRecord, len(res))
+		for i, f := range res {
+			records[i] = &FundingRecord{Symbol: f.Symbol, Rate: f.FundingRate, Time: f.FundingTime, MarkPrice: f.MarkPrice}
+		}
+		return records, nil
+	}
+}
+
+// FundingPosition define an open position paying or receiving funding
+type FundingPosition struct {
+	Symbol string `json:"symbol"`
+	// Amount is signed, in base asset for USD-M and in contracts for COIN-M
+	Amount string `json:"amount"`
+	// ContractSize is the quote value of a COIN-M contract, empty for USD-M
+	ContractSize string `json:"contractSize,omitempty"`
+}
+
+// FuturesFundingPositions return the funding positions of USD-M positions
+func FuturesFundingPositions(positions []*futures.PositionRisk) []*FundingPosition {
+	res := make([]*FundingPosition, 0, len(positions))
+	for _, p := range positions {
+		res = append(res, &FundingPosition{Symbol: p.Symbol, Amount: p.PositionAmt})
+	}
+	return res
+}
+
+// DeliveryFundingPositions return the funding positions of COIN-M positions,
+// the contract sizes by symbol are those of the exchange info and must
+// include every position symbol
+func DeliveryFundingPositions(positions []*delivery.PositionRisk, contractSizes map[string]int) ([]*FundingPosition, error) {
+	res := make([]*FundingPosition, 0, len(positions))
+	for _, p := range positions {
+		contractSize, ok := contractSizes[p.Symbol]
+		if !ok || contractSize <= 0 {
+			return nil, fmt.Errorf("unknown contract size of delivery symbol %s", p.Symbol)
+		}
+		res = append(res, &FundingPosition{
+			Symbol:       p.Symbol,
+			Amount:       p.PositionAmt,
+			ContractSize: decimal.NewFromInt(int64(contractSize)).String(),
+		})
+	}
+	return res, nil
+}
+
+// FundingIncome define a realized funding payment
+type FundingIncome struct {
+	Symbol string `json:"symbol"`
+	Asset  string `json:"asset"`
+	Amount string `json:"amount"`
+	Time   int64  `json:"time"`
+}
+
+// FuturesFundingIncomes return the funding payments of a USD-M income history
+func FuturesFundingIncomes(incomes []*futures.IncomeHistory) []*FundingIncome {
+	res := make([]*FundingIncome, 0, len(incomes))
+	for _, i := range incomes {
+		if i.IncomeType == incomeTypeFundingFee {
+			res = append(res, &FundingIncome{Symbol: i.Symbol, Asset: i.Asset, Amount: i.Income, Time: i.Time})
+		}
+	}
+	return res
+}
+
+// DeliveryFundingIncomes return the funding payments of a COIN-M income history
+func DeliveryFundingIncomes(incomes []*delivery.IncomeHistory) []*FundingIncome {
+	res := make([]*FundingIncome, 0, len(incomes))
+	for _, i := range incomes {
+		if i.IncomeType == incomeTypeFundingFee {
+			res = append(res, &FundingIncome{Symbol: i.Symbol, Asset: i.Asset, Amount: i.Income, Time: i.Time})
+		}
+	}
+	return res
+}
+
+// FundingAttribution define the expected and realized funding of a symbol,
+// positive amounts are received and negative ones paid
+type FundingAttribution struct {
+	Symbol string `json:"symbol"`
+	Amount string `json:"amount"` // net position
+	// Expected is the funding the current position would have got over the period
+	Expected string `json:"expected"`
+	// Realized is the funding received over the period
+	Realized string `json:"realized"`
+	// Difference is Realized minus Expected, non zero when the position changed
+	Difference string `json:"difference"`
+	// Next is the predicted payment of the next funding
+	Next            string `json:"next"`
+	NextFundingTime int64  `json:"nextFundingTime"`
+}
+
+// FundingTracker maintain the funding of perpetuals from the mark price
+// streams and their funding history
+type FundingTracker struct {
+	mu      sync.Mutex
+	states  map[string]*FundingState
+	history map[string][]*FundingRecord
+}
+
+// NewFundingTracker init a funding tracker
+func NewFundingTracker() *FundingTracker {
+	return &FundingTracker{
+		states:  make(map[string]*FundingState),
+		history: make(map[string][]*FundingRecord),
+	}
+}
+
+// HandleFuturesMarkPrice update the funding of a USD-M perpetual
+func (t *FundingTracker) HandleFuturesMarkPrice(event *futures.WsMarkPriceEvent) {
+	t.update(&FundingState{
+		Symbol:          event.Symbol,
+		MarkPrice:       event.MarkPrice,
+		IndexPrice:      event.IndexPrice,
+		FundingRate:     event.FundingRate,
+		NextFundingTime: event.NextFundingTime,
+		Time:            event.Time,
+	})
+}
+
+// HandleFuturesAllMarkPrice update the funding of all USD-M perpetuals
+func (t *FundingTracker) HandleFuturesAllMarkPrice(event futures.WsAllMarkPriceEvent) {
+	for _, e := range event {
+		t.HandleFuturesMarkPrice(e)
+	}
+}
+
+// HandleFuturesPremiumIndex update the funding of USD-M perpetuals from the
+// premium index service, to start without waiting for the streams
+func (t *FundingTracker) HandleFuturesPremiumIndex(indexes []*futures.PremiumIndex) {
+	for _, i := range indexes {
+		t.update(&FundingState{
+			Symbol:          i.Symbol,
+			MarkPrice:       i.MarkPrice,
+			IndexPrice:      i.IndexPrice,
+			FundingRate:     i.LastFundingRate, // the rate of the next funding despite its name
+			NextFundingTime: i.NextFundingTime,
+			Time:            i.Time,
+		})
+	}
+}
+
+// HandleDeliveryMarkPrice update the funding of a COIN-M perpetual
+func (t *FundingTracker) HandleDeliveryMarkPrice(event *delivery.WsMarkPriceEvent) {
+	if event.FundingRate == "" {
+		// delivery contracts have no funding
+		return
+	}
+	t.update(&FundingState{
+		Symbol:          event.Symbol,
+		MarkPrice:       event.MarkPrice,
+		FundingRate:     event.FundingRate,
+		NextFundingTime: event.NextFundingTime,
+		Time:            event.Time,
+	})
+}
+
+// HandleDeliveryPairMarkPrice update the funding of the COIN-M perpetuals of a pair
+func (t *FundingTracker) HandleDeliveryPairMarkPrice(event delivery.WsPairMarkPriceEvent) {
+	for _, e := range event {
+		t.HandleDeliveryMarkPrice(e)
+	}
+}
+
+func (t *FundingTracker) update(state *FundingState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev, ok := t.states[state.Symbol]
+	if ok && state.Time < prev.Time {
+		return
+	}
+	if ok {
+		state.LastFundingRate = prev.LastFundingRate
+		state.LastFundingTime = prev.LastFundingTime
+		// the predicted rate is settled once the funding time is passed
+		if prev.NextFundingTime != 0 && state.NextFundingTime > prev.NextFundingTime && state.Time >= prev.NextFundingTime {
+			state.LastFundingRate = prev.FundingRate
+			state.LastFundingTime = prev.NextFundingTime
+			t.add(&FundingRecord{Symbol: state.Symbol, Rate: prev.FundingRate, Time: prev.NextFundingTime, MarkPrice: prev.MarkPrice}, false)
+		}
+	}
+	t.states[state.Symbol] = state
+}
+
+// add insert a record in the history, replacing the record of the same time
+// when replace is set
+func (t *FundingTracker) add(record *FundingRecord, replace bool) {
+	history := t.history[record.Symbol]
+	i := sort.Search(len(history), func(i int) bool { return history[i].Time >= record.Time })
+	if i < len(history) && history[i].Time == record.Time {
+		if replace {
+			history[i] = record
+		}
+		return
+	}
+	history = append(history, nil)
+	copy(history[i+1:], history[i:])
+	history[i] = record
+	t.history[record.Symbol] = history
+}
+
+// State return the funding of a symbol, nil when unknown
+func (t *FundingTracker) State(symbol string) *FundingState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[symbol]
+	if !ok {
+		return nil
+	}
+	res := *state
+	return &res
+}
+
+// Backfill fetch the fundings of a symbol settled between startTime and
+// endTime included, the settled rates replace those seen on the streams
+func (t *FundingTracker) Backfill(ctx context.Context, fetch FundingRatePageFetcher, startTime, endTime int64) error {
+	for startTime <= endTime {
+		records, err := fetch(ctx, startTime, endTime, fundingRatePageSize)
+		if err != nil {
+			return err
+		}
+		next := startTime
+		t.mu.Lock()
+		for _, record := range records {
+			t.add(record, true)
+			if record.Time >= next {
+				next = record.Time + 1
+			}
+		}
+		t.mu.Unlock()
+		if len(records) < fundingRatePageSize || next == startTime {
+			return nil
+		}
+		startTime = next
+	}
+	return nil
+}
+
+// History return the fundings of a symbol settled between startTime and
+// endTime included
+func (t *FundingTracker) History(symbol string, startTime, endTime int64) []*FundingRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var res []*FundingRecord
+	for _, record := range t.history[symbol] {
+		if record.Time >= startTime && record.Time <= endTime {
+			r := *record
+			res = append(res, &r)
+		}
+	}
+	return res
+}
+
+// Attribute compare the funding the positions would have got between
+// startTime and endTime with the funding realized, by symbol. The expected
+// funding assumes the positions were held unchanged over the whole period,
+// the position size at each funding time isn't known, so a position changed
+// during the period shows up in the differences.
+func (t *FundingTracker) Attribute(positions []*FundingPosition, incomes []*FundingIncome, startTime, endTime int64) []*FundingAttribution {
+	type symbolPosition struct {
+		amount       decimal.Decimal
+		contractSize decimal.Decimal
+		realized     decimal.Decimal
+	}
+	bySymbol := make(map[string]*symbolPosition)
+	var symbols []string
+	get := func(symbol string) *symbolPosition {
+		p, ok := bySymbol[symbol]
+		if !ok {
+			p = &symbolPosition{}
+			bySymbol[symbol] = p
+			symbols = append(symbols, symbol)
+		}
+		return p
+	}
+	for _, position := range positions {
+		p := get(position.Symbol)
+		p.amount = p.amount.Add(decimalOrZero(position.Amount))
+		if position.ContractSize != "" {
+			p.contractSize = decimalOrZero(position.ContractSize)
+		}
+	}
+	for _, income := range incomes {
+		if income.Time >= startTime && income.Time <= endTime {
+			p := get(income.Symbol)
+			p.realized = p.realized.Add(decimalOrZero(income.Amount))
+		}
+	}
+	sort.Strings(symbols)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := make([]*FundingAttribution, 0, len(symbols))
+	for _, symbol := range symbols {
+		p := bySymbol[symbol]
+		var markPrice decimal.Decimal
+		a := &FundingAttribution{Symbol: symbol, Amount: p.amount.String()}
+		if state, ok := t.states[symbol]; ok {
+			markPrice = decimalOrZero(state.MarkPrice)
+			a.Next = fundingPayment(p.amount, p.contractSize, markPrice, decimalOrZero(state.FundingRate)).String()
+			a.NextFundingTime = state.NextFundingTime
+		}
+		expected := decimal.Zero
+		for _, record := range t.history[symbol] {
+			if record.Time < startTime || record.Time > endTime {
+				continue
+			}
+			price := markPrice
+			if record.MarkPrice != "" {
+				price = decimalOrZero(record.MarkPrice)
+			}
+			expected = expected.Add(fundingPayment(p.amount, p.contractSize, price, decimalOrZero(record.Rate)))
+		}
+		a.Expected = expected.String()
+		a.Realized = p.realized.String()
+		a.Difference = p.realized.Sub(expected).String()
+		res = append(res, a)
+	}
+	return res
+}
+
+// fundingPayment return the funding received by a position, in quote asset
+// for USD-M and in base asset for COIN-M, the longs pay the positive rates
+func fundingPayment(amount, contractSize, markPrice, rate decimal.Decimal) decimal.Decimal {
+	if contractSize.IsZero() {
+		return amount.Mul(markPrice).Mul(rate).Neg()
+	}
+	if markPrice.IsZero() {
+		return decimal.Zero
+	}
+	return amount.Mul(contractSize).Div(markPrice).Mul(rate).Neg()
+}
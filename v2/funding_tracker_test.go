@@ -0,0 +1,131 @@
+package binance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type fundingTrackerTestSuite struct {
+	baseTestSuite
+}
+
+func TestFundingTracker(t *testing.T) {
+	suite.Run(t, new(fundingTrackerTestSuite))
+}
+
+const fundingInterval = 8 * 3600 * 1000
+
+func (s *fundingTrackerTestSuite) TestStreams() {
+	r := s.r()
+	tracker := NewFundingTracker()
+	tracker.HandleFuturesAllMarkPrice(futures.WsAllMarkPriceEvent{
+		{Symbol: "BTCUSDT", Time: 1000, MarkPrice: "100", IndexPrice: "99", FundingRate: "0.0001", NextFundingTime: fundingInterval},
+	})
+	// an older event is ignored
+	tracker.HandleFuturesMarkPrice(&futures.WsMarkPriceEvent{Symbol: "BTCUSDT", Time: 500, MarkPrice: "90"})
+	tracker.HandleFuturesMarkPrice(&futures.WsMarkPriceEvent{
+		Symbol: "BTCUSDT", Time: fundingInterval - 1, MarkPrice: "101", FundingRate: "0.0002", NextFundingTime: fundingInterval,
+	})
+	// the funding is settled
+	tracker.HandleFuturesMarkPrice(&futures.WsMarkPriceEvent{
+		Symbol: "BTCUSDT", Time: fundingInterval + 1, MarkPrice: "102", FundingRate: "-0.0001", NextFundingTime: 2 * fundingInterval,
+	})
+	r.Equal(&FundingState{
+		Symbol: "BTCUSDT", MarkPrice: "102", FundingRate: "-0.0001", NextFundingTime: 2 * fundingInterval,
+		LastFundingRate: "0.0002", LastFundingTime: fundingInterval, Time: fundingInterval + 1,
+	}, tracker.State("BTCUSDT"))
+	r.Equal([]*FundingRecord{{Symbol: "BTCUSDT", Rate: "0.0002", Time: fundingInterval, MarkPrice: "101"}},
+		tracker.History("BTCUSDT", 0, 2*fundingInterval))
+
+	tracker.HandleDeliveryPairMarkPrice(delivery.WsPairMarkPriceEvent{
+		{Symbol: "BTCUSD_PERP", Time: 1000, MarkPrice: "50000", FundingRate: "0.0001", NextFundingTime: fundingInterval},
+		{Symbol: "BTCUSD_250627", Time: 1000, MarkPrice: "51000"},
+	})
+	r.NotNil(tracker.State("BTCUSD_PERP"))
+	r.Nil(tracker.State("BTCUSD_250627"))
+}
+
+func (s *fundingTrackerTestSuite) TestBackfill() {
+	r := s.r()
+	var calls [][2]int64
+	fetch := func(ctx context.Context, startTime, endTime int64, limit int) ([]*FundingRecord, error) {
+		calls = append(calls, [2]int64{startTime, endTime})
+		var res []*FundingRecord
+		for t := (startTime + fundingInterval - 1) / fundingInterval * fundingInterval; t <= endTime && len(res) < limit; t += fundingInterval {
+			res = append(res, &FundingRecord{Symbol: "BTCUSDT", Rate: "0.0001", Time: t, MarkPrice: "100"})
+		}
+		return res, nil
+	}
+	tracker := NewFundingTracker()
+	tracker.HandleFuturesMarkPrice(&futures.WsMarkPriceEvent{Symbol: "BTCUSDT", Time: 1, MarkPrice: "100", FundingRate: "0.0005", NextFundingTime: fundingInterval})
+	tracker.HandleFuturesMarkPrice(&futures.WsMarkPriceEvent{Symbol: "BTCUSDT", Time: fundingInterval + 1, MarkPrice: "100", NextFundingTime: 2 * fundingInterval})
+
+	endTime := int64(1500 * fundingInterval)
+	r.NoError(tracker.Backfill(context.Background(), fetch, 1, endTime))
+	r.Equal([][2]int64{{1, endTime}, {1000*fundingInterval + 1, endTime}}, calls)
+	history := tracker.History("BTCUSDT", 0, endTime)
+	r.Len(history, 1500)
+	// the settled rate replace the one of the stream
+	r.Equal("0.0001", history[0].Rate)
+}
+
+func (s *fundingTrackerTestSuite) TestAttribute() {
+	r := s.r()
+	tracker := NewFundingTracker()
+	fetch := func(ctx context.Context, startTime, endTime int64, limit int) ([]*FundingRecord, error) {
+		return []*FundingRecord{
+			{Symbol: "BTCUSDT", Rate: "0.0001", Time: fundingInterval, MarkPrice: "100"},
+			{Symbol: "BTCUSDT", Rate: "-0.0002", Time: 2 * fundingInterval, MarkPrice: "110"},
+		}, nil
+	}
+	r.NoError(tracker.Backfill(context.Background(), fetch, 0, 2*fundingInterval))
+	r.NoError(tracker.Backfill(context.Background(), func(ctx context.Context, startTime, endTime int64, limit int) ([]*FundingRecord, error) {
+		return []*FundingRecord{{Symbol: "BTCUSD_PERP", Rate: "0.0001", Time: fundingInterval, MarkPrice: "50000"}}, nil
+	}, 0, 2*fundingInterval))
+	tracker.HandleFuturesPremiumIndex([]*futures.PremiumIndex{
+		{Symbol: "BTCUSDT", MarkPrice: "120", LastFundingRate: "0.0001", NextFundingTime: 3 * fundingInterval, Time: 2*fundingInterval + 1},
+	})
+	tracker.HandleDeliveryMarkPrice(&delivery.WsMarkPriceEvent{
+		Symbol: "BTCUSD_PERP", Time: 2*fundingInterval + 1, MarkPrice: "40000", FundingRate: "0.0001", NextFundingTime: 3 * fundingInterval,
+	})
+
+	positions := FuturesFundingPositions([]*futures.PositionRisk{
+		{Symbol: "BTCUSDT", PositionAmt: "3", PositionSide: "LONG"},
+		{Symbol: "BTCUSDT", PositionAmt: "-1", PositionSide: "SHORT"},
+	})
+	deliveryPositions, err := DeliveryFundingPositions([]*delivery.PositionRisk{
+		{Symbol: "BTCUSD_PERP", PositionAmt: "-10"},
+	}, map[string]int{"BTCUSD_PERP": 100})
+	r.NoError(err)
+	positions = append(positions, deliveryPositions...)
+	_, err = DeliveryFundingPositions([]*delivery.PositionRisk{
+		{Symbol: "ETHUSD_PERP", PositionAmt: "1"},
+	}, map[string]int{"BTCUSD_PERP": 100})
+	r.Error(err)
+	incomes := FuturesFundingIncomes([]*futures.IncomeHistory{
+		{Symbol: "BTCUSDT", IncomeType: "FUNDING_FEE", Income: "-0.02", Asset: "USDT", Time: fundingInterval},
+		{Symbol: "BTCUSDT", IncomeType: "COMMISSION", Income: "-1", Asset: "USDT", Time: fundingInterval},
+		{Symbol: "BTCUSDT", IncomeType: "FUNDING_FEE", Income: "0.044", Asset: "USDT", Time: 2 * fundingInterval},
+	})
+	incomes = append(incomes, DeliveryFundingIncomes([]*delivery.IncomeHistory{
+		{Symbol: "BTCUSD_PERP", IncomeType: "FUNDING_FEE", Income: "0.000002", Asset: "BTC", Time: fundingInterval},
+	})...)
+
+	r.Equal([]*FundingAttribution{
+		{
+			// 2 BTC long pays 0.02 then receives 0.044
+			Symbol: "BTCUSDT", Amount: "2", Expected: "0.024", Realized: "0.024", Difference: "0",
+			Next: "-0.024", NextFundingTime: 3 * fundingInterval,
+		},
+		{
+			// 1000 USD short receives 0.000002 BTC
+			Symbol: "BTCUSD_PERP", Amount: "-10", Expected: "0.000002", Realized: "0.000002", Difference: "0",
+			Next: "0.0000025", NextFundingTime: 3 * fundingInterval,
+		},
+	}, tracker.Attribute(positions, incomes, 0, 2*fundingInterval))
+}
@@ -0,0 +1,56 @@
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetADLQuantileService get the position ADL quantile estimations
+type GetADLQuantileService struct {
+	c      *Client
+	symbol *string
+}
+
+// Symbol set symbol
+func (s *GetADLQuantileService) Symbol(symbol string) *GetADLQuantileService {
+	s.symbol = &symbol
+	return s
+}
+
+// Do send request
+func (s *GetADLQuantileService) Do(ctx context.Context, opts ...RequestOption) (res []*ADLQuantile, err error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/fapi/v1/adlQuantile",
+		secType:  secTypeSigned,
+	}
+	if s.symbol != nil {
+		r.setParam("symbol", *s.symbol)
+	}
+	data, _, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = make([]*ADLQuantile, 0)
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ADLQuantile define the ADL quantiles of a symbol
+type ADLQuantile struct {
+	Symbol      string            `json:"symbol"`
+	ADLQuantile ADLQuantileLevels `json:"adlQuantile"`
+}
+
+// ADLQuantileLevels define the ADL quantiles by position side, from 0 to 4
+// the higher the sooner the position is deleveraged
+type ADLQuantileLevels struct {
+	Long  int  `json:"LONG"`
+	Short int  `json:"SHORT"`
+	Both  *int `json:"BOTH,omitempty"`  // one-way mode
+	Hedge *int `json:"HEDGE,omitempty"` // only marks hedge mode, the value is meaningless
+}
@@ -176,6 +176,9 @@ const (
 	UserDataEventTypeAccountConfigUpdate           UserDataEventType = "ACCOUNT_CONFIG_UPDATE"
 	UserDataEventTypeTradeLite                     UserDataEventType = "TRADE_LITE"
 	UserDataEventTypeConditionalOrderTriggerReject UserDataEventType = "CONDITIONAL_ORDER_TRIGGER_REJECT"
+	UserDataEventTypeStrategyUpdate                UserDataEventType = "STRATEGY_UPDATE"
+	UserDataEventTypeGridUpdate                    UserDataEventType = "GRID_UPDATE"
+	UserDataEventTypeAlgoUpdate                    UserDataEventType = "ALGO_UPDATE"
 
 	UserDataEventReasonTypeDeposit             UserDataEventReasonType = "DEPOSIT"
 	UserDataEventReasonTypeWithdraw            UserDataEventReasonType = "WITHDRAW"
@@ -230,13 +233,14 @@ func getApiEndpoint() string {
 // Services will be created by the form client.NewXXXService().
 func NewClient(apiKey, secretKey string) *Client {
 	return &Client{
-		APIKey:     apiKey,
-		SecretKey:  secretKey,
-		KeyType:    common.KeyTypeHmac,
-		BaseURL:    getApiEndpoint(),
-		UserAgent:  "Binance/golang",
-		HTTPClient: http.DefaultClient,
-		Logger:     log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		APIKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		BaseURL:                getApiEndpoint(),
+		UserAgent:              "Binance/golang",
+		HTTPClient:             http.DefaultClient,
+		Logger:                 log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.CONTRACT_ORDER_PREFIX),
 	}
 }
 
@@ -259,7 +263,8 @@ func NewProxiedClient(apiKey, secretKey, proxyUrl string) *Client {
 		HTTPClient: &http.Client{
 			Transport: tr,
 		},
-		Logger: log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		Logger:                 log.New(os.Stderr, "Binance-golang ", log.LstdFlags),
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.CONTRACT_ORDER_PREFIX),
 	}
 }
 
@@ -280,6 +285,22 @@ type Client struct {
 
 	UsedWeight common.UsedWeight
 	OrderCount common.OrderCount
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed without one
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
+}
+
+// newClientOrderID return a client order id for an order placed without one
+func (c *Client) newClientOrderID() string {
+	return newSwapClientOrderID(c.ClientOrderIDGenerator)
+}
+
+// newSwapClientOrderID return an id made by ids, or a broker id when ids is nil
+func newSwapClientOrderID(ids *common.ClientOrderIDGenerator) string {
+	if ids == nil {
+		return common.GenerateSwapId()
+	}
+	return ids.NewID()
 }
 
 func (c *Client) debug(format string, v ...interface{}) {
@@ -768,3 +789,79 @@ func (c *Client) NewGetConvertStatusService() *ConvertStatusService {
 func (c *Client) NewApiTradingStatusService() *ApiTradingStatusService {
 	return &ApiTradingStatusService{c: c}
 }
+
+// NewGetIncomeDownloadIDService init getting income download id service
+func (c *Client) NewGetIncomeDownloadIDService() *GetIncomeDownloadIDService {
+	return &GetIncomeDownloadIDService{c: c}
+}
+
+// NewGetIncomeDownloadLinkService init getting income download link service
+func (c *Client) NewGetIncomeDownloadLinkService() *GetIncomeDownloadLinkService {
+	return &GetIncomeDownloadLinkService{c: c}
+}
+
+// NewGetOrderDownloadIDService init getting order download id service
+func (c *Client) NewGetOrderDownloadIDService() *GetOrderDownloadIDService {
+	return &GetOrderDownloadIDService{c: c}
+}
+
+// NewGetOrderDownloadLinkService init getting order download link service
+func (c *Client) NewGetOrderDownloadLinkService() *GetOrderDownloadLinkService {
+	return &GetOrderDownloadLinkService{c: c}
+}
+
+// NewGetTradeDownloadIDService init getting trade download id service
+func (c *Client) NewGetTradeDownloadIDService() *GetTradeDownloadIDService {
+	return &GetTradeDownloadIDService{c: c}
+}
+
+// NewGetTradeDownloadLinkService init getting trade download link service
+func (c *Client) NewGetTradeDownloadLinkService() *GetTradeDownloadLinkService {
+	return &GetTradeDownloadLinkService{c: c}
+}
+
+// NewExportIncomeService init exporting income history service
+func (c *Client) NewExportIncomeService() *ExportIncomeService {
+	return &ExportIncomeService{export: export{c: c}}
+}
+
+// NewExportOrdersService init exporting order history service
+func (c *Client) NewExportOrdersService() *ExportOrdersService {
+	return &ExportOrdersService{export: export{c: c}}
+}
+
+// NewExportTradesService init exporting trade history service
+func (c *Client) NewExportTradesService() *ExportTradesService {
+	return &ExportTradesService{export: export{c: c}}
+}
+
+// NewCountdownCancelAllService init countdown cancel all service
+func (c *Client) NewCountdownCancelAllService() *CountdownCancelAllService {
+	return &CountdownCancelAllService{c: c}
+}
+
+// NewDeadMansSwitch init a dead man's switch canceling the open orders of
+// symbols countdown after the last healthy refresh
+func (c *Client) NewDeadMansSwitch(countdown time.Duration, symbols ...string) *DeadMansSwitch {
+	return &DeadMansSwitch{c: c, countdown: countdown, symbols: symbols}
+}
+
+// NewGetADLQuantileService init getting ADL quantile service
+func (c *Client) NewGetADLQuantileService() *GetADLQuantileService {
+	return &GetADLQuantileService{c: c}
+}
+
+// NewGetOrderRateLimitService init getting order rate limit service
+func (c *Client) NewGetOrderRateLimitService() *GetOrderRateLimitService {
+	return &GetOrderRateLimitService{c: c}
+}
+
+// NewListOrderAmendmentService init listing order amendment service
+func (c *Client) NewListOrderAmendmentService() *ListOrderAmendmentService {
+	return &ListOrderAmendmentService{c: c}
+}
+
+// NewGetPMAccountInfoService init getting portfolio margin account info service
+func (c *Client) NewGetPMAccountInfoService() *GetPMAccountInfoService {
+	return &GetPMAccountInfoService{c: c}
+}
@@ -0,0 +1,150 @@
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CountdownCancelAllService cancel all open orders of a symbol at the end of
+// a countdown, the countdown is reset by each call
+type CountdownCancelAllService struct {
+	c             *Client
+	symbol        string
+	countdownTime int64
+}
+
+// Symbol set symbol
+func (s *CountdownCancelAllService) Symbol(symbol string) *CountdownCancelAllService {
+	s.symbol = symbol
+	return s
+}
+
+// CountdownTime set countdownTime in milliseconds, 0 cancel the countdown
+func (s *CountdownCancelAllService) CountdownTime(countdownTime int64) *CountdownCancelAllService {
+	s.countdownTime = countdownTime
+	return s
+}
+
+// Do send request
+func (s *CountdownCancelAllService) Do(ctx context.Context, opts ...RequestOption) (res *CountdownCancelAll, err error) {
+	r := &request{
+		method:   http.MethodPost,
+		endpoint: "/fapi/v1/countdownCancelAll",
+		secType:  secTypeSigned,
+	}
+	r.setFormParams(params{
+		"symbol":        s.symbol,
+		"countdownTime": s.countdownTime,
+	})
+	data, _, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = new(CountdownCancelAll)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CountdownCancelAll define countdown cancel all response
+type CountdownCancelAll struct {
+	Symbol        string `json:"symbol"`
+	CountdownTime string `json:"countdownTime"`
+}
+
+// DeadMansSwitch keep refreshing the countdown cancel all of symbols while
+// the process is healthy, the open orders are canceled once it crashes, hangs
+// or reports it isn't healthy
+type DeadMansSwitch struct {
+	c         *Client
+	symbols   []string
+	countdown time.Duration
+	interval  time.Duration
+	healthy   func() bool
+	// OnError is called with the errors of the refreshes
+	OnError func(err error)
+
+	mu    sync.Mutex
+	stopC chan struct{}
+	doneC chan struct{}
+}
+
+// Interval set the delay between two refreshes, a third of the countdown by default
+func (d *DeadMansSwitch) Interval(interval time.Duration) *DeadMansSwitch {
+	d.interval = interval
+	return d
+}
+
+// Healthy set the health check done before each refresh
+func (d *DeadMansSwitch) Healthy(healthy func() bool) *DeadMansSwitch {
+	d.healthy = healthy
+	return d
+}
+
+func (d *DeadMansSwitch) set(ctx context.Context, countdown time.Duration) error {
+	for _, symbol := range d.symbols {
+		_, err := d.c.NewCountdownCancelAllService().Symbol(symbol).CountdownTime(countdown.Milliseconds()).Do(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start arm the countdowns and refresh them until ctx is done or Stop is
+// called, the countdowns are left armed when ctx is done
+func (d *DeadMansSwitch) Start(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopC != nil {
+		return nil
+	}
+	if err := d.set(ctx, d.countdown); err != nil {
+		return err
+	}
+	interval := d.interval
+	if interval <= 0 {
+		interval = d.countdown / 3
+	}
+	d.stopC = make(chan struct{})
+	d.doneC = make(chan struct{})
+	go func(stopC, doneC chan struct{}) {
+		defer close(doneC)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopC:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if d.healthy != nil && !d.healthy() {
+				continue
+			}
+			if err := d.set(ctx, d.countdown); err != nil && d.OnError != nil {
+				d.OnError(err)
+			}
+		}
+	}(d.stopC, d.doneC)
+	return nil
+}
+
+// Stop stop refreshing and cancel the countdowns
+func (d *DeadMansSwitch) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopC == nil {
+		return nil
+	}
+	close(d.stopC)
+	<-d.doneC
+	d.stopC, d.doneC = nil, nil
+	return d.set(ctx, 0)
+}
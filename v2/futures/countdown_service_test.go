@@ -0,0 +1,78 @@
+package futures
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type countdownServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestCountdownService(t *testing.T) {
+	suite.Run(t, new(countdownServiceTestSuite))
+}
+
+func (s *countdownServiceTestSuite) TestCountdownCancelAll() {
+	data := []byte(`{"symbol":"BTCUSDT","countdownTime":"100000"}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setFormParams(params{
+			"symbol":        "BTCUSDT",
+			"countdownTime": 100000,
+		})
+		s.assertRequestEqual(e, r)
+	})
+	res, err := s.client.NewCountdownCancelAllService().Symbol("BTCUSDT").CountdownTime(100000).Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal(&CountdownCancelAll{Symbol: "BTCUSDT", CountdownTime: "100000"}, res)
+}
+
+func (s *countdownServiceTestSuite) TestDeadMansSwitch() {
+	r := s.r()
+	var mu sync.Mutex
+	var calls []string
+	s.client.Client.do = func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		form, _ := url.ParseQuery(string(body))
+		mu.Lock()
+		calls = append(calls, form.Get("symbol")+" "+form.Get("countdownTime"))
+		mu.Unlock()
+		return newHTTPResponse([]byte(`{"symbol":"`+form.Get("symbol")+`","countdownTime":"`+form.Get("countdownTime")+`"}`), http.StatusOK), nil
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls)
+	}
+
+	var healthy int32 = 1
+	d := s.client.NewDeadMansSwitch(10*time.Second, "BTCUSDT", "ETHUSDT").Interval(5 * time.Millisecond).
+		Healthy(func() bool { return atomic.LoadInt32(&healthy) == 1 })
+	r.NoError(d.Start(context.Background()))
+	r.Eventually(func() bool { return count() >= 6 }, time.Second, time.Millisecond)
+
+	// an unhealthy process lets the countdowns run out
+	atomic.StoreInt32(&healthy, 0)
+	time.Sleep(10 * time.Millisecond)
+	n := count()
+	time.Sleep(20 * time.Millisecond)
+	r.Equal(n, count())
+
+	atomic.StoreInt32(&healthy, 1)
+	r.NoError(d.Stop(context.Background()))
+	mu.Lock()
+	defer mu.Unlock()
+	r.Equal([]string{"BTCUSDT 10000", "ETHUSDT 10000"}, calls[:2])
+	r.Equal([]string{"BTCUSDT 0", "ETHUSDT 0"}, calls[len(calls)-2:])
+}
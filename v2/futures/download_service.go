@@ -0,0 +1,198 @@
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// DownloadStatusType define the status of an asynchronous download
+type DownloadStatusType string
+
+// Download statuses
+const (
+	DownloadStatusTypeCompleted  DownloadStatusType = "completed"
+	DownloadStatusTypeProcessing DownloadStatusType = "processing"
+)
+
+// DownloadID define the id of an asynchronous download
+type DownloadID struct {
+	AvgCostTimestampOfLast30d int64  `json:"avgCostTimestampOfLast30d"`
+	DownloadID                string `json:"downloadId"`
+}
+
+// DownloadLink define the link of an asynchronous download
+type DownloadLink struct {
+	DownloadID          string             `json:"downloadId"`
+	Status              DownloadStatusType `json:"status"`
+	URL                 string             `json:"url"` // empty while processing
+	S3Link              *string            `json:"s3Link"`
+	Notified            bool               `json:"notified"`
+	ExpirationTimestamp int64              `json:"expirationTimestamp"` // -1 while processing
+	IsExpired           *bool              `json:"isExpired"`
+}
+
+func getDownloadID(ctx context.Context, c *Client, endpoint string, startTime, endTime int64, opts ...RequestOption) (*DownloadID, error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: endpoint,
+		secType:  secTypeSigned,
+	}
+	r.setParam("startTime", startTime)
+	r.setParam("endTime", endTime)
+	data, _, err := c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := new(DownloadID)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func getDownloadLink(ctx context.Context, c *Client, endpoint string, downloadID string, opts ...RequestOption) (*DownloadLink, error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: endpoint,
+		secType:  secTypeSigned,
+	}
+	r.setParam("downloadId", downloadID)
+	data, _, err := c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := new(DownloadLink)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetIncomeDownloadIDService get the download id of the transaction history,
+// the time range can't be longer than 1 year
+type GetIncomeDownloadIDService struct {
+	c         *Client
+	startTime int64
+	endTime   int64
+}
+
+// StartTime set startTime
+func (s *GetIncomeDownloadIDService) StartTime(startTime int64) *GetIncomeDownloadIDService {
+	s.startTime = startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *GetIncomeDownloadIDService) EndTime(endTime int64) *GetIncomeDownloadIDService {
+	s.endTime = endTime
+	return s
+}
+
+// Do send request
+func (s *GetIncomeDownloadIDService) Do(ctx context.Context, opts ...RequestOption) (*DownloadID, error) {
+	return getDownloadID(ctx, s.c, "/fapi/v1/income/asyn", s.startTime, s.endTime, opts...)
+}
+
+// GetIncomeDownloadLinkService get the transaction history download link by id
+type GetIncomeDownloadLinkService struct {
+	c          *Client
+	downloadID string
+}
+
+// DownloadID set downloadId
+func (s *GetIncomeDownloadLinkService) DownloadID(downloadID string) *GetIncomeDownloadLinkService {
+	s.downloadID = downloadID
+	return s
+}
+
+// Do send request
+func (s *GetIncomeDownloadLinkService) Do(ctx context.Context, opts ...RequestOption) (*DownloadLink, error) {
+	return getDownloadLink(ctx, s.c, "/fapi/v1/income/asyn/id", s.downloadID, opts...)
+}
+
+// GetOrderDownloadIDService get the download id of the order history, the
+// time range can't be longer than 1 year
+type GetOrderDownloadIDService struct {
+	c         *Client
+	startTime int64
+	endTime   int64
+}
+
+// StartTime set startTime
+func (s *GetOrderDownloadIDService) StartTime(startTime int64) *GetOrderDownloadIDService {
+	s.startTime = startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *GetOrderDownloadIDService) EndTime(endTime int64) *GetOrderDownloadIDService {
+	s.endTime = endTime
+	return s
+}
+
+// Do send request
+func (s *GetOrderDownloadIDService) Do(ctx context.Context, opts ...RequestOption) (*DownloadID, error) {
+	return getDownloadID(ctx, s.c, "/fapi/v1/order/asyn", s.startTime, s.endTime, opts...)
+}
+
+// GetOrderDownloadLinkService get the order history download link by id
+type GetOrderDownloadLinkService struct {
+	c          *Client
+	downloadID string
+}
+
+// DownloadID set downloadId
+func (s *GetOrderDownloadLinkService) DownloadID(downloadID string) *GetOrderDownloadLinkService {
+	s.downloadID = downloadID
+	return s
+}
+
+// Do send request
+func (s *GetOrderDownloadLinkService) Do(ctx context.Context, opts ...RequestOption) (*DownloadLink, error) {
+	return getDownloadLink(ctx, s.c, "/fapi/v1/order/asyn/id", s.downloadID, opts...)
+}
+
+// GetTradeDownloadIDService get the download id of the trade history, the
+// time range can't be longer than 1 year
+type GetTradeDownloadIDService struct {
+	c         *Client
+	startTime int64
+	endTime   int64
+}
+
+// StartTime set startTime
+func (s *GetTradeDownloadIDService) StartTime(startTime int64) *GetTradeDownloadIDService {
+	s.startTime = startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *GetTradeDownloadIDService) EndTime(endTime int64) *GetTradeDownloadIDService {
+	s.endTime = endTime
+	return s
+}
+
+// Do send request
+func (s *GetTradeDownloadIDService) Do(ctx context.Context, opts ...RequestOption) (*DownloadID, error) {
+	return getDownloadID(ctx, s.c, "/fapi/v1/trade/asyn", s.startTime, s.endTime, opts...)
+}
+
+// GetTradeDownloadLinkService get the trade history download link by id
+type GetTradeDownloadLinkService struct {
+	c          *Client
+	downloadID string
+}
+
+// DownloadID set downloadId
+func (s *GetTradeDownloadLinkService) DownloadID(downloadID string) *GetTradeDownloadLinkService {
+	s.downloadID = downloadID
+	return s
+}
+
+// Do send request
+func (s *GetTradeDownloadLinkService) Do(ctx context.Context, opts ...RequestOption) (*DownloadLink, error) {
+	return getDownloadLink(ctx, s.c, "/fapi/v1/trade/asyn/id", s.downloadID, opts...)
+}
@@ -0,0 +1,64 @@
+package futures
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type downloadServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestDownloadService(t *testing.T) {
+	suite.Run(t, new(downloadServiceTestSuite))
+}
+
+func (s *downloadServiceTestSuite) TestGetIncomeDownloadID() {
+	data := []byte(`{
+		"avgCostTimestampOfLast30d": 7241837,
+		"downloadId": "546975389218332672"
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"startTime": int64(1672531200000),
+			"endTime":   int64(1704067199999),
+		})
+		s.assertRequestEqual(e, r)
+	})
+	res, err := s.client.NewGetIncomeDownloadIDService().StartTime(1672531200000).EndTime(1704067199999).
+		Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal(&DownloadID{AvgCostTimestampOfLast30d: 7241837, DownloadID: "546975389218332672"}, res)
+}
+
+func (s *downloadServiceTestSuite) TestGetTradeDownloadLink() {
+	data := []byte(`{
+		"downloadId": "545923594199212032",
+		"status": "completed",
+		"url": "www.binance.com",
+		"s3Link": null,
+		"notified": true,
+		"expirationTimestamp": 1645009771000,
+		"isExpired": null
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParam("downloadId", "545923594199212032")
+		s.assertRequestEqual(e, r)
+	})
+	res, err := s.client.NewGetTradeDownloadLinkService().DownloadID("545923594199212032").Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal(&DownloadLink{
+		DownloadID:          "545923594199212032",
+		Status:              DownloadStatusTypeCompleted,
+		URL:                 "www.binance.com",
+		Notified:            true,
+		ExpirationTimestamp: 1645009771000,
+	}, res)
+}
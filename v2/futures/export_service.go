@@ -0,0 +1,384 @@
+package futures
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// defaultExportPollInterval is the delay between two download link requests
+const defaultExportPollInterval = 10 * time.Second
+
+// export request an asynchronous download, wait for its link and read the
+// CSV rows of the downloaded file
+type export struct {
+	c            *Client
+	startTime    int64
+	endTime      int64
+	pollInterval time.Duration
+}
+
+func (e *export) rows(ctx context.Context, endpoint string, opts ...RequestOption) ([]exportRow, error) {
+	id, err := getDownloadID(ctx, e.c, endpoint, e.startTime, e.endTime, opts...)
+	if err != nil {
+		return nil, err
+	}
+	interval := e.pollInterval
+	if interval <= 0 {
+		interval = defaultExportPollInterval
+	}
+	for {
+		link, err := getDownloadLink(ctx, e.c, endpoint+"/id", id.DownloadID, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if link.IsExpired != nil && *link.IsExpired {
+			return nil, fmt.Errorf("download %s is expired", id.DownloadID)
+		}
+		if link.Status == DownloadStatusTypeCompleted && link.URL != "" {
+			data, err := e.download(ctx, link.URL)
+			if err != nil {
+				return nil, err
+			}
+			return readExport(data)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (e *export) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	f := e.c.do
+	if f == nil {
+		f = e.c.HTTPClient.Do
+	}
+	res, err := f(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %d %s", url, res.StatusCode, data)
+	}
+	return data, nil
+}
+
+// readExport read the rows of a CSV file, plain, gzipped or in a zip archive
+func readExport(data []byte) ([]exportRow, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		var rows []exportRow
+		for _, file := range zr.File {
+			if !strings.EqualFold(path.Ext(file.Name), ".csv") {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			fileRows, err := readExportCSV(rc)
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Name, err)
+			}
+			rows = append(rows, fileRows...)
+		}
+		return rows, nil
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		return readExportCSV(gr)
+	}
+	return readExportCSV(bytes.NewReader(data))
+}
+
+func readExportCSV(r io.Reader) ([]exportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range header {
+		header[i] = exportColumn(name)
+	}
+	var rows []exportRow
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := exportRow{line: line, fields: make(map[string]string, len(header))}
+		for i, value := range record {
+			if i < len(header) {
+				row.fields[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// exportColumn normalize a column name, "Date(UTC)" and "date" are the same
+func exportColumn(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "(UTC)"))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// exportRow define a CSV row by normalized column name
+type exportRow struct {
+	line   int
+	fields map[string]string
+	err    error
+}
+
+// string return the value of the first column found
+func (r *exportRow) string(columns ...string) string {
+	for _, column := range columns {
+		if v, ok := r.fields[column]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func (r *exportRow) int64(columns ...string) int64 {
+	v := r.string(columns...)
+	if v == "" || r.err != nil {
+		return 0
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		r.err = fmt.Errorf("line %d: invalid %s %q", r.line, columns[0], v)
+	}
+	return i
+}
+
+func (r *exportRow) bool(columns ...string) bool {
+	v := strings.ToLower(r.string(columns...))
+	return v == "true" || v == "yes" || v == "1"
+}
+
+// time return a time in milliseconds, from milliseconds or a UTC date
+func (r *exportRow) time(columns ...string) int64 {
+	v := r.string(columns...)
+	if v == "" || r.err != nil {
+		return 0
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return ms
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "06-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UnixMilli()
+		}
+	}
+	r.err = fmt.Errorf("line %d: invalid %s %q", r.line, columns[0], v)
+	return 0
+}
+
+// ExportIncomeService export the transaction history of up to 1 year, the
+// downloads are limited to 5 per month
+type ExportIncomeService struct {
+	export
+}
+
+// StartTime set startTime
+func (s *ExportIncomeService) StartTime(startTime int64) *ExportIncomeService {
+	s.startTime = startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *ExportIncomeService) EndTime(endTime int64) *ExportIncomeService {
+	s.endTime = endTime
+	return s
+}
+
+// PollInterval set the delay between two download link requests, 10s by default
+func (s *ExportIncomeService) PollInterval(interval time.Duration) *ExportIncomeService {
+	s.pollInterval = interval
+	return s
+}
+
+// Do request the export, wait until it's ready and parse it
+func (s *ExportIncomeService) Do(ctx context.Context, opts ...RequestOption) (res []*IncomeHistory, err error) {
+	rows, err := s.rows(ctx, "/fapi/v1/income/asyn", opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = make([]*IncomeHistory, 0, len(rows))
+	for _, row := range rows {
+		income := &IncomeHistory{
+			Time:       row.time("time", "date", "transactiontime"),
+			Symbol:     row.string("symbol"),
+			IncomeType: row.string("incometype", "type"),
+			Income:     row.string("income", "amount", "change"),
+			Asset:      row.string("asset", "coin"),
+			Info:       row.string("info", "remark"),
+			TranID:     row.int64("tranid", "transactionid"),
+			TradeID:    row.string("tradeid"),
+		}
+		if row.err != nil {
+			return nil, row.err
+		}
+		res = append(res, income)
+	}
+	return res, nil
+}
+
+// ExportOrdersService export the order history of up to 1 year, the
+// downloads are limited to 10 per month
+type ExportOrdersService struct {
+	export
+}
+
+// StartTime set startTime
+func (s *ExportOrdersService) StartTime(startTime int64) *ExportOrdersService {
+	s.startTime = startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *ExportOrdersService) EndTime(endTime int64) *ExportOrdersService {
+	s.endTime = endTime
+	return s
+}
+
+// PollInterval set the delay between two download link requests, 10s by default
+func (s *ExportOrdersService) PollInterval(interval time.Duration) *ExportOrdersService {
+	s.pollInterval = interval
+	return s
+}
+
+// Do request the export, wait until it's ready and parse it
+func (s *ExportOrdersService) Do(ctx context.Context, opts ...RequestOption) (res []*Order, err error) {
+	rows, err := s.rows(ctx, "/fapi/v1/order/asyn", opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = make([]*Order, 0, len(rows))
+	for _, row := range rows {
+		order := &Order{
+			Time:             row.time("time", "date", "ordertime"),
+			UpdateTime:       row.time("updatetime"),
+			Symbol:           row.string("symbol"),
+			OrderID:          row.int64("orderid", "orderno"),
+			ClientOrderID:    row.string("clientorderid"),
+			Side:             SideType(row.string("side")),
+			PositionSide:     PositionSideType(row.string("positionside")),
+			Type:             OrderType(row.string("type", "ordertype")),
+			OrigType:         OrderType(row.string("origtype")),
+			TimeInForce:      TimeInForceType(row.string("timeinforce")),
+			Price:            row.string("price"),
+			AvgPrice:         row.string("avgprice", "average", "averageprice"),
+			StopPrice:        row.string("stopprice", "triggerprice"),
+			OrigQuantity:     row.string("origqty", "quantity", "amount"),
+			ExecutedQuantity: row.string("executedqty", "filled"),
+			CumQuote:         row.string("cumquote", "total"),
+			Status:           OrderStatusType(row.string("status")),
+			ReduceOnly:       row.bool("reduceonly"),
+			ClosePosition:    row.bool("closeposition"),
+		}
+		if row.err != nil {
+			return nil, row.err
+		}
+		res = append(res, order)
+	}
+	return res, nil
+}
+
+// ExportTradesService export the trade history of up to 1 year, the
+// downloads are limited to 5 per month
+type ExportTradesService struct {
+	export
+}
+
+// StartTime set startTime
+func (s *ExportTradesService) StartTime(startTime int64) *ExportTradesService {
+	s.startTime = startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *ExportTradesService) EndTime(endTime int64) *ExportTradesService {
+	s.endTime = endTime
+	return s
+}
+
+// PollInterval set the delay between two download link requests, 10s by default
+func (s *ExportTradesService) PollInterval(interval time.Duration) *ExportTradesService {
+	s.pollInterval = interval
+	return s
+}
+
+// Do request the export, wait until it's ready and parse it
+func (s *ExportTradesService) Do(ctx context.Context, opts ...RequestOption) (res []*AccountTrade, err error) {
+	rows, err := s.rows(ctx, "/fapi/v1/trade/asyn", opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = make([]*AccountTrade, 0, len(rows))
+	for _, row := range rows {
+		trade := &AccountTrade{
+			Time:            row.time("time", "date"),
+			Symbol:          row.string("symbol"),
+			ID:              row.int64("id", "tradeid"),
+			OrderID:         row.int64("orderid", "orderno"),
+			Side:            SideType(row.string("side")),
+			PositionSide:    PositionSideType(row.string("positionside")),
+			Price:           row.string("price"),
+			Quantity:        row.string("qty", "quantity"),
+			QuoteQuantity:   row.string("quoteqty", "amount"),
+			Commission:      row.string("commission", "fee"),
+			CommissionAsset: row.string("commissionasset", "feecoin", "feeasset"),
+			RealizedPnl:     row.string("realizedpnl", "realizedprofit"),
+			Buyer:           row.bool("buyer"),
+			Maker:           row.bool("maker"),
+		}
+		if row.err != nil {
+			return nil, row.err
+		}
+		res = append(res, trade)
+	}
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package futures
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type exportServiceTestSuite struct {
+	baseTestSuite
+	links int
+}
+
+func TestExportService(t *testing.T) {
+	suite.Run(t, new(exportServiceTestSuite))
+}
+
+// serve answer the export requests, the link is ready at the second request
+func (s *exportServiceTestSuite) serve(endpoint string, file []byte) {
+	s.links = 0
+	s.client.Client.do = func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case endpoint:
+			s.r().Equal("1672531200000", req.URL.Query().Get("startTime"))
+			return newHTTPResponse([]byte(`{"avgCostTimestampOfLast30d":1000,"downloadId":"42"}`), http.StatusOK), nil
+		case endpoint + "/id":
+			s.r().Equal("42", req.URL.Query().Get("downloadId"))
+			s.links++
+			if s.links == 1 {
+				return newHTTPResponse([]byte(`{"downloadId":"42","status":"processing","url":"","notified":false,"expirationTimestamp":-1,"isExpired":null}`), http.StatusOK), nil
+			}
+			return newHTTPResponse([]byte(`{"downloadId":"42","status":"completed","url":"https://bucket.example.com/export","notified":true,"expirationTimestamp":1645009771000,"isExpired":null}`), http.StatusOK), nil
+		case "/export":
+			return newHTTPResponse(file, http.StatusOK), nil
+		}
+		return newHTTPResponse([]byte(`{"code":-1,"msg":"not found"}`), http.StatusNotFound), nil
+	}
+}
+
+func (s *exportServiceTestSuite) TestIncome() {
+	s.serve("/fapi/v1/income/asyn", []byte("Date(UTC),Symbol,Income Type,Amount,Asset,Info,Transaction ID\n"+
+		"2023-01-01 08:00:00,BTCUSDT,FUNDING_FEE,-0.0125,USDT,,9689322392\n"+
+		"1672560000000,,TRANSFER,100,USDT,,9689322393\n"))
+	res, err := s.client.NewExportIncomeService().StartTime(1672531200000).EndTime(1704067199999).
+		PollInterval(time.Millisecond).Do(newContext())
+	r := s.r()
+	r.NoError(err)
+	r.Equal(2, s.links)
+	r.Equal([]*IncomeHistory{
+		{Time: 1672560000000, Symbol: "BTCUSDT", IncomeType: "FUNDING_FEE", Income: "-0.0125", Asset: "USDT", TranID: 9689322392},
+		{Time: 1672560000000, IncomeType: "TRANSFER", Income: "100", Asset: "USDT", TranID: 9689322393},
+	}, res)
+}
+
+func (s *exportServiceTestSuite) TestTradesZip() {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("trades.csv")
+	s.r().NoError(err)
+	w.Write([]byte("Time,Symbol,Trade ID,Order ID,Side,Position Side,Price,Qty,Quote Qty,Fee,Fee Coin,Realized Profit,Maker\n" +
+		"1672560000000,BTCUSDT,28457,8886774,SELL,BOTH,16500.1,0.002,33.0002,0.0132,USDT,1.2,false\n"))
+	s.r().NoError(zw.Close())
+
+	s.serve("/fapi/v1/trade/asyn", buf.Bytes())
+	res, err := s.client.NewExportTradesService().StartTime(1672531200000).EndTime(1704067199999).
+		PollInterval(time.Millisecond).Do(newContext())
+	r := s.r()
+	r.NoError(err)
+	r.Equal([]*AccountTrade{{
+		Time: 1672560000000, Symbol: "BTCUSDT", ID: 28457, OrderID: 8886774, Side: SideTypeSell,
+		PositionSide: PositionSideTypeBoth, Price: "16500.1", Quantity: "0.002", QuoteQuantity: "33.0002",
+		Commission: "0.0132", CommissionAsset: "USDT", RealizedPnl: "1.2",
+	}}, res)
+}
+
+func (s *exportServiceTestSuite) TestOrdersInvalid() {
+	s.serve("/fapi/v1/order/asyn", []byte("Time,Order ID,Symbol\n1672560000000,abc,BTCUSDT\n"))
+	_, err := s.client.NewExportOrdersService().StartTime(1672531200000).EndTime(1704067199999).
+		PollInterval(time.Millisecond).Do(newContext())
+	s.r().EqualError(err, `line 2: invalid orderid "abc"`)
+}
+
+func (s *exportServiceTestSuite) TestCanceled() {
+	s.serve("/fapi/v1/income/asyn", nil)
+	ctx, cancel := context.WithCancel(newContext())
+	cancel()
+	_, err := s.client.NewExportIncomeService().StartTime(1672531200000).EndTime(1704067199999).Do(ctx)
+	s.r().Error(err)
+}
@@ -0,0 +1,113 @@
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ListOrderAmendmentService list the modifications of an order
+type ListOrderAmendmentService struct {
+	c                 *Client
+	symbol            string
+	orderID           *int64
+	origClientOrderID *string
+	startTime         *int64
+	endTime           *int64
+	limit             *int
+}
+
+// Symbol set symbol
+func (s *ListOrderAmendmentService) Symbol(symbol string) *ListOrderAmendmentService {
+	s.symbol = symbol
+	return s
+}
+
+// OrderID set orderID
+func (s *ListOrderAmendmentService) OrderID(orderID int64) *ListOrderAmendmentService {
+	s.orderID = &orderID
+	return s
+}
+
+// OrigClientOrderID set origClientOrderID
+func (s *ListOrderAmendmentService) OrigClientOrderID(origClientOrderID string) *ListOrderAmendmentService {
+	s.origClientOrderID = &origClientOrderID
+	return s
+}
+
+// StartTime set startTime
+func (s *ListOrderAmendmentService) StartTime(startTime int64) *ListOrderAmendmentService {
+	s.startTime = &startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *ListOrderAmendmentService) EndTime(endTime int64) *ListOrderAmendmentService {
+	s.endTime = &endTime
+	return s
+}
+
+// Limit set limit, 50 by default and 100 at most
+func (s *ListOrderAmendmentService) Limit(limit int) *ListOrderAmendmentService {
+	s.limit = &limit
+	return s
+}
+
+// Do send request
+func (s *ListOrderAmendmentService) Do(ctx context.Context, opts ...RequestOption) (res []*OrderAmendment, err error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/fapi/v1/orderAmendment",
+		secType:  secTypeSigned,
+	}
+	r.setParam("symbol", s.symbol)
+	if s.orderID != nil {
+		r.setParam("orderId", *s.orderID)
+	}
+	if s.origClientOrderID != nil {
+		r.setParam("origClientOrderId", *s.origClientOrderID)
+	}
+	if s.startTime != nil {
+		r.setParam("startTime", *s.startTime)
+	}
+	if s.endTime != nil {
+		r.setParam("endTime", *s.endTime)
+	}
+	if s.limit != nil {
+		r.setParam("limit", *s.limit)
+	}
+	data, _, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = make([]*OrderAmendment, 0)
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// OrderAmendment define a modification of an order
+type OrderAmendment struct {
+	AmendmentID   int64     `json:"amendmentId"`
+	Symbol        string    `json:"symbol"`
+	Pair          string    `json:"pair"`
+	OrderID       int64     `json:"orderId"`
+	ClientOrderID string    `json:"clientOrderId"`
+	Time          int64     `json:"time"`
+	Amendment     Amendment `json:"amendment"`
+}
+
+// Amendment define the changes of a modification
+type Amendment struct {
+	Price        AmendmentChange `json:"price"`
+	OrigQuantity AmendmentChange `json:"origQty"`
+	Count        int             `json:"count"` // number of modifications of the order
+}
+
+// AmendmentChange define a modified value
+type AmendmentChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
@@ -0,0 +1,60 @@
+package futures
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type orderAmendmentServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestOrderAmendmentService(t *testing.T) {
+	suite.Run(t, new(orderAmendmentServiceTestSuite))
+}
+
+func (s *orderAmendmentServiceTestSuite) TestListOrderAmendment() {
+	data := []byte(`[
+		{
+			"amendmentId": 5363,
+			"symbol": "BTCUSDT",
+			"pair": "BTCUSDT",
+			"orderId": 20072994037,
+			"clientOrderId": "LJ9R4QZDihCaS8UAOOLpgW",
+			"time": 1629184560899,
+			"amendment": {
+				"price": {"before": "30004", "after": "30003.2"},
+				"origQty": {"before": "1", "after": "1"},
+				"count": 3
+			}
+		}
+	]`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"symbol":  "BTCUSDT",
+			"orderId": 20072994037,
+			"limit":   10,
+		})
+		s.assertRequestEqual(e, r)
+	})
+	res, err := s.client.NewListOrderAmendmentService().Symbol("BTCUSDT").OrderID(20072994037).Limit(10).
+		Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal([]*OrderAmendment{{
+		AmendmentID:   5363,
+		Symbol:        "BTCUSDT",
+		Pair:          "BTCUSDT",
+		OrderID:       20072994037,
+		ClientOrderID: "LJ9R4QZDihCaS8UAOOLpgW",
+		Time:          1629184560899,
+		Amendment: Amendment{
+			Price:        AmendmentChange{Before: "30004", After: "30003.2"},
+			OrigQuantity: AmendmentChange{Before: "1", After: "1"},
+			Count:        3,
+		},
+	}}, res)
+}
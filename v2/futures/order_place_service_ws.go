@@ -15,6 +15,11 @@ type OrderPlaceWsService struct {
 	SecretKey  string
 	KeyType    string
 	TimeOffset int64
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed
+	// without one, a generator of its own by default. Set the generator of the
+	// client to share its prefix, tag and sequence.
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
 }
 
 // NewOrderPlaceWsService init OrderPlaceWsService
@@ -30,10 +35,11 @@ func NewOrderPlaceWsService(apiKey, secretKey string) (*OrderPlaceWsService, err
 	}
 
 	return &OrderPlaceWsService{
-		c:         client,
-		ApiKey:    apiKey,
-		SecretKey: secretKey,
-		KeyType:   common.KeyTypeHmac,
+		c:                      client,
+		ApiKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.CONTRACT_ORDER_PREFIX),
 	}, nil
 }
 
@@ -181,11 +187,11 @@ type CreateOrderWsResponse struct {
 }
 
 func (r *OrderPlaceWsRequest) GetParams() map[string]interface{} {
-	return r.buildParams()
+	return r.buildParams(nil)
 }
 
 // buildParams builds params
-func (s *OrderPlaceWsRequest) buildParams() params {
+func (s *OrderPlaceWsRequest) buildParams(ids *common.ClientOrderIDGenerator) params {
 	m := params{
 		"symbol":           s.symbol,
 		"side":             s.side,
@@ -210,7 +216,7 @@ func (s *OrderPlaceWsRequest) buildParams() params {
 	if s.newClientOrderID != nil {
 		m["newClientOrderId"] = *s.newClientOrderID
 	} else {
-		m["newClientOrderId"] = common.GenerateSwapId()
+		m["newClientOrderId"] = newSwapClientOrderID(ids)
 	}
 	if s.stopPrice != nil {
 		m["stopPrice"] = *s.stopPrice
@@ -248,7 +254,7 @@ func (s *OrderPlaceWsService) Do(requestID string, request *OrderPlaceWsRequest)
 			s.KeyType,
 		),
 		websocket.OrderPlaceFuturesWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return err
@@ -272,7 +278,7 @@ func (s *OrderPlaceWsService) SyncDo(requestID string, request *OrderPlaceWsRequ
 			s.KeyType,
 		),
 		websocket.OrderPlaceFuturesWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return nil, err
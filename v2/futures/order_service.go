@@ -186,7 +186,7 @@ func (s *CreateOrderService) createOrder(ctx context.Context, endpoint string, o
 	if s.newClientOrderID != nil {
 		m["newClientOrderId"] = *s.newClientOrderID
 	} else {
-		m["newClientOrderId"] = common.GenerateSwapId()
+		m["newClientOrderId"] = s.c.newClientOrderID()
 	}
 	if s.stopPrice != nil {
 		m["stopPrice"] = *s.stopPrice
@@ -1042,7 +1042,7 @@ func (s *CreateBatchOrdersService) Do(ctx context.Context, opts ...RequestOption
 		if order.newClientOrderID != nil {
 			m["newClientOrderId"] = *order.newClientOrderID
 		} else {
-			m["newClientOrderId"] = common.GenerateSwapId()
+			m["newClientOrderId"] = s.c.newClientOrderID()
 		}
 		if order.stopPrice != nil {
 			m["stopPrice"] = *order.stopPrice
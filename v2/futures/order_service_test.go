@@ -172,6 +172,30 @@ func (s *orderServiceTestSuite) TestCreateOrderId() {
 	s.r().NoError(err)
 }
 
+func (s *orderServiceTestSuite) TestCreateOrderGeneratedId() {
+	data := []byte(`{
+		"clientOrderId": "x-ftGmvgANhedge_s1_b",
+		"orderId": 22542179,
+		"status": "NEW",
+		"symbol": "BTCUSDT"
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+	s.client.ClientOrderIDGenerator = common.NewClientOrderIDGenerator(common.CONTRACT_ORDER_PREFIX).Session("s1").Tag("hedge").Sequence(10)
+
+	s.assertReq(func(r *request) {
+		s.r().Equal("x-ftGmvgANhedge_s1_b", r.form.Get("newClientOrderId"))
+	})
+	res, err := s.client.NewCreateOrderService().Symbol("BTCUSDT").Side(SideTypeSell).
+		Type(OrderTypeMarket).Quantity("1").Do(newContext())
+	r := s.r()
+	r.NoError(err)
+	meta, ok := s.client.ClientOrderIDGenerator.Decode(res.ClientOrderID)
+	r.True(ok)
+	r.Equal("hedge", meta.Tag)
+	r.Equal(uint64(11), meta.Sequence)
+}
+
 func (s *baseOrderTestSuite) assertCreateOrderResponseEqual(e, a *CreateOrderResponse) {
 	r := s.r()
 	r.Equal(e.ClientOrderID, a.ClientOrderID, "ClientOrderID")
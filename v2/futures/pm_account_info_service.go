@@ -0,0 +1,46 @@
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetPMAccountInfoService get the portfolio margin account info of an asset
+type GetPMAccountInfoService struct {
+	c     *Client
+	asset string
+}
+
+// Asset set asset
+func (s *GetPMAccountInfoService) Asset(asset string) *GetPMAccountInfoService {
+	s.asset = asset
+	return s
+}
+
+// Do send request
+func (s *GetPMAccountInfoService) Do(ctx context.Context, opts ...RequestOption) (res *PMAccountInfo, err error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/fapi/v1/pmAccountInfo",
+		secType:  secTypeSigned,
+	}
+	r.setParam("asset", s.asset)
+	data, _, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = new(PMAccountInfo)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PMAccountInfo define portfolio margin account info
+type PMAccountInfo struct {
+	MaxWithdrawAmountUSD string `json:"maxWithdrawAmountUSD"`
+	Asset                string `json:"asset"`
+	MaxWithdrawAmount    string `json:"maxWithdrawAmount"`
+}
@@ -0,0 +1,37 @@
+package futures
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type pmAccountInfoServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestPMAccountInfoService(t *testing.T) {
+	suite.Run(t, new(pmAccountInfoServiceTestSuite))
+}
+
+func (s *pmAccountInfoServiceTestSuite) TestGetPMAccountInfo() {
+	data := []byte(`{
+		"maxWithdrawAmountUSD": "1627523.32459208",
+		"asset": "BTC",
+		"maxWithdrawAmount": "27.43689636"
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParam("asset", "BTC")
+		s.assertRequestEqual(e, r)
+	})
+	res, err := s.client.NewGetPMAccountInfoService().Asset("BTC").Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal(&PMAccountInfo{
+		MaxWithdrawAmountUSD: "1627523.32459208",
+		Asset:                "BTC",
+		MaxWithdrawAmount:    "27.43689636",
+	}, res)
+}
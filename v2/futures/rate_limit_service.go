@@ -0,0 +1,31 @@
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetOrderRateLimitService get the order rate limits of the account
+type GetOrderRateLimitService struct {
+	c *Client
+}
+
+// Do send request
+func (s *GetOrderRateLimitService) Do(ctx context.Context, opts ...RequestOption) (res []*RateLimit, err error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/fapi/v1/rateLimit/order",
+		secType:  secTypeSigned,
+	}
+	data, _, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = make([]*RateLimit, 0)
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,35 @@
+package futures
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type rateLimitServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestRateLimitService(t *testing.T) {
+	suite.Run(t, new(rateLimitServiceTestSuite))
+}
+
+func (s *rateLimitServiceTestSuite) TestGetOrderRateLimit() {
+	data := []byte(`[
+		{"rateLimitType": "ORDERS", "interval": "SECOND", "intervalNum": 10, "limit": 10000},
+		{"rateLimitType": "ORDERS", "interval": "MINUTE", "intervalNum": 1, "limit": 20000}
+	]`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest()
+		s.assertRequestEqual(e, r)
+	})
+	res, err := s.client.NewGetOrderRateLimitService().Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal([]*RateLimit{
+		{RateLimitType: "ORDERS", Interval: "SECOND", IntervalNum: 10, Limit: 10000},
+		{RateLimitType: "ORDERS", Interval: "MINUTE", IntervalNum: 1, Limit: 20000},
+	}, res)
+}
@@ -27,6 +27,12 @@ func newWsConfig(endpoint string) *WsConfig {
 	}
 }
 
+// WsDial open the websocket connections, it can be replaced to route them,
+// e.g. through a cassette recorder
+var WsDial = func(dialer *websocket.Dialer, endpoint string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	return dialer.Dial(endpoint, header)
+}
+
 var wsServe = func(cfg *WsConfig, handler WsHandler, errHandler ErrHandler) (doneC, stopC chan struct{}, err error) {
 	proxy := http.ProxyFromEnvironment
 	if cfg.Proxy != nil {
@@ -42,7 +48,7 @@ var wsServe = func(cfg *WsConfig, handler WsHandler, errHandler ErrHandler) (don
 		EnableCompression: true,
 	}
 
-	c, _, err := Dialer.Dial(cfg.Endpoint, nil)
+	c, _, err := WsDial(&Dialer, cfg.Endpoint, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -132,7 +138,7 @@ var WsGetReadWriteConnection = func(cfg *WsConfig) (*websocket.Conn, error) {
 		EnableCompression: false,
 	}
 
-	c, _, err := Dialer.Dial(cfg.Endpoint, nil)
+	c, _, err := WsDial(&Dialer, cfg.Endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
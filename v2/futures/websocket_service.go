@@ -1065,6 +1065,15 @@ type WsUserDataEvent struct {
 
 	// CONDITIONAL_ORDER_TRIGGER_REJECT
 	WsUserDataConditionalOrderTriggerReject
+
+	// STRATEGY_UPDATE
+	WsUserDataStrategyUpdate
+
+	// GRID_UPDATE
+	WsUserDataGridUpdate
+
+	// ALGO_UPDATE
+	WsUserDataAlgoUpdate
 }
 
 type WsUserDataAccountConfigUpdate struct {
@@ -1101,6 +1110,21 @@ type WsUserDataConditionalOrderTriggerReject struct {
 	ConditionalOrderTriggerReject WsConditionalOrderTriggerReject `json:"or"`
 }
 
+type WsUserDataStrategyUpdate struct {
+	StrategyUpdate WsStrategyUpdate `json:"su"`
+}
+
+type WsUserDataGridUpdate struct {
+	GridUpdate WsGridUpdate `json:"gu"`
+}
+
+// WsUserDataAlgoUpdate define the ALGO_UPDATE payload, its "o" key is the
+// same as the order trade update one, so UnmarshalJSON decodes it into
+// AlgoUpdate for ALGO_UPDATE events only
+type WsUserDataAlgoUpdate struct {
+	AlgoUpdate WsAlgoUpdate
+}
+
 func (w *WsUserDataTradeLite) fromSimpleJson(j *simplejson.Json) (err error) {
 	w.Symbol = j.Get("s").MustString()
 	w.OriginalQty = j.Get("q").MustString()
@@ -1132,11 +1156,18 @@ func (e *WsUserDataEvent) UnmarshalJSON(data []byte) error {
 		UserDataEventTypeOrderTradeUpdate:              &e.WsUserDataOrderTradeUpdate,
 		UserDataEventTypeAccountConfigUpdate:           &e.WsUserDataAccountConfigUpdate,
 		UserDataEventTypeConditionalOrderTriggerReject: &e.WsUserDataConditionalOrderTriggerReject,
+		UserDataEventTypeStrategyUpdate:                &e.WsUserDataStrategyUpdate,
+		UserDataEventTypeGridUpdate:                    &e.WsUserDataGridUpdate,
 	}
 
 	switch e.Event {
 	case UserDataEventTypeTradeLite:
 		return e.WsUserDataTradeLite.fromSimpleJson(j)
+	case UserDataEventTypeAlgoUpdate:
+		v := struct {
+			AlgoUpdate *WsAlgoUpdate `json:"o"`
+		}{&e.AlgoUpdate}
+		return json.Unmarshal(data, &v)
 	case UserDataEventTypeListenKeyExpired:
 		// noting
 	default:
@@ -1174,6 +1205,7 @@ type WsPosition struct {
 	MarginType                MarginType       `json:"mt"`
 	IsolatedWallet            string           `json:"iw"`
 	EntryPrice                string           `json:"ep"`
+	BreakEvenPrice            string           `json:"bep"`
 	MarkPrice                 string           `json:"mp"`
 	UnrealizedPnL             string           `json:"up"`
 	AccumulatedRealized       string           `json:"cr"`
@@ -1230,6 +1262,59 @@ type WsConditionalOrderTriggerReject struct {
 	RejectReason string `json:"r"`
 }
 
+// WsStrategyUpdate define strategy update
+type WsStrategyUpdate struct {
+	StrategyID     int64  `json:"si"`
+	StrategyType   string `json:"st"` // GRID
+	StrategyStatus string `json:"ss"` // NEW, WORKING, CANCELLED, EXPIRED
+	Symbol         string `json:"s"`
+	UpdateTime     int64  `json:"ut"`
+	OpCode         int64  `json:"c"`
+}
+
+// WsGridUpdate define grid update
+type WsGridUpdate struct {
+	StrategyID            int64  `json:"si"`
+	StrategyType          string `json:"st"`
+	StrategyStatus        string `json:"ss"`
+	Symbol                string `json:"s"`
+	RealizedPnL           string `json:"r"`
+	UnmatchedAveragePrice string `json:"up"`
+	UnmatchedQty          string `json:"uq"`
+	UnmatchedFee          string `json:"uf"`
+	MatchedPnL            string `json:"mp"`
+	UpdateTime            int64  `json:"ut"`
+}
+
+// WsAlgoUpdate define algo order update
+type WsAlgoUpdate struct {
+	ClientAlgoID      string           `json:"caid"` // Client algo ID
+	AlgoID            int64            `json:"aid"`  // Algo ID
+	AlgoType          string           `json:"at"`   // Algo type, CONDITIONAL
+	Type              OrderType        `json:"o"`    // Order type
+	Symbol            string           `json:"s"`    // Symbol
+	Side              SideType         `json:"S"`    // Side
+	PositionSide      PositionSideType `json:"ps"`   // Position side
+	TimeInForce       TimeInForceType  `json:"f"`    // Time in force
+	Quantity          string           `json:"q"`    // Quantity
+	Status            string           `json:"X"`    // Algo status: NEW, CANCELED, TRIGGERING, TRIGGERED, FINISHED, REJECTED, EXPIRED
+	OrderID           string           `json:"ai"`   // ID of the order in the matching engine once triggered
+	AveragePrice      string           `json:"ap"`   // Average fill price in the matching engine
+	ExecutedQuantity  string           `json:"aq"`   // Executed quantity in the matching engine
+	ActualOrderType   string           `json:"act"`  // Order type in the matching engine
+	TriggerPrice      string           `json:"tp"`   // Trigger price
+	Price             string           `json:"p"`    // Order price
+	STP               string           `json:"V"`    // STP mode
+	WorkingType       WorkingType      `json:"wt"`   // Trigger price working type
+	PriceMode         string           `json:"pm"`   // Price match mode
+	IsClosingPosition bool             `json:"cp"`   // If Close-All
+	PriceProtect      bool             `json:"pP"`   // If price protection is turned on
+	IsReduceOnly      bool             `json:"R"`    // Is this reduce only
+	TriggerTime       int64            `json:"tt"`   // Trigger time
+	GTD               int64            `json:"gtd"`  // TIF GTD order auto cancel time
+	FailedReason      string           `json:"rm"`   // Reason of the failure
+}
+
 // WsUserDataHandler handle WsUserDataEvent
 type WsUserDataHandler func(event *WsUserDataEvent)
 
@@ -1980,6 +1980,142 @@ func (s *websocketServiceTestSuite) TestWsUserDataServeTradeLite() {
 	s.testWsUserDataServe(data, expectedEvent)
 }
 
+func (s *websocketServiceTestSuite) TestWsUserDataServeStrategyUpdate() {
+	data := []byte(`{
+		"e":"STRATEGY_UPDATE",
+		"T":1669782450003,
+		"E":1669782450009,
+		"su":{
+			"si":176054594,
+			"st":"GRID",
+			"ss":"NEW",
+			"s":"BTCUSDT",
+			"ut":1669782450003,
+			"c":8007
+		}
+	}`)
+	expectedEvent := &WsUserDataEvent{
+		Event:           "STRATEGY_UPDATE",
+		Time:            1669782450009,
+		TransactionTime: 1669782450003,
+		WsUserDataStrategyUpdate: WsUserDataStrategyUpdate{
+			StrategyUpdate: WsStrategyUpdate{
+				StrategyID:     176054594,
+				StrategyType:   "GRID",
+				StrategyStatus: "NEW",
+				Symbol:         "BTCUSDT",
+				UpdateTime:     1669782450003,
+				OpCode:         8007,
+			},
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeGridUpdate() {
+	data := []byte(`{
+		"e":"GRID_UPDATE",
+		"T":1669782450003,
+		"E":1669782450009,
+		"gu":{
+			"si":176057039,
+			"st":"GRID",
+			"ss":"WORKING",
+			"s":"BTCUSDT",
+			"r":"-0.00300716",
+			"up":"16720",
+			"uq":"-0.001",
+			"uf":"-0.00300716",
+			"mp":"0.0",
+			"ut":1669782450003
+		}
+	}`)
+	expectedEvent := &WsUserDataEvent{
+		Event:           "GRID_UPDATE",
+		Time:            1669782450009,
+		TransactionTime: 1669782450003,
+		WsUserDataGridUpdate: WsUserDataGridUpdate{
+			GridUpdate: WsGridUpdate{
+				StrategyID:            176057039,
+				StrategyType:          "GRID",
+				StrategyStatus:        "WORKING",
+				Symbol:                "BTCUSDT",
+				RealizedPnL:           "-0.00300716",
+				UnmatchedAveragePrice: "16720",
+				UnmatchedQty:          "-0.001",
+				UnmatchedFee:          "-0.00300716",
+				MatchedPnL:            "0.0",
+				UpdateTime:            1669782450003,
+			},
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeAlgoUpdate() {
+	data := []byte(`{
+		"e":"ALGO_UPDATE",
+		"T":1750515742297,
+		"E":1750515742303,
+		"o":{
+			"caid":"Q5xaq5EGKgXXa0fD7fs0Ip",
+			"aid":2148719,
+			"at":"CONDITIONAL",
+			"o":"TAKE_PROFIT",
+			"s":"BNBUSDT",
+			"S":"SELL",
+			"ps":"BOTH",
+			"f":"GTC",
+			"q":"0.01",
+			"X":"CANCELED",
+			"ai":"",
+			"ap":"0.00000",
+			"aq":"0.00000",
+			"act":"0",
+			"tp":"750",
+			"p":"750",
+			"V":"EXPIRE_MAKER",
+			"wt":"CONTRACT_PRICE",
+			"pm":"NONE",
+			"cp":false,
+			"pP":false,
+			"R":false,
+			"tt":0,
+			"gtd":0,
+			"rm":"Reduce Only reject"
+		}
+	}`)
+	expectedEvent := &WsUserDataEvent{
+		Event:           "ALGO_UPDATE",
+		Time:            1750515742303,
+		TransactionTime: 1750515742297,
+		WsUserDataAlgoUpdate: WsUserDataAlgoUpdate{
+			AlgoUpdate: WsAlgoUpdate{
+				ClientAlgoID:     "Q5xaq5EGKgXXa0fD7fs0Ip",
+				AlgoID:           2148719,
+				AlgoType:         "CONDITIONAL",
+				Type:             OrderTypeTakeProfit,
+				Symbol:           "BNBUSDT",
+				Side:             SideTypeSell,
+				PositionSide:     PositionSideTypeBoth,
+				TimeInForce:      TimeInForceTypeGTC,
+				Quantity:         "0.01",
+				Status:           "CANCELED",
+				AveragePrice:     "0.00000",
+				ExecutedQuantity: "0.00000",
+				ActualOrderType:  "0",
+				TriggerPrice:     "750",
+				Price:            "750",
+				STP:              "EXPIRE_MAKER",
+				WorkingType:      WorkingTypeContractPrice,
+				PriceMode:        "NONE",
+				FailedReason:     "Reduce Only reject",
+			},
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
 func (s *websocketServiceTestSuite) assertUserDataEvent(e, a *WsUserDataEvent) {
 	r := s.r()
 	r.Equal(e.Event, a.Event, "Event")
@@ -1994,6 +2130,9 @@ func (s *websocketServiceTestSuite) assertUserDataEvent(e, a *WsUserDataEvent) {
 	s.assertOrderTradeUpdate(e.OrderTradeUpdate, a.OrderTradeUpdate)
 	s.assertAccountConfigUpdate(e.AccountConfigUpdate, a.AccountConfigUpdate)
 	s.assertTradeLite(e.WsUserDataTradeLite, a.WsUserDataTradeLite)
+	r.Equal(e.StrategyUpdate, a.StrategyUpdate, "StrategyUpdate")
+	r.Equal(e.GridUpdate, a.GridUpdate, "GridUpdate")
+	r.Equal(e.AlgoUpdate, a.AlgoUpdate, "AlgoUpdate")
 }
 
 func (s *websocketServiceTestSuite) assertTradeLite(e, a WsUserDataTradeLite) {
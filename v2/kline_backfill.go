@@ -0,0 +1,434 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2/alpha"
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/adshao/go-binance/v2/options"
+)
+
+// DefaultKlineBackfillLimit is the page size used by a KlineBackfill when none is set
+const DefaultKlineBackfillLimit = 1000
+
+// KlinePageFetcher fetch at most limit klines opening between startTime and
+// endTime included, in milliseconds. Use one of the XxxKlinePages helpers to
+// get the fetcher of a kline flavour.
+type KlinePageFetcher func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error)
+
+// SpotKlinePages fetch spot klines
+func SpotKlinePages(c *Client, symbol, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		return c.NewKlinesService().Symbol(symbol).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+	}
+}
+
+// FuturesKlinePages fetch USD-M futures klines
+func FuturesKlinePages(c *futures.Client, symbol, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		res, err := c.NewKlinesService().Symbol(symbol).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		klines := make([]*Kline, len(res))
+		for i, k := range res {
+			// the futures and delivery klines share the spot kline layout
+			klines[i] = (*Kline)(k)
+		}
+		return klines, nil
+	}
+}
+
+// FuturesContinuousKlinePages fetch USD-M futures continuous contract klines
+func FuturesContinuousKlinePages(c *futures.Client, pair, contractType, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		res, err := c.NewContinuousKlinesService().Pair(pair).ContractType(contractType).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		klines := make([]*Kline, len(res))
+		for i, k := range res {
+			klines[i] = (*Kline)(k)
+		}
+		return klines, nil
+	}
+}
+
+// FuturesMarkPriceKlinePages fetch USD-M futures mark price klines
+func FuturesMarkPriceKlinePages(c *futures.Client, symbol, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		res, err := c.NewMarkPriceKlinesService().Symbol(symbol).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		klines := make([]*Kline, len(res))
+		for i, k := range res {
+			klines[i] = (*Kline)(k)
+		}
+		return klines, nil
+	}
+}
+
+// FuturesIndexPriceKlinePages fetch USD-M futures index price klines
+func FuturesIndexPriceKlinePages(c *futures.Client, pair, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		res, err := c.NewIndexPriceKlinesService().Pair(pair).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		klines := make([]*Kline, len(res))
+		for i, k := range res {
+			klines[i] = (*Kline)(k)
+		}
+		return klines, nil
+	}
+}
+
+// FuturesPremiumIndexKlinePages fetch USD-M futures premium index klines
+func FuturesPremiumIndexKlinePages(c *futures.Client, symbol, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		res, err := c.NewPremiumIndexKlinesService().Symbol(symbol).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		klines := make([]*Kline, len(res))
+		for i, k := range res {
+			klines[i] = (*Kline)(k)
+		}
+		return klines, nil
+	}
+}
+
+// DeliveryKlinePages fetch COIN-M futures klines
+func DeliveryKlinePages(c *delivery.Client, symbol, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		res, err := c.NewKlinesService().Symbol(symbol).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		klines := make([]*Kline, len(res))
+		for i, k := range res {
+			klines[i] = (*Kline)(k)
+		}
+		return klines, nil
+	}
+}
+
+// OptionsKlinePages fetch options klines, Amount and TakerAmount are returned
+// as QuoteAssetVolume and TakerBuyQuoteAssetVolume
+func OptionsKlinePages(c *options.Client, symbol, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		res, err := c.NewKlinesService().Symbol(symbol).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		klines := make([]*Kline, len(res))
+		for i, k := range res {
+			klines[i] = &Kline{
+				OpenTime:                 k.OpenTime,
+				Open:                     k.Open,
+				High:                     k.High,
+				Low:                      k.Low,
+				Close:                    k.Close,
+				Volume:                   k.Volume,
+				CloseTime:                k.CloseTime,
+				QuoteAssetVolume:         k.Amount,
+				TradeNum:                 k.TradeCount,
+				TakerBuyBaseAssetVolume:  k.TakerVolume,
+				TakerBuyQuoteAssetVolume: k.TakerAmount,
+			}
+		}
+		return klines, nil
+	}
+}
+
+// AlphaKlinePages fetch alpha token klines
+func AlphaKlinePages(c *alpha.Client, symbol, interval string) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		res, err := c.NewGetKlinesService().Symbol(symbol).Interval(interval).
+			StartTime(startTime).EndTime(endTime).Limit(limit).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		klines := make([]*Kline, len(res))
+		for i, k := range res {
+			klines[i] = &Kline{
+				OpenTime:                 k.OpenTime,
+				Open:                     k.Open,
+				High:                     k.High,
+				Low:                      k.Low,
+				Close:                    k.Close,
+				Volume:                   k.Volume,
+				CloseTime:                k.CloseTime,
+				QuoteAssetVolume:         k.QuoteAssetVolume,
+				TradeNum:                 int64(k.NumberOfTrades),
+				TakerBuyBaseAssetVolume:  k.TakerBuyBaseAssetVolume,
+				TakerBuyQuoteAssetVolume: k.TakerBuyQuoteAssetVolume,
+			}
+		}
+		return klines, nil
+	}
+}
+
+// KlineGap define candles missing between two klines, e.g. during an exchange maintenance
+type KlineGap struct {
+	// From is the open time of the first missing kline
+	From int64 `json:"from"`
+	// To is the open time of the kline following the gap, or the end of the range
+	To      int64 `json:"to"`
+	Missing int   `json:"missing"`
+}
+
+// KlineBackfillCheckpoint define where a backfill stopped, it can be stored
+// and passed to Resume to continue the backfill later
+type KlineBackfillCheckpoint struct {
+	NextStartTime int64 `json:"nextStartTime"`
+	// LastOpenTime is the open time of the last kline delivered, 0 when none was
+	LastOpenTime int64 `json:"lastOpenTime"`
+}
+
+// KlineBackfill walk a [startTime, endTime) range page by page, klines are
+// delivered in open time order without duplicates, and missing candles are
+// reported as gaps.
+type KlineBackfill struct {
+	fetch     KlinePageFetcher
+	interval  string
+	startTime int64
+	endTime   int64
+	limit     int
+	pageDelay time.Duration
+
+	resume       *KlineBackfillCheckpoint
+	onCheckpoint func(checkpoint *KlineBackfillCheckpoint) error
+	onGap        func(gap *KlineGap)
+}
+
+// NewKlineBackfill init a backfill of the klines opening in [startTime, endTime), in milliseconds
+func NewKlineBackfill(fetch KlinePageFetcher, interval string, startTime, endTime int64) *KlineBackfill {
+	return &KlineBackfill{
+		fetch:     fetch,
+		interval:  interval,
+		startTime: startTime,
+		endTime:   endTime,
+		limit:     DefaultKlineBackfillLimit,
+	}
+}
+
+// NewKlineBackfill init a backfill of the spot klines opening in [startTime, endTime), in milliseconds
+func (c *Client) NewKlineBackfill(symbol, interval string, startTime, endTime int64) *KlineBackfill {
+	return NewKlineBackfill(SpotKlinePages(c, symbol, interval), interval, startTime, endTime)
+}
+
+// Limit set the page size
+func (b *KlineBackfill) Limit(limit int) *KlineBackfill {
+	b.limit = limit
+	return b
+}
+
+// Throttle space the pages so that requests of pageWeight stay within weightPerMinute
+func (b *KlineBackfill) Throttle(pageWeight, weightPerMinute int) *KlineBackfill {
+	if weightPerMinute > 0 {
+		b.pageDelay = time.Minute * time.Duration(pageWeight) / time.Duration(weightPerMinute)
+	}
+	return b
+}
+
+// Resume continue a previous backfill from its last checkpoint
+func (b *KlineBackfill) Resume(checkpoint *KlineBackfillCheckpoint) *KlineBackfill {
+	b.resume = checkpoint
+	return b
+}
+
+// OnCheckpoint call f after every page is delivered, the backfill stops if f returns an error
+func (b *KlineBackfill) OnCheckpoint(f func(checkpoint *KlineBackfillCheckpoint) error) *KlineBackfill {
+	b.onCheckpoint = f
+	return b
+}
+
+// OnGap call f for every run of missing klines, including the ones at the
+// start and at the end of the range
+func (b *KlineBackfill) OnGap(f func(gap *KlineGap)) *KlineBackfill {
+	b.onGap = f
+	return b
+}
+
+// Do walk the range and call handler for every kline, it stops at the first error
+func (b *KlineBackfill) Do(ctx context.Context, handler func(kline *Kline) error) error {
+	if _, err := klineIntervalNext(0, b.interval); err != nil {
+		return err
+	}
+	next, lastOpen := b.startTime, int64(-1)
+	if b.resume != nil {
+		next = b.resume.NextStartTime
+		if b.resume.LastOpenTime > 0 {
+			lastOpen = b.resume.LastOpenTime
+		}
+	}
+	// expected is the open time of the next kline, to report the gaps
+	// before the first kline and after the last one too
+	expected, err := klineIntervalAlign(next, b.interval)
+	if err != nil {
+		return err
+	}
+	if lastOpen >= 0 {
+		expected, _ = klineIntervalNext(lastOpen, b.interval)
+	}
+	for page := 0; next < b.endTime; page++ {
+		if page > 0 && b.pageDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(b.pageDelay):
+			}
+		}
+		klines, err := b.fetch(ctx, next, b.endTime-1, b.limit)
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+		for _, k := range klines {
+			// pages may overlap, and the exchange may return klines out of the range
+			if k.OpenTime < next || k.OpenTime >= b.endTime || k.OpenTime <= lastOpen {
+				continue
+			}
+			b.detectGap(expected, k.OpenTime)
+			if err := handler(k); err != nil {
+				return err
+			}
+			lastOpen = k.OpenTime
+			expected, _ = klineIntervalNext(lastOpen, b.interval)
+		}
+		if lastOpen >= next {
+			next, _ = klineIntervalNext(lastOpen, b.interval)
+		}
+		if b.onCheckpoint != nil {
+			checkpoint := &KlineBackfillCheckpoint{NextStartTime: next}
+			if lastOpen >= 0 {
+				checkpoint.LastOpenTime = lastOpen
+			}
+			if err := b.onCheckpoint(checkpoint); err != nil {
+				return err
+			}
+		}
+		if len(klines) < b.limit {
+			// the exchange has nothing more in the range
+			break
+		}
+	}
+	b.detectGap(expected, b.endTime)
+	return nil
+}
+
+// Stream walk the range in background and send the klines on the returned
+// channel, which is closed at the end. The error channel receives at most one
+// error and is closed after the kline channel.
+func (b *KlineBackfill) Stream(ctx context.Context) (<-chan *Kline, <-chan error) {
+	klineC := make(chan *Kline)
+	errC := make(chan error, 1)
+	go func() {
+		defer close(errC)
+		defer close(klineC)
+		err := b.Do(ctx, func(kline *Kline) error {
+			select {
+			case klineC <- kline:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errC <- err
+		}
+	}()
+	return klineC, errC
+}
+
+// detectGap report the klines missing from expected to the one opening at openTime
+func (b *KlineBackfill) detectGap(expected, openTime int64) {
+	if openTime <= expected || b.onGap == nil {
+		return
+	}
+	gap := &KlineGap{From: expected, To: openTime}
+	if strings.HasSuffix(b.interval, "M") {
+		for t := expected; t < openTime; t, _ = klineIntervalNext(t, b.interval) {
+			gap.Missing++
+		}
+	} else {
+		next, _ := klineIntervalNext(expected, b.interval)
+		step := next - expected
+		gap.Missing = int((openTime - expected + step - 1) / step)
+	}
+	b.onGap(gap)
+}
+
+// klineIntervalAlign return the open time of the first kline opening at or after t,
+// the weeks open on mondays
+func klineIntervalAlign(t int64, interval string) (int64, error) {
+	next, err := klineIntervalNext(t, interval)
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasSuffix(interval, "M") {
+		month := time.UnixMilli(t).UTC()
+		start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+		if start < t {
+			start, _ = klineIntervalNext(start, interval)
+		}
+		return start, nil
+	}
+	var offset int64
+	if strings.HasSuffix(interval, "w") {
+		// the epoch is a thursday
+		offset = int64(4 * 24 * time.Hour / time.Millisecond)
+	}
+	step := next - t
+	aligned := (t - offset) / step * step
+	if aligned < t-offset {
+		aligned += step
+	}
+	return aligned + offset, nil
+}
+
+// klineIntervalNext return the open time of the kline following the one opening at openTime
+func klineIntervalNext(openTime int64, interval string) (int64, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("invalid kline interval %q", interval)
+	}
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid kline interval %q", interval)
+	}
+	var unit time.Duration
+	switch interval[len(interval)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	case 'M':
+		t := time.UnixMilli(openTime).UTC().AddDate(0, n, 0)
+		return t.UnixMilli(), nil
+	default:
+		return 0, fmt.Errorf("invalid kline interval %q", interval)
+	}
+	return openTime + int64(time.Duration(n)*unit/time.Millisecond), nil
+}
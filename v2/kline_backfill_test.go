@@ -0,0 +1,229 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type klineBackfillTestSuite struct {
+	baseTestSuite
+}
+
+func TestKlineBackfill(t *testing.T) {
+	suite.Run(t, new(klineBackfillTestSuite))
+}
+
+const testMinute = int64(60000)
+
+// fakeKlinePages serves 1m klines opening at the given minutes, every page
+// also repeats the kline before startTime to exercise the de-duplication
+func fakeKlinePages(minutes []int64, calls *int) KlinePageFetcher {
+	return func(ctx context.Context, startTime, endTime int64, limit int) ([]*Kline, error) {
+		*calls++
+		var res []*Kline
+		for _, m := range minutes {
+			openTime := m * testMinute
+			if openTime >= startTime-testMinute && openTime <= endTime && len(res) < limit {
+				res = append(res, &Kline{OpenTime: openTime, CloseTime: openTime + testMinute - 1})
+			}
+		}
+		return res, nil
+	}
+}
+
+func (s *klineBackfillTestSuite) TestDo() {
+	var calls int
+	fetch := fakeKlinePages([]int64{0, 1, 2, 3, 6, 7, 8, 9, 10}, &calls)
+
+	var openTimes []int64
+	var gaps []*KlineGap
+	var checkpoints []*KlineBackfillCheckpoint
+	err := NewKlineBackfill(fetch, "1m", 0, 10*testMinute).
+		Limit(3).
+		OnGap(func(gap *KlineGap) { gaps = append(gaps, gap) }).
+		OnCheckpoint(func(cp *KlineBackfillCheckpoint) error {
+			checkpoints = append(checkpoints, cp)
+			return nil
+		}).
+		Do(newContext(), func(k *Kline) error {
+			openTimes = append(openTimes, k.OpenTime/testMinute)
+			return nil
+		})
+	r := s.r()
+	r.NoError(err)
+	r.Equal([]int64{0, 1, 2, 3, 6, 7, 8, 9}, openTimes)
+	r.Equal([]*KlineGap{{From: 4 * testMinute, To: 6 * testMinute, Missing: 2}}, gaps)
+	r.Equal(&KlineBackfillCheckpoint{NextStartTime: 10 * testMinute, LastOpenTime: 9 * testMinute}, checkpoints[len(checkpoints)-1])
+	r.Equal(len(checkpoints), calls)
+}
+
+func (s *klineBackfillTestSuite) TestResume() {
+	var calls int
+	fetch := fakeKlinePages([]int64{0, 1, 2, 3, 4, 5, 7}, &calls)
+	stop := errors.New("stop")
+
+	var first []int64
+	var saved *KlineBackfillCheckpoint
+	err := NewKlineBackfill(fetch, "1m", 0, 8*testMinute).
+		Limit(2).
+		OnCheckpoint(func(cp *KlineBackfillCheckpoint) error {
+			saved = cp
+			if cp.LastOpenTime >= 2*testMinute {
+				return stop
+			}
+			return nil
+		}).
+		Do(newContext(), func(k *Kline) error {
+			first = append(first, k.OpenTime/testMinute)
+			return nil
+		})
+	r := s.r()
+	r.Equal(stop, err)
+	r.Equal(&KlineBackfillCheckpoint{NextStartTime: 3 * testMinute, LastOpenTime: 2 * testMinute}, saved)
+
+	var rest []int64
+	var gaps []*KlineGap
+	err = NewKlineBackfill(fetch, "1m", 0, 8*testMinute).
+		Limit(2).
+		Resume(saved).
+		OnGap(func(gap *KlineGap) { gaps = append(gaps, gap) }).
+		Do(newContext(), func(k *Kline) error {
+			rest = append(rest, k.OpenTime/testMinute)
+			return nil
+		})
+	r.NoError(err)
+	r.Equal([]int64{0, 1, 2}, first)
+	r.Equal([]int64{3, 4, 5, 7}, rest)
+	r.Len(gaps, 1)
+	r.Equal(1, gaps[0].Missing)
+}
+
+func (s *klineBackfillTestSuite) TestEdgeGaps() {
+	var calls int
+	fetch := fakeKlinePages([]int64{3, 4, 5}, &calls)
+	var gaps []*KlineGap
+	err := NewKlineBackfill(fetch, "1m", testMinute/2, 8*testMinute).
+		OnGap(func(gap *KlineGap) { gaps = append(gaps, gap) }).
+		Do(newContext(), func(k *Kline) error { return nil })
+	r := s.r()
+	r.NoError(err)
+	r.Equal([]*KlineGap{
+		{From: testMinute, To: 3 * testMinute, Missing: 2},
+		{From: 6 * testMinute, To: 8 * testMinute, Missing: 2},
+	}, gaps)
+
+	// an empty range is a single gap
+	gaps = nil
+	err = NewKlineBackfill(fetch, "1m", 10*testMinute, 15*testMinute).
+		OnGap(func(gap *KlineGap) { gaps = append(gaps, gap) }).
+		Do(newContext(), func(k *Kline) error { return nil })
+	r.NoError(err)
+	r.Equal([]*KlineGap{{From: 10 * testMinute, To: 15 * testMinute, Missing: 5}}, gaps)
+}
+
+func (s *klineBackfillTestSuite) TestIntervalAlign() {
+	r := s.r()
+	for _, test := range []struct {
+		interval string
+		t        string
+		expected string
+	}{
+		{"1m", "2024-03-05T10:00:00Z", "2024-03-05T10:00:00Z"},
+		{"15m", "2024-03-05T10:00:01Z", "2024-03-05T10:15:00Z"},
+		{"1d", "2024-03-05T10:00:00Z", "2024-03-06T00:00:00Z"},
+		{"1w", "2024-03-05T10:00:00Z", "2024-03-11T00:00:00Z"},
+		{"1M", "2024-03-05T10:00:00Z", "2024-04-01T00:00:00Z"},
+		{"1M", "2024-03-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+	} {
+		t, err := time.Parse(time.RFC3339, test.t)
+		r.NoError(err)
+		expected, err := time.Parse(time.RFC3339, test.expected)
+		r.NoError(err)
+		aligned, err := klineIntervalAlign(t.UnixMilli(), test.interval)
+		r.NoError(err)
+		r.Equal(expected.UnixMilli(), aligned, test.interval+" "+test.t)
+	}
+}
+
+func (s *klineBackfillTestSuite) TestStream() {
+	var calls int
+	fetch := fakeKlinePages([]int64{0, 1, 2, 3, 4}, &calls)
+	klineC, errC := NewKlineBackfill(fetch, "1m", 0, 5*testMinute).Limit(2).Stream(newContext())
+	var n int
+	for range klineC {
+		n++
+	}
+	s.r().NoError(<-errC)
+	s.r().Equal(5, n)
+
+	_, errC = NewKlineBackfill(fetch, "1x", 0, 5*testMinute).Stream(newContext())
+	s.r().Error(<-errC)
+}
+
+func (s *klineBackfillTestSuite) TestThrottle() {
+	b := NewKlineBackfill(nil, "1m", 0, 1).Throttle(2, 6000)
+	s.r().Equal(20*time.Millisecond, b.pageDelay)
+}
+
+func (s *klineBackfillTestSuite) TestSpotAndFuturesPages() {
+	s.mockRoutes(routedResponses{
+		"/api/v3/klines": `[
+			[60000, "1", "2", "0.5", "1.5", "10", 119999, "15", 3, "5", "7.5", "0"],
+			[120000, "1.5", "2", "1", "1.8", "20", 179999, "30", 4, "10", "15", "0"]
+		]`,
+	})
+	var spot []*Kline
+	err := s.client.NewKlineBackfill("BTCUSDT", "1m", 60000, 180000).
+		Do(newContext(), func(k *Kline) error {
+			spot = append(spot, k)
+			return nil
+		})
+	r := s.r()
+	r.NoError(err)
+	r.Len(spot, 2)
+	r.Equal("1.8", spot[1].Close)
+
+	futuresClient := futures.NewClient(s.apiKey, s.secretKey)
+	futuresClient.HTTPClient = &http.Client{Transport: routedResponses{
+		"/fapi/v1/markPriceKlines": `[
+			[60000, "1", "2", "0.5", "1.5", "0", 119999, "0", 0, "0", "0", "0"]
+		]`,
+	}}
+	res, err := FuturesMarkPriceKlinePages(futuresClient, "BTCUSDT", "1m")(newContext(), 0, 120000, 10)
+	r.NoError(err)
+	r.Len(res, 1)
+	r.Equal("1.5", res[0].Close)
+}
+
+func TestKlineIntervalNext(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	for _, c := range []struct {
+		openTime int64
+		interval string
+		next     int64
+	}{
+		{0, "1s", 1000},
+		{0, "15m", 15 * 60000},
+		{0, "4h", 4 * 3600000},
+		{0, "3d", 3 * 86400000},
+		{0, "1w", 7 * 86400000},
+		{jan, "1M", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).UnixMilli()},
+	} {
+		next, err := klineIntervalNext(c.openTime, c.interval)
+		if err != nil || next != c.next {
+			t.Errorf("klineIntervalNext(%d, %s) = %d, %v, want %d", c.openTime, c.interval, next, err, c.next)
+		}
+	}
+	for _, interval := range []string{"", "m", "0m", "1y"} {
+		if _, err := klineIntervalNext(0, interval); err == nil {
+			t.Errorf("klineIntervalNext(0, %q) should fail", interval)
+		}
+	}
+}
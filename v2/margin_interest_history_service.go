@@ -54,7 +54,7 @@ func (s *MarginInterestHistoryService) Size(size int64) *MarginInterestHistorySe
 }
 
 // Do sends the request.
-func (s *MarginInterestHistoryService) Do(ctx context.Context) (*MarginInterestHistory, error) {
+func (s *MarginInterestHistoryService) Do(ctx context.Context, opts ...RequestOption) (*MarginInterestHistory, error) {
 	r := &request{
 		method:   http.MethodGet,
 		endpoint: "/sapi/v1/margin/interestHistory",
@@ -78,7 +78,7 @@ func (s *MarginInterestHistoryService) Do(ctx context.Context) (*MarginInterestH
 	if s.size != nil {
 		r.setParam("size", *s.size)
 	}
-	data, err := s.c.callAPI(ctx, r)
+	data, err := s.c.callAPI(ctx, r, opts...)
 	if err != nil {
 		return nil, err
 	}
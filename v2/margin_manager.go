@@ -0,0 +1,450 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrMarginBorrowExceeded is returned when a borrow is larger than the safe borrowable amount
+var ErrMarginBorrowExceeded = errors.New("borrow amount exceeds the safe borrowable amount")
+
+// marginHistoryPageSize is the page size used to walk the borrow/repay and interest history
+const marginHistoryPageSize = 100
+
+// marginHistoryWindow is the longest time range of a borrow/repay or interest history request, in milliseconds
+const marginHistoryWindow = int64(30 * 24 * time.Hour / time.Millisecond)
+
+// MarginLevelState define the margin level of a cross or isolated margin account
+type MarginLevelState struct {
+	IsolatedSymbol string `json:"isolatedSymbol"`
+	MarginLevel    string `json:"marginLevel"`
+	// MarginLevelStatus is only reported for isolated margin accounts
+	MarginLevelStatus   string `json:"marginLevelStatus"`
+	TotalAssetOfBTC     string `json:"totalAssetOfBtc"`
+	TotalLiabilityOfBTC string `json:"totalLiabilityOfBtc"`
+}
+
+// OutstandingMarginLoan define the outstanding loan of an asset
+type OutstandingMarginLoan struct {
+	Asset    string `json:"asset"`
+	Borrowed string `json:"borrowed"`
+	Interest string `json:"interest"`
+	Free     string `json:"free"`
+}
+
+// MarginLedgerEntryType define the type of a margin ledger entry
+type MarginLedgerEntryType string
+
+const (
+	MarginLedgerEntryTypeBorrow   MarginLedgerEntryType = "BORROW"
+	MarginLedgerEntryTypeRepay    MarginLedgerEntryType = "REPAY"
+	MarginLedgerEntryTypeInterest MarginLedgerEntryType = "INTEREST"
+)
+
+// MarginLedgerEntry define a borrow, a repayment or an interest accrual
+type MarginLedgerEntry struct {
+	Type      MarginLedgerEntryType `json:"type"`
+	Asset     string                `json:"asset"`
+	TxID      int64                 `json:"txId"`
+	Time      int64                 `json:"time"`
+	Principal string                `json:"principal"`
+	Interest  string                `json:"interest"`
+}
+
+// MarginLedgerAsset define the totals of the ledger for an asset
+type MarginLedgerAsset struct {
+	Asset           string `json:"asset"`
+	Borrowed        string `json:"borrowed"`
+	RepaidPrincipal string `json:"repaidPrincipal"`
+	AccruedInterest string `json:"accruedInterest"`
+	RepaidInterest  string `json:"repaidInterest"`
+	UnpaidInterest  string `json:"unpaidInterest"`
+	OutstandingLoan string `json:"outstandingLoan"`
+}
+
+// MarginLedger define the borrow, repay and interest history of a margin account
+type MarginLedger struct {
+	Entries []*MarginLedgerEntry `json:"entries"`
+	Assets  []*MarginLedgerAsset `json:"assets"`
+}
+
+type marginLevelThreshold struct {
+	level    decimal.Decimal
+	callback func(state *MarginLevelState)
+	breached bool
+}
+
+// MarginManager wraps the low level margin services for a cross margin
+// account, or an isolated margin account when IsolatedSymbol is set. It
+// tracks the margin level from REST and the margin user data stream, caps
+// borrows to a safe share of the max borrowable amount, repays outstanding
+// loans after fills, and builds a ledger of the accrued interest.
+type MarginManager struct {
+	c              *Client
+	errHandler     ErrHandler
+	isolatedSymbol string
+	borrowRatio    decimal.Decimal
+	autoRepay      bool
+
+	mu         sync.Mutex
+	thresholds []*marginLevelThreshold
+	state      *MarginLevelState
+}
+
+// IsolatedSymbol manage the isolated margin account of symbol instead of the cross margin account
+func (m *MarginManager) IsolatedSymbol(symbol string) *MarginManager {
+	m.isolatedSymbol = symbol
+	return m
+}
+
+// BorrowRatio set the share of the max borrowable amount considered safe, default 0.9
+func (m *MarginManager) BorrowRatio(ratio string) *MarginManager {
+	m.borrowRatio = decimalOrZero(ratio)
+	return m
+}
+
+// AutoRepay repay the outstanding loans every time an order is filled
+func (m *MarginManager) AutoRepay(autoRepay bool) *MarginManager {
+	m.autoRepay = autoRepay
+	return m
+}
+
+// AddLevelThreshold call callback once when the margin level falls to or below
+// level, and again only after it recovered above it
+func (m *MarginManager) AddLevelThreshold(level string, callback func(state *MarginLevelState)) *MarginManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.thresholds = append(m.thresholds, &marginLevelThreshold{level: decimalOrZero(level), callback: callback})
+	sort.SliceStable(m.thresholds, func(i, j int) bool {
+		return m.thresholds[i].level.GreaterThan(m.thresholds[j].level)
+	})
+	return m
+}
+
+// State return the last known margin level, or nil before the first refresh
+func (m *MarginManager) State() *MarginLevelState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state == nil {
+		return nil
+	}
+	cp := *m.state
+	return &cp
+}
+
+// Refresh load the margin level and evaluate the thresholds
+func (m *MarginManager) Refresh(ctx context.Context, opts ...RequestOption) (*MarginLevelState, error) {
+	state := &MarginLevelState{IsolatedSymbol: m.isolatedSymbol}
+	if m.isolatedSymbol == "" {
+		account, err := m.c.NewGetMarginAccountService().Do(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		state.MarginLevel = account.MarginLevel
+		state.TotalAssetOfBTC = account.TotalAssetOfBTC
+		state.TotalLiabilityOfBTC = account.TotalLiabilityOfBTC
+	} else {
+		account, asset, err := m.isolatedAccount(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		state.MarginLevel = asset.MarginLevel
+		state.MarginLevelStatus = asset.MarginLevelStatus
+		state.TotalAssetOfBTC = account.TotalAssetOfBTC
+		state.TotalLiabilityOfBTC = account.TotalLiabilityOfBTC
+	}
+
+	m.setState(state)
+	return state, nil
+}
+
+// setState replace the margin level and evaluate the thresholds
+func (m *MarginManager) setState(state *MarginLevelState) {
+	m.mu.Lock()
+	m.state = state
+	level := decimalOrZero(state.MarginLevel)
+	var breached []*marginLevelThreshold
+	for _, t := range m.thresholds {
+		if level.GreaterThan(t.level) {
+			t.breached = false
+			continue
+		}
+		if !t.breached {
+			t.breached = true
+			breached = append(breached, t)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, t := range breached {
+		cp := *state
+		t.callback(&cp)
+	}
+}
+
+// SafeBorrowable return the amount of asset that can be borrowed, i.e. the
+// lowest of the max borrowable amount and the borrow limit, times the borrow ratio
+func (m *MarginManager) SafeBorrowable(ctx context.Context, asset string, opts ...RequestOption) (string, error) {
+	max, err := m.c.NewGetMaxBorrowableService().Asset(asset).IsolatedSymbol(m.isolatedSymbol).Do(ctx, opts...)
+	if err != nil {
+		return "", err
+	}
+	amount := decimalOrZero(max.Amount)
+	if max.BorrowLimit != "" {
+		amount = decimal.Min(amount, decimalOrZero(max.BorrowLimit))
+	}
+	return amount.Mul(m.borrowRatio).RoundDown(8).String(), nil
+}
+
+// Borrow borrow amount of asset, the borrow is refused with
+// ErrMarginBorrowExceeded when amount is above the safe borrowable amount
+func (m *MarginManager) Borrow(ctx context.Context, asset, amount string, opts ...RequestOption) (*TransactionResponse, error) {
+	safe, err := m.SafeBorrowable(ctx, asset, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if decimalOrZero(amount).GreaterThan(decimalOrZero(safe)) {
+		return nil, ErrMarginBorrowExceeded
+	}
+	return m.borrowRepay(MarginAccountBorrow, asset, amount).Do(ctx, opts...)
+}
+
+// Loans return the assets with an outstanding loan
+func (m *MarginManager) Loans(ctx context.Context, opts ...RequestOption) ([]*OutstandingMarginLoan, error) {
+	var assets []UserAsset
+	if m.isolatedSymbol == "" {
+		account, err := m.c.NewGetMarginAccountService().Do(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		assets = account.UserAssets
+	} else {
+		_, asset, err := m.isolatedAccount(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range []IsolatedUserAsset{asset.BaseAsset, asset.QuoteAsset} {
+			assets = append(assets, UserAsset{Asset: a.Asset, Borrowed: a.Borrowed, Free: a.Free, Interest: a.Interest})
+		}
+	}
+	loans := make([]*OutstandingMarginLoan, 0)
+	for _, a := range assets {
+		if decimalOrZero(a.Borrowed).IsPositive() || decimalOrZero(a.Interest).IsPositive() {
+			loans = append(loans, &OutstandingMarginLoan{Asset: a.Asset, Borrowed: a.Borrowed, Interest: a.Interest, Free: a.Free})
+		}
+	}
+	return loans, nil
+}
+
+// RepayLoans repay the outstanding loans, principal and interest, with the
+// free balance of each borrowed asset
+func (m *MarginManager) RepayLoans(ctx context.Context, opts ...RequestOption) ([]*TransactionResponse, error) {
+	loans, err := m.Loans(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*TransactionResponse, 0)
+	for _, loan := range loans {
+		amount := decimal.Min(decimalOrZero(loan.Free), decimalOrZero(loan.Borrowed).Add(decimalOrZero(loan.Interest)))
+		if !amount.IsPositive() {
+			continue
+		}
+		tx, err := m.borrowRepay(MarginAccountRepay, loan.Asset, amount.String()).Do(ctx, opts...)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, tx)
+	}
+	return res, nil
+}
+
+// HandleUserDataEvent consume a margin user data stream event: the cross
+// margin level is updated by MARGIN_LEVEL_STATUS_CHANGE events, the isolated
+// margin level, which has no event, is refreshed on balance changes, and
+// fills repay the outstanding loans when AutoRepay is enabled. It calls the
+// REST API synchronously.
+func (m *MarginManager) HandleUserDataEvent(event *WsUserDataEvent) {
+	ctx := context.Background()
+	switch event.Event {
+	case UserDataEventTypeMarginLevelStatusChange:
+		if m.isolatedSymbol != "" {
+			return
+		}
+		state := &MarginLevelState{}
+		if current := m.State(); current != nil {
+			state = current
+		}
+		state.MarginLevel = event.MarginLevelStatusChange.MarginLevel
+		state.MarginLevelStatus = event.MarginLevelStatusChange.Status
+		m.setState(state)
+	case UserDataEventTypeExecutionReport:
+		if event.OrderUpdate.ExecutionType != "TRADE" {
+			return
+		}
+		if m.isolatedSymbol != "" && event.OrderUpdate.Symbol != m.isolatedSymbol {
+			return
+		}
+		if m.autoRepay {
+			if _, err := m.RepayLoans(ctx); err != nil {
+				m.errHandler(err)
+			}
+		}
+	case UserDataEventTypeOutboundAccountPosition, UserDataEventTypeBalanceUpdate:
+		if m.isolatedSymbol == "" {
+			return
+		}
+		if _, err := m.Refresh(ctx); err != nil {
+			m.errHandler(err)
+		}
+	}
+}
+
+// Ledger join the borrow/repay history and the interest history between
+// startTime and endTime, in milliseconds, into a ledger sorted by time. The
+// range is walked by windows of 30 days, the longest range of the histories.
+func (m *MarginManager) Ledger(ctx context.Context, startTime, endTime int64, opts ...RequestOption) (*MarginLedger, error) {
+	ledger := &MarginLedger{}
+	for start := startTime; start <= endTime; start += marginHistoryWindow {
+		end := start + marginHistoryWindow - 1
+		if end > endTime {
+			end = endTime
+		}
+		entries, err := m.ledgerEntries(ctx, start, end, opts...)
+		if err != nil {
+			return nil, err
+		}
+		ledger.Entries = append(ledger.Entries, entries...)
+	}
+	sort.SliceStable(ledger.Entries, func(i, j int) bool { return ledger.Entries[i].Time < ledger.Entries[j].Time })
+	ledger.Assets = buildMarginLedgerAssets(ledger.Entries)
+	return ledger, nil
+}
+
+// ledgerEntries return the ledger entries of a window of at most 30 days
+func (m *MarginManager) ledgerEntries(ctx context.Context, startTime, endTime int64, opts ...RequestOption) ([]*MarginLedgerEntry, error) {
+	var entries []*MarginLedgerEntry
+	for _, typ := range []MarginAccountBorrowRepayType{MarginAccountBorrow, MarginAccountRepay} {
+		for page := int64(1); ; page++ {
+			s := m.c.NewListMarginBorrowRepayService().Type(typ).
+				StartTime(startTime).EndTime(endTime).
+				Current(page).Size(marginHistoryPageSize)
+			if m.isolatedSymbol != "" {
+				s.IsolatedSymbol(m.isolatedSymbol)
+			}
+			res, err := s.Do(ctx, opts...)
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range res.Rows {
+				if row.Status == "FAILED" {
+					continue
+				}
+				entry := &MarginLedgerEntry{
+					Type:      MarginLedgerEntryTypeBorrow,
+					Asset:     row.Asset,
+					TxID:      row.TxID,
+					Time:      row.Timestamp,
+					Principal: row.Amount,
+				}
+				if typ == MarginAccountRepay {
+					entry.Type = MarginLedgerEntryTypeRepay
+					entry.Principal = row.Principal
+					entry.Interest = row.Interest
+				}
+				entries = append(entries, entry)
+			}
+			if len(res.Rows) < marginHistoryPageSize || page*marginHistoryPageSize >= res.Total {
+				break
+			}
+		}
+	}
+	for page := int64(1); ; page++ {
+		s := m.c.NewMarginInterestHistoryService().
+			StartTime(startTime).EndTime(endTime).
+			Current(page).Size(marginHistoryPageSize)
+		if m.isolatedSymbol != "" {
+			s.IsolatedSymbol(m.isolatedSymbol)
+		}
+		res, err := s.Do(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range res.Rows {
+			entries = append(entries, &MarginLedgerEntry{
+				Type:     MarginLedgerEntryTypeInterest,
+				Asset:    row.Asset,
+				TxID:     row.TxId,
+				Time:     row.InterestAccuredTime,
+				Interest: row.Interest,
+			})
+		}
+		if len(res.Rows) < marginHistoryPageSize || page*marginHistoryPageSize >= res.Total {
+			break
+		}
+	}
+	return entries, nil
+}
+
+func buildMarginLedgerAssets(entries []*MarginLedgerEntry) []*MarginLedgerAsset {
+	type totals struct {
+		borrowed, repaidPrincipal, accrued, repaidInterest decimal.Decimal
+	}
+	byAsset := map[string]*totals{}
+	for _, e := range entries {
+		t, ok := byAsset[e.Asset]
+		if !ok {
+			t = &totals{}
+			byAsset[e.Asset] = t
+		}
+		switch e.Type {
+		case MarginLedgerEntryTypeBorrow:
+			t.borrowed = t.borrowed.Add(decimalOrZero(e.Principal))
+		case MarginLedgerEntryTypeRepay:
+			t.repaidPrincipal = t.repaidPrincipal.Add(decimalOrZero(e.Principal))
+			t.repaidInterest = t.repaidInterest.Add(decimalOrZero(e.Interest))
+		case MarginLedgerEntryTypeInterest:
+			t.accrued = t.accrued.Add(decimalOrZero(e.Interest))
+		}
+	}
+	res := make([]*MarginLedgerAsset, 0, len(byAsset))
+	for asset, t := range byAsset {
+		res = append(res, &MarginLedgerAsset{
+			Asset:           asset,
+			Borrowed:        t.borrowed.String(),
+			RepaidPrincipal: t.repaidPrincipal.String(),
+			AccruedInterest: t.accrued.String(),
+			RepaidInterest:  t.repaidInterest.String(),
+			UnpaidInterest:  t.accrued.Sub(t.repaidInterest).String(),
+			OutstandingLoan: t.borrowed.Sub(t.repaidPrincipal).String(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Asset < res[j].Asset })
+	return res
+}
+
+func (m *MarginManager) borrowRepay(typ MarginAccountBorrowRepayType, asset, amount string) *MarginBorrowRepayService {
+	s := m.c.NewMarginBorrowRepayService().Type(typ).Asset(asset).Amount(amount)
+	if m.isolatedSymbol != "" {
+		s.IsIsolated(true).Symbol(m.isolatedSymbol)
+	}
+	return s
+}
+
+func (m *MarginManager) isolatedAccount(ctx context.Context, opts ...RequestOption) (*IsolatedMarginAccount, *IsolatedMarginAsset, error) {
+	account, err := m.c.NewGetIsolatedMarginAccountService().Symbols(m.isolatedSymbol).Do(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range account.Assets {
+		if account.Assets[i].Symbol == m.isolatedSymbol {
+			return account, &account.Assets[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("isolated margin account %s not found", m.isolatedSymbol)
+}
@@ -0,0 +1,182 @@
+package binance
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type marginManagerTestSuite struct {
+	baseTestSuite
+}
+
+func TestMarginManager(t *testing.T) {
+	suite.Run(t, new(marginManagerTestSuite))
+}
+
+func (s *marginManagerTestSuite) TestLevelThresholds() {
+	routes := routedResponses{
+		"/sapi/v1/margin/account": `{"marginLevel": "2.5", "totalAssetOfBtc": "1", "totalLiabilityOfBtc": "0.4"}`,
+	}
+	s.mockRoutes(routes)
+
+	var warnings []string
+	manager := s.client.NewMarginManager(func(err error) { s.T().Error(err) }).
+		AddLevelThreshold("1.5", func(state *MarginLevelState) { warnings = append(warnings, "1.5@"+state.MarginLevel) }).
+		AddLevelThreshold("3", func(state *MarginLevelState) { warnings = append(warnings, "3@"+state.MarginLevel) })
+
+	state, err := manager.Refresh(newContext())
+	r := s.r()
+	r.NoError(err)
+	r.Equal("0.4", state.TotalLiabilityOfBTC)
+	r.Equal([]string{"3@2.5"}, warnings)
+
+	// the level changes come from the stream, thresholds fire once per breach
+	routes["/sapi/v1/margin/account"] = `{"code": -1, "msg": "unexpected refresh"}`
+	manager.HandleUserDataEvent(&WsUserDataEvent{Event: UserDataEventTypeOutboundAccountPosition})
+	manager.HandleUserDataEvent(&WsUserDataEvent{Event: UserDataEventTypeBalanceUpdate})
+	for i := 0; i < 2; i++ {
+		manager.HandleUserDataEvent(&WsUserDataEvent{
+			Event:                   UserDataEventTypeMarginLevelStatusChange,
+			MarginLevelStatusChange: WsMarginLevelStatusChange{MarginLevel: "1.4", Status: "MARGIN_CALL"},
+		})
+	}
+	r.Equal([]string{"3@2.5", "1.5@1.4"}, warnings)
+	state = manager.State()
+	r.Equal("1.4", state.MarginLevel)
+	r.Equal("MARGIN_CALL", state.MarginLevelStatus)
+	r.Equal("0.4", state.TotalLiabilityOfBTC)
+
+	routes["/sapi/v1/margin/account"] = `{"marginLevel": "999"}`
+	_, err = manager.Refresh(newContext())
+	r.NoError(err)
+	routes["/sapi/v1/margin/account"] = `{"marginLevel": "1.2"}`
+	_, err = manager.Refresh(newContext())
+	r.NoError(err)
+	r.Equal([]string{"3@2.5", "1.5@1.4", "3@1.2", "1.5@1.2"}, warnings)
+}
+
+func (s *marginManagerTestSuite) TestBorrowAndRepay() {
+	routes := routedResponses{
+		"/sapi/v1/margin/maxBorrowable":     `{"amount": "10", "borrowLimit": "8"}`,
+		"POST /sapi/v1/margin/borrow-repay": `{"tranId": 42}`,
+		"/sapi/v1/margin/isolated/account": `{
+			"totalAssetOfBtc": "2",
+			"totalLiabilityOfBtc": "1",
+			"assets": [{
+				"symbol": "BTCUSDT",
+				"marginLevel": "2",
+				"marginLevelStatus": "NORMAL",
+				"baseAsset": {"asset": "BTC", "borrowed": "0", "interest": "0", "free": "1"},
+				"quoteAsset": {"asset": "USDT", "borrowed": "100", "interest": "0.5", "free": "60"}
+			}]
+		}`,
+	}
+	s.mockRoutes(routes)
+
+	var errs []error
+	manager := s.client.NewMarginManager(func(err error) { errs = append(errs, err) }).
+		IsolatedSymbol("BTCUSDT").
+		AutoRepay(true)
+
+	r := s.r()
+	safe, err := manager.SafeBorrowable(newContext(), "USDT")
+	r.NoError(err)
+	r.Equal("7.2", safe)
+
+	_, err = manager.Borrow(newContext(), "USDT", "7.3")
+	r.Equal(ErrMarginBorrowExceeded, err)
+	tx, err := manager.Borrow(newContext(), "USDT", "7.2")
+	r.NoError(err)
+	r.Equal(int64(42), tx.TranID)
+
+	loans, err := manager.Loans(newContext())
+	r.NoError(err)
+	r.Len(loans, 1)
+	r.Equal("USDT", loans[0].Asset)
+
+	txs, err := manager.RepayLoans(newContext())
+	r.NoError(err)
+	r.Len(txs, 1)
+
+	// only trades of the isolated symbol trigger a repayment
+	delete(routes, "POST /sapi/v1/margin/borrow-repay")
+	manager.HandleUserDataEvent(&WsUserDataEvent{
+		Event:       UserDataEventTypeExecutionReport,
+		OrderUpdate: WsOrderUpdate{Symbol: "ETHUSDT", ExecutionType: "TRADE"},
+	})
+	r.Empty(errs)
+	manager.HandleUserDataEvent(&WsUserDataEvent{
+		Event:       UserDataEventTypeExecutionReport,
+		OrderUpdate: WsOrderUpdate{Symbol: "BTCUSDT", ExecutionType: "TRADE"},
+	})
+	r.Len(errs, 1)
+
+	// the isolated margin level is refreshed on balance changes only
+	manager.HandleUserDataEvent(&WsUserDataEvent{
+		Event:                   UserDataEventTypeMarginLevelStatusChange,
+		MarginLevelStatusChange: WsMarginLevelStatusChange{MarginLevel: "1.1", Status: "MARGIN_CALL"},
+	})
+	r.Nil(manager.State())
+	manager.HandleUserDataEvent(&WsUserDataEvent{Event: UserDataEventTypeOutboundAccountPosition})
+	r.Equal("NORMAL", manager.State().MarginLevelStatus)
+}
+
+func (s *marginManagerTestSuite) TestLedger() {
+	s.mockRoutes(routedResponses{
+		"/sapi/v1/margin/borrow-repay?type=BORROW": `{"total": 2, "rows": [
+			{"asset": "USDT", "amount": "100", "status": "CONFIRMED", "timestamp": 1000, "txId": 1},
+			{"asset": "USDT", "amount": "50", "status": "FAILED", "timestamp": 1500, "txId": 2}
+		]}`,
+		"/sapi/v1/margin/borrow-repay?type=REPAY": `{"total": 1, "rows": [
+			{"asset": "USDT", "amount": "60.2", "principal": "60", "interest": "0.2", "status": "CONFIRMED", "timestamp": 3000, "txId": 3}
+		]}`,
+		"/sapi/v1/margin/interestHistory": `{"total": 2, "rows": [
+			{"asset": "USDT", "interest": "0.2", "interestAccuredTime": 2000, "txId": 4},
+			{"asset": "USDT", "interest": "0.1", "interestAccuredTime": 4000, "txId": 5}
+		]}`,
+	})
+
+	ledger, err := s.client.NewMarginManager(nil).Ledger(newContext(), 0, 5000)
+	r := s.r()
+	r.NoError(err)
+	r.Len(ledger.Entries, 4)
+	r.Equal(MarginLedgerEntryTypeBorrow, ledger.Entries[0].Type)
+	r.Equal(MarginLedgerEntryTypeInterest, ledger.Entries[1].Type)
+	r.Equal(MarginLedgerEntryTypeRepay, ledger.Entries[2].Type)
+	r.Equal(int64(5), ledger.Entries[3].TxID)
+
+	r.Len(ledger.Assets, 1)
+	usdt := ledger.Assets[0]
+	r.Equal("100", usdt.Borrowed)
+	r.Equal("60", usdt.RepaidPrincipal)
+	r.Equal("40", usdt.OutstandingLoan)
+	r.Equal("0.3", usdt.AccruedInterest)
+	r.Equal("0.2", usdt.RepaidInterest)
+	r.Equal("0.1", usdt.UnpaidInterest)
+}
+
+func (s *marginManagerTestSuite) TestLedgerWindows() {
+	routes := routedResponses{
+		"/sapi/v1/margin/borrow-repay":    `{"total": 0, "rows": []}`,
+		"/sapi/v1/margin/interestHistory": `{"total": 0, "rows": []}`,
+	}
+	var windows [][2]string
+	s.client.Client.do = func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/sapi/v1/margin/interestHistory" {
+			windows = append(windows, [2]string{req.URL.Query().Get("startTime"), req.URL.Query().Get("endTime")})
+		}
+		return routes.do(req)
+	}
+	day := int64(24 * 3600 * 1000)
+	_, err := s.client.NewMarginManager(nil).Ledger(newContext(), 0, 70*day)
+	r := s.r()
+	r.NoError(err)
+	r.Equal([][2]string{
+		{"0", strconv.FormatInt(30*day-1, 10)},
+		{strconv.FormatInt(30*day, 10), strconv.FormatInt(60*day-1, 10)},
+		{strconv.FormatInt(60*day, 10), strconv.FormatInt(70*day, 10)},
+	}, windows)
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-
-	"github.com/adshao/go-binance/v2/common"
 )
 
 // CreateMarginOrderService create order
@@ -152,7 +150,7 @@ func (s *CreateMarginOrderService) Do(ctx context.Context, opts ...RequestOption
 	if s.newClientOrderID != nil {
 		m["newClientOrderId"] = *s.newClientOrderID
 	} else {
-		m["newClientOrderId"] = common.GenerateSpotId()
+		m["newClientOrderId"] = s.c.newClientOrderID()
 	}
 	if s.stopPrice != nil {
 		m["stopPrice"] = *s.stopPrice
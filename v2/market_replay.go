@@ -0,0 +1,411 @@
+package binance
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// MarketReplay drive the websocket handlers with recorded or downloaded market
+// data, in timestamp order across the streams, so that the strategies consume
+// a backtest like the live streams.
+//
+// Klines and aggTrades are read from the CSV files of data.binance.vision or of
+// CSVTradeSink. All the streams are read from JSON Lines of websocket events,
+// either raw or wrapped as {"time":..., "stream":..., "data":{...}} like the
+// combined streams with the receive time in milliseconds. The wrapper time is
+// needed for the spot book tickers which have no event time.
+type MarketReplay struct {
+	speed   float64
+	sources []*replaySource
+	now     int64
+}
+
+// replayEvent define an event of a source and how to hand it to its handler
+type replayEvent struct {
+	time int64
+	emit func()
+}
+
+type replaySource struct {
+	name  string
+	lines *bufio.Reader
+	parse func(line []byte) (*replayEvent, error)
+	event *replayEvent
+	// csv is set for the CSV files, header holds their columns once read
+	csv      bool
+	detected bool
+	header   map[string]int
+	lineNo   int
+}
+
+// aggTradeColumns are the columns of the aggTrades files of data.binance.vision
+// which have no header row
+var aggTradeColumns = map[string]int{
+	"agg_trade_id": 0, "price": 1, "quantity": 2, "first_trade_id": 3,
+	"last_trade_id": 4, "transact_time": 5, "is_buyer_maker": 6,
+}
+
+// NewMarketReplay init a replay running as fast as possible
+func NewMarketReplay() *MarketReplay {
+	return &MarketReplay{}
+}
+
+// Speed set the replay speed as a multiple of the real time, 1 replays in
+// real time and 0, the default, as fast as possible
+func (r *MarketReplay) Speed(multiplier float64) *MarketReplay {
+	r.speed = multiplier
+	return r
+}
+
+// Now return the time of the event being replayed, strategies should use it
+// instead of the wall clock
+func (r *MarketReplay) Now() time.Time {
+	return time.UnixMilli(atomic.LoadInt64(&r.now))
+}
+
+func (r *MarketReplay) addSource(name string, data io.Reader, parse func(s *replaySource, line []byte) (*replayEvent, error)) *MarketReplay {
+	s := &replaySource{name: name, lines: bufio.NewReader(data)}
+	s.parse = func(line []byte) (*replayEvent, error) { return parse(s, line) }
+	r.sources = append(r.sources, s)
+	return r
+}
+
+// AddKlines replay the klines of a symbol and interval as final kline events
+// sent at their close time, or the kline events of a JSON Lines file
+func (r *MarketReplay) AddKlines(symbol, interval string, data io.Reader, handler WsKlineHandler) *MarketReplay {
+	return r.addSource("klines", data, func(s *replaySource, line []byte) (*replayEvent, error) {
+		event := new(WsKlineEvent)
+		if !s.csv {
+			t, err := decodeReplayLine(line, event)
+			if err != nil {
+				return nil, err
+			}
+			return &replayEvent{time: t, emit: func() { handler(event) }}, nil
+		}
+		// open_time,open,high,low,close,volume,close_time,quote_volume,count,taker_buy_volume,taker_buy_quote_volume,ignore
+		fields, err := s.csvFields(line, 11)
+		if err != nil || fields == nil {
+			return nil, err
+		}
+		ints, err := parseInt64s(fields[0], fields[6], fields[8])
+		if err != nil {
+			return nil, err
+		}
+		openTime, closeTime, tradeNum := replayMilli(ints[0]), replayMilli(ints[1]), ints[2]
+		*event = WsKlineEvent{
+			Event:  "kline",
+			Time:   closeTime,
+			Symbol: symbol,
+			Kline: WsKline{
+				StartTime:            openTime,
+				EndTime:              closeTime,
+				Symbol:               symbol,
+				Interval:             interval,
+				FirstTradeID:         -1,
+				LastTradeID:          -1,
+				Open:                 fields[1],
+				Close:                fields[4],
+				High:                 fields[2],
+				Low:                  fields[3],
+				Volume:               fields[5],
+				TradeNum:             tradeNum,
+				IsFinal:              true,
+				QuoteVolume:          fields[7],
+				ActiveBuyVolume:      fields[9],
+				ActiveBuyQuoteVolume: fields[10],
+			},
+		}
+		return &replayEvent{time: closeTime, emit: func() { handler(event) }}, nil
+	})
+}
+
+// AddAggTrades replay the aggTrades of a symbol, the CSV files of
+// data.binance.vision and of CSVTradeSink are read as well as JSON Lines of
+// aggTrade events or of TradeRecord
+func (r *MarketReplay) AddAggTrades(symbol string, data io.Reader, handler WsAggTradeHandler) *MarketReplay {
+	return r.addSource("aggTrades", data, func(s *replaySource, line []byte) (*replayEvent, error) {
+		event := &WsAggTradeEvent{Event: "aggTrade", Symbol: symbol}
+		if !s.csv {
+			payload, t, err := replayPayload(line)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := payload.CheckGet("a"); ok {
+				if err := unmarshalReplayPayload(payload, event); err != nil {
+					return nil, err
+				}
+			} else {
+				trade := new(TradeRecord)
+				if err := unmarshalReplayPayload(payload, trade); err != nil {
+					return nil, err
+				}
+				event = aggTradeEventFromRecord(symbol, trade)
+			}
+			if t == 0 {
+				t = event.TradeTime
+			}
+			return &replayEvent{time: t, emit: func() { handler(event) }}, nil
+		}
+		fields, err := s.csvFields(line, 7)
+		if err != nil || fields == nil {
+			return nil, err
+		}
+		columns := s.header
+		if columns == nil {
+			columns = aggTradeColumns
+		}
+		column := func(names ...string) string {
+			for _, name := range names {
+				if i, ok := columns[name]; ok && i < len(fields) {
+					return fields[i]
+				}
+			}
+			return ""
+		}
+		ints, err := parseInt64s(column("agg_trade_id", "id"), column("first_trade_id", "firstTradeId"),
+			column("last_trade_id", "lastTradeId"), column("transact_time", "time"))
+		if err != nil {
+			return nil, err
+		}
+		event = aggTradeEventFromRecord(symbol, &TradeRecord{
+			ID:           ints[0],
+			Price:        column("price"),
+			Quantity:     column("quantity"),
+			Time:         ints[3],
+			IsBuyerMaker: strings.EqualFold(column("is_buyer_maker", "isBuyerMaker"), "true"),
+			FirstTradeID: ints[1],
+			LastTradeID:  ints[2],
+		})
+		return &replayEvent{time: event.TradeTime, emit: func() { handler(event) }}, nil
+	})
+}
+
+// AddDepth replay the depth diff events of a JSON Lines file
+func (r *MarketReplay) AddDepth(data io.Reader, handler WsDepthHandler) *MarketReplay {
+	return r.addSource("depth", data, func(s *replaySource, line []byte) (*replayEvent, error) {
+		payload, t, err := replayPayload(line)
+		if err != nil {
+			return nil, err
+		}
+		event := newWsDepthEvent(payload)
+		if t == 0 {
+			t = event.Time
+		}
+		return &replayEvent{time: t, emit: func() { handler(event) }}, nil
+	})
+}
+
+// AddBookTickers replay the book ticker events of a JSON Lines file
+func (r *MarketReplay) AddBookTickers(data io.Reader, handler WsBookTickerHandler) *MarketReplay {
+	return r.addSource("bookTickers", data, func(s *replaySource, line []byte) (*replayEvent, error) {
+		event := new(WsBookTickerEvent)
+		t, err := decodeReplayLine(line, event)
+		if err != nil {
+			return nil, err
+		}
+		return &replayEvent{time: t, emit: func() { handler(event) }}, nil
+	})
+}
+
+// AddMarkPrices replay the futures mark price events of a JSON Lines file
+func (r *MarketReplay) AddMarkPrices(data io.Reader, handler futures.WsMarkPriceHandler) *MarketReplay {
+	return r.addSource("markPrices", data, func(s *replaySource, line []byte) (*replayEvent, error) {
+		event := new(futures.WsMarkPriceEvent)
+		t, err := decodeReplayLine(line, event)
+		if err != nil {
+			return nil, err
+		}
+		return &replayEvent{time: t, emit: func() { handler(event) }}, nil
+	})
+}
+
+// Run replay the events until the sources are exhausted or the context is done
+func (r *MarketReplay) Run(ctx context.Context) error {
+	for _, s := range r.sources {
+		if err := s.advance(); err != nil {
+			return err
+		}
+	}
+	var startTime int64
+	var startWall time.Time
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// the earliest event, the sources added first win the ties
+		var next *replaySource
+		for _, s := range r.sources {
+			if s.event != nil && (next == nil || s.event.time < next.event.time) {
+				next = s
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		event := next.event
+		if r.speed > 0 {
+			if startWall.IsZero() {
+				startTime, startWall = event.time, time.Now()
+			}
+			elapsed := time.Duration(float64(time.Duration(event.time-startTime)*time.Millisecond) / r.speed)
+			if wait := elapsed - time.Since(startWall); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		atomic.StoreInt64(&r.now, event.time)
+		event.emit()
+		if err := next.advance(); err != nil {
+			return err
+		}
+	}
+}
+
+// advance read the next event of the source, event is nil at the end
+func (s *replaySource) advance() error {
+	s.event = nil
+	for {
+		line, err := s.lines.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		s.lineNo++
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !s.detected {
+			s.csv, s.detected = line[0] != '{', true
+		}
+		event, perr := s.parse(line)
+		if perr != nil {
+			return fmt.Errorf("%s line %d: %w", s.name, s.lineNo, perr)
+		}
+		if event != nil {
+			s.event = event
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// csvFields split a CSV line, it return nil for the header row which is kept
+// to find the columns by name
+func (s *replaySource) csvFields(line []byte, minFields int) ([]string, error) {
+	fields := strings.Split(string(line), ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	if _, err := strconv.ParseInt(fields[0], 10, 64); err != nil {
+		if s.header != nil {
+			return nil, fmt.Errorf("invalid row %q", line)
+		}
+		s.header = make(map[string]int)
+		for i, name := range fields {
+			s.header[name] = i
+		}
+		return nil, nil
+	}
+	if len(fields) < minFields {
+		return nil, fmt.Errorf("expected %d columns, got %d", minFields, len(fields))
+	}
+	return fields, nil
+}
+
+// replayPayload return the event of a JSON line and its wrapper time, if any
+func replayPayload(line []byte) (*simplejson.Json, int64, error) {
+	j, err := newJSON(line)
+	if err != nil {
+		return nil, 0, err
+	}
+	if data, ok := j.CheckGet("data"); ok {
+		return data, j.Get("time").MustInt64(), nil
+	}
+	return j, 0, nil
+}
+
+func unmarshalReplayPayload(payload *simplejson.Json, v interface{}) error {
+	data, err := payload.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeReplayLine decode the event of a JSON line and return its time, the
+// wrapper time or else the event time
+func decodeReplayLine(line []byte, v interface{}) (int64, error) {
+	payload, t, err := replayPayload(line)
+	if err != nil {
+		return 0, err
+	}
+	if err := unmarshalReplayPayload(payload, v); err != nil {
+		return 0, err
+	}
+	if t == 0 {
+		t = payload.Get("E").MustInt64()
+	}
+	if t == 0 {
+		return 0, fmt.Errorf("no time in %s", line)
+	}
+	return t, nil
+}
+
+func aggTradeEventFromRecord(symbol string, trade *TradeRecord) *WsAggTradeEvent {
+	t := replayMilli(trade.Time)
+	return &WsAggTradeEvent{
+		Event:                 "aggTrade",
+		Time:                  t,
+		Symbol:                symbol,
+		AggTradeID:            trade.ID,
+		Price:                 trade.Price,
+		Quantity:              trade.Quantity,
+		FirstBreakdownTradeID: trade.FirstTradeID,
+		LastBreakdownTradeID:  trade.LastTradeID,
+		TradeTime:             t,
+		IsBuyerMaker:          trade.IsBuyerMaker,
+	}
+}
+
+// replayMilli convert the microsecond timestamps of the recent
+// data.binance.vision files to milliseconds
+func replayMilli(t int64) int64 {
+	if t > 1e14 {
+		return t / 1000
+	}
+	return t
+}
+
+func parseInt64s(values ...string) ([]int64, error) {
+	res := make([]int64, len(values))
+	for i, v := range values {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = n
+	}
+	return res, nil
+}
@@ -0,0 +1,116 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type marketReplayTestSuite struct {
+	baseTestSuite
+}
+
+func TestMarketReplay(t *testing.T) {
+	suite.Run(t, new(marketReplayTestSuite))
+}
+
+func (s *marketReplayTestSuite) TestOrder() {
+	klines := "open_time,open,high,low,close,volume,close_time,quote_volume,count,taker_buy_volume,taker_buy_quote_volume,ignore\n" +
+		"0,10,12,9,11,5,59999,55,3,2,22,0\n" +
+		// microseconds like the recent data.binance.vision files
+		"1735689600000000,11,13,10,12,4,1735689659999999,48,2,1,12,0\n"
+	aggTrades := "1,10,1,1,1,1000,true,true\n" +
+		"2,11,2,2,3,70000,false,true\n"
+	depth := `{"e":"depthUpdate","E":30000,"s":"BTCUSDT","U":1,"u":2,"b":[["10","1"]],"a":[["11","2"]]}` + "\n" +
+		`{"stream":"ethusdt@depth","data":{"e":"depthUpdate","E":65000,"s":"ETHUSDT","U":5,"u":6,"b":[],"a":[["2","1"]]}}` + "\n"
+	bookTickers := `{"time":59999,"stream":"btcusdt@bookTicker","data":{"u":7,"s":"BTCUSDT","b":"10","B":"1","a":"11","A":"1"}}` + "\n"
+	markPrices := `{"e":"markPriceUpdate","E":61000,"s":"BTCUSDT","p":"11.5","i":"11.4","P":"11.5","r":"0.0001","T":28800000}` + "\n"
+
+	replay := NewMarketReplay()
+	var events []string
+	record := func(format string, args ...interface{}) {
+		events = append(events, fmt.Sprintf("%d ", replay.Now().UnixMilli())+fmt.Sprintf(format, args...))
+	}
+	replay.
+		AddKlines("BTCUSDT", "1m", strings.NewReader(klines), func(event *WsKlineEvent) {
+			record("kline %s %d %s %v", event.Symbol, event.Kline.StartTime, event.Kline.Close, event.Kline.IsFinal)
+		}).
+		AddAggTrades("BTCUSDT", strings.NewReader(aggTrades), func(event *WsAggTradeEvent) {
+			record("aggTrade %d %s %d-%d %v", event.AggTradeID, event.Price, event.FirstBreakdownTradeID, event.LastBreakdownTradeID, event.IsBuyerMaker)
+		}).
+		AddDepth(strings.NewReader(depth), func(event *WsDepthEvent) {
+			record("depth %s %d %v %v", event.Symbol, event.LastUpdateID, event.Bids, event.Asks)
+		}).
+		AddBookTickers(strings.NewReader(bookTickers), func(event *WsBookTickerEvent) {
+			record("bookTicker %s %s", event.Symbol, event.BestAskPrice)
+		}).
+		AddMarkPrices(strings.NewReader(markPrices), func(event *futures.WsMarkPriceEvent) {
+			record("markPrice %s %s", event.Symbol, event.MarkPrice)
+		})
+	s.r().NoError(replay.Run(context.Background()))
+	s.r().Equal([]string{
+		"1000 aggTrade 1 10 1-1 true",
+		"30000 depth BTCUSDT 2 [{10 1}] [{11 2}]",
+		"59999 kline BTCUSDT 0 11 true",
+		"59999 bookTicker BTCUSDT 11",
+		"61000 markPrice BTCUSDT 11.5",
+		"65000 depth ETHUSDT 6 [] [{2 1}]",
+		"70000 aggTrade 2 11 2-3 false",
+		"1735689659999 kline BTCUSDT 1735689600000 12 true",
+	}, events)
+}
+
+func (s *marketReplayTestSuite) TestTradeSinkFiles() {
+	csv := "id,price,quantity,quoteQuantity,time,isBuyerMaker,firstTradeId,lastTradeId\n" +
+		"7,10,1,10,1000,true,70,71\n"
+	jsonLines := `{"id":8,"price":"11","quantity":"2","time":2000,"isBuyerMaker":false,"firstTradeId":72,"lastTradeId":72}` + "\n"
+	var trades []*WsAggTradeEvent
+	handler := func(event *WsAggTradeEvent) { trades = append(trades, event) }
+	err := NewMarketReplay().
+		AddAggTrades("BTCUSDT", strings.NewReader(csv), handler).
+		AddAggTrades("BTCUSDT", strings.NewReader(jsonLines), handler).
+		Run(context.Background())
+	r := s.r()
+	r.NoError(err)
+	r.Equal([]*WsAggTradeEvent{
+		{Event: "aggTrade", Time: 1000, Symbol: "BTCUSDT", AggTradeID: 7, Price: "10", Quantity: "1",
+			FirstBreakdownTradeID: 70, LastBreakdownTradeID: 71, TradeTime: 1000, IsBuyerMaker: true},
+		{Event: "aggTrade", Time: 2000, Symbol: "BTCUSDT", AggTradeID: 8, Price: "11", Quantity: "2",
+			FirstBreakdownTradeID: 72, LastBreakdownTradeID: 72, TradeTime: 2000},
+	}, trades)
+}
+
+func (s *marketReplayTestSuite) TestSpeed() {
+	r := s.r()
+	bookTickers := `{"time":1000,"data":{"u":1,"s":"BTCUSDT"}}` + "\n" + `{"time":2000,"data":{"u":2,"s":"BTCUSDT"}}` + "\n"
+
+	// 1s of data at 20x takes 50ms
+	start := time.Now()
+	var count int
+	err := NewMarketReplay().Speed(20).
+		AddBookTickers(strings.NewReader(bookTickers), func(event *WsBookTickerEvent) { count++ }).
+		Run(context.Background())
+	r.NoError(err)
+	r.Equal(2, count)
+	r.True(time.Since(start) >= 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = NewMarketReplay().Speed(1).
+		AddBookTickers(strings.NewReader(bookTickers), func(event *WsBookTickerEvent) {}).
+		Run(ctx)
+	r.Equal(context.DeadlineExceeded, err)
+}
+
+func (s *marketReplayTestSuite) TestInvalidLine() {
+	err := NewMarketReplay().
+		AddBookTickers(strings.NewReader(`{"u":1,"s":"BTCUSDT"}`), func(event *WsBookTickerEvent) {}).
+		Run(context.Background())
+	s.r().EqualError(err, `bookTickers line 1: no time in {"u":1,"s":"BTCUSDT"}`)
+}
@@ -15,6 +15,11 @@ type OrderListPlaceOtoWsService struct {
 	SecretKey  string
 	KeyType    string
 	TimeOffset int64
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed
+	// without one, the generator of the client when the service is made by
+	// a Client, a generator of its own otherwise
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
 }
 
 // NewOrderListPlaceOtoWsService init OrderListPlaceOtoWsService
@@ -30,10 +35,11 @@ func NewOrderListPlaceOtoWsService(apiKey, secretKey string) (*OrderListPlaceOto
 	}
 
 	return &OrderListPlaceOtoWsService{
-		c:         client,
-		ApiKey:    apiKey,
-		SecretKey: secretKey,
-		KeyType:   common.KeyTypeHmac,
+		c:                      client,
+		ApiKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX),
 	}, nil
 }
 
@@ -74,11 +80,11 @@ func NewOrderListPlaceOtoWsRequest() *OrderListPlaceOtoWsRequest {
 }
 
 func (s *OrderListPlaceOtoWsRequest) GetParams() map[string]interface{} {
-	return s.buildParams()
+	return s.buildParams(nil)
 }
 
 // buildParams builds params
-func (s *OrderListPlaceOtoWsRequest) buildParams() params {
+func (s *OrderListPlaceOtoWsRequest) buildParams(ids *common.ClientOrderIDGenerator) params {
 	m := params{
 		"symbol":           s.symbol,
 		"newOrderRespType": s.newOrderRespType,
@@ -93,7 +99,7 @@ func (s *OrderListPlaceOtoWsRequest) buildParams() params {
 	if s.listClientOrderID != nil {
 		m["listClientOrderId"] = *s.listClientOrderID
 	} else {
-		m["listClientOrderId"] = common.GenerateSpotId()
+		m["listClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.selfTradePreventionMode != nil {
 		m["selfTradePreventionMode"] = *s.selfTradePreventionMode
@@ -101,7 +107,7 @@ func (s *OrderListPlaceOtoWsRequest) buildParams() params {
 	if s.workingClientOrderID != nil {
 		m["workingClientOrderId"] = *s.workingClientOrderID
 	} else {
-		m["workingClientOrderId"] = common.GenerateSpotId()
+		m["workingClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.workingIcebergQty != nil {
 		m["workingIcebergQty"] = *s.workingIcebergQty
@@ -118,7 +124,7 @@ func (s *OrderListPlaceOtoWsRequest) buildParams() params {
 	if s.pendingClientOrderID != nil {
 		m["pendingClientOrderId"] = *s.pendingClientOrderID
 	} else {
-		m["pendingClientOrderId"] = common.GenerateSpotId()
+		m["pendingClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.pendingPrice != nil {
 		m["pendingPrice"] = *s.pendingPrice
@@ -158,7 +164,7 @@ func (s *OrderListPlaceOtoWsService) Do(requestID string, request *OrderListPlac
 			s.KeyType,
 		),
 		websocket.OrderListPlaceOtoSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return err
@@ -182,7 +188,7 @@ func (s *OrderListPlaceOtoWsService) SyncDo(requestID string, request *OrderList
 			s.KeyType,
 		),
 		websocket.OrderListPlaceOtoSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return nil, err
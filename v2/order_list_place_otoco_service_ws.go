@@ -15,6 +15,11 @@ type OrderListPlaceOtocoWsService struct {
 	SecretKey  string
 	KeyType    string
 	TimeOffset int64
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed
+	// without one, the generator of the client when the service is made by
+	// a Client, a generator of its own otherwise
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
 }
 
 // NewOrderListPlaceOtocoWsService init OrderListPlaceOtocoWsService
@@ -30,10 +35,11 @@ func NewOrderListPlaceOtocoWsService(apiKey, secretKey string) (*OrderListPlaceO
 	}
 
 	return &OrderListPlaceOtocoWsService{
-		c:         client,
-		ApiKey:    apiKey,
-		SecretKey: secretKey,
-		KeyType:   common.KeyTypeHmac,
+		c:                      client,
+		ApiKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX),
 	}, nil
 }
 
@@ -83,11 +89,11 @@ func NewOrderListPlaceOtocoWsRequest() *OrderListPlaceOtocoWsRequest {
 }
 
 func (s *OrderListPlaceOtocoWsRequest) GetParams() map[string]interface{} {
-	return s.buildParams()
+	return s.buildParams(nil)
 }
 
 // buildParams builds params
-func (s *OrderListPlaceOtocoWsRequest) buildParams() params {
+func (s *OrderListPlaceOtocoWsRequest) buildParams(ids *common.ClientOrderIDGenerator) params {
 	m := params{
 		"symbol":           s.symbol,
 		"newOrderRespType": s.newOrderRespType,
@@ -102,7 +108,7 @@ func (s *OrderListPlaceOtocoWsRequest) buildParams() params {
 	if s.listClientOrderID != nil {
 		m["listClientOrderId"] = *s.listClientOrderID
 	} else {
-		m["listClientOrderId"] = common.GenerateSpotId()
+		m["listClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.selfTradePreventionMode != nil {
 		m["selfTradePreventionMode"] = *s.selfTradePreventionMode
@@ -110,7 +116,7 @@ func (s *OrderListPlaceOtocoWsRequest) buildParams() params {
 	if s.workingClientOrderID != nil {
 		m["workingClientOrderId"] = *s.workingClientOrderID
 	} else {
-		m["workingClientOrderId"] = common.GenerateSpotId()
+		m["workingClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.workingIcebergQty != nil {
 		m["workingIcebergQty"] = *s.workingIcebergQty
@@ -127,7 +133,7 @@ func (s *OrderListPlaceOtocoWsRequest) buildParams() params {
 	if s.pendingAboveClientOrderID != nil {
 		m["pendingAboveClientOrderId"] = *s.pendingAboveClientOrderID
 	} else {
-		m["pendingAboveClientOrderId"] = common.GenerateSpotId()
+		m["pendingAboveClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.pendingAbovePrice != nil {
 		m["pendingAbovePrice"] = *s.pendingAbovePrice
@@ -156,7 +162,7 @@ func (s *OrderListPlaceOtocoWsRequest) buildParams() params {
 	if s.pendingBelowClientOrderID != nil {
 		m["pendingBelowClientOrderId"] = *s.pendingBelowClientOrderID
 	} else if s.pendingBelowType != nil {
-		m["pendingBelowClientOrderId"] = common.GenerateSpotId()
+		m["pendingBelowClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.pendingBelowPrice != nil {
 		m["pendingBelowPrice"] = *s.pendingBelowPrice
@@ -196,7 +202,7 @@ func (s *OrderListPlaceOtocoWsService) Do(requestID string, request *OrderListPl
 			s.KeyType,
 		),
 		websocket.OrderListPlaceOtocoSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return err
@@ -220,7 +226,7 @@ func (s *OrderListPlaceOtocoWsService) SyncDo(requestID string, request *OrderLi
 			s.KeyType,
 		),
 		websocket.OrderListPlaceOtocoSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return nil, err
@@ -15,6 +15,11 @@ type OrderListPlaceWsService struct {
 	SecretKey  string
 	KeyType    string
 	TimeOffset int64
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed
+	// without one, the generator of the client when the service is made by
+	// a Client, a generator of its own otherwise
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
 }
 
 // NewOrderListPlaceWsService init OrderListPlaceWsService
@@ -30,10 +35,11 @@ func NewOrderListPlaceWsService(apiKey, secretKey string) (*OrderListPlaceWsServ
 	}
 
 	return &OrderListPlaceWsService{
-		c:         client,
-		ApiKey:    apiKey,
-		SecretKey: secretKey,
-		KeyType:   common.KeyTypeHmac,
+		c:                      client,
+		ApiKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX),
 	}, nil
 }
 
@@ -69,11 +75,11 @@ func NewOrderListPlaceWsRequest() *OrderListPlaceWsRequest {
 }
 
 func (s *OrderListPlaceWsRequest) GetParams() map[string]interface{} {
-	return s.buildParams()
+	return s.buildParams(nil)
 }
 
 // buildParams builds params
-func (s *OrderListPlaceWsRequest) buildParams() params {
+func (s *OrderListPlaceWsRequest) buildParams(ids *common.ClientOrderIDGenerator) params {
 	m := params{
 		"symbol":           s.symbol,
 		"side":             s.side,
@@ -84,12 +90,12 @@ func (s *OrderListPlaceWsRequest) buildParams() params {
 	if s.listClientOrderID != nil {
 		m["listClientOrderId"] = *s.listClientOrderID
 	} else {
-		m["listClientOrderId"] = common.GenerateSpotId()
+		m["listClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.limitClientOrderID != nil {
 		m["limitClientOrderId"] = *s.limitClientOrderID
 	} else {
-		m["limitClientOrderId"] = common.GenerateSpotId()
+		m["limitClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.limitIcebergQty != nil {
 		m["limitIcebergQty"] = *s.limitIcebergQty
@@ -109,7 +115,7 @@ func (s *OrderListPlaceWsRequest) buildParams() params {
 	if s.stopClientOrderID != nil {
 		m["stopClientOrderId"] = *s.stopClientOrderID
 	} else {
-		m["stopClientOrderId"] = common.GenerateSpotId()
+		m["stopClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.stopLimitPrice != nil {
 		m["stopLimitPrice"] = *s.stopLimitPrice
@@ -146,7 +152,7 @@ func (s *OrderListPlaceWsService) Do(requestID string, request *OrderListPlaceWs
 			s.KeyType,
 		),
 		websocket.OrderListPlaceSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return err
@@ -170,7 +176,7 @@ func (s *OrderListPlaceWsService) SyncDo(requestID string, request *OrderListPla
 			s.KeyType,
 		),
 		websocket.OrderListPlaceSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return nil, err
@@ -15,6 +15,11 @@ type OrderListCreateWsService struct {
 	SecretKey  string
 	KeyType    string
 	TimeOffset int64
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed
+	// without one, the generator of the client when the service is made by
+	// a Client, a generator of its own otherwise
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
 }
 
 // NewOrderListCreateWsService init OrderListCreateWsService
@@ -30,10 +35,11 @@ func NewOrderListCreateWsService(apiKey, secretKey string) (*OrderListCreateWsSe
 	}
 
 	return &OrderListCreateWsService{
-		c:         client,
-		ApiKey:    apiKey,
-		SecretKey: secretKey,
-		KeyType:   common.KeyTypeHmac,
+		c:                      client,
+		ApiKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX),
 	}, nil
 }
 
@@ -72,11 +78,11 @@ func NewOrderListCreateWsRequest() *OrderListCreateWsRequest {
 }
 
 func (s *OrderListCreateWsRequest) GetParams() map[string]interface{} {
-	return s.buildParams()
+	return s.buildParams(nil)
 }
 
 // buildParams builds params
-func (s *OrderListCreateWsRequest) buildParams() params {
+func (s *OrderListCreateWsRequest) buildParams(ids *common.ClientOrderIDGenerator) params {
 	m := params{
 		"symbol":           s.symbol,
 		"side":             s.side,
@@ -88,12 +94,12 @@ func (s *OrderListCreateWsRequest) buildParams() params {
 	if s.listClientOrderID != nil {
 		m["listClientOrderId"] = *s.listClientOrderID
 	} else {
-		m["listClientOrderId"] = common.GenerateSpotId()
+		m["listClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.aboveClientOrderID != nil {
 		m["aboveClientOrderId"] = *s.aboveClientOrderID
 	} else {
-		m["aboveClientOrderId"] = common.GenerateSpotId()
+		m["aboveClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.aboveIcebergQty != nil {
 		m["aboveIcebergQty"] = *s.aboveIcebergQty
@@ -119,7 +125,7 @@ func (s *OrderListCreateWsRequest) buildParams() params {
 	if s.belowClientOrderID != nil {
 		m["belowClientOrderId"] = *s.belowClientOrderID
 	} else {
-		m["belowClientOrderId"] = common.GenerateSpotId()
+		m["belowClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.belowIcebergQty != nil {
 		m["belowIcebergQty"] = *s.belowIcebergQty
@@ -162,7 +168,7 @@ func (s *OrderListCreateWsService) Do(requestID string, request *OrderListCreate
 			s.KeyType,
 		),
 		websocket.OrderListPlaceOcoSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return err
@@ -186,7 +192,7 @@ func (s *OrderListCreateWsService) SyncDo(requestID string, request *OrderListCr
 			s.KeyType,
 		),
 		websocket.OrderListPlaceOcoSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return nil, err
@@ -4,8 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-
-	"github.com/adshao/go-binance/v2/common"
 )
 
 // CreateOrderService create order
@@ -130,7 +128,7 @@ func (s *CreateOrderService) createOrder(ctx context.Context, endpoint string, o
 	if s.newClientOrderID != nil {
 		m["newClientOrderId"] = *s.newClientOrderID
 	} else {
-		m["newClientOrderId"] = common.GenerateSpotId()
+		m["newClientOrderId"] = s.c.newClientOrderID()
 	}
 	if s.stopPrice != nil {
 		m["stopPrice"] = *s.stopPrice
@@ -1001,7 +999,7 @@ func (s *CancelReplaceOrderService) Do(ctx context.Context, opts ...RequestOptio
 	if s.newClientOrderID != nil {
 		m["newClientOrderId"] = *s.newClientOrderID
 	} else {
-		m["newClientOrderId"] = common.GenerateSpotId()
+		m["newClientOrderId"] = s.c.newClientOrderID()
 	}
 	if s.stopPrice != nil {
 		m["stopPrice"] = *s.stopPrice
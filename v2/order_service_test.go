@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/adshao/go-binance/v2/common"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -124,6 +125,30 @@ func (s *orderServiceTestSuite) TestCreateOrderId() {
 
 }
 
+func (s *orderServiceTestSuite) TestCreateOrderGeneratedId() {
+	data := []byte(`{
+		"symbol": "LTCBTC",
+		"orderId": 1,
+		"clientOrderId": "x-B3AUXNYVgrid_s1_1",
+		"transactTime": 1499827319559
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+	s.client.ClientOrderIDGenerator = common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX).Session("s1").Tag("grid")
+
+	s.assertReq(func(r *request) {
+		s.r().Equal("x-B3AUXNYVgrid_s1_1", r.form.Get("newClientOrderId"))
+	})
+	res, err := s.client.NewCreateOrderService().Symbol("LTCBTC").Side(SideTypeBuy).
+		Type(OrderTypeMarket).Quantity("1").Do(newContext())
+	r := s.r()
+	r.NoError(err)
+	meta, ok := s.client.ClientOrderIDGenerator.Decode(res.ClientOrderID)
+	r.True(ok)
+	r.Equal("grid", meta.Tag)
+	r.Equal(uint64(1), meta.Sequence)
+}
+
 func (s *orderServiceTestSuite) TestCreateOrderFull() {
 	data := []byte(`{
 		"symbol": "LTCBTC",
@@ -15,6 +15,11 @@ type OrderCreateWsService struct {
 	SecretKey  string
 	KeyType    string
 	TimeOffset int64
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed
+	// without one, the generator of the client when the service is made by
+	// a Client, a generator of its own otherwise
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
 }
 
 // NewOrderCreateWsService init OrderCreateWsService
@@ -30,10 +35,11 @@ func NewOrderCreateWsService(apiKey, secretKey string) (*OrderCreateWsService, e
 	}
 
 	return &OrderCreateWsService{
-		c:         client,
-		ApiKey:    apiKey,
-		SecretKey: secretKey,
-		KeyType:   common.KeyTypeHmac,
+		c:                      client,
+		ApiKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX),
 	}, nil
 }
 
@@ -62,11 +68,11 @@ func NewOrderCreateWsRequest() *OrderCreateWsRequest {
 }
 
 func (s *OrderCreateWsRequest) GetParams() map[string]interface{} {
-	return s.buildParams()
+	return s.buildParams(nil)
 }
 
 // buildParams builds params
-func (s *OrderCreateWsRequest) buildParams() params {
+func (s *OrderCreateWsRequest) buildParams(ids *common.ClientOrderIDGenerator) params {
 	m := params{
 		"symbol":           s.symbol,
 		"side":             s.side,
@@ -85,7 +91,7 @@ func (s *OrderCreateWsRequest) buildParams() params {
 	if s.newClientOrderID != nil {
 		m["newClientOrderId"] = *s.newClientOrderID
 	} else {
-		m["newClientOrderId"] = common.GenerateSpotId()
+		m["newClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.stopPrice != nil {
 		m["stopPrice"] = *s.stopPrice
@@ -122,7 +128,7 @@ func (s *OrderCreateWsService) Do(requestID string, request *OrderCreateWsReques
 			s.KeyType,
 		),
 		websocket.OrderPlaceSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return err
@@ -146,7 +152,7 @@ func (s *OrderCreateWsService) SyncDo(requestID string, request *OrderCreateWsRe
 			s.KeyType,
 		),
 		websocket.OrderPlaceSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return nil, err
@@ -3,8 +3,10 @@ package binance
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/adshao/go-binance/v2/common"
 	"github.com/adshao/go-binance/v2/common/websocket"
 	"github.com/adshao/go-binance/v2/common/websocket/mock"
 	"github.com/golang/mock/gomock"
@@ -87,6 +89,24 @@ func (s *orderPlaceServiceWsTestSuite) TestOrderPlace() {
 	s.NoError(err)
 }
 
+func (s *orderPlaceServiceWsTestSuite) TestOrderPlace_GeneratedClientOrderID() {
+	s.reset(s.apiKey, s.secretKey, s.signedKey, s.timeOffset)
+	s.orderPlace.ClientOrderIDGenerator = common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX).Session("s1").Tag("ws")
+
+	var data []byte
+	s.client.EXPECT().Write(s.requestID, gomock.Any()).DoAndReturn(func(id string, raw []byte) error {
+		data = raw
+		return nil
+	}).Times(1)
+
+	request := NewOrderCreateWsRequest().Symbol(s.symbol).Side(s.side).Type(OrderTypeMarket).Quantity(s.quantity)
+	err := s.orderPlace.Do(s.requestID, request)
+	s.NoError(err)
+	s.True(strings.Contains(string(data), `"newClientOrderId":"x-B3AUXNYVws_s1_1"`), string(data))
+	// the params of a request don't use the generator of a service
+	s.True(strings.HasPrefix(request.GetParams()["newClientOrderId"].(string), common.SPOT_ORDER_PREFIX))
+}
+
 func (s *orderPlaceServiceWsTestSuite) TestOrderPlace_EmptyRequestID() {
 	s.reset(s.apiKey, s.secretKey, s.signedKey, s.timeOffset)
 
@@ -0,0 +1,617 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// errCodeUnknownOrder is the error code of the orders not found, spot and futures
+const errCodeUnknownOrder = -2013
+
+// OrderMarket define the market of a tracked order
+type OrderMarket string
+
+// Order markets
+const (
+	OrderMarketSpot    OrderMarket = "SPOT"
+	OrderMarketFutures OrderMarket = "FUTURES"
+)
+
+// OrderFill define a fill of a tracked order
+type OrderFill struct {
+	TradeID         int64
+	Price           string
+	Quantity        string
+	QuoteQuantity   string
+	Commission      string
+	CommissionAsset string
+	IsMaker         bool
+	Time            int64
+}
+
+// OrderState define the state of a tracked order
+type OrderState struct {
+	Market           OrderMarket
+	Symbol           string
+	ClientOrderID    string
+	OrderID          int64
+	Side             string
+	Type             string
+	Price            string
+	Quantity         string
+	ExecutedQuantity string
+	CumQuote         string
+	Status           string
+	Fills            []*OrderFill
+	UpdateTime       int64
+}
+
+// AvgPrice return the average fill price, empty before the first fill
+func (o *OrderState) AvgPrice() string {
+	executed := decimalOrZero(o.ExecutedQuantity)
+	if executed.IsZero() {
+		return ""
+	}
+	return decimalOrZero(o.CumQuote).Div(executed).String()
+}
+
+// RemainingQuantity return the quantity left to fill, zero once the order is done
+func (o *OrderState) RemainingQuantity() string {
+	if o.IsFinal() {
+		return "0"
+	}
+	remaining := decimalOrZero(o.Quantity).Sub(decimalOrZero(o.ExecutedQuantity))
+	if remaining.IsNegative() {
+		return "0"
+	}
+	return remaining.String()
+}
+
+// IsFinal return whether the order can't change anymore
+func (o *OrderState) IsFinal() bool {
+	return orderStatusRank(o.Status) == orderStatusRankFinal
+}
+
+func (o *OrderState) copy() *OrderState {
+	res := *o
+	res.Fills = make([]*OrderFill, len(o.Fills))
+	for i, f := range o.Fills {
+		fill := *f
+		res.Fills[i] = &fill
+	}
+	return &res
+}
+
+const (
+	orderStatusRankOpen = iota
+	orderStatusRankPartial
+	orderStatusRankFinal
+)
+
+func orderStatusRank(status string) int {
+	switch status {
+	case "PARTIALLY_FILLED", "PENDING_CANCEL":
+		return orderStatusRankPartial
+	case "FILLED", "CANCELED", "CANCELLED", "EXPIRED", "EXPIRED_IN_MATCH", "REJECTED":
+		return orderStatusRankFinal
+	}
+	return orderStatusRankOpen
+}
+
+// orderUpdate define an order update of any source
+type orderUpdate struct {
+	OrderState
+	fill *OrderFill
+}
+
+type orderKey struct {
+	market        OrderMarket
+	clientOrderID string
+}
+
+// OrderStore track the spot and USD-M futures orders by client order id,
+// whether they are placed by REST or websocket API, from the responses, the
+// user data events and periodic reconciliations. The events can be handled
+// in any order, an order never goes back to an older state.
+type OrderStore struct {
+	// OnError is called with the errors of the reconciliations done by Run
+	OnError func(err error)
+
+	c      *Client
+	fc     *futures.Client
+	mu     sync.Mutex
+	orders map[orderKey]*OrderState
+	subs   map[int]func(order *OrderState)
+	nextID int
+}
+
+// NewOrderStore init an order store, the clients are used to reconcile the
+// orders and can be nil
+func NewOrderStore(c *Client, fc *futures.Client) *OrderStore {
+	return &OrderStore{
+		c:      c,
+		fc:     fc,
+		orders: make(map[orderKey]*OrderState),
+		subs:   make(map[int]func(order *OrderState)),
+	}
+}
+
+// Subscribe call handler with a copy of each changed order, the returned
+// function removes the subscription
+func (s *OrderStore) Subscribe(handler func(order *OrderState)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.subs[id] = handler
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs, id)
+	}
+}
+
+// Order return a copy of an order, nil when unknown
+func (s *OrderStore) Order(market OrderMarket, clientOrderID string) *OrderState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[orderKey{market, clientOrderID}]
+	if !ok {
+		return nil
+	}
+	return o.copy()
+}
+
+// Orders return copies of the orders of a market, of all symbols when symbol
+// is empty, by update time
+func (s *OrderStore) Orders(market OrderMarket, symbol string) []*OrderState {
+	return s.list(market, symbol, false)
+}
+
+// OpenOrders return copies of the orders of a market not final yet
+func (s *OrderStore) OpenOrders(market OrderMarket, symbol string) []*OrderState {
+	return s.list(market, symbol, true)
+}
+
+func (s *OrderStore) list(market OrderMarket, symbol string, open bool) []*OrderState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []*OrderState
+	for key, o := range s.orders {
+		if key.market != market || (symbol != "" && o.Symbol != symbol) || (open && o.IsFinal()) {
+			continue
+		}
+		res = append(res, o.copy())
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].UpdateTime != res[j].UpdateTime {
+			return res[i].UpdateTime < res[j].UpdateTime
+		}
+		return res[i].ClientOrderID < res[j].ClientOrderID
+	})
+	return res
+}
+
+// apply merge an update in its order and notify the subscribers
+func (s *OrderStore) apply(u *orderUpdate) {
+	if u.ClientOrderID == "" {
+		return
+	}
+	s.mu.Lock()
+	key := orderKey{u.Market, u.ClientOrderID}
+	o, ok := s.orders[key]
+	changed := !ok
+	if !ok {
+		o = &OrderState{Market: u.Market, ClientOrderID: u.ClientOrderID}
+		s.orders[key] = o
+	}
+	if !ok || isNewerOrderUpdate(o, &u.OrderState) {
+		status, executed, cumQuote, updateTime := o.Status, o.ExecutedQuantity, o.CumQuote, o.UpdateTime
+		mergeOrderState(o, &u.OrderState)
+		changed = changed || status != o.Status || executed != o.ExecutedQuantity || cumQuote != o.CumQuote || updateTime != o.UpdateTime
+	} else {
+		// an older update still tells what a newer one may have left out
+		changed = fillOrderState(o, &u.OrderState)
+	}
+	if u.fill != nil && u.fill.TradeID != 0 {
+		i := sort.Search(len(o.Fills), func(i int) bool { return o.Fills[i].TradeID >= u.fill.TradeID })
+		if i == len(o.Fills) || o.Fills[i].TradeID != u.fill.TradeID {
+			o.Fills = append(o.Fills, nil)
+			copy(o.Fills[i+1:], o.Fills[i:])
+			o.Fills[i] = u.fill
+			changed = true
+		}
+	}
+	var order *OrderState
+	var handlers []func(order *OrderState)
+	if changed {
+		order = o.copy()
+		ids := make([]int, 0, len(s.subs))
+		for id := range s.subs {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			handlers = append(handlers, s.subs[id])
+		}
+	}
+	s.mu.Unlock()
+	for _, handler := range handlers {
+		handler(order)
+	}
+}
+
+// isNewerOrderUpdate return whether an update is more recent than an order,
+// the executed quantity and the status only move forward
+func isNewerOrderUpdate(o, u *OrderState) bool {
+	if o.IsFinal() && !u.IsFinal() {
+		return false
+	}
+	switch decimalOrZero(u.ExecutedQuantity).Cmp(decimalOrZero(o.ExecutedQuantity)) {
+	case 1:
+		return true
+	case -1:
+		return false
+	}
+	rank, prevRank := orderStatusRank(u.Status), orderStatusRank(o.Status)
+	if rank != prevRank {
+		return rank > prevRank
+	}
+	return u.UpdateTime >= o.UpdateTime
+}
+
+func fillOrderState(o, u *OrderState) bool {
+	var filled bool
+	set := func(dst *string, v string) {
+		if *dst == "" && v != "" {
+			*dst = v
+			filled = true
+		}
+	}
+	set(&o.Symbol, u.Symbol)
+	set(&o.Side, u.Side)
+	set(&o.Type, u.Type)
+	set(&o.Price, u.Price)
+	set(&o.Quantity, u.Quantity)
+	if o.OrderID == 0 && u.OrderID != 0 {
+		o.OrderID = u.OrderID
+		filled = true
+	}
+	return filled
+}
+
+func mergeOrderState(o, u *OrderState) {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&o.Symbol, u.Symbol)
+	set(&o.Side, u.Side)
+	set(&o.Type, u.Type)
+	set(&o.Price, u.Price)
+	set(&o.Quantity, u.Quantity)
+	set(&o.ExecutedQuantity, u.ExecutedQuantity)
+	set(&o.CumQuote, u.CumQuote)
+	set(&o.Status, u.Status)
+	if u.OrderID != 0 {
+		o.OrderID = u.OrderID
+	}
+	if u.UpdateTime > o.UpdateTime {
+		o.UpdateTime = u.UpdateTime
+	}
+}
+
+// HandleCreateOrderResponse track a spot order placed by REST or by the
+// websocket API, with the result of an OrderCreateWsService response
+func (s *OrderStore) HandleCreateOrderResponse(res *CreateOrderResponse) {
+	u := &orderUpdate{OrderState: OrderState{
+		Market:           OrderMarketSpot,
+		Symbol:           res.Symbol,
+		ClientOrderID:    res.ClientOrderID,
+		OrderID:          res.OrderID,
+		Side:             string(res.Side),
+		Type:             string(res.Type),
+		Price:            res.Price,
+		Quantity:         res.OrigQuantity,
+		ExecutedQuantity: res.ExecutedQuantity,
+		CumQuote:         res.CummulativeQuoteQuantity,
+		Status:           string(res.Status),
+		UpdateTime:       res.TransactTime,
+	}}
+	s.apply(u)
+	for _, f := range res.Fills {
+		s.apply(&orderUpdate{
+			OrderState: OrderState{Market: OrderMarketSpot, ClientOrderID: res.ClientOrderID},
+			fill: &OrderFill{
+				TradeID:         f.TradeID,
+				Price:           f.Price,
+				Quantity:        f.Quantity,
+				QuoteQuantity:   decimalOrZero(f.Price).Mul(decimalOrZero(f.Quantity)).String(),
+				Commission:      f.Commission,
+				CommissionAsset: f.CommissionAsset,
+				Time:            res.TransactTime,
+			},
+		})
+	}
+}
+
+// HandleCreateOrderListResult track the orders of an order list placed by
+// the websocket API
+func (s *OrderStore) HandleCreateOrderListResult(res *CreateOrderListResult) {
+	for _, r := range res.OrderReports {
+		s.apply(&orderUpdate{OrderState: OrderState{
+			Market:           OrderMarketSpot,
+			Symbol:           r.Symbol,
+			ClientOrderID:    r.ClientOrderId,
+			OrderID:          r.OrderId,
+			Side:             string(r.Side),
+			Type:             string(r.Type),
+			Price:            r.Price,
+			Quantity:         r.OrigQty,
+			ExecutedQuantity: r.ExecutedQty,
+			CumQuote:         r.CummulativeQuoteQty,
+			Status:           string(r.Status),
+			UpdateTime:       r.TransactTime,
+		}})
+	}
+}
+
+// HandleSorOrderPlaceResult track a SOR order placed by the websocket API
+func (s *OrderStore) HandleSorOrderPlaceResult(res *SorOrderPlaceResult) {
+	s.apply(&orderUpdate{OrderState: OrderState{
+		Market:           OrderMarketSpot,
+		Symbol:           res.Symbol,
+		ClientOrderID:    res.ClientOrderId,
+		OrderID:          res.OrderId,
+		Side:             string(res.Side),
+		Type:             string(res.Type),
+		Price:            res.Price,
+		Quantity:         res.OrigQty,
+		ExecutedQuantity: res.ExecutedQty,
+		CumQuote:         res.CummulativeQuoteQty,
+		Status:           string(res.Status),
+		UpdateTime:       res.TransactTime,
+	}})
+	for _, f := range res.Fills {
+		s.apply(&orderUpdate{
+			OrderState: OrderState{Market: OrderMarketSpot, ClientOrderID: res.ClientOrderId},
+			fill: &OrderFill{
+				TradeID:         f.TradeId,
+				Price:           f.Price,
+				Quantity:        f.Qty,
+				QuoteQuantity:   decimalOrZero(f.Price).Mul(decimalOrZero(f.Qty)).String(),
+				Commission:      f.Commission,
+				CommissionAsset: f.CommissionAsset,
+				Time:            res.TransactTime,
+			},
+		})
+	}
+}
+
+// HandleOrder track a spot order as queried
+func (s *OrderStore) HandleOrder(order *Order) {
+	s.apply(&orderUpdate{OrderState: OrderState{
+		Market:           OrderMarketSpot,
+		Symbol:           order.Symbol,
+		ClientOrderID:    order.ClientOrderID,
+		OrderID:          order.OrderID,
+		Side:             string(order.Side),
+		Type:             string(order.Type),
+		Price:            order.Price,
+		Quantity:         order.OrigQuantity,
+		ExecutedQuantity: order.ExecutedQuantity,
+		CumQuote:         order.CummulativeQuoteQuantity,
+		Status:           string(order.Status),
+		UpdateTime:       order.UpdateTime,
+	}})
+}
+
+// HandleUserDataEvent track the spot orders of the execution reports
+func (s *OrderStore) HandleUserDataEvent(event *WsUserDataEvent) {
+	if event.Event != UserDataEventTypeExecutionReport {
+		return
+	}
+	e := &event.OrderUpdate
+	clientOrderID := e.ClientOrderId
+	if e.ExecutionType == "CANCELED" && e.OrigCustomOrderId != "" {
+		// the client order id of the cancel request, the order one is the original
+		clientOrderID = e.OrigCustomOrderId
+	}
+	u := &orderUpdate{OrderState: OrderState{
+		Market:           OrderMarketSpot,
+		Symbol:           e.Symbol,
+		ClientOrderID:    clientOrderID,
+		OrderID:          e.Id,
+		Side:             e.Side,
+		Type:             e.Type,
+		Price:            e.Price,
+		Quantity:         e.Volume,
+		ExecutedQuantity: e.FilledVolume,
+		CumQuote:         e.FilledQuoteVolume,
+		Status:           e.Status,
+		UpdateTime:       e.TransactionTime,
+	}}
+	if e.ExecutionType == "TRADE" {
+		u.fill = &OrderFill{
+			TradeID:         e.TradeId,
+			Price:           e.LatestPrice,
+			Quantity:        e.LatestVolume,
+			QuoteQuantity:   e.LatestQuoteVolume,
+			Commission:      e.FeeCost,
+			CommissionAsset: e.FeeAsset,
+			IsMaker:         e.IsMaker,
+			Time:            e.TransactionTime,
+		}
+	}
+	s.apply(u)
+}
+
+// HandleFuturesCreateOrderResponse track a USD-M futures order placed by REST
+func (s *OrderStore) HandleFuturesCreateOrderResponse(res *futures.CreateOrderResponse) {
+	s.apply(&orderUpdate{OrderState: OrderState{
+		Market:           OrderMarketFutures,
+		Symbol:           res.Symbol,
+		ClientOrderID:    res.ClientOrderID,
+		OrderID:          res.OrderID,
+		Side:             string(res.Side),
+		Type:             string(res.Type),
+		Price:            res.Price,
+		Quantity:         res.OrigQuantity,
+		ExecutedQuantity: res.ExecutedQuantity,
+		CumQuote:         res.CumQuote,
+		Status:           string(res.Status),
+		UpdateTime:       res.UpdateTime,
+	}})
+}
+
+// HandleFuturesOrder track a USD-M futures order as queried
+func (s *OrderStore) HandleFuturesOrder(order *futures.Order) {
+	s.apply(&orderUpdate{OrderState: OrderState{
+		Market:           OrderMarketFutures,
+		Symbol:           order.Symbol,
+		ClientOrderID:    order.ClientOrderID,
+		OrderID:          order.OrderID,
+		Side:             string(order.Side),
+		Type:             string(order.Type),
+		Price:            order.Price,
+		Quantity:         order.OrigQuantity,
+		ExecutedQuantity: order.ExecutedQuantity,
+		CumQuote:         order.CumQuote,
+		Status:           string(order.Status),
+		UpdateTime:       order.UpdateTime,
+	}})
+}
+
+// HandleFuturesUserDataEvent track the USD-M futures orders of the order
+// trade updates
+func (s *OrderStore) HandleFuturesUserDataEvent(event *futures.WsUserDataEvent) {
+	if event.Event != futures.UserDataEventTypeOrderTradeUpdate {
+		return
+	}
+	e := &event.OrderTradeUpdate
+	executed := decimalOrZero(e.AccumulatedFilledQty)
+	u := &orderUpdate{OrderState: OrderState{
+		Market:           OrderMarketFutures,
+		Symbol:           e.Symbol,
+		ClientOrderID:    e.ClientOrderID,
+		OrderID:          e.ID,
+		Side:             string(e.Side),
+		Type:             string(e.Type),
+		Price:            e.OriginalPrice,
+		Quantity:         e.OriginalQty,
+		ExecutedQuantity: e.AccumulatedFilledQty,
+		CumQuote:         decimalOrZero(e.AveragePrice).Mul(executed).String(),
+		Status:           string(e.Status),
+		UpdateTime:       e.TradeTime,
+	}}
+	if e.ExecutionType == futures.OrderExecutionTypeTrade {
+		u.fill = &OrderFill{
+			TradeID:         e.TradeID,
+			Price:           e.LastFilledPrice,
+			Quantity:        e.LastFilledQty,
+			QuoteQuantity:   decimalOrZero(e.LastFilledPrice).Mul(decimalOrZero(e.LastFilledQty)).String(),
+			Commission:      e.Commission,
+			CommissionAsset: e.CommissionAsset,
+			IsMaker:         e.IsMaker,
+			Time:            e.TradeTime,
+		}
+	}
+	s.apply(u)
+}
+
+// Reconcile update the orders with the open orders and query the orders
+// which aren't open anymore, the ones unknown to the exchange are expired
+func (s *OrderStore) Reconcile(ctx context.Context) error {
+	if s.c != nil {
+		open, err := s.c.NewListOpenOrdersService().Do(ctx)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(open))
+		for _, order := range open {
+			s.HandleOrder(order)
+			seen[order.ClientOrderID] = true
+		}
+		for _, o := range s.OpenOrders(OrderMarketSpot, "") {
+			if seen[o.ClientOrderID] {
+				continue
+			}
+			order, err := s.c.NewGetOrderService().Symbol(o.Symbol).OrigClientOrderID(o.ClientOrderID).Do(ctx)
+			if isUnknownOrder(err) {
+				s.expire(o)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			s.HandleOrder(order)
+		}
+	}
+	if s.fc != nil {
+		open, err := s.fc.NewListOpenOrdersService().Do(ctx)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(open))
+		for _, order := range open {
+			s.HandleFuturesOrder(order)
+			seen[order.ClientOrderID] = true
+		}
+		for _, o := range s.OpenOrders(OrderMarketFutures, "") {
+			if seen[o.ClientOrderID] {
+				continue
+			}
+			order, err := s.fc.NewGetOrderService().Symbol(o.Symbol).OrigClientOrderID(o.ClientOrderID).Do(ctx)
+			if isUnknownOrder(err) {
+				s.expire(o)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			s.HandleFuturesOrder(order)
+		}
+	}
+	return nil
+}
+
+// expire end an order the exchange doesn't know, it was never placed or
+// archived after being canceled or expired without fills
+func (s *OrderStore) expire(o *OrderState) {
+	s.apply(&orderUpdate{OrderState: OrderState{
+		Market:           o.Market,
+		Symbol:           o.Symbol,
+		ClientOrderID:    o.ClientOrderID,
+		ExecutedQuantity: o.ExecutedQuantity,
+		Status:           "EXPIRED",
+		UpdateTime:       time.Now().UnixMilli(),
+	}})
+}
+
+// Run reconcile the orders every interval until ctx is done
+func (s *OrderStore) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Reconcile(ctx); err != nil && ctx.Err() == nil && s.OnError != nil {
+			s.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func isUnknownOrder(err error) bool {
+	var apiErr *common.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == errCodeUnknownOrder
+}
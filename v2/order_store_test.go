@@ -0,0 +1,164 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type orderStoreTestSuite struct {
+	baseTestSuite
+}
+
+func TestOrderStore(t *testing.T) {
+	suite.Run(t, new(orderStoreTestSuite))
+}
+
+func executionReport(update WsOrderUpdate) *WsUserDataEvent {
+	return &WsUserDataEvent{Event: UserDataEventTypeExecutionReport, Time: update.TransactionTime, OrderUpdate: update}
+}
+
+func (s *orderStoreTestSuite) TestSpotEvents() {
+	r := s.r()
+	store := NewOrderStore(nil, nil)
+	var updates []string
+	unsubscribe := store.Subscribe(func(order *OrderState) {
+		updates = append(updates, order.Status+" "+order.ExecutedQuantity)
+	})
+
+	store.HandleCreateOrderResponse(&CreateOrderResponse{
+		Symbol: "BTCUSDT", OrderID: 1, ClientOrderID: "a", TransactTime: 1000, Price: "100", OrigQuantity: "3",
+		ExecutedQuantity: "0", CummulativeQuoteQuantity: "0", Status: OrderStatusTypeNew, Type: OrderTypeLimit, Side: SideTypeBuy,
+	})
+	// the second trade comes first
+	store.HandleUserDataEvent(executionReport(WsOrderUpdate{
+		Symbol: "BTCUSDT", ClientOrderId: "a", Id: 1, ExecutionType: "TRADE", Status: "PARTIALLY_FILLED", Volume: "3",
+		FilledVolume: "2", FilledQuoteVolume: "199", LatestVolume: "1", LatestPrice: "99", LatestQuoteVolume: "99",
+		TradeId: 11, FeeCost: "0.001", FeeAsset: "BTC", TransactionTime: 1002,
+	}))
+	store.HandleUserDataEvent(executionReport(WsOrderUpdate{
+		Symbol: "BTCUSDT", ClientOrderId: "a", Id: 1, ExecutionType: "TRADE", Status: "PARTIALLY_FILLED", Volume: "3",
+		FilledVolume: "1", FilledQuoteVolume: "100", LatestVolume: "1", LatestPrice: "100", LatestQuoteVolume: "100",
+		TradeId: 10, FeeCost: "0.001", FeeAsset: "BTC", IsMaker: true, TransactionTime: 1001,
+	}))
+	// a duplicate
+	store.HandleUserDataEvent(executionReport(WsOrderUpdate{
+		Symbol: "BTCUSDT", ClientOrderId: "a", Id: 1, ExecutionType: "TRADE", Status: "PARTIALLY_FILLED", Volume: "3",
+		FilledVolume: "2", FilledQuoteVolume: "199", LatestVolume: "1", LatestPrice: "99", LatestQuoteVolume: "99",
+		TradeId: 11, TransactionTime: 1002,
+	}))
+	order := store.Order(OrderMarketSpot, "a")
+	r.Equal("PARTIALLY_FILLED", order.Status)
+	r.Equal("99.5", order.AvgPrice())
+	r.Equal("1", order.RemainingQuantity())
+	r.Len(order.Fills, 2)
+	r.Equal(int64(10), order.Fills[0].TradeID)
+	r.True(order.Fills[0].IsMaker)
+
+	// canceled by a cancel request with its own client order id
+	store.HandleUserDataEvent(executionReport(WsOrderUpdate{
+		Symbol: "BTCUSDT", ClientOrderId: "cancel", OrigCustomOrderId: "a", Id: 1, ExecutionType: "CANCELED",
+		Status: "CANCELED", Volume: "3", FilledVolume: "2", FilledQuoteVolume: "199", TransactionTime: 1003,
+	}))
+	// an old NEW event arrives late
+	store.HandleUserDataEvent(executionReport(WsOrderUpdate{
+		Symbol: "BTCUSDT", ClientOrderId: "a", Id: 1, ExecutionType: "NEW", Status: "NEW", Volume: "3",
+		FilledVolume: "0", FilledQuoteVolume: "0", TransactionTime: 1000,
+	}))
+	order = store.Order(OrderMarketSpot, "a")
+	r.Equal("CANCELED", order.Status)
+	r.True(order.IsFinal())
+	r.Equal("0", order.RemainingQuantity())
+	r.Nil(store.Order(OrderMarketSpot, "cancel"))
+	r.Empty(store.OpenOrders(OrderMarketSpot, "BTCUSDT"))
+	r.Equal([]string{"NEW 0", "PARTIALLY_FILLED 2", "PARTIALLY_FILLED 2", "CANCELED 2"}, updates)
+
+	unsubscribe()
+	store.HandleUserDataEvent(executionReport(WsOrderUpdate{
+		Symbol: "BTCUSDT", ClientOrderId: "b", Id: 2, ExecutionType: "NEW", Status: "NEW", Volume: "1", TransactionTime: 1004,
+	}))
+	r.Len(updates, 4)
+	r.Len(store.Orders(OrderMarketSpot, ""), 2)
+}
+
+func (s *orderStoreTestSuite) TestOutOfOrderCreation() {
+	r := s.r()
+	store := NewOrderStore(nil, nil)
+	// the fill comes before the order response
+	store.HandleUserDataEvent(executionReport(WsOrderUpdate{
+		Symbol: "BTCUSDT", ClientOrderId: "a", Id: 1, ExecutionType: "TRADE", Status: "FILLED", Volume: "1",
+		FilledVolume: "1", FilledQuoteVolume: "100", LatestVolume: "1", LatestPrice: "100", TradeId: 10, TransactionTime: 1001,
+	}))
+	store.HandleCreateOrderResponse(&CreateOrderResponse{
+		Symbol: "BTCUSDT", OrderID: 1, ClientOrderID: "a", TransactTime: 1000, OrigQuantity: "1", ExecutedQuantity: "0",
+		Status: OrderStatusTypeNew, Type: OrderTypeMarket, Side: SideTypeSell,
+	})
+	order := store.Order(OrderMarketSpot, "a")
+	r.Equal("FILLED", order.Status)
+	r.Equal("SELL", order.Side)
+	r.Equal("MARKET", order.Type)
+	r.Equal(int64(1001), order.UpdateTime)
+}
+
+func (s *orderStoreTestSuite) TestFuturesEvents() {
+	r := s.r()
+	store := NewOrderStore(nil, nil)
+	store.HandleFuturesCreateOrderResponse(&futures.CreateOrderResponse{
+		Symbol: "BTCUSDT", OrderID: 1, ClientOrderID: "a", OrigQuantity: "2", ExecutedQuantity: "0", CumQuote: "0",
+		Status: futures.OrderStatusTypeNew, Type: futures.OrderTypeMarket, Side: futures.SideTypeBuy, UpdateTime: 1000,
+	})
+	store.HandleFuturesUserDataEvent(&futures.WsUserDataEvent{
+		Event: futures.UserDataEventTypeOrderTradeUpdate,
+		WsUserDataOrderTradeUpdate: futures.WsUserDataOrderTradeUpdate{OrderTradeUpdate: futures.WsOrderTradeUpdate{
+			Symbol: "BTCUSDT", ClientOrderID: "a", ID: 1, ExecutionType: futures.OrderExecutionTypeTrade,
+			Status: futures.OrderStatusTypeFilled, OriginalQty: "2", AccumulatedFilledQty: "2", AveragePrice: "100.5",
+			LastFilledQty: "2", LastFilledPrice: "100.5", TradeID: 7, Commission: "0.08", CommissionAsset: "USDT", TradeTime: 1001,
+		}},
+	})
+	order := store.Order(OrderMarketFutures, "a")
+	r.Equal("FILLED", order.Status)
+	r.Equal("100.5", order.AvgPrice())
+	r.Equal([]*OrderFill{{TradeID: 7, Price: "100.5", Quantity: "2", QuoteQuantity: "201", Commission: "0.08", CommissionAsset: "USDT", Time: 1001}}, order.Fills)
+	r.Nil(store.Order(OrderMarketSpot, "a"))
+}
+
+func (s *orderStoreTestSuite) TestReconcile() {
+	r := s.r()
+	var paths []string
+	s.client.Client.do = func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.Path+" "+req.URL.Query().Get("origClientOrderId"))
+		switch {
+		case req.URL.Path == "/api/v3/openOrders":
+			return newHTTPResponse([]byte(`[{"symbol":"BTCUSDT","orderId":2,"clientOrderId":"b","origQty":"1","executedQty":"0.5","cummulativeQuoteQty":"50","status":"PARTIALLY_FILLED","updateTime":2000}]`), http.StatusOK), nil
+		case req.URL.Query().Get("origClientOrderId") == "a":
+			return newHTTPResponse([]byte(`{"symbol":"BTCUSDT","orderId":1,"clientOrderId":"a","origQty":"1","executedQty":"1","cummulativeQuoteQty":"101","status":"FILLED","updateTime":2000}`), http.StatusOK), nil
+		}
+		return newHTTPResponse([]byte(`{"code":-2013,"msg":"Order does not exist."}`), http.StatusBadRequest), nil
+	}
+	store := NewOrderStore(s.client.Client, nil)
+	for _, id := range []string{"a", "b", "c"} {
+		store.HandleCreateOrderResponse(&CreateOrderResponse{
+			Symbol: "BTCUSDT", ClientOrderID: id, OrigQuantity: "1", ExecutedQuantity: "0", Status: OrderStatusTypeNew, TransactTime: 1000,
+		})
+	}
+	var notified []string
+	store.Subscribe(func(order *OrderState) {
+		notified = append(notified, order.ClientOrderID+" "+order.Status)
+	})
+	r.NoError(store.Reconcile(context.Background()))
+	r.ElementsMatch([]string{"/api/v3/openOrders ", "/api/v3/order a", "/api/v3/order c"}, paths)
+	r.Contains(notified, "c EXPIRED")
+	r.Equal("FILLED", store.Order(OrderMarketSpot, "a").Status)
+	r.Equal("101", store.Order(OrderMarketSpot, "a").AvgPrice())
+	r.Equal("0.5", store.Order(OrderMarketSpot, "b").RemainingQuantity())
+	r.Equal("EXPIRED", store.Order(OrderMarketSpot, "c").Status)
+	r.Len(store.OpenOrders(OrderMarketSpot, ""), 1)
+
+	paths = nil
+	r.NoError(store.Reconcile(context.Background()))
+	r.Equal([]string{"/api/v3/openOrders "}, paths)
+}
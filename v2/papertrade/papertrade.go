@@ -0,0 +1,196 @@
+// Package papertrade dry runs strategies on the live market data without
+// sending any real order.
+//
+// An Exchange is a binancetest fake exchange whose order books follow the
+// live depth or book ticker streams of the watched symbols. The spot and USD-M
+// futures clients of its account place, query and cancel orders as usual, the
+// orders are filled against the live books with the fees of a live account,
+// and the user data events are sent on its user data streams:
+//
+//	paper := papertrade.New()
+//	defer paper.Close()
+//	paper.Account().SetBalance("USDT", "1000")
+//	err := paper.Watch(binancetest.MarketSpot, "BTCUSDT", "BTC", "USDT")
+//	...
+//	err = paper.LoadFees(ctx, liveClient, "BTCUSDT")
+//	...
+//	restore := paper.UseWsEndpoints()
+//	defer restore()
+//	client := paper.Client()
+//
+// The market data streams of the clients are still the live ones once the
+// websocket endpoints point at the exchange, only the user data streams are
+// served by the exchange.
+package papertrade
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/binancetest"
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// bookLevels is the number of levels of the partial depth streams watched
+const bookLevels = 20
+
+// Exchange define a paper trading exchange and its account
+type Exchange struct {
+	*binancetest.Server
+	// OnError is called with the errors of the live streams
+	OnError func(err error)
+
+	account *binancetest.Account
+	mu      sync.Mutex
+	stopCs  []chan struct{}
+	doneCs  []chan struct{}
+}
+
+// New start a paper trading exchange, the live market data is read from the
+// current websocket endpoints of the clients
+func New() *Exchange {
+	s := binancetest.NewServer()
+	s.Upstream(binancetest.MarketSpot, strings.TrimSuffix(binance.BaseWsMainURL, "/ws"))
+	s.Upstream(binancetest.MarketFutures, strings.TrimSuffix(futures.BaseWsMainUrl, "/ws"))
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return &Exchange{
+		Server:  s,
+		account: s.AddAccount("paper", hex.EncodeToString(secret)),
+	}
+}
+
+// Account return the paper trading account, to set its balances
+func (e *Exchange) Account() *binancetest.Account {
+	return e.account
+}
+
+// Client return a spot client of the paper trading account
+func (e *Exchange) Client() *binance.Client {
+	return e.NewClient(e.account)
+}
+
+// FuturesClient return a USD-M futures client of the paper trading account
+func (e *Exchange) FuturesClient() *futures.Client {
+	return e.NewFuturesClient(e.account)
+}
+
+// Close stop the live streams and the exchange
+func (e *Exchange) Close() {
+	e.mu.Lock()
+	for _, stopC := range e.stopCs {
+		close(stopC)
+	}
+	for _, doneC := range e.doneCs {
+		<-doneC
+	}
+	e.stopCs, e.doneCs = nil, nil
+	e.mu.Unlock()
+	e.Server.Close()
+}
+
+func (e *Exchange) handleError(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
+
+func (e *Exchange) watching(doneC, stopC chan struct{}, err error) error {
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopCs = append(e.stopCs, stopC)
+	e.doneCs = append(e.doneCs, doneC)
+	return nil
+}
+
+// Watch list a symbol and follow its live partial depth stream
+func (e *Exchange) Watch(market binancetest.Market, symbol, base, quote string) error {
+	e.AddSymbol(market, symbol, base, quote)
+	if market == binancetest.MarketFutures {
+		return e.watching(futures.WsPartialDepthServeWithRate(symbol, bookLevels, 100*time.Millisecond, e.HandleFuturesDepth, e.handleError))
+	}
+	return e.watching(binance.WsPartialDepthServe100Ms(symbol, strconv.Itoa(bookLevels), e.HandleDepth, e.handleError))
+}
+
+// WatchBookTicker list a symbol and follow its live book ticker stream, only
+// the best prices are then filled
+func (e *Exchange) WatchBookTicker(market binancetest.Market, symbol, base, quote string) error {
+	e.AddSymbol(market, symbol, base, quote)
+	if market == binancetest.MarketFutures {
+		return e.watching(futures.WsBookTickerServe(symbol, e.HandleFuturesBookTicker, e.handleError))
+	}
+	return e.watching(binance.WsBookTickerServe(symbol, e.HandleBookTicker, e.handleError))
+}
+
+// HandleDepth set the book of a spot symbol, it can be fed with recorded data
+func (e *Exchange) HandleDepth(event *binance.WsPartialDepthEvent) {
+	e.setBook(binancetest.MarketSpot, event.Symbol, event.Bids, event.Asks)
+}
+
+// HandleBookTicker set the best prices of a spot symbol
+func (e *Exchange) HandleBookTicker(event *binance.WsBookTickerEvent) {
+	e.setBook(binancetest.MarketSpot, event.Symbol,
+		[]common.PriceLevel{{Price: event.BestBidPrice, Quantity: event.BestBidQty}},
+		[]common.PriceLevel{{Price: event.BestAskPrice, Quantity: event.BestAskQty}})
+}
+
+// HandleFuturesDepth set the book of a futures symbol
+func (e *Exchange) HandleFuturesDepth(event *futures.WsDepthEvent) {
+	e.setBook(binancetest.MarketFutures, event.Symbol, event.Bids, event.Asks)
+}
+
+// HandleFuturesBookTicker set the best prices of a futures symbol
+func (e *Exchange) HandleFuturesBookTicker(event *futures.WsBookTickerEvent) {
+	e.setBook(binancetest.MarketFutures, event.Symbol,
+		[]common.PriceLevel{{Price: event.BestBidPrice, Quantity: event.BestBidQty}},
+		[]common.PriceLevel{{Price: event.BestAskPrice, Quantity: event.BestAskQty}})
+}
+
+func (e *Exchange) setBook(market binancetest.Market, symbol string, bids, asks []common.PriceLevel) {
+	levels := func(pl []common.PriceLevel) [][2]string {
+		res := make([][2]string, 0, len(pl))
+		for _, l := range pl {
+			res = append(res, [2]string{l.Price, l.Quantity})
+		}
+		return res
+	}
+	if err := e.SetOrderBook(market, symbol, levels(bids), levels(asks)); err != nil {
+		e.handleError(err)
+	}
+}
+
+// LoadFees apply the spot trading fees of a live account to the paper one
+func (e *Exchange) LoadFees(ctx context.Context, c *binance.Client, symbols ...string) error {
+	for _, symbol := range symbols {
+		fees, err := c.NewTradeFeeService().Symbol(symbol).Do(ctx)
+		if err != nil {
+			return err
+		}
+		for _, fee := range fees {
+			e.account.SetCommission(binancetest.MarketSpot, fee.Symbol, fee.MakerCommission, fee.TakerCommission)
+		}
+	}
+	return nil
+}
+
+// LoadFuturesFees apply the futures commission rates of a live account to the paper one
+func (e *Exchange) LoadFuturesFees(ctx context.Context, c *futures.Client, symbols ...string) error {
+	for _, symbol := range symbols {
+		rate, err := c.NewCommissionRateService().Symbol(symbol).Do(ctx)
+		if err != nil {
+			return err
+		}
+		e.account.SetCommission(binancetest.MarketFutures, rate.Symbol, rate.MakerCommissionRate, rate.TakerCommissionRate)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package papertrade
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/binancetest"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type papertradeTestSuite struct {
+	suite.Suite
+	// live stands for the real exchange
+	live        *binancetest.Server
+	liveAccount *binancetest.Account
+	restore     func()
+	paper       *Exchange
+}
+
+func TestPapertrade(t *testing.T) {
+	suite.Run(t, new(papertradeTestSuite))
+}
+
+func (s *papertradeTestSuite) SetupTest() {
+	s.live = binancetest.NewServer()
+	s.restore = s.live.UseWsEndpoints()
+	s.live.AddSymbol(binancetest.MarketSpot, "BTCUSDT", "BTC", "USDT")
+	s.live.AddSymbol(binancetest.MarketFutures, "BTCUSDT", "BTC", "USDT")
+	s.liveAccount = s.live.AddAccount("live", "live-secret")
+	s.paper = New()
+}
+
+func (s *papertradeTestSuite) TearDownTest() {
+	s.paper.Close()
+	s.restore()
+	s.live.Close()
+}
+
+// waitBook publish a live event until the paper book has a best bid
+func (s *papertradeTestSuite) waitBook(market binancetest.Market, stream string, event interface{}, bestBid func() (string, error)) {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.live.Publish(market, stream, event)
+		time.Sleep(10 * time.Millisecond)
+		if bid, err := bestBid(); err == nil && bid != "" {
+			return
+		}
+		s.Require().True(time.Now().Before(deadline), "the paper book doesn't follow %s", stream)
+	}
+}
+
+func (s *papertradeTestSuite) TestSpot() {
+	r := s.Require()
+	ctx := context.Background()
+	s.liveAccount.SetCommission(binancetest.MarketSpot, "BTCUSDT", "0.001", "0.002")
+	r.NoError(s.paper.LoadFees(ctx, s.live.NewClient(s.liveAccount), "BTCUSDT"))
+	s.paper.Account().SetBalance("USDT", "1000")
+	r.NoError(s.paper.Watch(binancetest.MarketSpot, "BTCUSDT", "BTC", "USDT"))
+
+	c := s.paper.Client()
+	s.waitBook(binancetest.MarketSpot, "btcusdt@depth20@100ms", map[string]interface{}{
+		"lastUpdateId": 1, "bids": [][2]string{{"99", "1"}}, "asks": [][2]string{{"100", "1"}, {"101", "5"}},
+	}, func() (string, error) {
+		depth, err := c.NewDepthService().Symbol("BTCUSDT").Do(ctx)
+		if err != nil || len(depth.Bids) == 0 {
+			return "", err
+		}
+		return depth.Bids[0].Price, nil
+	})
+
+	// the user data stream is the paper one, the market data streams the live ones
+	restore := s.paper.UseWsEndpoints()
+	defer restore()
+	listenKey, err := c.NewStartUserStreamService().Do(ctx)
+	r.NoError(err)
+	eventC := make(chan *binance.WsUserDataEvent, 16)
+	doneC, stopC, err := binance.WsUserDataServe(listenKey, func(event *binance.WsUserDataEvent) {
+		eventC <- event
+	}, func(err error) {})
+	r.NoError(err)
+	defer func() {
+		close(stopC)
+		<-doneC
+	}()
+
+	res, err := c.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeBuy).
+		Type(binance.OrderTypeMarket).Quantity("2").Do(ctx)
+	r.NoError(err)
+	r.Equal(binance.OrderStatusTypeFilled, res.Status)
+	r.Equal("201", res.CummulativeQuoteQuantity)
+	r.Len(res.Fills, 2)
+	r.Equal("0.002", res.Fills[0].Commission)
+	free, _ := s.paper.Account().Balance("BTC")
+	r.Equal("1.996", free)
+
+	order, err := c.NewCreateOrderService().Symbol("BTCUSDT").Side(binance.SideTypeSell).
+		Type(binance.OrderTypeLimit).TimeInForce(binance.TimeInForceTypeGTC).Quantity("1").Price("105").Do(ctx)
+	r.NoError(err)
+	open, err := c.NewListOpenOrdersService().Symbol("BTCUSDT").Do(ctx)
+	r.NoError(err)
+	r.Len(open, 1)
+	canceled, err := c.NewCancelOrderService().Symbol("BTCUSDT").OrderID(order.OrderID).Do(ctx)
+	r.NoError(err)
+	r.Equal(binance.OrderStatusTypeCanceled, canceled.Status)
+	got, err := c.NewGetOrderService().Symbol("BTCUSDT").OrderID(order.OrderID).Do(ctx)
+	r.NoError(err)
+	r.Equal(binance.OrderStatusTypeCanceled, got.Status)
+
+	var trades []*binance.WsOrderUpdate
+	for len(trades) < 2 {
+		select {
+		case event := <-eventC:
+			if event.Event == binance.UserDataEventTypeExecutionReport && event.OrderUpdate.ExecutionType == "TRADE" {
+				trades = append(trades, &event.OrderUpdate)
+			}
+		case <-time.After(time.Second):
+			s.FailNow("no execution report")
+		}
+	}
+	r.Equal("0.002", trades[0].FeeCost)
+	r.Equal("BTC", trades[0].FeeAsset)
+}
+
+func (s *papertradeTestSuite) TestFutures() {
+	r := s.Require()
+	ctx := context.Background()
+	s.liveAccount.SetCommission(binancetest.MarketFutures, "BTCUSDT", "0.0002", "0.0005")
+	r.NoError(s.paper.LoadFuturesFees(ctx, s.live.NewFuturesClient(s.liveAccount), "BTCUSDT"))
+	s.paper.Account().SetFuturesBalance("USDT", "100")
+	r.NoError(s.paper.WatchBookTicker(binancetest.MarketFutures, "BTCUSDT", "BTC", "USDT"))
+
+	c := s.paper.FuturesClient()
+	s.waitBook(binancetest.MarketFutures, "btcusdt@bookTicker", map[string]interface{}{
+		"e": "bookTicker", "u": 1, "s": "BTCUSDT", "b": "99", "B": "10", "a": "100", "A": "10",
+	}, func() (string, error) {
+		depth, err := c.NewDepthService().Symbol("BTCUSDT").Do(ctx)
+		if err != nil || len(depth.Bids) == 0 {
+			return "", err
+		}
+		return depth.Bids[0].Price, nil
+	})
+
+	_, err := c.NewChangeLeverageService().Symbol("BTCUSDT").Leverage(10).Do(ctx)
+	r.NoError(err)
+	r.NoError(c.NewChangePositionModeService().DualSide(true).Do(ctx))
+	res, err := c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeBuy).PositionSide(futures.PositionSideTypeLong).
+		Type(futures.OrderTypeMarket).Quantity("5").Do(ctx)
+	r.NoError(err)
+	r.Equal(futures.OrderStatusTypeFilled, res.Status)
+	r.Equal("5", s.paper.Account().Position("BTCUSDT"))
+	r.Equal("99.75", s.paper.Account().FuturesBalance("USDT"))
+
+	// 6 more at 100 needs 60 of margin at 10x, 50 are used
+	_, err = c.NewCreateOrderService().Symbol("BTCUSDT").Side(futures.SideTypeBuy).PositionSide(futures.PositionSideTypeLong).
+		Type(futures.OrderTypeMarket).Quantity("6").Do(ctx)
+	r.Error(err)
+}
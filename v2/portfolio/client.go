@@ -217,12 +217,13 @@ const (
 	ContractTypeCurrentQuarter ContractType = "CURRENT_QUARTER"
 	ContractTypeNextQuarter    ContractType = "NEXT_QUARTER"
 
-	UserDataEventTypeListenKeyExpired    UserDataEventType = "listenKeyExpired"
-	UserDataEventTypeMarginCall          UserDataEventType = "MARGIN_CALL"
-	UserDataEventTypeAccountUpdate       UserDataEventType = "ACCOUNT_UPDATE"
-	UserDataEventTypeOrderTradeUpdate    UserDataEventType = "ORDER_TRADE_UPDATE"
-	UserDataEventTypeAccountConfigUpdate UserDataEventType = "ACCOUNT_CONFIG_UPDATE"
-	UserDataEventTypeTradeLite           UserDataEventType = "TRADE_LITE"
+	UserDataEventTypeListenKeyExpired            UserDataEventType = "listenKeyExpired"
+	UserDataEventTypeMarginCall                  UserDataEventType = "MARGIN_CALL"
+	UserDataEventTypeAccountUpdate               UserDataEventType = "ACCOUNT_UPDATE"
+	UserDataEventTypeOrderTradeUpdate            UserDataEventType = "ORDER_TRADE_UPDATE"
+	UserDataEventTypeAccountConfigUpdate         UserDataEventType = "ACCOUNT_CONFIG_UPDATE"
+	UserDataEventTypeTradeLite                   UserDataEventType = "TRADE_LITE"
+	UserDataEventTypeConditionalOrderTradeUpdate UserDataEventType = "CONDITIONAL_ORDER_TRADE_UPDATE"
 
 	UserDataEventReasonTypeDeposit             UserDataEventReasonType = "DEPOSIT"
 	UserDataEventReasonTypeWithdraw            UserDataEventReasonType = "WITHDRAW"
@@ -1009,3 +1010,24 @@ func (c *Client) NewKeepaliveUserStreamService() *KeepaliveUserStreamService {
 func (c *Client) NewCloseUserStreamService() *CloseUserStreamService {
 	return &CloseUserStreamService{c: c}
 }
+
+// NewUserDataStream init a managed user data stream, handler receives every
+// event after the open order book has been updated and may be nil
+func (c *Client) NewUserDataStream(handler WsUserDataHandler, errHandler ErrHandler) *UserDataStream {
+	return &UserDataStream{
+		c:          c,
+		handler:    handler,
+		errHandler: errHandler,
+		book:       NewOpenOrderBook(),
+	}
+}
+
+// NewRiskMonitor init a uniMMR risk monitor, errHandler receives the poll and action errors
+func (c *Client) NewRiskMonitor(errHandler ErrHandler) *RiskMonitor {
+	return &RiskMonitor{
+		c:            c,
+		errHandler:   errHandler,
+		pollInterval: RiskMonitorPollInterval,
+		liquidation:  decimalOrZero(UniMMRForceLiquidation),
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -142,3 +143,54 @@ func (m *mockedClient) do(req *http.Request) (*http.Response, error) {
 	args := m.Called(req)
 	return args.Get(0).(*http.Response), args.Error(1)
 }
+
+// routedClient serves canned JSON bodies keyed by "METHOD /path" or "/path",
+// and records the calls, for helpers that call several endpoints.
+type routedClient struct {
+	mu     sync.Mutex
+	routes map[string]string
+	calls  []string
+}
+
+func newRoutedClient(routes map[string]string) *routedClient {
+	return &routedClient{routes: routes}
+}
+
+func (rc *routedClient) do(req *http.Request) (*http.Response, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	key := req.Method + " " + req.URL.Path
+	rc.calls = append(rc.calls, key)
+	data, ok := rc.routes[key]
+	if !ok {
+		data, ok = rc.routes[req.URL.Path]
+	}
+	if !ok {
+		return newHTTPResponse([]byte(`{"code":-1,"msg":"unexpected path `+req.URL.Path+`"}`), http.StatusNotFound), nil
+	}
+	return newHTTPResponse([]byte(data), http.StatusOK), nil
+}
+
+func (rc *routedClient) setRoute(key, data string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.routes[key] = data
+}
+
+func (rc *routedClient) count(key string) int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	n := 0
+	for _, c := range rc.calls {
+		if c == key {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *baseTestSuite) mockRoutes(routes map[string]string) *routedClient {
+	rc := newRoutedClient(routes)
+	s.client.Client.do = rc.do
+	return rc
+}
@@ -0,0 +1,308 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// uniMMR levels at which Binance changes the status of a portfolio margin account
+const (
+	UniMMRMarginCall       = "1.5"
+	UniMMRReduceOnly       = "1.2"
+	UniMMRForceLiquidation = "1.05"
+)
+
+// RiskMonitorPollInterval is the default interval between two account polls of a RiskMonitor
+var RiskMonitorPollInterval = 10 * time.Second
+
+// ErrRiskMonitorStarted is returned when starting a monitor that is already running
+var ErrRiskMonitorStarted = errors.New("risk monitor already started")
+
+// RiskState define the risk of a portfolio margin account at a point in time
+type RiskState struct {
+	UniMMR        string `json:"uniMMR"`
+	AccountEquity string `json:"accountEquity"`
+	ActualEquity  string `json:"actualEquity"`
+	MaintMargin   string `json:"maintMargin"`
+	Status        string `json:"status"`
+	// EquityToLiquidation is the USD equity the account can lose before uniMMR
+	// reaches the liquidation level, assuming the maintenance margin stays the same
+	EquityToLiquidation string `json:"equityToLiquidation"`
+	// DistanceToLiquidation is EquityToLiquidation as a ratio of the account equity
+	DistanceToLiquidation string `json:"distanceToLiquidation"`
+	UpdateTime            int64  `json:"updateTime"`
+}
+
+// RiskAction define an automated de-risking action run when a threshold is breached
+type RiskAction func(ctx context.Context, c *Client, state *RiskState) error
+
+// RiskThreshold define a uniMMR level, Callback and Actions run once when
+// uniMMR falls to or below it, and again only after it recovered above it
+type RiskThreshold struct {
+	UniMMR   string
+	Callback func(state *RiskState)
+	Actions  []RiskAction
+}
+
+// CancelUMOrdersAction cancel all open UM orders of the given symbols
+func CancelUMOrdersAction(symbols ...string) RiskAction {
+	return func(ctx context.Context, c *Client, state *RiskState) error {
+		for _, symbol := range symbols {
+			if _, err := c.NewUMCancelAllOrdersService().Symbol(symbol).Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// RepayFuturesNegativeBalanceAction repay the futures negative balance
+func RepayFuturesNegativeBalanceAction() RiskAction {
+	return func(ctx context.Context, c *Client, state *RiskState) error {
+		_, err := c.NewRepayFuturesNegativeBalanceService().Do(ctx)
+		return err
+	}
+}
+
+// FundAutoCollectionAction collect the assets of the futures accounts back to the margin account
+func FundAutoCollectionAction() RiskAction {
+	return func(ctx context.Context, c *Client, state *RiskState) error {
+		_, err := c.NewFundAutoCollectionService().Do(ctx)
+		return err
+	}
+}
+
+type riskThreshold struct {
+	level    decimal.Decimal
+	breached bool
+	*RiskThreshold
+}
+
+// RiskMonitor follow the uniMMR of a portfolio margin account by polling the
+// account and by consuming riskLevelChange and MARGIN_CALL events, it runs
+// the callbacks and actions of the thresholds it crosses.
+type RiskMonitor struct {
+	c            *Client
+	errHandler   ErrHandler
+	pollInterval time.Duration
+	liquidation  decimal.Decimal
+
+	mu         sync.Mutex
+	thresholds []*riskThreshold
+	state      *RiskState
+	// the polls report the last balance change of the account and the events
+	// their event time, each source is only compared with itself
+	lastPoll  int64
+	lastEvent int64
+	checkC    chan struct{}
+	stopC     chan struct{}
+	doneC     chan struct{}
+}
+
+// PollInterval set the interval between two account polls
+func (m *RiskMonitor) PollInterval(pollInterval time.Duration) *RiskMonitor {
+	m.pollInterval = pollInterval
+	return m
+}
+
+// LiquidationUniMMR set the uniMMR level used to compute the distance to liquidation
+func (m *RiskMonitor) LiquidationUniMMR(uniMMR string) *RiskMonitor {
+	m.liquidation = decimalOrZero(uniMMR)
+	return m
+}
+
+// AddThreshold add a threshold, thresholds are evaluated from the highest uniMMR to the lowest
+func (m *RiskMonitor) AddThreshold(threshold *RiskThreshold) *RiskMonitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.thresholds = append(m.thresholds, &riskThreshold{
+		level:         decimalOrZero(threshold.UniMMR),
+		RiskThreshold: threshold,
+	})
+	sort.SliceStable(m.thresholds, func(i, j int) bool {
+		return m.thresholds[i].level.GreaterThan(m.thresholds[j].level)
+	})
+	return m
+}
+
+// State return the last known risk state, or nil before the first update
+func (m *RiskMonitor) State() *RiskState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state == nil {
+		return nil
+	}
+	cp := *m.state
+	return &cp
+}
+
+// Check poll the account once and evaluate the thresholds
+func (m *RiskMonitor) Check(ctx context.Context) (*RiskState, error) {
+	account, err := m.c.NewGetAccountService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state := m.newState(account.UniMMR, account.AccountEquity, account.ActualEquity,
+		account.AccountMaintMargin, account.AccountStatus, account.UpdateTime)
+	m.update(ctx, state, &m.lastPoll)
+	return state, nil
+}
+
+// Start poll the account in background until Stop is called, the first poll
+// is done before returning. A stopped monitor can't be started again.
+func (m *RiskMonitor) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.stopC != nil {
+		m.mu.Unlock()
+		return ErrRiskMonitorStarted
+	}
+	m.stopC = make(chan struct{})
+	m.doneC = make(chan struct{})
+	m.mu.Unlock()
+
+	if _, err := m.Check(ctx); err != nil {
+		m.mu.Lock()
+		doneC := m.doneC
+		m.stopC, m.doneC = nil, nil
+		m.mu.Unlock()
+		// release a Stop called during the first poll
+		close(doneC)
+		return err
+	}
+	m.mu.Lock()
+	m.checkC = make(chan struct{}, 1)
+	m.mu.Unlock()
+	go m.run()
+	return nil
+}
+
+// Stop the background polling
+func (m *RiskMonitor) Stop() {
+	m.mu.Lock()
+	stopC, doneC := m.stopC, m.doneC
+	m.mu.Unlock()
+	if stopC == nil {
+		return
+	}
+	select {
+	case <-stopC:
+	default:
+		close(stopC)
+	}
+	<-doneC
+}
+
+func (m *RiskMonitor) run() {
+	defer close(m.doneC)
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopC:
+			return
+		case <-ticker.C:
+		case <-m.checkC:
+		}
+		if _, err := m.Check(context.Background()); err != nil {
+			m.errHandler(err)
+		}
+	}
+}
+
+// HandleRiskLevelChange evaluate the thresholds with a riskLevelChange event,
+// it can be called from a WsUserDataHandler
+func (m *RiskMonitor) HandleRiskLevelChange(e *WsRiskLevelChange) {
+	state := m.newState(e.UniMMRLevel, e.EquityUSD, e.ActualEquityUSD,
+		e.MaintenanceMarginUSD, e.Status, e.EventTime)
+	m.update(context.Background(), state, &m.lastEvent)
+}
+
+// HandleUserDataEvent trigger an immediate poll of a started monitor on MARGIN_CALL events
+func (m *RiskMonitor) HandleUserDataEvent(e *WsUserDataEvent) {
+	if e.Event != UserDataEventTypeMarginCall {
+		return
+	}
+	m.mu.Lock()
+	checkC := m.checkC
+	m.mu.Unlock()
+	if checkC == nil {
+		return
+	}
+	select {
+	case checkC <- struct{}{}:
+	default:
+	}
+}
+
+func (m *RiskMonitor) newState(uniMMR, equity, actualEquity, maintMargin, status string, updateTime int64) *RiskState {
+	state := &RiskState{
+		UniMMR:        uniMMR,
+		AccountEquity: equity,
+		ActualEquity:  actualEquity,
+		MaintMargin:   maintMargin,
+		Status:        status,
+		UpdateTime:    updateTime,
+	}
+	eq := decimalOrZero(equity)
+	mm := decimalOrZero(maintMargin)
+	if mm.IsPositive() {
+		toLiquidation := eq.Sub(m.liquidation.Mul(mm))
+		state.EquityToLiquidation = toLiquidation.String()
+		if eq.IsPositive() {
+			state.DistanceToLiquidation = toLiquidation.DivRound(eq, 8).String()
+		}
+	}
+	return state
+}
+
+// update store the state and run the thresholds it crossed, the states older
+// than the last one of their source are ignored
+func (m *RiskMonitor) update(ctx context.Context, state *RiskState, last *int64) {
+	m.mu.Lock()
+	if *last > state.UpdateTime {
+		m.mu.Unlock()
+		return
+	}
+	*last = state.UpdateTime
+	m.state = state
+	// without maintenance margin there is no risk, whatever uniMMR is reported
+	safe := !decimalOrZero(state.MaintMargin).IsPositive()
+	uniMMR := decimalOrZero(state.UniMMR)
+	var breached []*RiskThreshold
+	for _, t := range m.thresholds {
+		if safe || uniMMR.GreaterThan(t.level) {
+			t.breached = false
+			continue
+		}
+		if !t.breached {
+			t.breached = true
+			breached = append(breached, t.RiskThreshold)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, t := range breached {
+		if t.Callback != nil {
+			cp := *state
+			t.Callback(&cp)
+		}
+		for _, action := range t.Actions {
+			if err := action(ctx, m.c, state); err != nil {
+				m.errHandler(err)
+			}
+		}
+	}
+}
+
+func decimalOrZero(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
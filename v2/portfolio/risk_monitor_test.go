@@ -0,0 +1,167 @@
+package portfolio
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type riskMonitorTestSuite struct {
+	baseTestSuite
+}
+
+func TestRiskMonitor(t *testing.T) {
+	suite.Run(t, new(riskMonitorTestSuite))
+}
+
+func (s *riskMonitorTestSuite) TestThresholds() {
+	rc := s.mockRoutes(map[string]string{
+		"/papi/v1/account": `{
+			"uniMMR": "1.4",
+			"accountEquity": "1400",
+			"actualEquity": "1500",
+			"accountMaintMargin": "1000",
+			"accountStatus": "MARGIN_CALL",
+			"updateTime": 100
+		}`,
+		"POST /papi/v1/auto-collection":                `{"msg": "success"}`,
+		"POST /papi/v1/repay-futures-negative-balance": `{"msg": "success"}`,
+		"DELETE /papi/v1/um/allOpenOrders":             `{"code": 200, "msg": "The operation of cancel all open order is done."}`,
+	})
+
+	var marginCalls, reduceOnly []*RiskState
+	var errs []error
+	monitor := s.client.NewRiskMonitor(func(err error) { errs = append(errs, err) }).
+		AddThreshold(&RiskThreshold{
+			UniMMR:   UniMMRReduceOnly,
+			Callback: func(state *RiskState) { reduceOnly = append(reduceOnly, state) },
+			Actions: []RiskAction{
+				CancelUMOrdersAction("BTCUSDT", "ETHUSDT"),
+				RepayFuturesNegativeBalanceAction(),
+				FundAutoCollectionAction(),
+			},
+		}).
+		AddThreshold(&RiskThreshold{
+			UniMMR:   UniMMRMarginCall,
+			Callback: func(state *RiskState) { marginCalls = append(marginCalls, state) },
+		})
+
+	state, err := monitor.Check(newContext())
+	r := s.r()
+	r.NoError(err)
+	r.Equal("350", state.EquityToLiquidation)
+	r.Equal("0.25", state.DistanceToLiquidation)
+	r.Len(marginCalls, 1)
+	r.Empty(reduceOnly)
+
+	// still breached, nothing runs again
+	_, err = monitor.Check(newContext())
+	r.NoError(err)
+	r.Len(marginCalls, 1)
+
+	monitor.HandleRiskLevelChange(&WsRiskLevelChange{
+		EventTime: 200, UniMMRLevel: "1.1", EquityUSD: "1100", MaintenanceMarginUSD: "1000", Status: RiskStatusReduceOnly,
+	})
+	r.Len(marginCalls, 1)
+	r.Len(reduceOnly, 1)
+	r.Equal("50", reduceOnly[0].EquityToLiquidation)
+	r.Equal(2, rc.count("DELETE /papi/v1/um/allOpenOrders"))
+	r.Equal(1, rc.count("POST /papi/v1/repay-futures-negative-balance"))
+	r.Equal(1, rc.count("POST /papi/v1/auto-collection"))
+	r.Empty(errs)
+
+	// a stale event doesn't change the state
+	monitor.HandleRiskLevelChange(&WsRiskLevelChange{EventTime: 150, UniMMRLevel: "3", EquityUSD: "3000", MaintenanceMarginUSD: "1000"})
+	r.Equal("1.1", monitor.State().UniMMR)
+
+	// recovering above both levels re-arms them
+	monitor.HandleRiskLevelChange(&WsRiskLevelChange{EventTime: 300, UniMMRLevel: "2", EquityUSD: "2000", MaintenanceMarginUSD: "1000"})
+	monitor.HandleRiskLevelChange(&WsRiskLevelChange{EventTime: 400, UniMMRLevel: "1.45", EquityUSD: "1450", MaintenanceMarginUSD: "1000"})
+	r.Len(marginCalls, 2)
+	r.Len(reduceOnly, 1)
+
+	// no maintenance margin means no risk
+	monitor.HandleRiskLevelChange(&WsRiskLevelChange{EventTime: 500, UniMMRLevel: "0", EquityUSD: "1000", MaintenanceMarginUSD: "0"})
+	r.Equal("", monitor.State().DistanceToLiquidation)
+	r.Len(reduceOnly, 1)
+
+	// the polls are applied after the events, the account update time is the last balance change
+	_, err = monitor.Check(newContext())
+	r.NoError(err)
+	r.Equal("1.4", monitor.State().UniMMR)
+	r.Len(marginCalls, 3)
+}
+
+func (s *riskMonitorTestSuite) TestActionError() {
+	s.mockRoutes(map[string]string{
+		"/papi/v1/account": `{"uniMMR": "1.1", "accountEquity": "1100", "accountMaintMargin": "1000", "updateTime": 100}`,
+	})
+	var errs []error
+	_, err := s.client.NewRiskMonitor(func(err error) { errs = append(errs, err) }).
+		AddThreshold(&RiskThreshold{UniMMR: UniMMRReduceOnly, Actions: []RiskAction{FundAutoCollectionAction()}}).
+		Check(newContext())
+	s.r().NoError(err)
+	s.r().Len(errs, 1)
+}
+
+func (s *riskMonitorTestSuite) TestStartAndMarginCall() {
+	rc := s.mockRoutes(map[string]string{
+		"/papi/v1/account": `{"uniMMR": "2", "accountEquity": "2000", "accountMaintMargin": "1000", "updateTime": 100}`,
+	})
+	monitor := s.client.NewRiskMonitor(func(err error) {}).PollInterval(time.Hour)
+	s.r().NoError(monitor.Start(newContext()))
+	s.r().Equal(ErrRiskMonitorStarted, monitor.Start(newContext()))
+	s.r().Equal(1, rc.count("GET /papi/v1/account"))
+
+	monitor.HandleUserDataEvent(&WsUserDataEvent{Event: UserDataEventTypeAccountUpdate})
+	monitor.HandleUserDataEvent(&WsUserDataEvent{Event: UserDataEventTypeMarginCall})
+	s.r().Eventually(func() bool { return rc.count("GET /papi/v1/account") == 2 }, time.Second, time.Millisecond)
+	monitor.Stop()
+}
+
+func (s *riskMonitorTestSuite) TestStartError() {
+	s.mockRoutes(map[string]string{})
+	monitor := s.client.NewRiskMonitor(func(err error) {})
+	err := monitor.Start(newContext())
+	s.r().Error(err)
+	s.r().False(errors.Is(err, ErrRiskMonitorStarted))
+}
+
+func (s *riskMonitorTestSuite) TestStopDuringStartError() {
+	pollC := make(chan struct{})
+	releaseC := make(chan struct{})
+	s.client.Client.do = func(req *http.Request) (*http.Response, error) {
+		close(pollC)
+		<-releaseC
+		return nil, errors.New("unreachable")
+	}
+	monitor := s.client.NewRiskMonitor(func(err error) {})
+	errC := make(chan error, 1)
+	go func() { errC <- monitor.Start(newContext()) }()
+	<-pollC
+	stoppedC := make(chan struct{})
+	go func() {
+		monitor.Stop()
+		close(stoppedC)
+	}()
+	s.r().Eventually(func() bool {
+		monitor.mu.Lock()
+		defer monitor.mu.Unlock()
+		select {
+		case <-monitor.stopC:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	close(releaseC)
+	s.r().Error(<-errC)
+	select {
+	case <-stoppedC:
+	case <-time.After(time.Second):
+		s.T().Fatal("Stop is blocked")
+	}
+}
@@ -0,0 +1,633 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jpillora/backoff"
+)
+
+// BusinessUnit define the portfolio margin account an order belongs to
+type BusinessUnit string
+
+const (
+	BusinessUnitUM     BusinessUnit = "UM"
+	BusinessUnitCM     BusinessUnit = "CM"
+	BusinessUnitMargin BusinessUnit = "MARGIN"
+)
+
+var (
+	// UserDataStreamKeepaliveInterval is how often a managed user data stream extends its listen key
+	UserDataStreamKeepaliveInterval = 30 * time.Minute
+	// UserDataStreamReconnectMinInterval is the first delay before reconnecting a managed user data stream
+	UserDataStreamReconnectMinInterval = 100 * time.Millisecond
+	// UserDataStreamReconnectMaxInterval is the maximum delay between two reconnect attempts
+	UserDataStreamReconnectMaxInterval = 30 * time.Second
+)
+
+// ErrUserDataStreamStarted is returned when starting a stream that is already running
+var ErrUserDataStreamStarted = errors.New("user data stream already started")
+
+// OpenOrder define an open UM, CM or margin order kept by the OpenOrderBook
+type OpenOrder struct {
+	Business      BusinessUnit     `json:"business"`
+	Symbol        string           `json:"symbol"`
+	OrderID       int64            `json:"orderId"`
+	ClientOrderID string           `json:"clientOrderId"`
+	Side          SideType         `json:"side"`
+	Type          OrderType        `json:"type"`
+	TimeInForce   TimeInForceType  `json:"timeInForce"`
+	PositionSide  PositionSideType `json:"positionSide"`
+	Price         string           `json:"price"`
+	StopPrice     string           `json:"stopPrice"`
+	OrigQty       string           `json:"origQty"`
+	ExecutedQty   string           `json:"executedQty"`
+	Status        OrderStatusType  `json:"status"`
+	ReduceOnly    bool             `json:"reduceOnly"`
+	UpdateTime    int64            `json:"updateTime"`
+}
+
+// OpenConditionalOrder define an open UM or CM conditional order kept by the OpenOrderBook
+type OpenConditionalOrder struct {
+	Business         BusinessUnit     `json:"business"`
+	Symbol           string           `json:"symbol"`
+	StrategyID       int64            `json:"strategyId"`
+	ClientStrategyID string           `json:"clientStrategyId"`
+	StrategyType     string           `json:"strategyType"`
+	StrategyStatus   string           `json:"strategyStatus"`
+	Side             SideType         `json:"side"`
+	PositionSide     PositionSideType `json:"positionSide"`
+	Price            string           `json:"price"`
+	StopPrice        string           `json:"stopPrice"`
+	OrigQty          string           `json:"origQty"`
+	ReduceOnly       bool             `json:"reduceOnly"`
+	UpdateTime       int64            `json:"updateTime"`
+}
+
+type openOrderKey struct {
+	business BusinessUnit
+	id       int64
+}
+
+// OpenOrderBook keeps the open orders and conditional orders of a portfolio
+// margin account, it is safe for concurrent use.
+type OpenOrderBook struct {
+	mu          sync.RWMutex
+	orders      map[openOrderKey]*OpenOrder
+	conditional map[openOrderKey]*OpenConditionalOrder
+}
+
+// NewOpenOrderBook create an empty open order book
+func NewOpenOrderBook() *OpenOrderBook {
+	return &OpenOrderBook{
+		orders:      map[openOrderKey]*OpenOrder{},
+		conditional: map[openOrderKey]*OpenConditionalOrder{},
+	}
+}
+
+// Orders return a copy of the open orders of a business unit, sorted by order id
+func (b *OpenOrderBook) Orders(business BusinessUnit) []*OpenOrder {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	res := make([]*OpenOrder, 0)
+	for k, o := range b.orders {
+		if k.business == business {
+			cp := *o
+			res = append(res, &cp)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].OrderID < res[j].OrderID })
+	return res
+}
+
+// Order return a copy of an open order, or nil if it isn't open
+func (b *OpenOrderBook) Order(business BusinessUnit, orderID int64) *OpenOrder {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	o, ok := b.orders[openOrderKey{business, orderID}]
+	if !ok {
+		return nil
+	}
+	cp := *o
+	return &cp
+}
+
+// ConditionalOrders return a copy of the open conditional orders of a business unit, sorted by strategy id
+func (b *OpenOrderBook) ConditionalOrders(business BusinessUnit) []*OpenConditionalOrder {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	res := make([]*OpenConditionalOrder, 0)
+	for k, o := range b.conditional {
+		if k.business == business {
+			cp := *o
+			res = append(res, &cp)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].StrategyID < res[j].StrategyID })
+	return res
+}
+
+// ConditionalOrder return a copy of an open conditional order, or nil if it isn't open
+func (b *OpenOrderBook) ConditionalOrder(business BusinessUnit, strategyID int64) *OpenConditionalOrder {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	o, ok := b.conditional[openOrderKey{business, strategyID}]
+	if !ok {
+		return nil
+	}
+	cp := *o
+	return &cp
+}
+
+// applyOrder insert, update or remove an order, stale updates are ignored
+func (b *OpenOrderBook) applyOrder(o *OpenOrder) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := openOrderKey{o.Business, o.OrderID}
+	if cur, ok := b.orders[key]; ok && cur.UpdateTime > o.UpdateTime {
+		return
+	}
+	switch o.Status {
+	case OrderStatusTypeNew, OrderStatusTypePartiallyFilled:
+		b.orders[key] = o
+	default:
+		delete(b.orders, key)
+	}
+}
+
+// applyConditionalOrder insert, update or remove a conditional order, stale updates are ignored
+func (b *OpenOrderBook) applyConditionalOrder(o *OpenConditionalOrder) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := openOrderKey{o.Business, o.StrategyID}
+	if cur, ok := b.conditional[key]; ok && cur.UpdateTime > o.UpdateTime {
+		return
+	}
+	if o.StrategyStatus == "NEW" {
+		b.conditional[key] = o
+	} else {
+		// TRIGGERED, FINISHED, CANCELED and EXPIRED strategies are no longer open
+		delete(b.conditional, key)
+	}
+}
+
+// reset replace the whole book with a REST snapshot
+func (b *OpenOrderBook) reset(orders []*OpenOrder, conditional []*OpenConditionalOrder) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.orders = make(map[openOrderKey]*OpenOrder, len(orders))
+	for _, o := range orders {
+		b.orders[openOrderKey{o.Business, o.OrderID}] = o
+	}
+	b.conditional = make(map[openOrderKey]*OpenConditionalOrder, len(conditional))
+	for _, o := range conditional {
+		b.conditional[openOrderKey{o.Business, o.StrategyID}] = o
+	}
+}
+
+// UserDataStream manage a portfolio margin user data stream: it creates and
+// keeps alive the listen key, reconnects when the connection drops or the key
+// expires, and maintains an OpenOrderBook of the UM, CM and margin open orders
+// and conditional orders. The book is re-seeded from the REST endpoints after
+// every (re)connection, so no update is lost during a gap.
+type UserDataStream struct {
+	c          *Client
+	handler    WsUserDataHandler
+	errHandler ErrHandler
+	book       *OpenOrderBook
+
+	// dispatchMu serialize the events passed to the handlers, mu is never
+	// held while the handlers run so that they can call the accessors
+	dispatchMu sync.Mutex
+
+	mu        sync.Mutex
+	listenKey string
+	seeding   bool
+	pending   [][]byte
+	stopC     chan struct{}
+	doneC     chan struct{}
+	resetC    chan struct{}
+}
+
+// Book return the open order book maintained by the stream
+func (s *UserDataStream) Book() *OpenOrderBook {
+	return s.book
+}
+
+// ListenKey return the listen key currently in use
+func (s *UserDataStream) ListenKey() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listenKey
+}
+
+// Start connect the stream and seed the book, it returns once the first
+// connection is established and keeps the stream running in background
+// until Stop is called. A stopped stream can't be started again.
+func (s *UserDataStream) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.stopC != nil {
+		s.mu.Unlock()
+		return ErrUserDataStreamStarted
+	}
+	s.stopC = make(chan struct{})
+	s.doneC = make(chan struct{})
+	s.resetC = make(chan struct{}, 1)
+	s.mu.Unlock()
+
+	connDoneC, connStopC, err := s.connect(ctx)
+	if err != nil {
+		s.mu.Lock()
+		s.stopC = nil
+		s.mu.Unlock()
+		return err
+	}
+	go s.keepalive()
+	go s.run(connDoneC, connStopC)
+	return nil
+}
+
+// Stop close the connection and the listen key, then wait for the background goroutines to exit
+func (s *UserDataStream) Stop() {
+	s.mu.Lock()
+	stopC, doneC := s.stopC, s.doneC
+	s.mu.Unlock()
+	if stopC == nil {
+		return
+	}
+	select {
+	case <-stopC:
+	default:
+		close(stopC)
+	}
+	<-doneC
+}
+
+func (s *UserDataStream) run(connDoneC, connStopC chan struct{}) {
+	defer close(s.doneC)
+	b := &backoff.Backoff{
+		Min:    UserDataStreamReconnectMinInterval,
+		Max:    UserDataStreamReconnectMaxInterval,
+		Factor: 1.8,
+	}
+	for {
+		select {
+		case <-s.stopC:
+			close(connStopC)
+			<-connDoneC
+			if err := s.c.NewCloseUserStreamService().ListenKey(s.ListenKey()).Do(context.Background()); err != nil {
+				s.errHandler(err)
+			}
+			return
+		case <-s.resetC:
+			close(connStopC)
+			<-connDoneC
+		case <-connDoneC:
+		}
+
+		for {
+			var err error
+			connDoneC, connStopC, err = s.connect(context.Background())
+			if err == nil {
+				b.Reset()
+				break
+			}
+			s.errHandler(err)
+			select {
+			case <-s.stopC:
+				return
+			case <-time.After(b.Duration()):
+			}
+		}
+	}
+}
+
+func (s *UserDataStream) keepalive() {
+	ticker := time.NewTicker(UserDataStreamKeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopC:
+			return
+		case <-ticker.C:
+			if err := s.c.NewKeepaliveUserStreamService().ListenKey(s.ListenKey()).Do(context.Background()); err != nil {
+				s.errHandler(err)
+				s.reconnect()
+			}
+		}
+	}
+}
+
+// reconnect ask the run loop to drop the current connection and start a new one
+func (s *UserDataStream) reconnect() {
+	select {
+	case s.resetC <- struct{}{}:
+	default:
+	}
+}
+
+// connect get a listen key, open the websocket and seed the book from REST.
+// Events received while seeding are buffered and replayed on top of the snapshot.
+func (s *UserDataStream) connect(ctx context.Context) (doneC, stopC chan struct{}, err error) {
+	listenKey, err := s.c.NewStartUserStreamService().Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.mu.Lock()
+	oldListenKey := s.listenKey
+	s.listenKey = listenKey
+	s.seeding = true
+	s.pending = nil
+	s.mu.Unlock()
+	if oldListenKey != "" && oldListenKey != listenKey {
+		if err := s.c.NewCloseUserStreamService().ListenKey(oldListenKey).Do(ctx); err != nil {
+			s.errHandler(err)
+		}
+	}
+
+	endpoint := fmt.Sprintf("%s/ws/%s", getWsEndpoint(), listenKey)
+	doneC, stopC, err = wsServe(newWsConfig(endpoint), s.handleMessage, s.errHandler)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.seed(ctx); err != nil {
+		close(stopC)
+		<-doneC
+		return nil, nil, err
+	}
+	return doneC, stopC, nil
+}
+
+func (s *UserDataStream) handleMessage(message []byte) {
+	s.mu.Lock()
+	if s.seeding {
+		s.pending = append(s.pending, message)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+	wsUserDataHandler(&userDataStreamHandler{s})(message)
+}
+
+// seed load the open orders from the REST endpoints and replace the book
+func (s *UserDataStream) seed(ctx context.Context) error {
+	var orders []*OpenOrder
+	var conditional []*OpenConditionalOrder
+
+	um, err := s.c.NewUMOpenOrdersService().Do(ctx)
+	if err != nil {
+		return err
+	}
+	for _, o := range um {
+		orders = append(orders, &OpenOrder{
+			Business:      BusinessUnitUM,
+			Symbol:        o.Symbol,
+			OrderID:       o.OrderID,
+			ClientOrderID: o.ClientOrderID,
+			Side:          SideType(o.Side),
+			Type:          OrderType(o.Type),
+			TimeInForce:   TimeInForceType(o.TimeInForce),
+			PositionSide:  PositionSideType(o.PositionSide),
+			Price:         o.Price,
+			OrigQty:       o.OrigQty,
+			ExecutedQty:   o.ExecutedQty,
+			Status:        OrderStatusType(o.Status),
+			ReduceOnly:    o.ReduceOnly,
+			UpdateTime:    o.UpdateTime,
+		})
+	}
+	cm, err := s.c.NewCMOpenOrdersService().Do(ctx)
+	if err != nil {
+		return err
+	}
+	for _, o := range cm {
+		orders = append(orders, &OpenOrder{
+			Business:      BusinessUnitCM,
+			Symbol:        o.Symbol,
+			OrderID:       o.OrderID,
+			ClientOrderID: o.ClientOrderID,
+			Side:          SideType(o.Side),
+			Type:          OrderType(o.Type),
+			TimeInForce:   TimeInForceType(o.TimeInForce),
+			PositionSide:  PositionSideType(o.PositionSide),
+			Price:         o.Price,
+			OrigQty:       o.OrigQty,
+			ExecutedQty:   o.ExecutedQty,
+			Status:        OrderStatusType(o.Status),
+			ReduceOnly:    o.ReduceOnly,
+			UpdateTime:    o.UpdateTime,
+		})
+	}
+	margin, err := s.c.NewGetMarginOpenOrdersService().Do(ctx)
+	if err != nil {
+		return err
+	}
+	for _, o := range margin {
+		orders = append(orders, &OpenOrder{
+			Business:      BusinessUnitMargin,
+			Symbol:        o.Symbol,
+			OrderID:       o.OrderID,
+			ClientOrderID: o.ClientOrderID,
+			Side:          o.Side,
+			Type:          o.Type,
+			TimeInForce:   o.TimeInForce,
+			Price:         o.Price,
+			StopPrice:     o.StopPrice,
+			OrigQty:       o.OrigQty,
+			ExecutedQty:   o.ExecutedQty,
+			Status:        OrderStatusType(o.Status),
+			UpdateTime:    o.UpdateTime,
+		})
+	}
+	umConditional, err := s.c.NewUMOpenConditionalOrdersService().Do(ctx)
+	if err != nil {
+		return err
+	}
+	for _, o := range umConditional {
+		conditional = append(conditional, &OpenConditionalOrder{
+			Business:         BusinessUnitUM,
+			Symbol:           o.Symbol,
+			StrategyID:       o.StrategyID,
+			ClientStrategyID: o.NewClientStrategyID,
+			StrategyType:     o.StrategyType,
+			StrategyStatus:   o.StrategyStatus,
+			Side:             SideType(o.Side),
+			PositionSide:     PositionSideType(o.PositionSide),
+			Price:            o.Price,
+			StopPrice:        o.StopPrice,
+			OrigQty:          o.OrigQty,
+			ReduceOnly:       o.ReduceOnly,
+			UpdateTime:       o.UpdateTime,
+		})
+	}
+	cmConditional, err := s.c.NewCMOpenConditionalOrdersService().Do(ctx)
+	if err != nil {
+		return err
+	}
+	for _, o := range cmConditional {
+		conditional = append(conditional, &OpenConditionalOrder{
+			Business:         BusinessUnitCM,
+			Symbol:           o.Symbol,
+			StrategyID:       o.StrategyID,
+			ClientStrategyID: o.NewClientStrategyID,
+			StrategyType:     o.StrategyType,
+			StrategyStatus:   o.StrategyStatus,
+			Side:             SideType(o.Side),
+			PositionSide:     PositionSideType(o.PositionSide),
+			Price:            o.Price,
+			StopPrice:        o.StopPrice,
+			OrigQty:          o.OrigQty,
+			ReduceOnly:       o.ReduceOnly,
+			UpdateTime:       o.UpdateTime,
+		})
+	}
+
+	s.book.reset(orders, conditional)
+
+	// replay the buffered events until none is left, the live events wait
+	// for the replay on dispatchMu
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+	h := wsUserDataHandler(&userDataStreamHandler{s})
+	for {
+		s.mu.Lock()
+		pending := s.pending
+		s.pending = nil
+		if len(pending) == 0 {
+			s.seeding = false
+			s.mu.Unlock()
+			return nil
+		}
+		s.mu.Unlock()
+		for _, message := range pending {
+			h(message)
+		}
+	}
+}
+
+// userDataStreamHandler update the book of a UserDataStream and forward the events to the user handler
+type userDataStreamHandler struct {
+	s *UserDataStream
+}
+
+func (h *userDataStreamHandler) HandleListenKeyExpired(e *WsListenKeyExpired) {
+	h.s.reconnect()
+	if h.s.handler != nil {
+		h.s.handler.HandleListenKeyExpired(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleMarginBalanceUpdate(e *WsMarginBalanceUpdate) {
+	if h.s.handler != nil {
+		h.s.handler.HandleMarginBalanceUpdate(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleRiskLevelChange(e *WsRiskLevelChange) {
+	if h.s.handler != nil {
+		h.s.handler.HandleRiskLevelChange(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleFuturesAccountConfigUpdate(e *WsFuturesAccountConfigUpdate) {
+	if h.s.handler != nil {
+		h.s.handler.HandleFuturesAccountConfigUpdate(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleFuturesAccountUpdate(e *WsFuturesAccountUpdate) {
+	if h.s.handler != nil {
+		h.s.handler.HandleFuturesAccountUpdate(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleFuturesOrderUpdate(e *WsFuturesOrderUpdate) {
+	o := e.Order
+	h.s.book.applyOrder(&OpenOrder{
+		Business:      BusinessUnit(e.BusinessUnit),
+		Symbol:        o.Symbol,
+		OrderID:       o.OrderID,
+		ClientOrderID: o.ClientOrderID,
+		Side:          o.Side,
+		Type:          o.OrderType,
+		TimeInForce:   o.TimeInForce,
+		PositionSide:  o.PositionSide,
+		Price:         o.OriginalPrice,
+		StopPrice:     o.StopPrice,
+		OrigQty:       o.OriginalQty,
+		ExecutedQty:   o.FilledAccumQty,
+		Status:        o.OrderStatus,
+		ReduceOnly:    o.IsReduceOnly,
+		UpdateTime:    e.TransactionTime,
+	})
+	if h.s.handler != nil {
+		h.s.handler.HandleFuturesOrderUpdate(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleMarginOrderUpdate(e *WsMarginOrderUpdate) {
+	h.s.book.applyOrder(&OpenOrder{
+		Business:      BusinessUnitMargin,
+		Symbol:        e.Symbol,
+		OrderID:       e.OrderID,
+		ClientOrderID: e.ClientOrderID,
+		Side:          SideType(e.Side),
+		Type:          OrderType(e.OrderType),
+		TimeInForce:   TimeInForceType(e.TimeInForce),
+		Price:         e.Price,
+		StopPrice:     e.StopPrice,
+		OrigQty:       e.Quantity,
+		ExecutedQty:   e.CumulativeFilledQty,
+		Status:        OrderStatusType(e.OrderStatus),
+		UpdateTime:    e.TransactionTime,
+	})
+	if h.s.handler != nil {
+		h.s.handler.HandleMarginOrderUpdate(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleLiabilityUpdate(e *WsLiabilityUpdate) {
+	if h.s.handler != nil {
+		h.s.handler.HandleLiabilityUpdate(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleMarginAccountUpdate(e *WsMarginAccountUpdate) {
+	if h.s.handler != nil {
+		h.s.handler.HandleMarginAccountUpdate(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleOpenOrderLossUpdate(e *WsOpenOrderLossUpdate) {
+	if h.s.handler != nil {
+		h.s.handler.HandleOpenOrderLossUpdate(e)
+	}
+}
+
+func (h *userDataStreamHandler) HandleConditionalOrderTradeUpdate(e *WsConditionalOrderTradeUpdate) {
+	o := e.Order
+	h.s.book.applyConditionalOrder(&OpenConditionalOrder{
+		Business:         BusinessUnit(e.Business),
+		Symbol:           o.Symbol,
+		StrategyID:       o.StrategyID,
+		ClientStrategyID: o.ClientOrderID,
+		StrategyType:     o.StrategyType,
+		StrategyStatus:   o.OrderStatus,
+		Side:             SideType(o.Side),
+		PositionSide:     PositionSideType(o.PositionSide),
+		Price:            o.Price,
+		StopPrice:        o.StopPrice,
+		OrigQty:          o.Quantity,
+		ReduceOnly:       o.ReduceOnly,
+		UpdateTime:       o.UpdateTime,
+	})
+	if h.s.handler != nil {
+		h.s.handler.HandleConditionalOrderTradeUpdate(e)
+	}
+}
@@ -0,0 +1,205 @@
+package portfolio
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type userDataStreamTestSuite struct {
+	baseTestSuite
+	origWsServe func(*WsConfig, WsHandler, ErrHandler) (chan struct{}, chan struct{}, error)
+
+	mu        sync.Mutex
+	endpoints []string
+	handlers  []WsHandler
+	doneCs    []chan struct{}
+}
+
+func TestUserDataStream(t *testing.T) {
+	suite.Run(t, new(userDataStreamTestSuite))
+}
+
+func (s *userDataStreamTestSuite) SetupTest() {
+	s.baseTestSuite.SetupTest()
+	s.origWsServe = wsServe
+	s.endpoints = nil
+	s.handlers = nil
+	s.doneCs = nil
+}
+
+func (s *userDataStreamTestSuite) TearDownTest() {
+	wsServe = s.origWsServe
+}
+
+// mockWsServe record every connection, and push the given messages before
+// returning, i.e. while the book is still being seeded
+func (s *userDataStreamTestSuite) mockWsServe(messages ...string) {
+	wsServe = func(cfg *WsConfig, handler WsHandler, errHandler ErrHandler) (doneC, stopC chan struct{}, err error) {
+		doneC = make(chan struct{})
+		stopC = make(chan struct{})
+		s.mu.Lock()
+		s.endpoints = append(s.endpoints, cfg.Endpoint)
+		s.handlers = append(s.handlers, handler)
+		s.doneCs = append(s.doneCs, doneC)
+		s.mu.Unlock()
+		go func() {
+			<-stopC
+			s.closeConn(doneC)
+		}()
+		for _, m := range messages {
+			handler([]byte(m))
+		}
+		return doneC, stopC, nil
+	}
+}
+
+func (s *userDataStreamTestSuite) closeConn(doneC chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-doneC:
+	default:
+		close(doneC)
+	}
+}
+
+func (s *userDataStreamTestSuite) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.handlers)
+}
+
+func (s *userDataStreamTestSuite) lastHandler() WsHandler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handlers[len(s.handlers)-1]
+}
+
+func (s *userDataStreamTestSuite) TestBookSeedAndUpdates() {
+	rc := s.mockRoutes(map[string]string{
+		"POST /papi/v1/listenKey":   `{"listenKey": "key1"}`,
+		"DELETE /papi/v1/listenKey": `{}`,
+		"/papi/v1/um/openOrders": `[
+			{"symbol": "BTCUSDT", "orderId": 1, "clientOrderId": "um1", "side": "BUY", "type": "LIMIT",
+			 "price": "30000", "origQty": "1", "executedQty": "0", "status": "NEW", "updateTime": 100},
+			{"symbol": "ETHUSDT", "orderId": 3, "clientOrderId": "um3", "side": "SELL", "type": "LIMIT",
+			 "price": "2000", "origQty": "2", "executedQty": "0", "status": "NEW", "updateTime": 100}
+		]`,
+		"/papi/v1/cm/openOrders":     `[]`,
+		"/papi/v1/margin/openOrders": `[{"symbol": "BNBUSDT", "orderId": 7, "clientOrderId": "m7", "side": "BUY", "type": "LIMIT", "status": "NEW", "updateTime": 100}]`,
+		"/papi/v1/um/conditional/openOrders": `[
+			{"symbol": "BTCUSDT", "strategyId": 5, "newClientStrategyId": "s5", "strategyStatus": "NEW", "strategyType": "STOP", "updateTime": 100}
+		]`,
+		"/papi/v1/cm/conditional/openOrders": `[]`,
+	})
+	// this update arrives before the REST snapshot is applied and must be
+	// replayed on top of it
+	s.mockWsServe(`{"e": "ORDER_TRADE_UPDATE", "fs": "UM", "E": 200, "T": 200,
+		"o": {"s": "BTCUSDT", "c": "um1", "S": "BUY", "o": "LIMIT", "q": "1", "p": "30000", "X": "FILLED", "i": 1, "z": "1"}}`)
+
+	var forwarded []int64
+	var listenKeys []string
+	var stream *UserDataStream
+	// the handlers can call the stream, including for the replayed events
+	handler := &recordingUserDataHandler{onOrder: func(e *WsFuturesOrderUpdate) {
+		forwarded = append(forwarded, e.Order.OrderID)
+		listenKeys = append(listenKeys, stream.ListenKey())
+	}}
+	stream = s.client.NewUserDataStream(handler, func(err error) { s.T().Log(err) })
+	s.r().NoError(stream.Start(newContext()))
+	s.r().Equal(ErrUserDataStreamStarted, stream.Start(newContext()))
+	s.r().Equal("key1", stream.ListenKey())
+	s.r().Equal(BaseWsMainUrl+"/ws/key1", s.endpoints[0])
+
+	book := stream.Book()
+	s.r().Nil(book.Order(BusinessUnitUM, 1))
+	s.r().Len(book.Orders(BusinessUnitUM), 1)
+	s.r().Equal("um3", book.Orders(BusinessUnitUM)[0].ClientOrderID)
+	s.r().Len(book.Orders(BusinessUnitMargin), 1)
+	s.r().NotNil(book.ConditionalOrder(BusinessUnitUM, 5))
+	s.r().Equal([]int64{1}, forwarded)
+	s.r().Equal([]string{"key1"}, listenKeys)
+
+	h := s.lastHandler()
+	// a stale partial fill older than the snapshot is ignored
+	h([]byte(`{"e": "ORDER_TRADE_UPDATE", "fs": "UM", "E": 50, "T": 50,
+		"o": {"s": "ETHUSDT", "c": "um3", "S": "SELL", "o": "LIMIT", "q": "2", "p": "2000", "X": "CANCELED", "i": 3}}`))
+	s.r().NotNil(book.Order(BusinessUnitUM, 3))
+	h([]byte(`{"e": "ORDER_TRADE_UPDATE", "fs": "CM", "E": 300, "T": 300,
+		"o": {"s": "BTCUSD_PERP", "c": "cm2", "S": "SELL", "o": "LIMIT", "q": "5", "p": "31000", "X": "NEW", "i": 2}}`))
+	s.r().Equal("cm2", book.Order(BusinessUnitCM, 2).ClientOrderID)
+	s.r().Len(listenKeys, 3)
+	h([]byte(`{"e": "CONDITIONAL_ORDER_TRADE_UPDATE", "fs": "UM", "E": 300, "T": 300,
+		"so": {"s": "BTCUSDT", "c": "s5", "si": 5, "st": "STOP", "os": "TRIGGERED", "ut": 300}}`))
+	s.r().Empty(book.ConditionalOrders(BusinessUnitUM))
+	h([]byte(`{"e": "executionReport", "E": 300, "s": "BNBUSDT", "c": "m7", "S": "BUY", "o": "LIMIT", "X": "CANCELED", "i": 7, "T": 300}`))
+	s.r().Empty(book.Orders(BusinessUnitMargin))
+
+	stream.Stop()
+	s.r().Equal(1, rc.count("DELETE /papi/v1/listenKey"))
+}
+
+func (s *userDataStreamTestSuite) TestReconnectAndReseed() {
+	rc := s.mockRoutes(map[string]string{
+		"POST /papi/v1/listenKey":            `{"listenKey": "key1"}`,
+		"DELETE /papi/v1/listenKey":          `{}`,
+		"/papi/v1/um/openOrders":             `[]`,
+		"/papi/v1/cm/openOrders":             `[]`,
+		"/papi/v1/margin/openOrders":         `[]`,
+		"/papi/v1/um/conditional/openOrders": `[]`,
+		"/papi/v1/cm/conditional/openOrders": `[]`,
+	})
+	s.mockWsServe()
+	origMin := UserDataStreamReconnectMinInterval
+	UserDataStreamReconnectMinInterval = time.Millisecond
+	defer func() { UserDataStreamReconnectMinInterval = origMin }()
+
+	stream := s.client.NewUserDataStream(nil, func(err error) {})
+	s.r().NoError(stream.Start(newContext()))
+	s.r().Empty(stream.Book().Orders(BusinessUnitUM))
+
+	// the order shows up in REST during the gap and the stream drops
+	rc.setRoute("/papi/v1/um/openOrders", `[{"symbol": "BTCUSDT", "orderId": 9, "status": "NEW", "updateTime": 400}]`)
+	s.mu.Lock()
+	first := s.doneCs[0]
+	s.mu.Unlock()
+	s.closeConn(first)
+	s.r().Eventually(func() bool { return s.connections() == 2 }, time.Second, time.Millisecond)
+	s.r().Eventually(func() bool { return stream.Book().Order(BusinessUnitUM, 9) != nil }, time.Second, time.Millisecond)
+
+	// an expired listen key forces a new connection as well
+	rc.setRoute("POST /papi/v1/listenKey", `{"listenKey": "key2"}`)
+	s.lastHandler()([]byte(`{"e": "listenKeyExpired", "E": 500}`))
+	s.r().Eventually(func() bool { return s.connections() == 3 }, time.Second, time.Millisecond)
+	s.r().Eventually(func() bool { return stream.ListenKey() == "key2" }, time.Second, time.Millisecond)
+	s.r().Equal(3, rc.count("POST /papi/v1/listenKey"))
+	// the replaced key is closed
+	s.r().Equal(1, rc.count("DELETE /papi/v1/listenKey"))
+
+	stream.Stop()
+	s.r().Equal(2, rc.count("DELETE /papi/v1/listenKey"))
+}
+
+// recordingUserDataHandler implements WsUserDataHandler and records order updates
+type recordingUserDataHandler struct {
+	onOrder func(*WsFuturesOrderUpdate)
+}
+
+func (h *recordingUserDataHandler) HandleListenKeyExpired(*WsListenKeyExpired)       {}
+func (h *recordingUserDataHandler) HandleMarginBalanceUpdate(*WsMarginBalanceUpdate) {}
+func (h *recordingUserDataHandler) HandleRiskLevelChange(*WsRiskLevelChange)         {}
+func (h *recordingUserDataHandler) HandleFuturesAccountConfigUpdate(*WsFuturesAccountConfigUpdate) {
+}
+func (h *recordingUserDataHandler) HandleFuturesAccountUpdate(*WsFuturesAccountUpdate) {}
+func (h *recordingUserDataHandler) HandleFuturesOrderUpdate(e *WsFuturesOrderUpdate) {
+	h.onOrder(e)
+}
+func (h *recordingUserDataHandler) HandleMarginOrderUpdate(*WsMarginOrderUpdate)     {}
+func (h *recordingUserDataHandler) HandleLiabilityUpdate(*WsLiabilityUpdate)         {}
+func (h *recordingUserDataHandler) HandleMarginAccountUpdate(*WsMarginAccountUpdate) {}
+func (h *recordingUserDataHandler) HandleOpenOrderLossUpdate(*WsOpenOrderLossUpdate) {}
+func (h *recordingUserDataHandler) HandleConditionalOrderTradeUpdate(*WsConditionalOrderTradeUpdate) {
+}
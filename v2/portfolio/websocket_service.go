@@ -65,6 +65,9 @@ type WsUserDataEvent struct {
 
 	// TRADE_LITE
 	WsUserDataTradeLite
+
+	// CONDITIONAL_ORDER_TRADE_UPDATE
+	WsUserDataConditionalOrderTradeUpdate
 }
 
 type WsUserDataAccountConfigUpdate struct {
@@ -97,6 +100,10 @@ type WsUserDataTradeLite struct {
 	OrderID         int64    `json:"i"`
 }
 
+type WsUserDataConditionalOrderTradeUpdate struct {
+	ConditionalOrderTradeUpdate WsConditionalOrderTradeUpdate // the whole event
+}
+
 func (w *WsUserDataTradeLite) fromSimpleJson(j *simplejson.Json) (err error) {
 	w.Symbol = j.Get("s").MustString()
 	w.OriginalQty = j.Get("q").MustString()
@@ -132,6 +139,8 @@ func (e *WsUserDataEvent) UnmarshalJSON(data []byte) error {
 	switch e.Event {
 	case UserDataEventTypeTradeLite:
 		return e.WsUserDataTradeLite.fromSimpleJson(j)
+	case UserDataEventTypeConditionalOrderTradeUpdate:
+		return json.Unmarshal(data, &e.ConditionalOrderTradeUpdate)
 	case UserDataEventTypeListenKeyExpired:
 		// noting
 	default:
@@ -1,6 +1,7 @@
 package portfolio
 
 import (
+	"encoding/json"
 	"errors"
 
 	"testing"
@@ -416,6 +417,54 @@ func (s *websocketServiceTestSuite) TestWsUserDataServeTradeLite() {
 	s.testWsUserDataServe(data, expectedEvent)
 }
 
+func (s *websocketServiceTestSuite) TestWsUserDataEventConditionalOrderTradeUpdate() {
+	data := []byte(`{
+		"e":"CONDITIONAL_ORDER_TRADE_UPDATE",
+		"T":1669262908216,
+		"E":1669262908218,
+		"fs":"UM",
+		"so":{
+			"s":"BTCUSDT",
+			"c":"TEST",
+			"si":176057039,
+			"S":"SELL",
+			"st":"TRAILING_STOP_MARKET",
+			"f":"GTC",
+			"q":"0.001",
+			"p":"0",
+			"sp":"7103.04",
+			"os":"NEW",
+			"T":1568879465650,
+			"ut":1669262908197,
+			"R":false,
+			"wt":"MARK_PRICE",
+			"ps":"LONG",
+			"cp":false,
+			"AP":"7476.89",
+			"cr":"5.0",
+			"i":8886774,
+			"V":"EXPIRE_TAKER",
+			"gtd":0
+		}
+	}`)
+	event := new(WsUserDataEvent)
+	err := json.Unmarshal(data, event)
+	r := s.r()
+	r.NoError(err)
+	r.Equal(UserDataEventTypeConditionalOrderTradeUpdate, event.Event)
+	r.Equal(int64(1669262908218), event.Time)
+	r.Equal(int64(1669262908216), event.TransactionTime)
+	update := event.ConditionalOrderTradeUpdate
+	r.Equal("UM", update.Business)
+	r.Equal("BTCUSDT", update.Order.Symbol)
+	r.Equal(int64(176057039), update.Order.StrategyID)
+	r.Equal("TRAILING_STOP_MARKET", update.Order.StrategyType)
+	r.Equal("NEW", update.Order.OrderStatus)
+	r.Equal("7103.04", update.Order.StopPrice)
+	r.Equal("7476.89", update.Order.ActivationPrice)
+	r.Equal(int64(8886774), update.Order.OrderID)
+}
+
 func (s *websocketServiceTestSuite) assertTradeLite(e, a WsUserDataTradeLite) {
 	r := s.r()
 	r.Equal(e.Symbol, a.Symbol, "Symbol")
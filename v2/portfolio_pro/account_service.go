@@ -0,0 +1,43 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetAccountService get portfolio margin pro account info
+type GetAccountService struct {
+	c *Client
+}
+
+// Do send request
+func (s *GetAccountService) Do(ctx context.Context, opts ...RequestOption) (*Account, error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/sapi/v1/portfolio/account",
+		secType:  secTypeSigned,
+	}
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := new(Account)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Account define portfolio margin pro account info
+type Account struct {
+	UniMMR                string `json:"uniMMR"`        // Portfolio margin account maintenance margin rate
+	AccountEquity         string `json:"accountEquity"` // Account equity, in USD value
+	ActualEquity          string `json:"actualEquity"`  // Account equity without collateral rate, in USD value
+	AccountMaintMargin    string `json:"accountMaintMargin"`
+	AccountInitialMargin  string `json:"accountInitialMargin"`
+	TotalAvailableBalance string `json:"totalAvailableBalance"`
+	AccountStatus         string `json:"accountStatus"` // NORMAL, MARGIN_CALL, SUPPLY_MARGIN, REDUCE_ONLY, ACTIVE_LIQUIDATION, FORCE_LIQUIDATION, BANKRUPTED
+	AccountType           string `json:"accountType"`   // PM_1 for classic portfolio margin, PM_2 for portfolio margin pro
+}
@@ -0,0 +1,46 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type accountServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestAccountService(t *testing.T) {
+	suite.Run(t, new(accountServiceTestSuite))
+}
+
+func (s *accountServiceTestSuite) TestGetAccount() {
+	data := []byte(`{
+		"uniMMR": "5167.92171923",
+		"accountEquity": "122607.35137903",
+		"actualEquity": "142423.35137903",
+		"accountMaintMargin": "23.72469206",
+		"accountInitialMargin": "47.44938412",
+		"totalAvailableBalance": "122559.90199491",
+		"accountStatus": "NORMAL",
+		"accountType": "PM_2"
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest()
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewGetAccountService().Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal("5167.92171923", res.UniMMR)
+	s.r().Equal("122607.35137903", res.AccountEquity)
+	s.r().Equal("142423.35137903", res.ActualEquity)
+	s.r().Equal("23.72469206", res.AccountMaintMargin)
+	s.r().Equal("47.44938412", res.AccountInitialMargin)
+	s.r().Equal("122559.90199491", res.TotalAvailableBalance)
+	s.r().Equal("NORMAL", res.AccountStatus)
+	s.r().Equal("PM_2", res.AccountType)
+}
@@ -0,0 +1,32 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type autoRepayFuturesSwitchServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestAutoRepayFuturesSwitchService(t *testing.T) {
+	suite.Run(t, new(autoRepayFuturesSwitchServiceTestSuite))
+}
+
+func (s *autoRepayFuturesSwitchServiceTestSuite) TestChangeAutoRepayFuturesStatus() {
+	data := []byte(`{"msg": "success"}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"autoRepay": false,
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewChangeAutoRepayFuturesStatusService().AutoRepay(false).Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal("success", res.Msg)
+}
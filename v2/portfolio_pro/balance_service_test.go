@@ -0,0 +1,54 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type balanceServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestBalanceService(t *testing.T) {
+	suite.Run(t, new(balanceServiceTestSuite))
+}
+
+func (s *balanceServiceTestSuite) TestGetBalance() {
+	data := []byte(`[{
+		"asset": "BTC",
+		"totalWalletBalance": "100",
+		"crossMarginAsset": "100",
+		"crossMarginBorrowed": "0",
+		"crossMarginFree": "100",
+		"crossMarginInterest": "0",
+		"crossMarginLocked": "0",
+		"umWalletBalance": "0",
+		"umUnrealizedPNL": "0",
+		"cmWalletBalance": "0",
+		"cmUnrealizedPNL": "0",
+		"updateTime": 0,
+		"negativeBalance": "0",
+		"optionWalletBalance": "0",
+		"optionEquity": "0"
+	}]`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"asset": "BTC",
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewGetBalanceService().Asset("BTC").Do(newContext())
+	s.r().NoError(err)
+	s.r().Len(res, 1)
+	s.r().Equal("BTC", res[0].Asset)
+	s.r().Equal("100", res[0].TotalWalletBalance)
+	s.r().Equal("100", res[0].CrossMarginFree)
+	s.r().Equal("0", res[0].UMWalletBalance)
+	s.r().Equal("0", res[0].NegativeBalance)
+	s.r().Equal("0", res[0].OptionEquity)
+}
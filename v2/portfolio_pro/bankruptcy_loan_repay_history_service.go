@@ -0,0 +1,85 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetBankruptcyLoanRepayHistoryService get portfolio margin pro bankruptcy loan repay history
+type GetBankruptcyLoanRepayHistoryService struct {
+	c         *Client
+	startTime *int64
+	endTime   *int64
+	current   *int64
+	size      *int64
+}
+
+// StartTime set startTime
+func (s *GetBankruptcyLoanRepayHistoryService) StartTime(startTime int64) *GetBankruptcyLoanRepayHistoryService {
+	s.startTime = &startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *GetBankruptcyLoanRepayHistoryService) EndTime(endTime int64) *GetBankruptcyLoanRepayHistoryService {
+	s.endTime = &endTime
+	return s
+}
+
+// Current set current page, starting from 1
+func (s *GetBankruptcyLoanRepayHistoryService) Current(current int64) *GetBankruptcyLoanRepayHistoryService {
+	s.current = &current
+	return s
+}
+
+// Size set page size, default 10, max 100
+func (s *GetBankruptcyLoanRepayHistoryService) Size(size int64) *GetBankruptcyLoanRepayHistoryService {
+	s.size = &size
+	return s
+}
+
+// Do send request
+func (s *GetBankruptcyLoanRepayHistoryService) Do(ctx context.Context, opts ...RequestOption) (*BankruptcyLoanRepayHistory, error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/sapi/v1/portfolio/pmloan-history",
+		secType:  secTypeSigned,
+	}
+	if s.startTime != nil {
+		r.setParam("startTime", *s.startTime)
+	}
+	if s.endTime != nil {
+		r.setParam("endTime", *s.endTime)
+	}
+	if s.current != nil {
+		r.setParam("current", *s.current)
+	}
+	if s.size != nil {
+		r.setParam("size", *s.size)
+	}
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := new(BankruptcyLoanRepayHistory)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// BankruptcyLoanRepayHistory define a page of bankruptcy loan repayments
+type BankruptcyLoanRepayHistory struct {
+	Total int64                      `json:"total"`
+	Rows  []*BankruptcyLoanRepayment `json:"rows"`
+}
+
+// BankruptcyLoanRepayment define a bankruptcy loan repayment
+type BankruptcyLoanRepayment struct {
+	Amount    string `json:"amount"`
+	Asset     string `json:"asset"`
+	RepayTime int64  `json:"repayTime"`
+	TranID    int64  `json:"tranId"`
+}
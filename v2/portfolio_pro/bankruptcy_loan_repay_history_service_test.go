@@ -0,0 +1,50 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type bankruptcyLoanRepayHistoryServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestBankruptcyLoanRepayHistoryService(t *testing.T) {
+	suite.Run(t, new(bankruptcyLoanRepayHistoryServiceTestSuite))
+}
+
+func (s *bankruptcyLoanRepayHistoryServiceTestSuite) TestGetBankruptcyLoanRepayHistory() {
+	data := []byte(`{
+		"total": 1,
+		"rows": [
+			{"amount": "10.00000000", "asset": "USDT", "repayTime": 1600000000000, "tranId": 1}
+		]
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"startTime": int64(1500000000000),
+			"endTime":   int64(1700000000000),
+			"current":   int64(1),
+			"size":      int64(10),
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewGetBankruptcyLoanRepayHistoryService().
+		StartTime(1500000000000).
+		EndTime(1700000000000).
+		Current(1).
+		Size(10).
+		Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal(int64(1), res.Total)
+	s.r().Len(res.Rows, 1)
+	s.r().Equal("10.00000000", res.Rows[0].Amount)
+	s.r().Equal("USDT", res.Rows[0].Asset)
+	s.r().Equal(int64(1600000000000), res.Rows[0].RepayTime)
+	s.r().Equal(int64(1), res.Rows[0].TranID)
+}
@@ -0,0 +1,37 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetBankruptcyLoanService get portfolio margin pro bankruptcy loan amount
+type GetBankruptcyLoanService struct {
+	c *Client
+}
+
+// Do send request
+func (s *GetBankruptcyLoanService) Do(ctx context.Context, opts ...RequestOption) (*BankruptcyLoan, error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/sapi/v1/portfolio/pmLoan",
+		secType:  secTypeSigned,
+	}
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := new(BankruptcyLoan)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// BankruptcyLoan define the bankruptcy loan of the account
+type BankruptcyLoan struct {
+	Asset  string `json:"asset"`
+	Amount string `json:"amount"` // total repayment amount, in USD
+}
@@ -0,0 +1,31 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type bankruptcyLoanServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestBankruptcyLoanService(t *testing.T) {
+	suite.Run(t, new(bankruptcyLoanServiceTestSuite))
+}
+
+func (s *bankruptcyLoanServiceTestSuite) TestGetBankruptcyLoan() {
+	data := []byte(`{"asset": "BUSD", "amount": "579.45"}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest()
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewGetBankruptcyLoanService().Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal("BUSD", res.Asset)
+	s.r().Equal("579.45", res.Amount)
+}
@@ -0,0 +1,36 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type bnbTransferServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestBNBTransferService(t *testing.T) {
+	suite.Run(t, new(bnbTransferServiceTestSuite))
+}
+
+func (s *bnbTransferServiceTestSuite) TestBNBTransfer() {
+	data := []byte(`{"tranId": 100000001}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"amount":       "1.5",
+			"transferSide": TransferSideToUM,
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewBNBTransferService().
+		Amount("1.5").
+		TransferSide(TransferSideToUM).
+		Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal(int64(100000001), res.TranID)
+}
@@ -233,3 +233,78 @@ func (c *Client) NewMintBFUSDService() *MintBFUSDService {
 func (c *Client) NewRedeemBFUSDService() *RedeemBFUSDService {
 	return &RedeemBFUSDService{c: c}
 }
+
+// NewGetAccountService init get account service
+func (c *Client) NewGetAccountService() *GetAccountService {
+	return &GetAccountService{c: c}
+}
+
+// NewGetBalanceService init get balance service
+func (c *Client) NewGetBalanceService() *GetBalanceService {
+	return &GetBalanceService{c: c}
+}
+
+// NewGetCollateralRateService init get collateral rate service
+func (c *Client) NewGetCollateralRateService() *GetCollateralRateService {
+	return &GetCollateralRateService{c: c}
+}
+
+// NewGetTieredCollateralRateService init get tiered collateral rate service
+func (c *Client) NewGetTieredCollateralRateService() *GetTieredCollateralRateService {
+	return &GetTieredCollateralRateService{c: c}
+}
+
+// NewGetBankruptcyLoanService init get bankruptcy loan service
+func (c *Client) NewGetBankruptcyLoanService() *GetBankruptcyLoanService {
+	return &GetBankruptcyLoanService{c: c}
+}
+
+// NewRepayBankruptcyLoanService init repay bankruptcy loan service
+func (c *Client) NewRepayBankruptcyLoanService() *RepayBankruptcyLoanService {
+	return &RepayBankruptcyLoanService{c: c}
+}
+
+// NewGetBankruptcyLoanRepayHistoryService init get bankruptcy loan repay history service
+func (c *Client) NewGetBankruptcyLoanRepayHistoryService() *GetBankruptcyLoanRepayHistoryService {
+	return &GetBankruptcyLoanRepayHistoryService{c: c}
+}
+
+// NewGetInterestHistoryService init get interest history service
+func (c *Client) NewGetInterestHistoryService() *GetInterestHistoryService {
+	return &GetInterestHistoryService{c: c}
+}
+
+// NewEarnAssetTransferService init earn asset transfer service
+func (c *Client) NewEarnAssetTransferService() *EarnAssetTransferService {
+	return &EarnAssetTransferService{c: c}
+}
+
+// NewFundAutoCollectionService init fund auto collection service
+func (c *Client) NewFundAutoCollectionService() *FundAutoCollectionService {
+	return &FundAutoCollectionService{c: c}
+}
+
+// NewFundCollectionByAssetService init fund collection by asset service
+func (c *Client) NewFundCollectionByAssetService() *FundCollectionByAssetService {
+	return &FundCollectionByAssetService{c: c}
+}
+
+// NewBNBTransferService init BNB transfer service
+func (c *Client) NewBNBTransferService() *BNBTransferService {
+	return &BNBTransferService{c: c}
+}
+
+// NewGetAutoRepayFuturesStatusService init get auto repay futures status service
+func (c *Client) NewGetAutoRepayFuturesStatusService() *GetAutoRepayFuturesStatusService {
+	return &GetAutoRepayFuturesStatusService{c: c}
+}
+
+// NewChangeAutoRepayFuturesStatusService init change auto repay futures status service
+func (c *Client) NewChangeAutoRepayFuturesStatusService() *ChangeAutoRepayFuturesStatusService {
+	return &ChangeAutoRepayFuturesStatusService{c: c}
+}
+
+// NewRepayFuturesNegativeBalanceService init repay futures negative balance service
+func (c *Client) NewRepayFuturesNegativeBalanceService() *RepayFuturesNegativeBalanceService {
+	return &RepayFuturesNegativeBalanceService{c: c}
+}
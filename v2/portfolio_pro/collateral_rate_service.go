@@ -0,0 +1,37 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetCollateralRateService get portfolio margin pro collateral rate of every asset
+type GetCollateralRateService struct {
+	c *Client
+}
+
+// Do send request
+func (s *GetCollateralRateService) Do(ctx context.Context, opts ...RequestOption) ([]*CollateralRate, error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/sapi/v1/portfolio/collateralRate",
+		secType:  secTypeAPIKey,
+	}
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*CollateralRate, 0)
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CollateralRate define the collateral rate of an asset
+type CollateralRate struct {
+	Asset          string `json:"asset"`
+	CollateralRate string `json:"collateralRate"`
+}
@@ -0,0 +1,37 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type collateralRateServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestCollateralRateService(t *testing.T) {
+	suite.Run(t, new(collateralRateServiceTestSuite))
+}
+
+func (s *collateralRateServiceTestSuite) TestGetCollateralRate() {
+	data := []byte(`[
+		{"asset": "USDC", "collateralRate": "1.0000"},
+		{"asset": "BNB", "collateralRate": "0.9500"}
+	]`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newRequest()
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewGetCollateralRateService().Do(newContext())
+	s.r().NoError(err)
+	s.r().Len(res, 2)
+	s.r().Equal("USDC", res[0].Asset)
+	s.r().Equal("1.0000", res[0].CollateralRate)
+	s.r().Equal("BNB", res[1].Asset)
+	s.r().Equal("0.9500", res[1].CollateralRate)
+}
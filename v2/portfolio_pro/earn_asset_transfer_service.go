@@ -0,0 +1,61 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Constants for earn asset transfer type
+const (
+	EarnAssetTransferTypeEarnToFuture = "EARN_TO_FUTURE"
+	EarnAssetTransferTypeFutureToEarn = "FUTURE_TO_EARN"
+)
+
+// EarnAssetTransferService transfer LDUSDT or BFUSD between the earn and the futures account
+type EarnAssetTransferService struct {
+	c            *Client
+	asset        string
+	transferType string
+	amount       string
+}
+
+// Asset set asset, LDUSDT or BFUSD
+func (s *EarnAssetTransferService) Asset(asset string) *EarnAssetTransferService {
+	s.asset = asset
+	return s
+}
+
+// TransferType set transfer type
+func (s *EarnAssetTransferService) TransferType(transferType string) *EarnAssetTransferService {
+	s.transferType = transferType
+	return s
+}
+
+// Amount set amount
+func (s *EarnAssetTransferService) Amount(amount string) *EarnAssetTransferService {
+	s.amount = amount
+	return s
+}
+
+// Do send request
+func (s *EarnAssetTransferService) Do(ctx context.Context, opts ...RequestOption) (*SuccessResponse, error) {
+	r := &request{
+		method:   http.MethodPost,
+		endpoint: "/sapi/v1/portfolio/earn-asset-transfer",
+		secType:  secTypeSigned,
+	}
+	r.setParam("asset", s.asset)
+	r.setParam("transferType", s.transferType)
+	r.setParam("amount", s.amount)
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := new(SuccessResponse)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,38 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type earnAssetTransferServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestEarnAssetTransferService(t *testing.T) {
+	suite.Run(t, new(earnAssetTransferServiceTestSuite))
+}
+
+func (s *earnAssetTransferServiceTestSuite) TestEarnAssetTransfer() {
+	data := []byte(`{"msg": "success"}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"asset":        "BFUSD",
+			"transferType": EarnAssetTransferTypeEarnToFuture,
+			"amount":       "100",
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewEarnAssetTransferService().
+		Asset("BFUSD").
+		TransferType(EarnAssetTransferTypeEarnToFuture).
+		Amount("100").
+		Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal("success", res.Msg)
+}
@@ -0,0 +1,36 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// FundAutoCollectionService transfers all assets from the futures account to the margin account
+type FundAutoCollectionService struct {
+	c *Client
+}
+
+// Do send request
+func (s *FundAutoCollectionService) Do(ctx context.Context, opts ...RequestOption) (*SuccessResponse, error) {
+	r := &request{
+		method:   http.MethodPost,
+		endpoint: "/sapi/v1/portfolio/auto-collection",
+		secType:  secTypeSigned,
+	}
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := new(SuccessResponse)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SuccessResponse define success response
+type SuccessResponse struct {
+	Msg string `json:"msg"`
+}
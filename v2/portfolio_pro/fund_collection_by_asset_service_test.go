@@ -0,0 +1,32 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type fundCollectionByAssetServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestFundCollectionByAssetService(t *testing.T) {
+	suite.Run(t, new(fundCollectionByAssetServiceTestSuite))
+}
+
+func (s *fundCollectionByAssetServiceTestSuite) TestFundCollectionByAsset() {
+	data := []byte(`{"msg": "success"}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"asset": "USDT",
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewFundCollectionByAssetService().Asset("USDT").Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal("success", res.Msg)
+}
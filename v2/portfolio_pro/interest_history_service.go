@@ -0,0 +1,80 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetInterestHistoryService get portfolio margin pro negative balance interest history
+type GetInterestHistoryService struct {
+	c         *Client
+	asset     *string
+	startTime *int64
+	endTime   *int64
+	size      *int64
+}
+
+// Asset set asset
+func (s *GetInterestHistoryService) Asset(asset string) *GetInterestHistoryService {
+	s.asset = &asset
+	return s
+}
+
+// StartTime set startTime
+func (s *GetInterestHistoryService) StartTime(startTime int64) *GetInterestHistoryService {
+	s.startTime = &startTime
+	return s
+}
+
+// EndTime set endTime
+func (s *GetInterestHistoryService) EndTime(endTime int64) *GetInterestHistoryService {
+	s.endTime = &endTime
+	return s
+}
+
+// Size set size, default 10, max 100
+func (s *GetInterestHistoryService) Size(size int64) *GetInterestHistoryService {
+	s.size = &size
+	return s
+}
+
+// Do send request
+func (s *GetInterestHistoryService) Do(ctx context.Context, opts ...RequestOption) ([]*Interest, error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/sapi/v1/portfolio/interest-history",
+		secType:  secTypeSigned,
+	}
+	if s.asset != nil {
+		r.setParam("asset", *s.asset)
+	}
+	if s.startTime != nil {
+		r.setParam("startTime", *s.startTime)
+	}
+	if s.endTime != nil {
+		r.setParam("endTime", *s.endTime)
+	}
+	if s.size != nil {
+		r.setParam("size", *s.size)
+	}
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Interest, 0)
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Interest define an interest charged on a negative balance
+type Interest struct {
+	Asset               string `json:"asset"`
+	Interest            string `json:"interest"`
+	InterestAccruedTime int64  `json:"interestAccruedTime"`
+	InterestRate        string `json:"interestRate"`
+	Principal           string `json:"principal"`
+}
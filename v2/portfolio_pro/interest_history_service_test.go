@@ -0,0 +1,51 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type interestHistoryServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestInterestHistoryService(t *testing.T) {
+	suite.Run(t, new(interestHistoryServiceTestSuite))
+}
+
+func (s *interestHistoryServiceTestSuite) TestGetInterestHistory() {
+	data := []byte(`[{
+		"asset": "USDT",
+		"interest": "24.4440",
+		"interestAccruedTime": 1670227200000,
+		"interestRate": "0.0001",
+		"principal": "244440"
+	}]`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"asset":     "USDT",
+			"startTime": int64(1670000000000),
+			"endTime":   int64(1680000000000),
+			"size":      int64(100),
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewGetInterestHistoryService().
+		Asset("USDT").
+		StartTime(1670000000000).
+		EndTime(1680000000000).
+		Size(100).
+		Do(newContext())
+	s.r().NoError(err)
+	s.r().Len(res, 1)
+	s.r().Equal("USDT", res[0].Asset)
+	s.r().Equal("24.4440", res[0].Interest)
+	s.r().Equal(int64(1670227200000), res[0].InterestAccruedTime)
+	s.r().Equal("0.0001", res[0].InterestRate)
+	s.r().Equal("244440", res[0].Principal)
+}
@@ -0,0 +1,46 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// RepayBankruptcyLoanService repay portfolio margin pro bankruptcy loan
+type RepayBankruptcyLoanService struct {
+	c    *Client
+	from *string
+}
+
+// From set the account the repayment is taken from, SPOT or MARGIN, SPOT by default
+func (s *RepayBankruptcyLoanService) From(from string) *RepayBankruptcyLoanService {
+	s.from = &from
+	return s
+}
+
+// Do send request
+func (s *RepayBankruptcyLoanService) Do(ctx context.Context, opts ...RequestOption) (*RepayBankruptcyLoanResponse, error) {
+	r := &request{
+		method:   http.MethodPost,
+		endpoint: "/sapi/v1/portfolio/repay",
+		secType:  secTypeSigned,
+	}
+	if s.from != nil {
+		r.setParam("from", *s.from)
+	}
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := new(RepayBankruptcyLoanResponse)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RepayBankruptcyLoanResponse define repay bankruptcy loan response
+type RepayBankruptcyLoanResponse struct {
+	TranID int64 `json:"tranId"`
+}
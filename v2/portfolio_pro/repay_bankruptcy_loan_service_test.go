@@ -0,0 +1,32 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type repayBankruptcyLoanServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestRepayBankruptcyLoanService(t *testing.T) {
+	suite.Run(t, new(repayBankruptcyLoanServiceTestSuite))
+}
+
+func (s *repayBankruptcyLoanServiceTestSuite) TestRepayBankruptcyLoan() {
+	data := []byte(`{"tranId": 58203331886213504}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setParams(params{
+			"from": "MARGIN",
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewRepayBankruptcyLoanService().From("MARGIN").Do(newContext())
+	s.r().NoError(err)
+	s.r().Equal(int64(58203331886213504), res.TranID)
+}
@@ -0,0 +1,45 @@
+package portfolio_pro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// GetTieredCollateralRateService get portfolio margin pro tiered collateral rate of every asset
+type GetTieredCollateralRateService struct {
+	c *Client
+}
+
+// Do send request
+func (s *GetTieredCollateralRateService) Do(ctx context.Context, opts ...RequestOption) ([]*TieredCollateralRate, error) {
+	r := &request{
+		method:   http.MethodGet,
+		endpoint: "/sapi/v2/portfolio/collateralRate",
+		secType:  secTypeSigned,
+	}
+	data, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*TieredCollateralRate, 0)
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// TieredCollateralRate define the collateral rate tiers of an asset
+type TieredCollateralRate struct {
+	Asset          string            `json:"asset"`
+	CollateralInfo []*CollateralTier `json:"collateralInfo"`
+}
+
+// CollateralTier define the collateral rate applied to the asset amount between TierFloor and TierCap
+type CollateralTier struct {
+	TierFloor      string `json:"tierFloor"`
+	TierCap        string `json:"tierCap"`
+	CollateralRate string `json:"collateralRate"`
+	Cum            string `json:"cum"` // cumulative collateral of the lower tiers, in USD
+}
@@ -0,0 +1,42 @@
+package portfolio_pro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type tieredCollateralRateServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestTieredCollateralRateService(t *testing.T) {
+	suite.Run(t, new(tieredCollateralRateServiceTestSuite))
+}
+
+func (s *tieredCollateralRateServiceTestSuite) TestGetTieredCollateralRate() {
+	data := []byte(`[{
+		"asset": "BNB",
+		"collateralInfo": [
+			{"tierFloor": "0.0000", "tierCap": "1000.0000", "collateralRate": "1.0000", "cum": "0.0000"},
+			{"tierFloor": "1000.0000", "tierCap": "2000.0000", "collateralRate": "0.9000", "cum": "0.0000"}
+		]
+	}]`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	s.assertReq(func(r *request) {
+		e := newSignedRequest()
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewGetTieredCollateralRateService().Do(newContext())
+	s.r().NoError(err)
+	s.r().Len(res, 1)
+	s.r().Equal("BNB", res[0].Asset)
+	s.r().Len(res[0].CollateralInfo, 2)
+	s.r().Equal("1000.0000", res[0].CollateralInfo[1].TierFloor)
+	s.r().Equal("2000.0000", res[0].CollateralInfo[1].TierCap)
+	s.r().Equal("0.9000", res[0].CollateralInfo[1].CollateralRate)
+	s.r().Equal("0.0000", res[0].CollateralInfo[1].Cum)
+}
@@ -0,0 +1,530 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// Position sides of USD-M and COIN-M futures
+const (
+	positionSideBoth  = "BOTH"
+	positionSideLong  = "LONG"
+	positionSideShort = "SHORT"
+)
+
+// PositionState define a futures position by symbol and position side, with
+// the metrics derived from its mark price
+type PositionState struct {
+	Symbol string
+	// PositionSide is BOTH in one-way mode, LONG or SHORT in hedge mode
+	PositionSide string
+	// Amount is signed, in base asset for USD-M and in contracts for COIN-M
+	Amount           string
+	EntryPrice       string
+	BreakEvenPrice   string
+	MarkPrice        string
+	MarginType       string // cross or isolated
+	IsolatedWallet   string
+	Leverage         int
+	LiquidationPrice string // updated by Sync only
+	// RealizedPnL is the accumulated realized profit of the user data events
+	RealizedPnL string
+	UpdateTime  int64
+
+	// UnrealizedPnL is in quote asset for USD-M and in margin asset for COIN-M
+	UnrealizedPnL string
+	// Notional is in quote asset for USD-M and in margin asset for COIN-M
+	Notional string
+	// LiquidationDistance is the distance from the mark price to the
+	// liquidation price relative to the mark price, empty when unknown
+	LiquidationDistance string
+	// Bracket is the leverage bracket of the position, 0 when unknown
+	Bracket int
+	// MaxLeverage is the initial leverage of the bracket
+	MaxLeverage int
+	// BracketUsage is the share of the bracket cap used by the notional of the position
+	BracketUsage string
+}
+
+// PositionBracket define a leverage bracket, its caps and floors are
+// notionals in quote asset for USD-M and in base asset for COIN-M
+type PositionBracket struct {
+	Bracket          int
+	InitialLeverage  int
+	Cap              string
+	Floor            string
+	MaintMarginRatio string
+}
+
+// PositionVenue query the positions of a futures market and place the orders flattening them
+type PositionVenue interface {
+	Positions(ctx context.Context) ([]*PositionState, error)
+	DualSidePosition(ctx context.Context) (bool, error)
+	LeverageBrackets(ctx context.Context) (map[string][]*PositionBracket, error)
+	// ContractSize return the quote value of a contract of an inverse
+	// symbol, zero for a linear symbol
+	ContractSize(symbol string) decimal.Decimal
+	PlaceOrder(ctx context.Context, order *ContingentOrder) (*ChildOrderStatus, error)
+}
+
+type futuresPositionVenue struct {
+	futuresContingentVenue
+}
+
+// FuturesPositionVenue return the venue of USD-M futures positions
+func FuturesPositionVenue(c *futures.Client) PositionVenue {
+	return &futuresPositionVenue{futuresContingentVenue{futuresExecutionVenue{c: c}}}
+}
+
+func (v *futuresPositionVenue) Positions(ctx context.Context) ([]*PositionState, error) {
+	res, err := v.c.NewGetPositionRiskService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]*PositionState, 0, len(res))
+	for _, p := range res {
+		leverage, _ := strconv.Atoi(p.Leverage)
+		positions = append(positions, &PositionState{
+			Symbol:           p.Symbol,
+			PositionSide:     p.PositionSide,
+			Amount:           p.PositionAmt,
+			EntryPrice:       p.EntryPrice,
+			BreakEvenPrice:   p.BreakEvenPrice,
+			MarkPrice:        p.MarkPrice,
+			MarginType:       p.MarginType,
+			IsolatedWallet:   p.IsolatedWallet,
+			Leverage:         leverage,
+			LiquidationPrice: p.LiquidationPrice,
+		})
+	}
+	return positions, nil
+}
+
+func (v *futuresPositionVenue) DualSidePosition(ctx context.Context) (bool, error) {
+	res, err := v.c.NewGetPositionModeService().Do(ctx)
+	if err != nil {
+		return false, err
+	}
+	return res.DualSidePosition, nil
+}
+
+func (v *futuresPositionVenue) LeverageBrackets(ctx context.Context) (map[string][]*PositionBracket, error) {
+	res, err := v.c.NewGetLeverageBracketService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	brackets := make(map[string][]*PositionBracket, len(res))
+	for _, l := range res {
+		for _, b := range l.Brackets {
+			brackets[l.Symbol] = append(brackets[l.Symbol], &PositionBracket{
+				Bracket:          b.Bracket,
+				InitialLeverage:  b.InitialLeverage,
+				Cap:              decimal.NewFromFloat(b.NotionalCap).String(),
+				Floor:            decimal.NewFromFloat(b.NotionalFloor).String(),
+				MaintMarginRatio: decimal.NewFromFloat(b.MaintMarginRatio).String(),
+			})
+		}
+	}
+	return brackets, nil
+}
+
+func (v *futuresPositionVenue) ContractSize(symbol string) decimal.Decimal {
+	return decimal.Zero
+}
+
+type deliveryPositionVenue struct {
+	deliveryContingentVenue
+	contractSizes map[string]int
+}
+
+// DeliveryPositionVenue return the venue of COIN-M futures positions, the
+// contract sizes by symbol are those of the exchange info
+func DeliveryPositionVenue(c *delivery.Client, contractSizes map[string]int) PositionVenue {
+	return &deliveryPositionVenue{deliveryContingentVenue{deliveryExecutionVenue{c: c}}, contractSizes}
+}
+
+func (v *deliveryPositionVenue) Positions(ctx context.Context) ([]*PositionState, error) {
+	res, err := v.c.NewGetPositionRiskService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]*PositionState, 0, len(res))
+	for _, p := range res {
+		leverage, _ := strconv.Atoi(p.Leverage)
+		positions = append(positions, &PositionState{
+			Symbol:           p.Symbol,
+			PositionSide:     p.PositionSide,
+			Amount:           p.PositionAmt,
+			EntryPrice:       p.EntryPrice,
+			BreakEvenPrice:   p.BreakEvenPrice,
+			MarkPrice:        p.MarkPrice,
+			MarginType:       p.MarginType,
+			IsolatedWallet:   p.IsolatedMargin,
+			Leverage:         leverage,
+			LiquidationPrice: p.LiquidationPrice,
+		})
+	}
+	return positions, nil
+}
+
+func (v *deliveryPositionVenue) DualSidePosition(ctx context.Context) (bool, error) {
+	res, err := v.c.NewGetPositionModeService().Do(ctx)
+	if err != nil {
+		return false, err
+	}
+	return res.DualSidePosition, nil
+}
+
+func (v *deliveryPositionVenue) LeverageBrackets(ctx context.Context) (map[string][]*PositionBracket, error) {
+	res, err := v.c.NewGetLeverageBracketService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	brackets := make(map[string][]*PositionBracket, len(res))
+	for _, l := range res {
+		for _, b := range l.Brackets {
+			brackets[l.Symbol] = append(brackets[l.Symbol], &PositionBracket{
+				Bracket:          b.Bracket,
+				InitialLeverage:  b.InitialLeverage,
+				Cap:              decimal.NewFromFloat(b.QtyCap).String(),
+				Floor:            decimal.NewFromFloat(b.QtyFloor).String(),
+				MaintMarginRatio: decimal.NewFromFloat(b.MaintMarginRatio).String(),
+			})
+		}
+	}
+	return brackets, nil
+}
+
+func (v *deliveryPositionVenue) ContractSize(symbol string) decimal.Decimal {
+	return decimal.NewFromInt(int64(v.contractSizes[symbol]))
+}
+
+type positionKey struct {
+	symbol string
+	side   string
+}
+
+// PositionManager keep the live positions of a futures market by symbol and
+// position side. Sync load the positions, the position mode and the leverage
+// brackets, the user data events and the mark prices keep them up to date.
+type PositionManager struct {
+	// OnChange is called with a copy of a position every time it changes
+	OnChange func(position *PositionState)
+
+	venue     PositionVenue
+	mu        sync.Mutex
+	dualSide  bool
+	positions map[positionKey]*PositionState
+	brackets  map[string][]*PositionBracket
+}
+
+// NewPositionManager init a position manager of the positions of venue
+func NewPositionManager(venue PositionVenue) *PositionManager {
+	return &PositionManager{
+		venue:     venue,
+		positions: make(map[positionKey]*PositionState),
+		brackets:  make(map[string][]*PositionBracket),
+	}
+}
+
+// Sync load the position mode, the leverage brackets and the positions
+func (m *PositionManager) Sync(ctx context.Context) error {
+	dualSide, err := m.venue.DualSidePosition(ctx)
+	if err != nil {
+		return err
+	}
+	brackets, err := m.venue.LeverageBrackets(ctx)
+	if err != nil {
+		return err
+	}
+	positions, err := m.venue.Positions(ctx)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.dualSide = dualSide
+	m.brackets = brackets
+	m.positions = make(map[positionKey]*PositionState, len(positions))
+	changed := make([]*PositionState, 0, len(positions))
+	for _, p := range positions {
+		m.refresh(p)
+		m.positions[positionKey{p.Symbol, p.PositionSide}] = p
+		res := *p
+		changed = append(changed, &res)
+	}
+	m.mu.Unlock()
+	m.changed(changed)
+	return nil
+}
+
+// DualSidePosition return whether the account is in hedge mode
+func (m *PositionManager) DualSidePosition() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dualSide
+}
+
+// Position return a copy of the position of a symbol and position side, nil if unknown
+func (m *PositionManager) Position(symbol, positionSide string) *PositionState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.positions[positionKey{symbol, positionSide}]
+	if !ok {
+		return nil
+	}
+	res := *p
+	return &res
+}
+
+// Positions return a copy of the open positions, sorted by symbol and position side
+func (m *PositionManager) Positions() []*PositionState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]*PositionState, 0, len(m.positions))
+	for _, p := range m.positions {
+		if !decimalOrZero(p.Amount).IsZero() {
+			position := *p
+			res = append(res, &position)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Symbol != res[j].Symbol {
+			return res[i].Symbol < res[j].Symbol
+		}
+		return res[i].PositionSide < res[j].PositionSide
+	})
+	return res
+}
+
+// HandleFuturesUserDataEvent apply the ACCOUNT_UPDATE and ACCOUNT_CONFIG_UPDATE events of USD-M futures
+func (m *PositionManager) HandleFuturesUserDataEvent(event *futures.WsUserDataEvent) {
+	switch event.Event {
+	case futures.UserDataEventTypeAccountUpdate:
+		positions := make([]*PositionState, len(event.AccountUpdate.Positions))
+		for i, p := range event.AccountUpdate.Positions {
+			positions[i] = &PositionState{
+				Symbol:         p.Symbol,
+				PositionSide:   string(p.Side),
+				Amount:         p.Amount,
+				EntryPrice:     p.EntryPrice,
+				BreakEvenPrice: p.BreakEvenPrice,
+				MarginType:     string(p.MarginType),
+				IsolatedWallet: p.IsolatedWallet,
+				RealizedPnL:    p.AccumulatedRealized,
+				UpdateTime:     event.TransactionTime,
+			}
+		}
+		m.update(positions)
+	case futures.UserDataEventTypeAccountConfigUpdate:
+		u := event.AccountConfigUpdate
+		if u.Symbol == "" {
+			return
+		}
+		var changed []*PositionState
+		m.mu.Lock()
+		for key, p := range m.positions {
+			if key.symbol == u.Symbol {
+				p.Leverage = int(u.Leverage)
+				res := *p
+				changed = append(changed, &res)
+			}
+		}
+		m.mu.Unlock()
+		m.changed(changed)
+	}
+}
+
+// HandleDeliveryUserDataEvent apply the ACCOUNT_UPDATE events of COIN-M futures
+func (m *PositionManager) HandleDeliveryUserDataEvent(event *delivery.WsUserDataEvent) {
+	if event.Event != delivery.UserDataEventTypeAccountUpdate {
+		return
+	}
+	positions := make([]*PositionState, len(event.AccountUpdate.Positions))
+	for i, p := range event.AccountUpdate.Positions {
+		positions[i] = &PositionState{
+			Symbol:         p.Symbol,
+			PositionSide:   string(p.Side),
+			Amount:         p.Amount,
+			EntryPrice:     p.EntryPrice,
+			BreakEvenPrice: p.BreakEvenPrice,
+			MarginType:     string(p.MarginType),
+			IsolatedWallet: p.IsolatedWallet,
+			RealizedPnL:    p.AccumulatedRealized,
+			UpdateTime:     event.TransactionTime,
+		}
+	}
+	m.update(positions)
+}
+
+// update merge the positions of an account update, the fields they don't
+// carry are kept
+func (m *PositionManager) update(positions []*PositionState) {
+	changed := make([]*PositionState, 0, len(positions))
+	m.mu.Lock()
+	for _, u := range positions {
+		key := positionKey{u.Symbol, u.PositionSide}
+		p, ok := m.positions[key]
+		if !ok {
+			p = &PositionState{Symbol: u.Symbol, PositionSide: u.PositionSide}
+			m.positions[key] = p
+		}
+		if u.UpdateTime < p.UpdateTime {
+			continue
+		}
+		if !decimalOrZero(u.Amount).Equal(decimalOrZero(p.Amount)) {
+			// the liquidation price of the previous amount is stale
+			p.LiquidationPrice = ""
+		}
+		p.Amount = u.Amount
+		p.EntryPrice = u.EntryPrice
+		p.BreakEvenPrice = u.BreakEvenPrice
+		if p.BreakEvenPrice == "" {
+			p.BreakEvenPrice = u.EntryPrice
+		}
+		p.MarginType = u.MarginType
+		p.IsolatedWallet = u.IsolatedWallet
+		p.RealizedPnL = u.RealizedPnL
+		p.UpdateTime = u.UpdateTime
+		m.refresh(p)
+		res := *p
+		changed = append(changed, &res)
+	}
+	m.mu.Unlock()
+	m.changed(changed)
+}
+
+// HandleFuturesMarkPrice apply a USD-M futures mark price
+func (m *PositionManager) HandleFuturesMarkPrice(event *futures.WsMarkPriceEvent) {
+	m.HandleMarkPrice(event.Symbol, event.MarkPrice)
+}
+
+// HandleDeliveryMarkPrice apply a COIN-M futures mark price
+func (m *PositionManager) HandleDeliveryMarkPrice(event *delivery.WsMarkPriceEvent) {
+	m.HandleMarkPrice(event.Symbol, event.MarkPrice)
+}
+
+// HandleMarkPrice apply the mark price of a symbol to its open positions
+func (m *PositionManager) HandleMarkPrice(symbol, markPrice string) {
+	var changed []*PositionState
+	m.mu.Lock()
+	for key, p := range m.positions {
+		if key.symbol != symbol || decimalOrZero(p.Amount).IsZero() {
+			continue
+		}
+		p.MarkPrice = markPrice
+		m.refresh(p)
+		res := *p
+		changed = append(changed, &res)
+	}
+	m.mu.Unlock()
+	m.changed(changed)
+}
+
+// refresh compute the metrics of a position from its mark price, the lock must be held
+func (m *PositionManager) refresh(p *PositionState) {
+	amount := decimalOrZero(p.Amount)
+	mark := decimalOrZero(p.MarkPrice)
+	entry := decimalOrZero(p.EntryPrice)
+	p.UnrealizedPnL, p.Notional, p.LiquidationDistance, p.BracketUsage = "", "", "", ""
+	p.Bracket, p.MaxLeverage = 0, 0
+	if amount.IsZero() {
+		p.UnrealizedPnL, p.Notional = "0", "0"
+		return
+	}
+	if !mark.IsPositive() {
+		return
+	}
+	// size is the notional compared to the bracket caps
+	size := amount.Abs().Mul(mark)
+	contractSize := m.venue.ContractSize(p.Symbol)
+	if contractSize.IsPositive() {
+		size = amount.Abs().Mul(contractSize).Div(mark)
+		p.Notional = size.String()
+		if entry.IsPositive() {
+			one := decimal.NewFromInt(1)
+			p.UnrealizedPnL = amount.Mul(contractSize).Mul(one.Div(entry).Sub(one.Div(mark))).String()
+		}
+	} else {
+		p.Notional = size.String()
+		if entry.IsPositive() {
+			p.UnrealizedPnL = amount.Mul(mark.Sub(entry)).String()
+		}
+	}
+	if liquidation := decimalOrZero(p.LiquidationPrice); liquidation.IsPositive() {
+		p.LiquidationDistance = mark.Sub(liquidation).Abs().Div(mark).String()
+	}
+	for _, b := range m.brackets[p.Symbol] {
+		floor, ceiling := decimalOrZero(b.Floor), decimalOrZero(b.Cap)
+		if size.GreaterThanOrEqual(floor) && size.LessThan(ceiling) {
+			p.Bracket, p.MaxLeverage = b.Bracket, b.InitialLeverage
+			p.BracketUsage = size.Div(ceiling).String()
+			break
+		}
+	}
+}
+
+func (m *PositionManager) changed(positions []*PositionState) {
+	if m.OnChange == nil {
+		return
+	}
+	for _, p := range positions {
+		m.OnChange(p)
+	}
+}
+
+// FlattenOrders return the market orders closing the open positions of a
+// symbol, of a position side only when positionSide isn't empty. In hedge
+// mode the orders close their position side, in one-way mode they are reduce
+// only.
+func (m *PositionManager) FlattenOrders(symbol, positionSide string) []*ContingentOrder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var orders []*ContingentOrder
+	for _, side := range []string{positionSideBoth, positionSideLong, positionSideShort} {
+		p, ok := m.positions[positionKey{symbol, side}]
+		if !ok || (positionSide != "" && positionSide != side) {
+			continue
+		}
+		amount := decimalOrZero(p.Amount)
+		if amount.IsZero() {
+			continue
+		}
+		order := &ContingentOrder{Symbol: symbol, Side: SideTypeSell, Type: "MARKET", Quantity: amount.Abs().String()}
+		if amount.IsNegative() {
+			order.Side = SideTypeBuy
+		}
+		if side == positionSideBoth {
+			order.ReduceOnly = true
+		} else {
+			order.PositionSide = side
+		}
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// Flatten close the open positions of a symbol, of a position side only
+// when positionSide isn't empty, with market orders
+func (m *PositionManager) Flatten(ctx context.Context, symbol, positionSide string) ([]*ChildOrderStatus, error) {
+	orders := m.FlattenOrders(symbol, positionSide)
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("no open position for %s %s", symbol, positionSide)
+	}
+	res := make([]*ChildOrderStatus, 0, len(orders))
+	for _, o := range orders {
+		status, err := m.venue.PlaceOrder(ctx, o)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, status)
+	}
+	return res, nil
+}
@@ -0,0 +1,211 @@
+package binance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type positionManagerTestSuite struct {
+	baseTestSuite
+}
+
+func TestPositionManager(t *testing.T) {
+	suite.Run(t, new(positionManagerTestSuite))
+}
+
+type fakePositionVenue struct {
+	dualSide      bool
+	positions     []*PositionState
+	brackets      map[string][]*PositionBracket
+	contractSizes map[string]int
+	placed        []*ContingentOrder
+}
+
+func (v *fakePositionVenue) Positions(ctx context.Context) ([]*PositionState, error) {
+	res := make([]*PositionState, len(v.positions))
+	for i, p := range v.positions {
+		position := *p
+		res[i] = &position
+	}
+	return res, nil
+}
+
+func (v *fakePositionVenue) DualSidePosition(ctx context.Context) (bool, error) {
+	return v.dualSide, nil
+}
+
+func (v *fakePositionVenue) LeverageBrackets(ctx context.Context) (map[string][]*PositionBracket, error) {
+	return v.brackets, nil
+}
+
+func (v *fakePositionVenue) ContractSize(symbol string) decimal.Decimal {
+	return decimal.NewFromInt(int64(v.contractSizes[symbol]))
+}
+
+func (v *fakePositionVenue) PlaceOrder(ctx context.Context, order *ContingentOrder) (*ChildOrderStatus, error) {
+	v.placed = append(v.placed, order)
+	return &ChildOrderStatus{OrderID: int64(len(v.placed)), Status: "FILLED", ExecutedQuantity: order.Quantity}, nil
+}
+
+func newFakePositionVenue() *fakePositionVenue {
+	return &fakePositionVenue{
+		dualSide: true,
+		positions: []*PositionState{
+			{Symbol: "BTCUSDT", PositionSide: "LONG", Amount: "2", EntryPrice: "100", BreakEvenPrice: "100.1",
+				MarkPrice: "110", MarginType: "cross", Leverage: 10, LiquidationPrice: "55"},
+			{Symbol: "BTCUSDT", PositionSide: "SHORT", Amount: "-1", EntryPrice: "120", BreakEvenPrice: "119.9",
+				MarkPrice: "110", MarginType: "isolated", IsolatedWallet: "12", Leverage: 10},
+			{Symbol: "ETHUSDT", PositionSide: "LONG", Amount: "0", MarkPrice: "10", Leverage: 5},
+		},
+		brackets: map[string][]*PositionBracket{
+			"BTCUSDT": {
+				{Bracket: 1, InitialLeverage: 125, Floor: "0", Cap: "200", MaintMarginRatio: "0.004"},
+				{Bracket: 2, InitialLeverage: 100, Floor: "200", Cap: "1000", MaintMarginRatio: "0.005"},
+			},
+		},
+	}
+}
+
+func (s *positionManagerTestSuite) TestSync() {
+	m := NewPositionManager(newFakePositionVenue())
+	var changes int
+	m.OnChange = func(position *PositionState) {
+		changes++
+	}
+	r := s.r()
+	r.NoError(m.Sync(newContext()))
+	r.Equal(3, changes)
+	r.True(m.DualSidePosition())
+
+	long := m.Position("BTCUSDT", "LONG")
+	r.Equal("20", long.UnrealizedPnL)
+	r.Equal("220", long.Notional)
+	r.Equal("0.5", long.LiquidationDistance)
+	r.Equal(2, long.Bracket)
+	r.Equal(100, long.MaxLeverage)
+	r.Equal("0.22", long.BracketUsage)
+	r.Equal("100.1", long.BreakEvenPrice)
+
+	short := m.Position("BTCUSDT", "SHORT")
+	r.Equal("10", short.UnrealizedPnL)
+	r.Equal("110", short.Notional)
+	r.Equal("", short.LiquidationDistance)
+	r.Equal(1, short.Bracket)
+	r.Equal("0.55", short.BracketUsage)
+	r.Equal("isolated", short.MarginType)
+
+	positions := m.Positions()
+	r.Len(positions, 2)
+	r.Equal("LONG", positions[0].PositionSide)
+	r.Equal("SHORT", positions[1].PositionSide)
+	r.Nil(m.Position("BTCUSDT", "BOTH"))
+}
+
+func (s *positionManagerTestSuite) TestUserDataEvents() {
+	m := NewPositionManager(newFakePositionVenue())
+	r := s.r()
+	r.NoError(m.Sync(newContext()))
+
+	event := &futures.WsUserDataEvent{Event: futures.UserDataEventTypeAccountUpdate, TransactionTime: 1000}
+	event.AccountUpdate.Positions = []futures.WsPosition{{
+		Symbol: "BTCUSDT", Side: futures.PositionSideTypeLong, Amount: "3", EntryPrice: "105",
+		BreakEvenPrice: "105.2", MarginType: "cross", AccumulatedRealized: "4",
+	}}
+	m.HandleFuturesUserDataEvent(event)
+	long := m.Position("BTCUSDT", "LONG")
+	r.Equal("3", long.Amount)
+	r.Equal("105.2", long.BreakEvenPrice)
+	r.Equal("4", long.RealizedPnL)
+	r.Equal("15", long.UnrealizedPnL)
+	r.Equal("", long.LiquidationPrice)
+	r.Equal("", long.LiquidationDistance)
+
+	// events older than the state are ignored
+	event.TransactionTime = 999
+	event.AccountUpdate.Positions[0].Amount = "1"
+	m.HandleFuturesUserDataEvent(event)
+	r.Equal("3", m.Position("BTCUSDT", "LONG").Amount)
+
+	m.HandleFuturesMarkPrice(&futures.WsMarkPriceEvent{Symbol: "BTCUSDT", MarkPrice: "100"})
+	long = m.Position("BTCUSDT", "LONG")
+	r.Equal("-15", long.UnrealizedPnL)
+	r.Equal("300", long.Notional)
+	r.Equal("20", m.Position("BTCUSDT", "SHORT").UnrealizedPnL)
+
+	config := &futures.WsUserDataEvent{Event: futures.UserDataEventTypeAccountConfigUpdate}
+	config.AccountConfigUpdate = futures.WsAccountConfigUpdate{Symbol: "BTCUSDT", Leverage: 20}
+	m.HandleFuturesUserDataEvent(config)
+	r.Equal(20, m.Position("BTCUSDT", "LONG").Leverage)
+	r.Equal(20, m.Position("BTCUSDT", "SHORT").Leverage)
+}
+
+func (s *positionManagerTestSuite) TestInverse() {
+	venue := &fakePositionVenue{
+		positions: []*PositionState{
+			{Symbol: "BTCUSD_PERP", PositionSide: "BOTH", Amount: "10", EntryPrice: "50000", MarkPrice: "40000"},
+		},
+		brackets: map[string][]*PositionBracket{
+			"BTCUSD_PERP": {
+				{Bracket: 1, InitialLeverage: 125, Floor: "0", Cap: "0.02"},
+				{Bracket: 2, InitialLeverage: 100, Floor: "0.02", Cap: "0.1"},
+			},
+		},
+		contractSizes: map[string]int{"BTCUSD_PERP": 100},
+	}
+	m := NewPositionManager(venue)
+	r := s.r()
+	r.NoError(m.Sync(newContext()))
+	p := m.Position("BTCUSD_PERP", "BOTH")
+	r.Equal("-0.005", p.UnrealizedPnL)
+	r.Equal("0.025", p.Notional)
+	// the caps are in base asset
+	r.Equal(2, p.Bracket)
+	r.Equal("0.25", p.BracketUsage)
+
+	event := &delivery.WsUserDataEvent{Event: delivery.UserDataEventTypeAccountUpdate, TransactionTime: 1}
+	event.AccountUpdate.Positions = []delivery.WsPosition{{
+		Symbol: "BTCUSD_PERP", Side: delivery.PositionSideTypeBoth, Amount: "-5", EntryPrice: "50000",
+	}}
+	m.HandleDeliveryUserDataEvent(event)
+	p = m.Position("BTCUSD_PERP", "BOTH")
+	r.Equal("0.0025", p.UnrealizedPnL)
+	r.Equal("50000", p.BreakEvenPrice)
+}
+
+func (s *positionManagerTestSuite) TestFlatten() {
+	venue := newFakePositionVenue()
+	m := NewPositionManager(venue)
+	r := s.r()
+	r.NoError(m.Sync(newContext()))
+
+	res, err := m.Flatten(newContext(), "BTCUSDT", "")
+	r.NoError(err)
+	r.Len(res, 2)
+	r.Equal([]*ContingentOrder{
+		{Symbol: "BTCUSDT", Side: SideTypeSell, PositionSide: "LONG", Type: "MARKET", Quantity: "2"},
+		{Symbol: "BTCUSDT", Side: SideTypeBuy, PositionSide: "SHORT", Type: "MARKET", Quantity: "1"},
+	}, venue.placed)
+
+	r.Len(m.FlattenOrders("BTCUSDT", "SHORT"), 1)
+	_, err = m.Flatten(newContext(), "ETHUSDT", "")
+	r.Error(err)
+}
+
+func (s *positionManagerTestSuite) TestFlattenOneWay() {
+	venue := &fakePositionVenue{
+		positions: []*PositionState{{Symbol: "BTCUSDT", PositionSide: "BOTH", Amount: "-0.5", MarkPrice: "100"}},
+	}
+	m := NewPositionManager(venue)
+	r := s.r()
+	r.NoError(m.Sync(newContext()))
+	r.False(m.DualSidePosition())
+	r.Equal([]*ContingentOrder{
+		{Symbol: "BTCUSDT", Side: SideTypeBuy, Type: "MARKET", Quantity: "0.5", ReduceOnly: true},
+	}, m.FlattenOrders("BTCUSDT", ""))
+}
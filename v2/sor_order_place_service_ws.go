@@ -15,6 +15,11 @@ type SorOrderPlaceWsService struct {
 	SecretKey  string
 	KeyType    string
 	TimeOffset int64
+
+	// ClientOrderIDGenerator generate the client order ids of the orders placed
+	// without one, the generator of the client when the service is made by
+	// a Client, a generator of its own otherwise
+	ClientOrderIDGenerator *common.ClientOrderIDGenerator
 }
 
 // NewSorOrderPlaceWsService init SorOrderPlaceWsService
@@ -30,10 +35,11 @@ func NewSorOrderPlaceWsService(apiKey, secretKey string) (*SorOrderPlaceWsServic
 	}
 
 	return &SorOrderPlaceWsService{
-		c:         client,
-		ApiKey:    apiKey,
-		SecretKey: secretKey,
-		KeyType:   common.KeyTypeHmac,
+		c:                      client,
+		ApiKey:                 apiKey,
+		SecretKey:              secretKey,
+		KeyType:                common.KeyTypeHmac,
+		ClientOrderIDGenerator: common.NewClientOrderIDGenerator(common.SPOT_ORDER_PREFIX),
 	}, nil
 }
 
@@ -62,11 +68,11 @@ func NewSorOrderPlaceWsRequest() *SorOrderPlaceWsRequest {
 }
 
 func (s *SorOrderPlaceWsRequest) GetParams() map[string]interface{} {
-	return s.buildParams()
+	return s.buildParams(nil)
 }
 
 // buildParams builds params
-func (s *SorOrderPlaceWsRequest) buildParams() params {
+func (s *SorOrderPlaceWsRequest) buildParams(ids *common.ClientOrderIDGenerator) params {
 	m := params{
 		"symbol":           s.symbol,
 		"side":             s.side,
@@ -83,7 +89,7 @@ func (s *SorOrderPlaceWsRequest) buildParams() params {
 	if s.newClientOrderID != nil {
 		m["newClientOrderId"] = *s.newClientOrderID
 	} else {
-		m["newClientOrderId"] = common.GenerateSpotId()
+		m["newClientOrderId"] = newSpotClientOrderID(ids)
 	}
 	if s.icebergQty != nil {
 		m["icebergQty"] = *s.icebergQty
@@ -114,7 +120,7 @@ func (s *SorOrderPlaceWsService) Do(requestID string, request *SorOrderPlaceWsRe
 			s.KeyType,
 		),
 		websocket.SorOrderPlaceSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return err
@@ -138,7 +144,7 @@ func (s *SorOrderPlaceWsService) SyncDo(requestID string, request *SorOrderPlace
 			s.KeyType,
 		),
 		websocket.SorOrderPlaceSpotWsApiMethod,
-		request.buildParams(),
+		request.buildParams(s.ClientOrderIDGenerator),
 	)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,496 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// ErrSubAccountPolicyInvalid is returned for policies missing a wallet, an asset or a limit
+var ErrSubAccountPolicyInvalid = errors.New("invalid sub-account policy")
+
+// subAccountListPageSize is the max page size of the sub-account list
+const subAccountListPageSize = 200
+
+// Default throttle of the sub-account requests, the margin and futures
+// account requests weigh 10, and half of the 12000 SAPI weight per minute
+// of an IP is left to the other requests
+const (
+	subAccountRequestWeight   = 10
+	subAccountWeightPerMinute = 6000
+)
+
+// SubAccountWallet define a wallet of the universal transfers
+type SubAccountWallet string
+
+// SubAccountTransferStatus define the status of a transfer of a policy
+type SubAccountTransferStatus string
+
+// Global enums
+const (
+	SubAccountWalletSpot        SubAccountWallet = "SPOT"
+	SubAccountWalletMargin      SubAccountWallet = "MARGIN"
+	SubAccountWalletUSDTFutures SubAccountWallet = "USDT_FUTURE"
+	SubAccountWalletCoinFutures SubAccountWallet = "COIN_FUTURE"
+
+	SubAccountTransferStatusPlanned SubAccountTransferStatus = "PLANNED"
+	SubAccountTransferStatusSuccess SubAccountTransferStatus = "SUCCESS"
+	SubAccountTransferStatusFailed  SubAccountTransferStatus = "FAILED"
+)
+
+// SubAccountBalance define the balance of an asset in a wallet of a sub-account
+type SubAccountBalance struct {
+	Wallet SubAccountWallet `json:"wallet"`
+	Asset  string           `json:"asset"`
+	// Free is the amount that can be transferred out
+	Free  string `json:"free"`
+	Total string `json:"total"`
+}
+
+// SubAccountSnapshot define the balances of a sub-account
+type SubAccountSnapshot struct {
+	SubAccount
+	Balances []*SubAccountBalance `json:"balances"`
+	// Errors are the errors of the wallets that couldn't be fetched, e.g.
+	// margin not enabled for the sub-account
+	Errors map[SubAccountWallet]string `json:"errors,omitempty"`
+}
+
+// Balance return the balance of asset in wallet, nil when there is none
+func (s *SubAccountSnapshot) Balance(wallet SubAccountWallet, asset string) *SubAccountBalance {
+	for _, b := range s.Balances {
+		if b.Wallet == wallet && b.Asset == asset {
+			return b
+		}
+	}
+	return nil
+}
+
+// SubAccountPolicy define the balance to keep in a wallet of the sub-accounts,
+// funded from and swept to a wallet of the master account
+type SubAccountPolicy struct {
+	Name string `json:"name"`
+	// Emails are the sub-accounts of the policy, all the sub-accounts not
+	// frozen when empty
+	Emails []string         `json:"emails,omitempty"`
+	Wallet SubAccountWallet `json:"wallet"`
+	Asset  string           `json:"asset"`
+	// Min is the balance below which the wallet is topped up to Target, empty to never top up
+	Min string `json:"min,omitempty"`
+	// Max is the balance above which the wallet is swept down to Target, empty to never sweep
+	Max    string `json:"max,omitempty"`
+	Target string `json:"target"`
+	// MasterWallet is the wallet of the master account, SPOT when empty
+	MasterWallet SubAccountWallet `json:"masterWallet,omitempty"`
+}
+
+// KeepBalancePolicy return a policy topping up and sweeping wallet to amount of asset
+func KeepBalancePolicy(wallet SubAccountWallet, asset, amount string) *SubAccountPolicy {
+	return &SubAccountPolicy{
+		Name:   fmt.Sprintf("keep_%s_%s", asset, wallet),
+		Wallet: wallet, Asset: asset, Min: amount, Max: amount, Target: amount,
+	}
+}
+
+// SweepPolicy return a policy sweeping to the master account all of asset in wallet above keep
+func SweepPolicy(wallet SubAccountWallet, asset, keep string) *SubAccountPolicy {
+	return &SubAccountPolicy{
+		Name:   fmt.Sprintf("sweep_%s_%s", asset, wallet),
+		Wallet: wallet, Asset: asset, Max: keep, Target: keep,
+	}
+}
+
+func (p *SubAccountPolicy) validate() error {
+	if p.Wallet == "" || p.Asset == "" || (p.Min == "" && p.Max == "") {
+		return fmt.Errorf("%w: %s", ErrSubAccountPolicyInvalid, p.Name)
+	}
+	for _, v := range []string{p.Min, p.Max, p.Target} {
+		if _, err := decimal.NewFromString(v); v != "" && err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrSubAccountPolicyInvalid, p.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *SubAccountPolicy) applies(account *SubAccount) bool {
+	if len(p.Emails) == 0 {
+		return !account.IsFreeze
+	}
+	for _, email := range p.Emails {
+		if email == account.Email {
+			return true
+		}
+	}
+	return false
+}
+
+// SubAccountTransfer define a transfer of a policy between the master account and a sub-account
+type SubAccountTransfer struct {
+	Policy string `json:"policy"`
+	Email  string `json:"email"`
+	// FromEmail and ToEmail are empty for the master account
+	FromEmail  string           `json:"fromEmail,omitempty"`
+	ToEmail    string           `json:"toEmail,omitempty"`
+	FromWallet SubAccountWallet `json:"fromWallet"`
+	ToWallet   SubAccountWallet `json:"toWallet"`
+	Asset      string           `json:"asset"`
+	Amount     string           `json:"amount"`
+	// Balance is the balance of the wallet of the sub-account before the transfer
+	Balance      string                   `json:"balance"`
+	ClientTranID string                   `json:"clientTranId"`
+	TranID       int64                    `json:"tranId,omitempty"`
+	Status       SubAccountTransferStatus `json:"status"`
+	Error        string                   `json:"error,omitempty"`
+	Time         int64                    `json:"time,omitempty"`
+}
+
+// SubAccountTransferReport define the audit of a run of policies
+type SubAccountTransferReport struct {
+	StartTime int64                 `json:"startTime"`
+	EndTime   int64                 `json:"endTime"`
+	Transfers []*SubAccountTransfer `json:"transfers"`
+	// Skipped are the sub-accounts whose wallet of a policy couldn't be
+	// fetched, with the error, by policy name and email joined by a slash
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// Failed return the transfers that failed
+func (r *SubAccountTransferReport) Failed() []*SubAccountTransfer {
+	var res []*SubAccountTransfer
+	for _, t := range r.Transfers {
+		if t.Status == SubAccountTransferStatusFailed {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// SubAccountManager fetch the balances of all the sub-accounts of a master
+// account and run balance policies on them through universal transfers
+type SubAccountManager struct {
+	// ClientTranIDGenerator generate the client ids of the transfers, tagged with the policy names
+	ClientTranIDGenerator *common.ClientOrderIDGenerator
+
+	c           *Client
+	wallets     []SubAccountWallet
+	concurrency int
+	delay       time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewSubAccountManager init a manager of the sub-accounts of the master account of c,
+// the requests are throttled within half of the SAPI weight limit by default
+func NewSubAccountManager(c *Client) *SubAccountManager {
+	m := &SubAccountManager{
+		ClientTranIDGenerator: common.NewClientOrderIDGenerator(""),
+		c:                     c,
+		wallets: []SubAccountWallet{
+			SubAccountWalletSpot, SubAccountWalletMargin, SubAccountWalletUSDTFutures, SubAccountWalletCoinFutures,
+		},
+		concurrency: 5,
+	}
+	return m.Throttle(subAccountRequestWeight, subAccountWeightPerMinute)
+}
+
+// Wallets set the wallets fetched by Balances, all of them by default
+func (m *SubAccountManager) Wallets(wallets ...SubAccountWallet) *SubAccountManager {
+	m.wallets = wallets
+	return m
+}
+
+// Concurrency set the max number of requests in flight
+func (m *SubAccountManager) Concurrency(n int) *SubAccountManager {
+	if n > 0 {
+		m.concurrency = n
+	}
+	return m
+}
+
+// Throttle space the requests so that requests of requestWeight stay within
+// weightPerMinute, a zero requestWeight disables the throttle
+func (m *SubAccountManager) Throttle(requestWeight, weightPerMinute int) *SubAccountManager {
+	if weightPerMinute > 0 {
+		m.delay = time.Minute * time.Duration(requestWeight) / time.Duration(weightPerMinute)
+	}
+	return m
+}
+
+// wait block until the next request is allowed by the throttle
+func (m *SubAccountManager) wait(ctx context.Context) error {
+	if m.delay <= 0 {
+		return ctx.Err()
+	}
+	m.mu.Lock()
+	now := time.Now()
+	at := m.next
+	if at.Before(now) {
+		at = now
+	}
+	m.next = at.Add(m.delay)
+	m.mu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(at)):
+		return nil
+	}
+}
+
+// SubAccounts return all the sub-accounts, walking every page of the list
+func (m *SubAccountManager) SubAccounts(ctx context.Context) ([]SubAccount, error) {
+	var res []SubAccount
+	for page := 1; ; page++ {
+		if err := m.wait(ctx); err != nil {
+			return nil, err
+		}
+		list, err := m.c.NewSubAccountListService().Page(page).Limit(subAccountListPageSize).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, list.SubAccounts...)
+		if len(list.SubAccounts) < subAccountListPageSize {
+			return res, nil
+		}
+	}
+}
+
+// Balances return the balances of all the sub-accounts, the wallets of the
+// sub-accounts are fetched concurrently
+func (m *SubAccountManager) Balances(ctx context.Context) ([]*SubAccountSnapshot, error) {
+	accounts, err := m.SubAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.balances(ctx, accounts, m.wallets)
+}
+
+// balances fetch the wallets of the accounts, the errors of a wallet are
+// recorded in its snapshot but a done ctx fails the whole fetch
+func (m *SubAccountManager) balances(ctx context.Context, accounts []SubAccount, wallets []SubAccountWallet) ([]*SubAccountSnapshot, error) {
+	snapshots := make([]*SubAccountSnapshot, len(accounts))
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, m.concurrency)
+	)
+	for i := range accounts {
+		snapshot := &SubAccountSnapshot{SubAccount: accounts[i]}
+		snapshots[i] = snapshot
+		for _, wallet := range wallets {
+			wg.Add(1)
+			go func(wallet SubAccountWallet) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				var balances []*SubAccountBalance
+				err := m.wait(ctx)
+				if err == nil {
+					balances, err = m.walletBalances(ctx, snapshot.Email, wallet)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if snapshot.Errors == nil {
+						snapshot.Errors = make(map[SubAccountWallet]string)
+					}
+					snapshot.Errors[wallet] = err.Error()
+					return
+				}
+				snapshot.Balances = append(snapshot.Balances, balances...)
+			}(wallet)
+		}
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		sort.Slice(snapshot.Balances, func(i, j int) bool {
+			a, b := snapshot.Balances[i], snapshot.Balances[j]
+			if a.Wallet != b.Wallet {
+				return a.Wallet < b.Wallet
+			}
+			return a.Asset < b.Asset
+		})
+	}
+	return snapshots, nil
+}
+
+func (m *SubAccountManager) walletBalances(ctx context.Context, email string, wallet SubAccountWallet) ([]*SubAccountBalance, error) {
+	var res []*SubAccountBalance
+	switch wallet {
+	case SubAccountWalletSpot:
+		assets, err := m.c.NewSubaccountAssetsService().Email(email).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assets.Balances {
+			free, locked := decimal.NewFromFloat(a.Free), decimal.NewFromFloat(a.Locked)
+			res = append(res, &SubAccountBalance{
+				Wallet: wallet, Asset: a.Asset, Free: free.String(), Total: free.Add(locked).String(),
+			})
+		}
+	case SubAccountWalletMargin:
+		account, err := m.c.NewSubAccountMarginAccountInfoService().Email(email).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range account.MarginUserAssetVoList {
+			res = append(res, &SubAccountBalance{
+				Wallet: wallet, Asset: a.Asset, Free: a.Free,
+				Total: decimalOrZero(a.Free).Add(decimalOrZero(a.Locked)).String(),
+			})
+		}
+	case SubAccountWalletUSDTFutures, SubAccountWalletCoinFutures:
+		futuresType := int32(1)
+		if wallet == SubAccountWalletCoinFutures {
+			futuresType = 2
+		}
+		account, err := m.c.NewSubAccountFuturesAccountV2Service().Email(email).FuturesType(futuresType).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var assets []*FuturesAsset
+		if account.FutureAccountResp != nil {
+			assets = account.FutureAccountResp.Assets
+		}
+		if account.DeliveryAccountResp != nil {
+			assets = account.DeliveryAccountResp.Assets
+		}
+		for _, a := range assets {
+			res = append(res, &SubAccountBalance{
+				Wallet: wallet, Asset: a.Asset, Free: a.MaxWithdrawAmount, Total: a.WalletBalance,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported wallet %s", wallet)
+	}
+	return res, nil
+}
+
+// Plan return the transfers the policies would make, without making them
+func (m *SubAccountManager) Plan(ctx context.Context, policies ...*SubAccountPolicy) (*SubAccountTransferReport, error) {
+	for _, p := range policies {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+	}
+	report := &SubAccountTransferReport{StartTime: time.Now().UnixMilli()}
+	accounts, err := m.SubAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var wallets []SubAccountWallet
+	seen := make(map[SubAccountWallet]bool)
+	for _, p := range policies {
+		if !seen[p.Wallet] {
+			seen[p.Wallet] = true
+			wallets = append(wallets, p.Wallet)
+		}
+	}
+	snapshots, err := m.balances(ctx, accounts, wallets)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		for _, p := range policies {
+			if !p.applies(&snapshot.SubAccount) {
+				continue
+			}
+			if msg, ok := snapshot.Errors[p.Wallet]; ok {
+				if report.Skipped == nil {
+					report.Skipped = make(map[string]string)
+				}
+				report.Skipped[p.Name+"/"+snapshot.Email] = fmt.Sprintf("%s: %s", p.Wallet, msg)
+				continue
+			}
+			if t := m.planTransfer(p, snapshot); t != nil {
+				report.Transfers = append(report.Transfers, t)
+			}
+		}
+	}
+	report.EndTime = time.Now().UnixMilli()
+	return report, nil
+}
+
+// planTransfer return the transfer bringing the wallet of snapshot within the limits of p, nil when none is needed
+func (m *SubAccountManager) planTransfer(p *SubAccountPolicy, snapshot *SubAccountSnapshot) *SubAccountTransfer {
+	total, free := decimal.Zero, decimal.Zero
+	if b := snapshot.Balance(p.Wallet, p.Asset); b != nil {
+		total, free = decimalOrZero(b.Total), decimalOrZero(b.Free)
+	}
+	target := decimalOrZero(p.Target)
+	masterWallet := p.MasterWallet
+	if masterWallet == "" {
+		masterWallet = SubAccountWalletSpot
+	}
+	t := &SubAccountTransfer{
+		Policy: p.Name, Email: snapshot.Email, Asset: p.Asset, Balance: total.String(),
+		Status: SubAccountTransferStatusPlanned,
+	}
+	switch {
+	case p.Min != "" && total.LessThan(decimalOrZero(p.Min)) && target.GreaterThan(total):
+		t.ToEmail, t.FromWallet, t.ToWallet = snapshot.Email, masterWallet, p.Wallet
+		t.Amount = target.Sub(total).String()
+	case p.Max != "" && total.GreaterThan(decimalOrZero(p.Max)):
+		amount := decimal.Min(total.Sub(target), free)
+		if !amount.IsPositive() {
+			return nil
+		}
+		t.FromEmail, t.FromWallet, t.ToWallet = snapshot.Email, p.Wallet, masterWallet
+		t.Amount = amount.String()
+	default:
+		return nil
+	}
+	t.ClientTranID = m.ClientTranIDGenerator.NewTaggedID(p.Name)
+	return t
+}
+
+// Apply run the policies and return the report of every transfer, the
+// transfers failing don't stop the others
+func (m *SubAccountManager) Apply(ctx context.Context, policies ...*SubAccountPolicy) (*SubAccountTransferReport, error) {
+	report, err := m.Plan(ctx, policies...)
+	if err != nil {
+		return nil, err
+	}
+	return report, m.Execute(ctx, report)
+}
+
+// Execute make the planned transfers of report, one at a time so that
+// top-ups don't race for the balance of the master account
+func (m *SubAccountManager) Execute(ctx context.Context, report *SubAccountTransferReport) error {
+	for _, t := range report.Transfers {
+		if t.Status != SubAccountTransferStatusPlanned {
+			continue
+		}
+		if err := m.wait(ctx); err != nil {
+			return err
+		}
+		s := m.c.NewSubAccountUniversalTransferService().FromAccountType(string(t.FromWallet)).
+			ToAccountType(string(t.ToWallet)).Asset(t.Asset).Amount(t.Amount).ClientTranId(t.ClientTranID)
+		if t.FromEmail != "" {
+			s.FromEmail(t.FromEmail)
+		}
+		if t.ToEmail != "" {
+			s.ToEmail(t.ToEmail)
+		}
+		res, err := s.Do(ctx)
+		t.Time = time.Now().UnixMilli()
+		if err != nil {
+			t.Status, t.Error = SubAccountTransferStatusFailed, err.Error()
+			continue
+		}
+		t.Status, t.TranID = SubAccountTransferStatusSuccess, res.TranId
+	}
+	report.EndTime = time.Now().UnixMilli()
+	return nil
+}
@@ -0,0 +1,176 @@
+package binance
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+type subAccountManagerTestSuite struct {
+	baseTestSuite
+	mu        sync.Mutex
+	transfers []map[string]string
+}
+
+func TestSubAccountManager(t *testing.T) {
+	suite.Run(t, new(subAccountManagerTestSuite))
+}
+
+func (s *subAccountManagerTestSuite) SetupTest() {
+	s.baseTestSuite.SetupTest()
+	s.transfers = nil
+	routes := routedResponses{
+		"/sapi/v1/sub-account/list": `{"subAccounts": [
+			{"email": "a@test.com", "isFreeze": false},
+			{"email": "b@test.com", "isFreeze": false},
+			{"email": "c@test.com", "isFreeze": true}
+		]}`,
+		"/sapi/v3/sub-account/assets?email=a@test.com": `{"balances": [{"asset": "USDT", "free": 10.5, "locked": 1}]}`,
+		"/sapi/v3/sub-account/assets":                  `{"balances": []}`,
+		"/sapi/v1/sub-account/margin/account?email=a@test.com": `{"email": "a@test.com", "marginUserAssetVoList": [
+			{"asset": "BTC", "free": "0.1", "locked": "0.2", "borrowed": "0", "interest": "0", "netAsset": "0.3"}
+		]}`,
+		"/sapi/v2/sub-account/futures/account?email=a@test.com&futuresType=1": `{"futureAccountResp": {"email": "a@test.com", "assets": [
+			{"asset": "USDT", "walletBalance": "400", "maxWithdrawAmount": "350"}
+		]}}`,
+		"/sapi/v2/sub-account/futures/account?email=b@test.com&futuresType=1": `{"futureAccountResp": {"email": "b@test.com", "assets": [
+			{"asset": "USDT", "walletBalance": "2500", "maxWithdrawAmount": "1200"}
+		]}}`,
+		"/sapi/v2/sub-account/futures/account?email=c@test.com&futuresType=1": `{"futureAccountResp": {"email": "c@test.com", "assets": []}}`,
+		"/sapi/v2/sub-account/futures/account?futuresType=2": `{"deliveryAccountResp": {"assets": [
+			{"asset": "BTC", "walletBalance": "1", "maxWithdrawAmount": "0.5"}
+		]}}`,
+		"POST /sapi/v1/sub-account/universalTransfer": `{"tranId": 11, "clientTranId": "id"}`,
+	}
+	s.client.Client.do = func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			if err := req.ParseForm(); err != nil {
+				return nil, err
+			}
+			form := make(map[string]string)
+			for k := range req.PostForm {
+				form[k] = req.PostForm.Get(k)
+			}
+			s.mu.Lock()
+			s.transfers = append(s.transfers, form)
+			s.mu.Unlock()
+			if form["toEmail"] == "fail@test.com" {
+				return newHTTPResponse([]byte(`{"code": -1, "msg": "failed"}`), http.StatusBadRequest), nil
+			}
+		}
+		if req.URL.Path == "/sapi/v1/sub-account/margin/account" && req.URL.Query().Get("email") != "a@test.com" {
+			return newHTTPResponse([]byte(`{"code": -3003, "msg": "Margin account does not exist."}`), http.StatusBadRequest), nil
+		}
+		return routes.do(req)
+	}
+}
+
+func (s *subAccountManagerTestSuite) TestBalances() {
+	m := NewSubAccountManager(s.client.Client).Concurrency(2).Throttle(0, 1)
+	r := s.r()
+	snapshots, err := m.Balances(newContext())
+	r.NoError(err)
+	r.Len(snapshots, 3)
+
+	a := snapshots[0]
+	r.Equal("a@test.com", a.Email)
+	r.Empty(a.Errors)
+	r.Equal([]*SubAccountBalance{
+		{Wallet: SubAccountWalletCoinFutures, Asset: "BTC", Free: "0.5", Total: "1"},
+		{Wallet: SubAccountWalletMargin, Asset: "BTC", Free: "0.1", Total: "0.3"},
+		{Wallet: SubAccountWalletSpot, Asset: "USDT", Free: "10.5", Total: "11.5"},
+		{Wallet: SubAccountWalletUSDTFutures, Asset: "USDT", Free: "350", Total: "400"},
+	}, a.Balances)
+
+	b := snapshots[1]
+	r.Len(b.Errors, 1)
+	r.Contains(b.Errors[SubAccountWalletMargin], "Margin account does not exist")
+	r.Equal("2500", b.Balance(SubAccountWalletUSDTFutures, "USDT").Total)
+	r.Nil(b.Balance(SubAccountWalletSpot, "USDT"))
+}
+
+func (s *subAccountManagerTestSuite) TestApply() {
+	m := NewSubAccountManager(s.client.Client).Throttle(1, 600000)
+	m.ClientTranIDGenerator = common.NewClientOrderIDGenerator("").Session("s")
+	r := s.r()
+	report, err := m.Apply(newContext(), KeepBalancePolicy(SubAccountWalletUSDTFutures, "USDT", "1000"))
+	r.NoError(err)
+	r.Empty(report.Skipped)
+	r.Len(report.Transfers, 2)
+
+	// a is topped up from the master account, c is frozen
+	topUp := report.Transfers[0]
+	r.Equal("a@test.com", topUp.ToEmail)
+	r.Equal("", topUp.FromEmail)
+	r.Equal(SubAccountWalletSpot, topUp.FromWallet)
+	r.Equal(SubAccountWalletUSDTFutures, topUp.ToWallet)
+	r.Equal("600", topUp.Amount)
+	r.Equal("400", topUp.Balance)
+	r.Equal(SubAccountTransferStatusSuccess, topUp.Status)
+	r.Equal(int64(11), topUp.TranID)
+	r.Equal("keep_USDT_USDT_FUTURE_s_1", topUp.ClientTranID)
+
+	// b is swept down to what can be withdrawn
+	sweep := report.Transfers[1]
+	r.Equal("b@test.com", sweep.FromEmail)
+	r.Equal("", sweep.ToEmail)
+	r.Equal("1200", sweep.Amount)
+	r.Equal(SubAccountWalletUSDTFutures, sweep.FromWallet)
+	r.Equal(SubAccountWalletSpot, sweep.ToWallet)
+
+	r.Len(s.transfers, 2)
+	r.Equal(map[string]string{
+		"toEmail": "a@test.com", "fromAccountType": "SPOT", "toAccountType": "USDT_FUTURE",
+		"asset": "USDT", "amount": "600", "clientTranId": "keep_USDT_USDT_FUTURE_s_1",
+	}, s.transfers[0])
+	r.Equal("b@test.com", s.transfers[1]["fromEmail"])
+}
+
+func (s *subAccountManagerTestSuite) TestPlan() {
+	m := NewSubAccountManager(s.client.Client).Throttle(0, 1)
+	r := s.r()
+	sweep := SweepPolicy(SubAccountWalletMargin, "BTC", "0")
+	report, err := m.Plan(newContext(), sweep, SweepPolicy(SubAccountWalletMargin, "ETH", "0"))
+	r.NoError(err)
+	r.Len(report.Transfers, 1)
+	r.Equal(SubAccountTransferStatusPlanned, report.Transfers[0].Status)
+	r.Equal("0.1", report.Transfers[0].Amount)
+	r.Len(report.Skipped, 2)
+	r.Contains(report.Skipped["sweep_BTC_MARGIN/b@test.com"], "MARGIN")
+	r.Contains(report.Skipped["sweep_ETH_MARGIN/b@test.com"], "MARGIN")
+	r.Empty(s.transfers)
+
+	ctx, cancel := context.WithCancel(newContext())
+	cancel()
+	_, err = m.balances(ctx, []SubAccount{{Email: "a@test.com"}}, []SubAccountWallet{SubAccountWalletSpot})
+	r.ErrorIs(err, context.Canceled)
+
+	_, err = m.Plan(newContext(), &SubAccountPolicy{Name: "empty", Wallet: SubAccountWalletSpot, Asset: "USDT"})
+	r.ErrorIs(err, ErrSubAccountPolicyInvalid)
+}
+
+func (s *subAccountManagerTestSuite) TestDefaultThrottle() {
+	s.r().Equal(100*time.Millisecond, NewSubAccountManager(s.client.Client).delay)
+}
+
+func (s *subAccountManagerTestSuite) TestExecuteFailed() {
+	m := NewSubAccountManager(s.client.Client).Throttle(0, 1)
+	r := s.r()
+	report := &SubAccountTransferReport{Transfers: []*SubAccountTransfer{
+		{ToEmail: "fail@test.com", FromWallet: SubAccountWalletSpot, ToWallet: SubAccountWalletSpot,
+			Asset: "USDT", Amount: "1", Status: SubAccountTransferStatusPlanned},
+		{ToEmail: "a@test.com", FromWallet: SubAccountWalletSpot, ToWallet: SubAccountWalletSpot,
+			Asset: "USDT", Amount: "1", Status: SubAccountTransferStatusPlanned},
+	}}
+	r.NoError(m.Execute(newContext(), report))
+	r.Len(report.Failed(), 1)
+	r.Equal("fail@test.com", report.Failed()[0].ToEmail)
+	r.NotEmpty(report.Failed()[0].Error)
+	r.Equal(SubAccountTransferStatusSuccess, report.Transfers[1].Status)
+}
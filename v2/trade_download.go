@@ -0,0 +1,550 @@
+package binance
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/adshao/go-binance/v2/options"
+)
+
+// DefaultTradeDownloadLimit is the page size used by a TradeDownloader when none is set
+const DefaultTradeDownloadLimit = 1000
+
+const (
+	// aggTradesWindow is the longest startTime to endTime range accepted by aggTrades
+	aggTradesWindow = int64(time.Hour / time.Millisecond)
+	// accountTradesWindow is the longest startTime to endTime range accepted by myTrades
+	accountTradesWindow = int64(24 * time.Hour / time.Millisecond)
+)
+
+// TradeRecord define a trade of a tape, whatever the endpoint it comes from
+type TradeRecord struct {
+	// ID is the aggregate trade id for aggTrades, the trade id otherwise
+	ID            int64  `json:"id"`
+	Price         string `json:"price"`
+	Quantity      string `json:"quantity"`
+	QuoteQuantity string `json:"quoteQuantity,omitempty"`
+	Time          int64  `json:"time"`
+	IsBuyerMaker  bool   `json:"isBuyerMaker"`
+	// FirstTradeID and LastTradeID are only set for aggTrades
+	FirstTradeID int64 `json:"firstTradeId,omitempty"`
+	LastTradeID  int64 `json:"lastTradeId,omitempty"`
+}
+
+// TradeSource define how to page through the trades of a symbol, use one of
+// the XxxTradeSource helpers to get the source of an endpoint.
+type TradeSource struct {
+	// Fetch fetch at most limit trades with an id greater or equal to fromID,
+	// or the most recent ones when fromID is negative
+	Fetch func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error)
+	// Locate return the id of the first trade made between startTime and
+	// endTime excluded, ok is false when there is none. The downloader falls
+	// back to a binary search over Fetch when Locate is nil.
+	Locate func(ctx context.Context, startTime, endTime int64) (id int64, ok bool, err error)
+}
+
+func spotAggTradeRecords(res []*AggTrade) []*TradeRecord {
+	trades := make([]*TradeRecord, len(res))
+	for i, t := range res {
+		trades[i] = &TradeRecord{
+			ID:           t.AggTradeID,
+			Price:        t.Price,
+			Quantity:     t.Quantity,
+			Time:         t.Timestamp,
+			IsBuyerMaker: t.IsBuyerMaker,
+			FirstTradeID: t.FirstTradeID,
+			LastTradeID:  t.LastTradeID,
+		}
+	}
+	return trades
+}
+
+// SpotAggTradeSource page through the spot aggregate trades
+func SpotAggTradeSource(c *Client, symbol string) *TradeSource {
+	return &TradeSource{
+		Fetch: func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error) {
+			s := c.NewAggTradesService().Symbol(symbol).Limit(limit)
+			if fromID >= 0 {
+				s.FromID(fromID)
+			}
+			res, err := s.Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return spotAggTradeRecords(res), nil
+		},
+		Locate: locateByWindow(aggTradesWindow, func(ctx context.Context, startTime, endTime int64) ([]*TradeRecord, error) {
+			res, err := c.NewAggTradesService().Symbol(symbol).
+				StartTime(startTime).EndTime(endTime).Limit(1).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return spotAggTradeRecords(res), nil
+		}),
+	}
+}
+
+// SpotHistoricalTradeSource page through the spot trades, the first trade of
+// a range is located with aggTrades
+func SpotHistoricalTradeSource(c *Client, symbol string) *TradeSource {
+	agg := SpotAggTradeSource(c, symbol)
+	return &TradeSource{
+		Fetch: func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error) {
+			s := c.NewHistoricalTradesService().Symbol(symbol).Limit(limit)
+			if fromID >= 0 {
+				s.FromID(fromID)
+			}
+			res, err := s.Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			trades := make([]*TradeRecord, len(res))
+			for i, t := range res {
+				trades[i] = &TradeRecord{
+					ID:            t.ID,
+					Price:         t.Price,
+					Quantity:      t.Quantity,
+					QuoteQuantity: t.QuoteQuantity,
+					Time:          t.Time,
+					IsBuyerMaker:  t.IsBuyerMaker,
+				}
+			}
+			return trades, nil
+		},
+		Locate: firstTradeOfAggTrade(agg.Locate, agg.Fetch),
+	}
+}
+
+// SpotAccountTradeSource page through the trades of the account on a spot symbol
+func SpotAccountTradeSource(c *Client, symbol string) *TradeSource {
+	records := func(res []*TradeV3) []*TradeRecord {
+		trades := make([]*TradeRecord, len(res))
+		for i, t := range res {
+			trades[i] = &TradeRecord{
+				ID:            t.ID,
+				Price:         t.Price,
+				Quantity:      t.Quantity,
+				QuoteQuantity: t.QuoteQuantity,
+				Time:          t.Time,
+				// the buyer is the maker when the account is both or neither
+				IsBuyerMaker: t.IsBuyer == t.IsMaker,
+			}
+		}
+		return trades
+	}
+	return &TradeSource{
+		Fetch: func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error) {
+			s := c.NewListTradesService().Symbol(symbol).Limit(limit)
+			if fromID >= 0 {
+				s.FromID(fromID)
+			}
+			res, err := s.Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return records(res), nil
+		},
+		Locate: locateByWindow(accountTradesWindow, func(ctx context.Context, startTime, endTime int64) ([]*TradeRecord, error) {
+			res, err := c.NewListTradesService().Symbol(symbol).
+				StartTime(startTime).EndTime(endTime).Limit(1).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return records(res), nil
+		}),
+	}
+}
+
+func futuresAggTradeRecords(res []*futures.AggTrade) []*TradeRecord {
+	trades := make([]*TradeRecord, len(res))
+	for i, t := range res {
+		trades[i] = &TradeRecord{
+			ID:           t.AggTradeID,
+			Price:        t.Price,
+			Quantity:     t.Quantity,
+			Time:         t.Timestamp,
+			IsBuyerMaker: t.IsBuyerMaker,
+			FirstTradeID: t.FirstTradeID,
+			LastTradeID:  t.LastTradeID,
+		}
+	}
+	return trades
+}
+
+// FuturesAggTradeSource page through the USD-M futures aggregate trades
+func FuturesAggTradeSource(c *futures.Client, symbol string) *TradeSource {
+	return &TradeSource{
+		Fetch: func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error) {
+			s := c.NewAggTradesService().Symbol(symbol).Limit(limit)
+			if fromID >= 0 {
+				s.FromID(fromID)
+			}
+			res, err := s.Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return futuresAggTradeRecords(res), nil
+		},
+		Locate: locateByWindow(aggTradesWindow, func(ctx context.Context, startTime, endTime int64) ([]*TradeRecord, error) {
+			res, err := c.NewAggTradesService().Symbol(symbol).
+				StartTime(startTime).EndTime(endTime).Limit(1).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return futuresAggTradeRecords(res), nil
+		}),
+	}
+}
+
+// FuturesHistoricalTradeSource page through the USD-M futures trades, the
+// first trade of a range is located with aggTrades
+func FuturesHistoricalTradeSource(c *futures.Client, symbol string) *TradeSource {
+	agg := FuturesAggTradeSource(c, symbol)
+	return &TradeSource{
+		Fetch: func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error) {
+			s := c.NewHistoricalTradesService().Symbol(symbol).Limit(limit)
+			if fromID >= 0 {
+				s.FromID(fromID)
+			}
+			res, err := s.Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			trades := make([]*TradeRecord, len(res))
+			for i, t := range res {
+				trades[i] = &TradeRecord{
+					ID:            t.ID,
+					Price:         t.Price,
+					Quantity:      t.Quantity,
+					QuoteQuantity: t.QuoteQuantity,
+					Time:          t.Time,
+					IsBuyerMaker:  t.IsBuyerMaker,
+				}
+			}
+			return trades, nil
+		},
+		Locate: firstTradeOfAggTrade(agg.Locate, agg.Fetch),
+	}
+}
+
+// OptionsHistoricalTradeSource page through the options trades, ids are the
+// unique ids accepted by fromId. The endpoint has no time filter, so the first
+// trade of a range is found by a binary search.
+func OptionsHistoricalTradeSource(c *options.Client, symbol string) *TradeSource {
+	return &TradeSource{
+		Fetch: func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error) {
+			s := c.NewHistoricalTradesService().Symbol(symbol).Limit(limit)
+			if fromID >= 0 {
+				s.FromID(fromID)
+			}
+			res, err := s.Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+			trades := make([]*TradeRecord, len(res))
+			for i, t := range res {
+				trades[i] = &TradeRecord{
+					ID:            int64(t.Id),
+					Price:         t.Price,
+					Quantity:      t.Qty,
+					QuoteQuantity: t.QuoteQty,
+					Time:          int64(t.Time),
+					// side is the taker side, -1 for a sell
+					IsBuyerMaker: t.Side < 0,
+				}
+			}
+			return trades, nil
+		},
+	}
+}
+
+// locateByWindow walk [startTime, endTime) by windows of the given length,
+// the longest range the endpoint accepts, until one has a trade
+func locateByWindow(window int64, first func(ctx context.Context, startTime, endTime int64) ([]*TradeRecord, error)) func(ctx context.Context, startTime, endTime int64) (int64, bool, error) {
+	return func(ctx context.Context, startTime, endTime int64) (int64, bool, error) {
+		for from := startTime; from < endTime; from += window {
+			to := from + window
+			if to > endTime {
+				to = endTime
+			}
+			// both bounds are inclusive
+			trades, err := first(ctx, from, to-1)
+			if err != nil {
+				return 0, false, err
+			}
+			if len(trades) > 0 {
+				return trades[0].ID, true, nil
+			}
+		}
+		return 0, false, nil
+	}
+}
+
+// firstTradeOfAggTrade locate a trade id through the aggregate trade containing it
+func firstTradeOfAggTrade(locate func(ctx context.Context, startTime, endTime int64) (int64, bool, error), fetch func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error)) func(ctx context.Context, startTime, endTime int64) (int64, bool, error) {
+	return func(ctx context.Context, startTime, endTime int64) (int64, bool, error) {
+		id, ok, err := locate(ctx, startTime, endTime)
+		if err != nil || !ok {
+			return 0, ok, err
+		}
+		trades, err := fetch(ctx, id, 1)
+		if err != nil || len(trades) == 0 {
+			return 0, false, err
+		}
+		return trades[0].FirstTradeID, true, nil
+	}
+}
+
+// TradeDownloadCheckpoint define where a download stopped, it can be stored
+// and passed to Resume to continue the download later
+type TradeDownloadCheckpoint struct {
+	NextFromID int64 `json:"nextFromId"`
+	// LastTime is the time of the last trade delivered, 0 when none was
+	LastTime int64 `json:"lastTime"`
+}
+
+// TradeDownloader walk the trades of a [startTime, endTime) range by fromId
+// pages, trades are delivered in id order without duplicates.
+type TradeDownloader struct {
+	source    *TradeSource
+	startTime int64
+	endTime   int64
+	limit     int
+	pageDelay time.Duration
+
+	resume       *TradeDownloadCheckpoint
+	onCheckpoint func(checkpoint *TradeDownloadCheckpoint) error
+}
+
+// NewTradeDownloader init a download of the trades made in [startTime, endTime), in milliseconds
+func NewTradeDownloader(source *TradeSource, startTime, endTime int64) *TradeDownloader {
+	return &TradeDownloader{
+		source:    source,
+		startTime: startTime,
+		endTime:   endTime,
+		limit:     DefaultTradeDownloadLimit,
+	}
+}
+
+// NewAggTradeDownloader init a download of the spot aggregate trades made in [startTime, endTime), in milliseconds
+func (c *Client) NewAggTradeDownloader(symbol string, startTime, endTime int64) *TradeDownloader {
+	return NewTradeDownloader(SpotAggTradeSource(c, symbol), startTime, endTime)
+}
+
+// Limit set the page size
+func (d *TradeDownloader) Limit(limit int) *TradeDownloader {
+	d.limit = limit
+	return d
+}
+
+// Throttle space the pages so that requests of pageWeight stay within weightPerMinute
+func (d *TradeDownloader) Throttle(pageWeight, weightPerMinute int) *TradeDownloader {
+	if weightPerMinute > 0 {
+		d.pageDelay = time.Minute * time.Duration(pageWeight) / time.Duration(weightPerMinute)
+	}
+	return d
+}
+
+// Resume continue a previous download from its last checkpoint
+func (d *TradeDownloader) Resume(checkpoint *TradeDownloadCheckpoint) *TradeDownloader {
+	d.resume = checkpoint
+	return d
+}
+
+// OnCheckpoint call f after every page is delivered, the download stops if f returns an error
+func (d *TradeDownloader) OnCheckpoint(f func(checkpoint *TradeDownloadCheckpoint) error) *TradeDownloader {
+	d.onCheckpoint = f
+	return d
+}
+
+// Do walk the range and call handler for every trade, it stops at the first error
+func (d *TradeDownloader) Do(ctx context.Context, handler func(trade *TradeRecord) error) error {
+	var next, lastTime int64
+	if d.resume != nil {
+		next, lastTime = d.resume.NextFromID, d.resume.LastTime
+	} else {
+		id, ok, err := d.locate(ctx)
+		if err != nil || !ok {
+			return err
+		}
+		next = id
+	}
+	for page := 0; ; page++ {
+		if page > 0 && d.pageDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d.pageDelay):
+			}
+		}
+		trades, err := d.source.Fetch(ctx, next, d.limit)
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(trades, func(i, j int) bool { return trades[i].ID < trades[j].ID })
+		for _, t := range trades {
+			if t.ID < next {
+				continue
+			}
+			if t.Time >= d.endTime {
+				return d.checkpoint(next, lastTime)
+			}
+			if t.Time >= d.startTime {
+				if err := handler(t); err != nil {
+					return err
+				}
+				lastTime = t.Time
+			}
+			next = t.ID + 1
+		}
+		if err := d.checkpoint(next, lastTime); err != nil {
+			return err
+		}
+		if len(trades) < d.limit {
+			// the tape is up to date
+			return nil
+		}
+	}
+}
+
+// Save walk the range and write every trade to sink, which is flushed at the end
+func (d *TradeDownloader) Save(ctx context.Context, sink TradeSink) error {
+	if err := d.Do(ctx, sink.WriteTrade); err != nil {
+		sink.Flush()
+		return err
+	}
+	return sink.Flush()
+}
+
+// Stream walk the range in background and send the trades on the returned
+// channel, which is closed at the end. The error channel receives at most one
+// error and is closed after the trade channel.
+func (d *TradeDownloader) Stream(ctx context.Context) (<-chan *TradeRecord, <-chan error) {
+	tradeC := make(chan *TradeRecord)
+	errC := make(chan error, 1)
+	go func() {
+		defer close(errC)
+		defer close(tradeC)
+		err := d.Do(ctx, func(trade *TradeRecord) error {
+			select {
+			case tradeC <- trade:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errC <- err
+		}
+	}()
+	return tradeC, errC
+}
+
+func (d *TradeDownloader) checkpoint(next, lastTime int64) error {
+	if d.onCheckpoint == nil {
+		return nil
+	}
+	return d.onCheckpoint(&TradeDownloadCheckpoint{NextFromID: next, LastTime: lastTime})
+}
+
+func (d *TradeDownloader) locate(ctx context.Context) (int64, bool, error) {
+	if d.source.Locate != nil {
+		return d.source.Locate(ctx, d.startTime, d.endTime)
+	}
+	// binary search the first id traded at or after startTime, ids may have holes
+	latest, err := d.source.Fetch(ctx, -1, 1)
+	if err != nil || len(latest) == 0 {
+		return 0, false, err
+	}
+	last := latest[len(latest)-1]
+	if last.Time < d.startTime {
+		return 0, false, nil
+	}
+	lo, hi := int64(0), last.ID
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		trades, err := d.source.Fetch(ctx, mid, 1)
+		if err != nil {
+			return 0, false, err
+		}
+		if len(trades) == 0 || trades[0].Time >= d.startTime {
+			hi = mid
+		} else {
+			lo = trades[0].ID + 1
+		}
+	}
+	return lo, true, nil
+}
+
+// TradeSink define where a TradeDownloader writes its trades
+type TradeSink interface {
+	WriteTrade(trade *TradeRecord) error
+	Flush() error
+}
+
+// CSVTradeSink write trades as CSV rows, preceded by a header row
+type CSVTradeSink struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVTradeSink init a CSV sink writing to w
+func NewCSVTradeSink(w io.Writer) *CSVTradeSink {
+	return &CSVTradeSink{w: csv.NewWriter(w)}
+}
+
+// WriteTrade write a trade row
+func (s *CSVTradeSink) WriteTrade(trade *TradeRecord) error {
+	if !s.header {
+		err := s.w.Write([]string{"id", "price", "quantity", "quoteQuantity", "time", "isBuyerMaker", "firstTradeId", "lastTradeId"})
+		if err != nil {
+			return err
+		}
+		s.header = true
+	}
+	return s.w.Write([]string{
+		strconv.FormatInt(trade.ID, 10),
+		trade.Price,
+		trade.Quantity,
+		trade.QuoteQuantity,
+		strconv.FormatInt(trade.Time, 10),
+		strconv.FormatBool(trade.IsBuyerMaker),
+		strconv.FormatInt(trade.FirstTradeID, 10),
+		strconv.FormatInt(trade.LastTradeID, 10),
+	})
+}
+
+// Flush write the buffered rows
+func (s *CSVTradeSink) Flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// JSONLinesTradeSink write trades as JSON objects, one per line
+type JSONLinesTradeSink struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLinesTradeSink init a JSON Lines sink writing to w
+func NewJSONLinesTradeSink(w io.Writer) *JSONLinesTradeSink {
+	bw := bufio.NewWriter(w)
+	return &JSONLinesTradeSink{w: bw, enc: json.NewEncoder(bw)}
+}
+
+// WriteTrade write a trade line
+func (s *JSONLinesTradeSink) WriteTrade(trade *TradeRecord) error {
+	return s.enc.Encode(trade)
+}
+
+// Flush write the buffered lines
+func (s *JSONLinesTradeSink) Flush() error {
+	return s.w.Flush()
+}
@@ -0,0 +1,170 @@
+package binance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type tradeDownloadTestSuite struct {
+	baseTestSuite
+}
+
+func TestTradeDownload(t *testing.T) {
+	suite.Run(t, new(tradeDownloadTestSuite))
+}
+
+// fakeTradeSource serves trades with the given ids, each traded at id*10
+func fakeTradeSource(ids []int64, calls *int) *TradeSource {
+	return &TradeSource{
+		Fetch: func(ctx context.Context, fromID int64, limit int) ([]*TradeRecord, error) {
+			*calls++
+			var res []*TradeRecord
+			for i, id := range ids {
+				if fromID < 0 {
+					// the most recent trades
+					if i < len(ids)-limit {
+						continue
+					}
+				} else if id < fromID {
+					continue
+				}
+				if len(res) < limit {
+					res = append(res, &TradeRecord{ID: id, Price: "1", Quantity: "2", Time: id * 10})
+				}
+			}
+			return res, nil
+		},
+	}
+}
+
+func (s *tradeDownloadTestSuite) TestBinarySearch() {
+	var calls int
+	source := fakeTradeSource([]int64{1, 2, 3, 5, 8, 9, 10, 11, 15, 20}, &calls)
+
+	var ids []int64
+	var checkpoints []*TradeDownloadCheckpoint
+	err := NewTradeDownloader(source, 45, 150).
+		Limit(3).
+		OnCheckpoint(func(cp *TradeDownloadCheckpoint) error {
+			checkpoints = append(checkpoints, cp)
+			return nil
+		}).
+		Do(newContext(), func(trade *TradeRecord) error {
+			ids = append(ids, trade.ID)
+			return nil
+		})
+	r := s.r()
+	r.NoError(err)
+	r.Equal([]int64{5, 8, 9, 10, 11}, ids)
+	r.Equal(&TradeDownloadCheckpoint{NextFromID: 12, LastTime: 110}, checkpoints[len(checkpoints)-1])
+
+	// a range after the last trade has nothing
+	ids = nil
+	err = NewTradeDownloader(source, 250, 300).Do(newContext(), func(trade *TradeRecord) error {
+		ids = append(ids, trade.ID)
+		return nil
+	})
+	r.NoError(err)
+	r.Empty(ids)
+}
+
+func (s *tradeDownloadTestSuite) TestResume() {
+	var calls int
+	source := fakeTradeSource([]int64{1, 2, 3, 4, 5, 6}, &calls)
+	stop := errors.New("stop")
+
+	var saved *TradeDownloadCheckpoint
+	var first []int64
+	err := NewTradeDownloader(source, 0, 1000).
+		Limit(2).
+		OnCheckpoint(func(cp *TradeDownloadCheckpoint) error {
+			saved = cp
+			return stop
+		}).
+		Do(newContext(), func(trade *TradeRecord) error {
+			first = append(first, trade.ID)
+			return nil
+		})
+	r := s.r()
+	r.Equal(stop, err)
+	r.Equal([]int64{1, 2}, first)
+
+	calls = 0
+	tradeC, errC := NewTradeDownloader(source, 0, 1000).Limit(2).Resume(saved).Stream(newContext())
+	var rest []int64
+	for trade := range tradeC {
+		rest = append(rest, trade.ID)
+	}
+	r.NoError(<-errC)
+	r.Equal([]int64{3, 4, 5, 6}, rest)
+	// no lookup when resuming, the last page is empty
+	r.Equal(3, calls)
+}
+
+func (s *tradeDownloadTestSuite) TestSinks() {
+	var calls int
+	source := fakeTradeSource([]int64{1, 2}, &calls)
+
+	var buf bytes.Buffer
+	r := s.r()
+	r.NoError(NewTradeDownloader(source, 0, 100).Save(newContext(), NewCSVTradeSink(&buf)))
+	r.Equal("id,price,quantity,quoteQuantity,time,isBuyerMaker,firstTradeId,lastTradeId\n"+
+		"1,1,2,,10,false,0,0\n"+
+		"2,1,2,,20,false,0,0\n", buf.String())
+
+	buf.Reset()
+	r.NoError(NewTradeDownloader(source, 0, 100).Save(newContext(), NewJSONLinesTradeSink(&buf)))
+	r.Equal(`{"id":1,"price":"1","quantity":"2","time":10,"isBuyerMaker":false}`+"\n"+
+		`{"id":2,"price":"1","quantity":"2","time":20,"isBuyerMaker":false}`+"\n", buf.String())
+}
+
+func (s *tradeDownloadTestSuite) TestSpotAggTrades() {
+	// nothing traded during the first hour of the range
+	s.mockRoutes(routedResponses{
+		"/api/v3/aggTrades?startTime=0&endTime=3599999":       `[]`,
+		"/api/v3/aggTrades?startTime=3600000&endTime=7199999": `[{"a": 7, "p": "1", "q": "1", "f": 70, "l": 71, "T": 3600500, "m": true}]`,
+		"/api/v3/aggTrades?fromId=7": `[
+			{"a": 7, "p": "1", "q": "1", "f": 70, "l": 71, "T": 3600500, "m": true},
+			{"a": 8, "p": "2", "q": "1", "f": 72, "l": 72, "T": 3700000, "m": false},
+			{"a": 9, "p": "3", "q": "1", "f": 73, "l": 73, "T": 9000000, "m": false}
+		]`,
+	})
+	var trades []*TradeRecord
+	err := s.client.NewAggTradeDownloader("BTCUSDT", 0, 9000000).Limit(3).
+		Do(newContext(), func(trade *TradeRecord) error {
+			trades = append(trades, trade)
+			return nil
+		})
+	r := s.r()
+	r.NoError(err)
+	r.Len(trades, 2)
+	r.Equal(int64(70), trades[0].FirstTradeID)
+	r.True(trades[0].IsBuyerMaker)
+	r.Equal("2", trades[1].Price)
+}
+
+func (s *tradeDownloadTestSuite) TestFuturesHistoricalTrades() {
+	futuresClient := futures.NewClient(s.apiKey, s.secretKey)
+	futuresClient.HTTPClient = &http.Client{Transport: routedResponses{
+		"/fapi/v1/aggTrades?startTime=0": `[{"a": 3, "p": "1", "q": "1", "f": 30, "l": 31, "T": 100, "m": true}]`,
+		"/fapi/v1/aggTrades?fromId=3":    `[{"a": 3, "p": "1", "q": "1", "f": 30, "l": 31, "T": 100, "m": true}]`,
+		"/fapi/v1/historicalTrades?fromId=30": `[
+			{"id": 30, "price": "1", "qty": "0.5", "quoteQty": "0.5", "time": 100, "isBuyerMaker": true},
+			{"id": 31, "price": "1", "qty": "0.5", "quoteQty": "0.5", "time": 100, "isBuyerMaker": true}
+		]`,
+	}}
+	tradeC, errC := NewTradeDownloader(FuturesHistoricalTradeSource(futuresClient, "BTCUSDT"), 0, 1000).Stream(newContext())
+	var ids []int64
+	for trade := range tradeC {
+		ids = append(ids, trade.ID)
+	}
+	s.r().NoError(<-errC)
+	s.r().Equal([]int64{30, 31}, ids)
+}
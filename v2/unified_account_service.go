@@ -0,0 +1,628 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/adshao/go-binance/v2/options"
+	"github.com/adshao/go-binance/v2/portfolio"
+)
+
+// WalletType define the wallet a unified balance or position comes from
+type WalletType string
+
+const (
+	WalletTypeSpot            WalletType = "SPOT"
+	WalletTypeMargin          WalletType = "MARGIN"
+	WalletTypeIsolatedMargin  WalletType = "ISOLATED_MARGIN"
+	WalletTypeUSDTFuture      WalletType = "USDT_FUTURE"
+	WalletTypeCoinFuture      WalletType = "COIN_FUTURE"
+	WalletTypeOption          WalletType = "OPTION"
+	WalletTypePortfolioMargin WalletType = "PORTFOLIO_MARGIN"
+	WalletTypeSimpleEarn      WalletType = "SIMPLE_EARN"
+	WalletTypeFunding         WalletType = "FUNDING"
+)
+
+// defaultValuationBridges are the assets tried as an intermediate hop when an
+// asset has no direct ticker against the quote asset.
+var defaultValuationBridges = []string{"USDT", "BTC", "BNB", "ETH", "FDUSD", "USDC"}
+
+// knownQuoteAssets are stripped from derivative symbols to find their underlying asset.
+var knownQuoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "USD", "BTC", "ETH", "BNB"}
+
+// UnifiedAccountService collects the balances and positions of every product
+// account concurrently and normalizes them into a single UnifiedAccount,
+// valued in one quote asset with the spot ticker prices.
+//
+// The spot account is always included. Other wallets are included when the
+// matching client is given or the matching flag is enabled.
+type UnifiedAccountService struct {
+	c               *Client
+	quoteAsset      string
+	bridges         []string
+	futuresClient   *futures.Client
+	deliveryClient  *delivery.Client
+	optionsClient   *options.Client
+	portfolioClient *portfolio.Client
+	margin          bool
+	isolatedMargin  bool
+	simpleEarn      bool
+	funding         bool
+}
+
+// QuoteAsset set the asset every balance is valued in, default USDT
+func (s *UnifiedAccountService) QuoteAsset(quoteAsset string) *UnifiedAccountService {
+	s.quoteAsset = quoteAsset
+	return s
+}
+
+// ValuationBridges set the assets used as an intermediate hop when pricing an
+// asset without a direct ticker against the quote asset
+func (s *UnifiedAccountService) ValuationBridges(bridges ...string) *UnifiedAccountService {
+	s.bridges = bridges
+	return s
+}
+
+// FuturesClient include the USD-M futures account
+func (s *UnifiedAccountService) FuturesClient(client *futures.Client) *UnifiedAccountService {
+	s.futuresClient = client
+	return s
+}
+
+// DeliveryClient include the COIN-M futures account
+func (s *UnifiedAccountService) DeliveryClient(client *delivery.Client) *UnifiedAccountService {
+	s.deliveryClient = client
+	return s
+}
+
+// OptionsClient include the options account
+func (s *UnifiedAccountService) OptionsClient(client *options.Client) *UnifiedAccountService {
+	s.optionsClient = client
+	return s
+}
+
+// PortfolioClient include the portfolio margin account
+func (s *UnifiedAccountService) PortfolioClient(client *portfolio.Client) *UnifiedAccountService {
+	s.portfolioClient = client
+	return s
+}
+
+// Margin include the cross margin account
+func (s *UnifiedAccountService) Margin(margin bool) *UnifiedAccountService {
+	s.margin = margin
+	return s
+}
+
+// IsolatedMargin include all the isolated margin accounts
+func (s *UnifiedAccountService) IsolatedMargin(isolatedMargin bool) *UnifiedAccountService {
+	s.isolatedMargin = isolatedMargin
+	return s
+}
+
+// SimpleEarn include the simple earn flexible positions
+func (s *UnifiedAccountService) SimpleEarn(simpleEarn bool) *UnifiedAccountService {
+	s.simpleEarn = simpleEarn
+	return s
+}
+
+// Funding include the funding wallet
+func (s *UnifiedAccountService) Funding(funding bool) *UnifiedAccountService {
+	s.funding = funding
+	return s
+}
+
+// UnifiedAccount define the normalized view of all the accounts
+type UnifiedAccount struct {
+	QuoteAsset string             `json:"quoteAsset"`
+	TotalValue string             `json:"totalValue"`
+	Balances   []*UnifiedBalance  `json:"balances"`
+	Positions  []*UnifiedPosition `json:"positions"`
+	Exposures  []*AssetExposure   `json:"exposures"`
+	// Unpriced lists the assets for which no price could be found, they are
+	// excluded from TotalValue and have an empty Notional.
+	Unpriced []string `json:"unpriced"`
+}
+
+// UnifiedBalance define the balance of an asset in one wallet
+type UnifiedBalance struct {
+	Asset         string     `json:"asset"`
+	Wallet        WalletType `json:"wallet"`
+	Symbol        string     `json:"symbol,omitempty"` // isolated margin symbol
+	Free          string     `json:"free"`
+	Locked        string     `json:"locked"`
+	Borrowed      string     `json:"borrowed"`
+	UnrealizedPNL string     `json:"unrealizedPnl"`
+	// NetAsset is free + locked - borrowed + unrealized PnL
+	NetAsset string `json:"netAsset"`
+	Price    string `json:"price"`
+	Notional string `json:"notional"`
+}
+
+// UnifiedPosition define a derivatives position expressed in its underlying asset
+type UnifiedPosition struct {
+	Wallet        WalletType `json:"wallet"`
+	Symbol        string     `json:"symbol"`
+	Underlying    string     `json:"underlying"`
+	PositionSide  string     `json:"positionSide"`
+	PositionAmt   string     `json:"positionAmt"`
+	Quantity      string     `json:"quantity"` // signed, in underlying units
+	UnrealizedPNL string     `json:"unrealizedPnl"`
+	Notional      string     `json:"notional"`
+}
+
+// AssetExposure define the net exposure to an underlying asset across all wallets
+type AssetExposure struct {
+	Asset    string `json:"asset"`
+	Balance  string `json:"balance"`  // net asset held in all the wallets
+	Position string `json:"position"` // signed derivatives exposure in underlying units
+	Net      string `json:"net"`
+	Notional string `json:"notional"`
+}
+
+// Do send requests
+func (s *UnifiedAccountService) Do(ctx context.Context, opts ...RequestOption) (res *UnifiedAccount, err error) {
+	quoteAsset := s.quoteAsset
+	if quoteAsset == "" {
+		quoteAsset = "USDT"
+	}
+	bridges := s.bridges
+	if bridges == nil {
+		bridges = defaultValuationBridges
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		firstErr  error
+		balances  []*UnifiedBalance
+		positions []*UnifiedPosition
+		prices    map[string]decimal.Decimal
+	)
+	run := func(name string, f func() ([]*UnifiedBalance, []*UnifiedPosition, error)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, p, err := f()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", name, err)
+				}
+				return
+			}
+			balances = append(balances, b...)
+			positions = append(positions, p...)
+		}()
+	}
+
+	run("prices", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+		list, err := s.c.NewListPricesService().Do(ctx, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		m := make(map[string]decimal.Decimal, len(list))
+		for _, p := range list {
+			m[p.Symbol] = decimalOrZero(p.Price)
+		}
+		prices = m
+		return nil, nil, nil
+	})
+	run("spot", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+		return s.spotBalances(ctx, opts...)
+	})
+	if s.margin {
+		run("margin", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+			return s.marginBalances(ctx, opts...)
+		})
+	}
+	if s.isolatedMargin {
+		run("isolated margin", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+			return s.isolatedMarginBalances(ctx, opts...)
+		})
+	}
+	if s.simpleEarn {
+		run("simple earn", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+			return s.simpleEarnBalances(ctx, opts...)
+		})
+	}
+	if s.funding {
+		run("funding", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+			return s.fundingBalances(ctx)
+		})
+	}
+	if s.futuresClient != nil {
+		run("futures", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+			return s.futuresAccount(ctx)
+		})
+	}
+	if s.deliveryClient != nil {
+		run("delivery", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+			return s.deliveryAccount(ctx)
+		})
+	}
+	if s.optionsClient != nil {
+		run("options", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+			return s.optionsAccount(ctx)
+		})
+	}
+	if s.portfolioClient != nil {
+		run("portfolio", func() ([]*UnifiedBalance, []*UnifiedPosition, error) {
+			return s.portfolioBalances(ctx)
+		})
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	pricer := &assetPricer{prices: prices, quoteAsset: quoteAsset, bridges: bridges}
+	return buildUnifiedAccount(pricer, balances, positions), nil
+}
+
+func (s *UnifiedAccountService) spotBalances(ctx context.Context, opts ...RequestOption) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	account, err := s.c.NewGetAccountService().OmitZeroBalances(true).Do(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*UnifiedBalance, 0, len(account.Balances))
+	for _, b := range account.Balances {
+		res = append(res, newUnifiedBalance(b.Asset, WalletTypeSpot, b.Free, b.Locked, "", ""))
+	}
+	return res, nil, nil
+}
+
+func (s *UnifiedAccountService) marginBalances(ctx context.Context, opts ...RequestOption) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	account, err := s.c.NewGetMarginAccountService().Do(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*UnifiedBalance, 0, len(account.UserAssets))
+	for _, a := range account.UserAssets {
+		borrowed := decimalOrZero(a.Borrowed).Add(decimalOrZero(a.Interest))
+		res = append(res, newUnifiedBalance(a.Asset, WalletTypeMargin, a.Free, a.Locked, borrowed.String(), ""))
+	}
+	return res, nil, nil
+}
+
+func (s *UnifiedAccountService) isolatedMarginBalances(ctx context.Context, opts ...RequestOption) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	account, err := s.c.NewGetIsolatedMarginAccountService().Do(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*UnifiedBalance, 0, 2*len(account.Assets))
+	for _, a := range account.Assets {
+		for _, u := range []IsolatedUserAsset{a.BaseAsset, a.QuoteAsset} {
+			borrowed := decimalOrZero(u.Borrowed).Add(decimalOrZero(u.Interest))
+			b := newUnifiedBalance(u.Asset, WalletTypeIsolatedMargin, u.Free, u.Locked, borrowed.String(), "")
+			b.Symbol = a.Symbol
+			res = append(res, b)
+		}
+	}
+	return res, nil, nil
+}
+
+func (s *UnifiedAccountService) simpleEarnBalances(ctx context.Context, opts ...RequestOption) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	var res []*UnifiedBalance
+	for current := 1; ; current++ {
+		page, err := s.c.NewSimpleEarnService().FlexibleService().GetPosition().Current(current).Size(100).Do(ctx, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range page.Rows {
+			res = append(res, newUnifiedBalance(p.Asset, WalletTypeSimpleEarn, p.TotalAmount, "", "", ""))
+		}
+		if len(page.Rows) < 100 || len(res) >= page.Total {
+			break
+		}
+	}
+	return res, nil, nil
+}
+
+func (s *UnifiedAccountService) fundingBalances(ctx context.Context) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	assets, err := s.c.NewGetFundingAssetService().Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*UnifiedBalance, 0, len(assets))
+	for _, a := range assets {
+		locked := decimalOrZero(a.Locked).Add(decimalOrZero(a.Freeze)).Add(decimalOrZero(a.Withdrawing))
+		res = append(res, newUnifiedBalance(a.Asset, WalletTypeFunding, a.Free, locked.String(), "", ""))
+	}
+	return res, nil, nil
+}
+
+func (s *UnifiedAccountService) futuresAccount(ctx context.Context) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	account, err := s.futuresClient.NewGetAccountV3Service().Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	balances := make([]*UnifiedBalance, 0, len(account.Assets))
+	for _, a := range account.Assets {
+		if decimalOrZero(a.WalletBalance).IsZero() && decimalOrZero(a.UnrealizedProfit).IsZero() {
+			continue
+		}
+		balances = append(balances, newUnifiedBalance(a.Asset, WalletTypeUSDTFuture, a.WalletBalance, "", "", a.UnrealizedProfit))
+	}
+	positions := make([]*UnifiedPosition, 0, len(account.Positions))
+	for _, p := range account.Positions {
+		amt := decimalOrZero(p.PositionAmt)
+		if amt.IsZero() {
+			continue
+		}
+		positions = append(positions, &UnifiedPosition{
+			Wallet:        WalletTypeUSDTFuture,
+			Symbol:        p.Symbol,
+			Underlying:    underlyingAsset(p.Symbol),
+			PositionSide:  p.PositionSide,
+			PositionAmt:   p.PositionAmt,
+			Quantity:      amt.String(),
+			UnrealizedPNL: p.UnrealizedProfit,
+		})
+	}
+	return balances, positions, nil
+}
+
+func (s *UnifiedAccountService) deliveryAccount(ctx context.Context) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	account, err := s.deliveryClient.NewGetAccountService().Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	balances := make([]*UnifiedBalance, 0, len(account.Assets))
+	for _, a := range account.Assets {
+		if decimalOrZero(a.WalletBalance).IsZero() && decimalOrZero(a.UnrealizedProfit).IsZero() {
+			continue
+		}
+		balances = append(balances, newUnifiedBalance(a.Asset, WalletTypeCoinFuture, a.WalletBalance, "", "", a.UnrealizedProfit))
+	}
+
+	risks, err := s.deliveryClient.NewGetPositionRiskService().Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var open []*delivery.PositionRisk
+	for _, p := range risks {
+		if !decimalOrZero(p.PositionAmt).IsZero() {
+			open = append(open, p)
+		}
+	}
+	if len(open) == 0 {
+		return balances, nil, nil
+	}
+	info, err := s.deliveryClient.NewExchangeInfoService().Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	symbols := make(map[string]delivery.Symbol, len(info.Symbols))
+	for _, sym := range info.Symbols {
+		symbols[sym.Symbol] = sym
+	}
+	positions := make([]*UnifiedPosition, 0, len(open))
+	for _, p := range open {
+		sym, ok := symbols[p.Symbol]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown delivery symbol %s", p.Symbol)
+		}
+		// A COIN-M contract is worth contractSize in quote asset, so the
+		// exposure in the base asset is contracts * contractSize / markPrice.
+		qty := decimal.Zero
+		markPrice := decimalOrZero(p.MarkPrice)
+		if !markPrice.IsZero() {
+			qty = decimalOrZero(p.PositionAmt).Mul(decimal.NewFromInt(int64(sym.ContractSize))).Div(markPrice)
+		}
+		positions = append(positions, &UnifiedPosition{
+			Wallet:        WalletTypeCoinFuture,
+			Symbol:        p.Symbol,
+			Underlying:    sym.BaseAsset,
+			PositionSide:  p.PositionSide,
+			PositionAmt:   p.PositionAmt,
+			Quantity:      qty.String(),
+			UnrealizedPNL: p.UnRealizedProfit,
+		})
+	}
+	return balances, positions, nil
+}
+
+func (s *UnifiedAccountService) optionsAccount(ctx context.Context) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	account, err := s.optionsClient.NewAccountService().Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	balances := make([]*UnifiedBalance, 0, len(account.Asset))
+	for _, a := range account.Asset {
+		balances = append(balances, newUnifiedBalance(a.Asset, WalletTypeOption, a.Available, a.Locked, "", a.UnrealizedPNL))
+	}
+	// options exposure is reported as the delta of each underlying
+	positions := make([]*UnifiedPosition, 0, len(account.Greek))
+	for _, g := range account.Greek {
+		delta := decimalOrZero(g.Delta)
+		if delta.IsZero() {
+			continue
+		}
+		positions = append(positions, &UnifiedPosition{
+			Wallet:     WalletTypeOption,
+			Symbol:     g.Underlying,
+			Underlying: underlyingAsset(g.Underlying),
+			Quantity:   delta.String(),
+		})
+	}
+	return balances, positions, nil
+}
+
+func (s *UnifiedAccountService) portfolioBalances(ctx context.Context) ([]*UnifiedBalance, []*UnifiedPosition, error) {
+	list, err := s.portfolioClient.NewGetBalanceService().Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*UnifiedBalance, 0, len(list))
+	for _, b := range list {
+		// cross margin free/locked are part of the wallet balance, the rest
+		// of the wallet sits in the UM and CM futures wallets.
+		free := decimalOrZero(b.TotalWalletBalance).Sub(decimalOrZero(b.CrossMarginLocked))
+		borrowed := decimalOrZero(b.CrossMarginBorrowed).Add(decimalOrZero(b.CrossMarginInterest)).Add(decimalOrZero(b.NegativeBalance))
+		pnl := decimalOrZero(b.UMUnrealizedPNL).Add(decimalOrZero(b.CMUnrealizedPNL))
+		res = append(res, newUnifiedBalance(b.Asset, WalletTypePortfolioMargin, free.String(), b.CrossMarginLocked, borrowed.String(), pnl.String()))
+	}
+	return res, nil, nil
+}
+
+func newUnifiedBalance(asset string, wallet WalletType, free, locked, borrowed, unrealizedPNL string) *UnifiedBalance {
+	net := decimalOrZero(free).Add(decimalOrZero(locked)).Sub(decimalOrZero(borrowed)).Add(decimalOrZero(unrealizedPNL))
+	return &UnifiedBalance{
+		Asset:         asset,
+		Wallet:        wallet,
+		Free:          decimalOrZero(free).String(),
+		Locked:        decimalOrZero(locked).String(),
+		Borrowed:      decimalOrZero(borrowed).String(),
+		UnrealizedPNL: decimalOrZero(unrealizedPNL).String(),
+		NetAsset:      net.String(),
+	}
+}
+
+func buildUnifiedAccount(pricer *assetPricer, balances []*UnifiedBalance, positions []*UnifiedPosition) *UnifiedAccount {
+	res := &UnifiedAccount{
+		QuoteAsset: pricer.quoteAsset,
+		Balances:   make([]*UnifiedBalance, 0, len(balances)),
+		Positions:  positions,
+	}
+	total := decimal.Zero
+	unpriced := map[string]bool{}
+	held := map[string]decimal.Decimal{}
+	derivative := map[string]decimal.Decimal{}
+
+	for _, b := range balances {
+		net := decimalOrZero(b.NetAsset)
+		if net.IsZero() && decimalOrZero(b.Borrowed).IsZero() {
+			continue
+		}
+		held[b.Asset] = held[b.Asset].Add(net)
+		if price, ok := pricer.price(b.Asset); ok {
+			b.Price = price.String()
+			notional := net.Mul(price)
+			b.Notional = notional.String()
+			total = total.Add(notional)
+		} else {
+			unpriced[b.Asset] = true
+		}
+		res.Balances = append(res.Balances, b)
+	}
+	for _, p := range positions {
+		qty := decimalOrZero(p.Quantity)
+		derivative[p.Underlying] = derivative[p.Underlying].Add(qty)
+		if price, ok := pricer.price(p.Underlying); ok {
+			p.Notional = qty.Mul(price).String()
+		} else {
+			unpriced[p.Underlying] = true
+		}
+	}
+
+	assets := make([]string, 0, len(held)+len(derivative))
+	for asset := range held {
+		assets = append(assets, asset)
+	}
+	for asset := range derivative {
+		if _, ok := held[asset]; !ok {
+			assets = append(assets, asset)
+		}
+	}
+	sort.Strings(assets)
+	for _, asset := range assets {
+		net := held[asset].Add(derivative[asset])
+		e := &AssetExposure{
+			Asset:    asset,
+			Balance:  held[asset].String(),
+			Position: derivative[asset].String(),
+			Net:      net.String(),
+		}
+		if price, ok := pricer.price(asset); ok {
+			e.Notional = net.Mul(price).String()
+		}
+		res.Exposures = append(res.Exposures, e)
+	}
+	for asset := range unpriced {
+		res.Unpriced = append(res.Unpriced, asset)
+	}
+	sort.Strings(res.Unpriced)
+	sort.SliceStable(res.Balances, func(i, j int) bool {
+		if res.Balances[i].Asset != res.Balances[j].Asset {
+			return res.Balances[i].Asset < res.Balances[j].Asset
+		}
+		return res.Balances[i].Wallet < res.Balances[j].Wallet
+	})
+	res.TotalValue = total.String()
+	return res
+}
+
+// assetPricer values assets in a quote asset from a symbol -> price map
+type assetPricer struct {
+	prices     map[string]decimal.Decimal
+	quoteAsset string
+	bridges    []string
+}
+
+func (p *assetPricer) direct(asset, quote string) (decimal.Decimal, bool) {
+	if asset == quote {
+		return decimal.NewFromInt(1), true
+	}
+	if price, ok := p.prices[asset+quote]; ok && !price.IsZero() {
+		return price, true
+	}
+	if price, ok := p.prices[quote+asset]; ok && !price.IsZero() {
+		return decimal.NewFromInt(1).Div(price), true
+	}
+	return decimal.Zero, false
+}
+
+func (p *assetPricer) price(asset string) (decimal.Decimal, bool) {
+	if price, ok := p.direct(asset, p.quoteAsset); ok {
+		return price, true
+	}
+	for _, bridge := range p.bridges {
+		if bridge == asset || bridge == p.quoteAsset {
+			continue
+		}
+		first, ok := p.direct(asset, bridge)
+		if !ok {
+			continue
+		}
+		second, ok := p.direct(bridge, p.quoteAsset)
+		if !ok {
+			continue
+		}
+		return first.Mul(second), true
+	}
+	return decimal.Zero, false
+}
+
+// underlyingAsset guess the underlying asset of a derivatives symbol like
+// BTCUSDT, BTCUSD_PERP or ETHUSDT_250328
+func underlyingAsset(symbol string) string {
+	if i := strings.Index(symbol, "_"); i > 0 {
+		symbol = symbol[:i]
+	}
+	for _, quote := range knownQuoteAssets {
+		if strings.HasSuffix(symbol, quote) && len(symbol) > len(quote) {
+			return strings.TrimSuffix(symbol, quote)
+		}
+	}
+	return symbol
+}
+
+// decimalOrZero parse a decimal string returned by the API, empty or invalid values are zero
+func decimalOrZero(s string) decimal.Decimal {
+	if s == "" {
+		return decimal.Zero
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
@@ -0,0 +1,141 @@
+package binance
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/adshao/go-binance/v2/delivery"
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+type unifiedAccountServiceTestSuite struct {
+	baseTestSuite
+}
+
+func TestUnifiedAccountService(t *testing.T) {
+	suite.Run(t, new(unifiedAccountServiceTestSuite))
+}
+
+func (s *unifiedAccountServiceTestSuite) TestUnifiedAccount() {
+	s.mockRoutes(routedResponses{
+		"/api/v3/ticker/price": `[
+			{"symbol": "BTCUSDT", "price": "50000"},
+			{"symbol": "ETHBTC", "price": "0.05"},
+			{"symbol": "USDCUSDT", "price": "1"}
+		]`,
+		"/api/v3/account": `{
+			"balances": [
+				{"asset": "BTC", "free": "1", "locked": "0.5"},
+				{"asset": "USDT", "free": "1000", "locked": "0"},
+				{"asset": "XYZ", "free": "3", "locked": "0"}
+			]
+		}`,
+		"/sapi/v1/margin/account": `{
+			"userAssets": [
+				{"asset": "ETH", "free": "2", "locked": "0", "borrowed": "1", "interest": "0.1", "netAsset": "0.9"}
+			]
+		}`,
+	})
+	futuresClient := futures.NewClient(s.apiKey, s.secretKey)
+	futuresClient.HTTPClient = &http.Client{Transport: routedResponses{
+		"/fapi/v3/account": `{
+			"assets": [
+				{"asset": "USDT", "walletBalance": "500", "unrealizedProfit": "-50"},
+				{"asset": "USDC", "walletBalance": "0", "unrealizedProfit": "0"}
+			],
+			"positions": [
+				{"symbol": "BTCUSDT", "positionSide": "BOTH", "positionAmt": "-2", "unrealizedProfit": "-50"},
+				{"symbol": "ETHUSDT", "positionSide": "BOTH", "positionAmt": "0", "unrealizedProfit": "0"}
+			]
+		}`,
+	}}
+	deliveryClient := delivery.NewClient(s.apiKey, s.secretKey)
+	deliveryClient.HTTPClient = &http.Client{Transport: routedResponses{
+		"/dapi/v1/account": `{
+			"assets": [
+				{"asset": "BTC", "walletBalance": "0.1", "unrealizedProfit": "0.01"}
+			]
+		}`,
+		"/dapi/v1/positionRisk": `[
+			{"symbol": "BTCUSD_PERP", "positionAmt": "10", "markPrice": "50000", "unRealizedProfit": "0.01", "positionSide": "BOTH"}
+		]`,
+		"/dapi/v1/exchangeInfo": `{
+			"symbols": [
+				{"symbol": "BTCUSD_PERP", "baseAsset": "BTC", "quoteAsset": "USD", "marginAsset": "BTC", "contractSize": 100}
+			]
+		}`,
+	}}
+
+	res, err := s.client.NewUnifiedAccountService().
+		QuoteAsset("USDT").
+		Margin(true).
+		FuturesClient(futuresClient).
+		DeliveryClient(deliveryClient).
+		Do(newContext())
+	r := s.r()
+	r.NoError(err)
+	r.Equal("USDT", res.QuoteAsset)
+	r.Equal([]string{"XYZ"}, res.Unpriced)
+
+	r.Len(res.Balances, 6)
+	balances := map[string]*UnifiedBalance{}
+	for _, b := range res.Balances {
+		balances[string(b.Wallet)+":"+b.Asset] = b
+	}
+	r.Equal("1.5", balances["SPOT:BTC"].NetAsset)
+	r.Equal("75000", balances["SPOT:BTC"].Notional)
+	r.Equal("1.1", balances["MARGIN:ETH"].Borrowed)
+	r.Equal("0.9", balances["MARGIN:ETH"].NetAsset)
+	r.Equal("2500", balances["MARGIN:ETH"].Price)
+	r.Equal("450", balances["USDT_FUTURE:USDT"].NetAsset)
+	r.Equal("0.11", balances["COIN_FUTURE:BTC"].NetAsset)
+	r.Equal("", balances["SPOT:XYZ"].Notional)
+
+	r.Len(res.Positions, 2)
+	r.Equal("BTC", res.Positions[0].Underlying)
+	positions := map[WalletType]*UnifiedPosition{}
+	for _, p := range res.Positions {
+		positions[p.Wallet] = p
+	}
+	r.Equal("-2", positions[WalletTypeUSDTFuture].Quantity)
+	r.Equal("-100000", positions[WalletTypeUSDTFuture].Notional)
+	r.Equal("0.02", positions[WalletTypeCoinFuture].Quantity)
+
+	exposures := map[string]*AssetExposure{}
+	for _, e := range res.Exposures {
+		exposures[e.Asset] = e
+	}
+	r.Equal("1.61", exposures["BTC"].Balance)
+	r.Equal("-1.98", exposures["BTC"].Position)
+	r.Equal("-0.37", exposures["BTC"].Net)
+	r.Equal("-18500", exposures["BTC"].Notional)
+	r.Equal("1450", exposures["USDT"].Net)
+
+	// 75000 + 1000 + 2250 + 450 + 5500
+	r.Equal("84200", res.TotalValue)
+}
+
+func (s *unifiedAccountServiceTestSuite) TestUnifiedAccountError() {
+	s.mockRoutes(routedResponses{
+		"/api/v3/ticker/price": `[]`,
+	})
+	_, err := s.client.NewUnifiedAccountService().Do(newContext())
+	s.r().Error(err)
+	s.r().Contains(err.Error(), "spot")
+}
+
+func TestUnderlyingAsset(t *testing.T) {
+	for symbol, expected := range map[string]string{
+		"BTCUSDT":        "BTC",
+		"BTCUSD_PERP":    "BTC",
+		"ETHUSDT_250328": "ETH",
+		"SOLFDUSD":       "SOL",
+		"1000PEPEUSDC":   "1000PEPE",
+	} {
+		if got := underlyingAsset(symbol); got != expected {
+			t.Errorf("underlyingAsset(%s) = %s, want %s", symbol, got, expected)
+		}
+	}
+}
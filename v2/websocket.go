@@ -31,6 +31,12 @@ func newWsConfig(endpoint string) *WsConfig {
 	}
 }
 
+// WsDial open the websocket connections, it can be replaced to route them,
+// e.g. through a cassette recorder
+var WsDial = func(dialer *websocket.Dialer, endpoint string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	return dialer.Dial(endpoint, header)
+}
+
 func wsServe(cfg *WsConfig, handler WsHandler, errHandler ErrHandler) (doneC, stopC chan struct{}, err error) {
 	return wsServeWithConnHandler(cfg, handler, errHandler, func(ctx context.Context, c *websocket.Conn) {
 		if WebsocketKeepalive {
@@ -59,7 +65,7 @@ var wsServeWithConnHandler = func(cfg *WsConfig, handler WsHandler, errHandler E
 		EnableCompression: true,
 	}
 
-	c, _, err := Dialer.Dial(cfg.Endpoint, cfg.Header)
+	c, _, err := WsDial(&Dialer, cfg.Endpoint, cfg.Header)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -193,7 +199,7 @@ var WsGetReadWriteConnection = func(cfg *WsConfig) (*websocket.Conn, error) {
 		EnableCompression: false,
 	}
 
-	c, _, err := Dialer.Dial(cfg.Endpoint, cfg.Header)
+	c, _, err := WsDial(&Dialer, cfg.Endpoint, cfg.Header)
 	if err != nil {
 		return nil, err
 	}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/adshao/go-binance/v2/common/websocket"
+	"github.com/bitly/go-simplejson"
 	"github.com/google/uuid"
 	gorilla "github.com/gorilla/websocket"
 )
@@ -34,6 +35,8 @@ var (
 	// using for websocket API (read/write)
 	WebsocketTimeoutReadWriteConnection = time.Second * 10
 	ProxyUrl                            = ""
+	// WsUserDataRawUnknownEvents keeps the payload of the user data events of unknown types in WsUserDataEvent.Raw
+	WsUserDataRawUnknownEvents = false
 )
 
 func getWsProxyUrl() *string {
@@ -189,33 +192,38 @@ func wsDepthServe(endpoint string, handler WsDepthHandler, errHandler ErrHandler
 			errHandler(err)
 			return
 		}
-		event := new(WsDepthEvent)
-		event.Event = j.Get("e").MustString()
-		event.Time = j.Get("E").MustInt64()
-		event.Symbol = j.Get("s").MustString()
-		event.LastUpdateID = j.Get("u").MustInt64()
-		event.FirstUpdateID = j.Get("U").MustInt64()
-		bidsLen := len(j.Get("b").MustArray())
-		event.Bids = make([]Bid, bidsLen)
-		for i := 0; i < bidsLen; i++ {
-			item := j.Get("b").GetIndex(i)
-			event.Bids[i] = Bid{
-				Price:    item.GetIndex(0).MustString(),
-				Quantity: item.GetIndex(1).MustString(),
-			}
+		handler(newWsDepthEvent(j))
+	}
+	return wsServe(cfg, wsHandler, errHandler)
+}
+
+// newWsDepthEvent decode a depth event, its price levels are arrays
+func newWsDepthEvent(j *simplejson.Json) *WsDepthEvent {
+	event := new(WsDepthEvent)
+	event.Event = j.Get("e").MustString()
+	event.Time = j.Get("E").MustInt64()
+	event.Symbol = j.Get("s").MustString()
+	event.LastUpdateID = j.Get("u").MustInt64()
+	event.FirstUpdateID = j.Get("U").MustInt64()
+	bidsLen := len(j.Get("b").MustArray())
+	event.Bids = make([]Bid, bidsLen)
+	for i := 0; i < bidsLen; i++ {
+		item := j.Get("b").GetIndex(i)
+		event.Bids[i] = Bid{
+			Price:    item.GetIndex(0).MustString(),
+			Quantity: item.GetIndex(1).MustString(),
 		}
-		asksLen := len(j.Get("a").MustArray())
-		event.Asks = make([]Ask, asksLen)
-		for i := 0; i < asksLen; i++ {
-			item := j.Get("a").GetIndex(i)
-			event.Asks[i] = Ask{
-				Price:    item.GetIndex(0).MustString(),
-				Quantity: item.GetIndex(1).MustString(),
-			}
+	}
+	asksLen := len(j.Get("a").MustArray())
+	event.Asks = make([]Ask, asksLen)
+	for i := 0; i < asksLen; i++ {
+		item := j.Get("a").GetIndex(i)
+		event.Asks[i] = Ask{
+			Price:    item.GetIndex(0).MustString(),
+			Quantity: item.GetIndex(1).MustString(),
 		}
-		handler(event)
 	}
-	return wsServe(cfg, wsHandler, errHandler)
+	return event
 }
 
 // WsDepthEvent define websocket depth event
@@ -538,12 +546,87 @@ type WsCombinedTradeEvent struct {
 
 // WsUserDataEvent define user data event
 type WsUserDataEvent struct {
-	Event         UserDataEventType `json:"e"`
-	Time          int64             `json:"E"`
-	AccountUpdate WsAccountUpdateList
-	BalanceUpdate WsBalanceUpdate
-	OrderUpdate   WsOrderUpdate
-	OCOUpdate     WsOCOUpdate
+	Event                   UserDataEventType `json:"e"`
+	Time                    int64             `json:"E"`
+	AccountUpdate           WsAccountUpdateList
+	BalanceUpdate           WsBalanceUpdate
+	OrderUpdate             WsOrderUpdate
+	OCOUpdate               WsOCOUpdate
+	ExternalLockUpdate      WsExternalLockUpdate
+	ListenKeyExpired        WsListenKeyExpired
+	LiabilityUpdate         WsLiabilityUpdate
+	MarginLevelStatusChange WsMarginLevelStatusChange
+
+	// Raw is the payload of an event of unknown type when WsUserDataRawUnknownEvents is set
+	Raw []byte `json:"-"`
+}
+
+// WsExternalLockUpdate define the lock or unlock of a spot balance by an external system
+type WsExternalLockUpdate struct {
+	Asset           string `json:"a"`
+	Delta           string `json:"d"`
+	TransactionTime int64  `json:"T"`
+}
+
+// WsListenKeyExpired define the expiration of the listen key of a stream
+type WsListenKeyExpired struct {
+	ListenKey string `json:"listenKey"`
+}
+
+// WsLiabilityUpdate define a change of the margin liability of an asset
+type WsLiabilityUpdate struct {
+	Asset         string `json:"a"`
+	Type          string `json:"t"` // BORROW, REPAY...
+	TransactionID int64  `json:"tx"`
+	Principal     string `json:"p"`
+	Interest      string `json:"i"`
+}
+
+// WsMarginLevelStatusChange define a change of the margin level status
+type WsMarginLevelStatusChange struct {
+	MarginLevel string `json:"l"`
+	Status      string `json:"s"` // EXCESSIVE, NORMAL, MARGIN_CALL, PRE_LIQUIDATION, FORCE_LIQUIDATION
+}
+
+// newWsUserDataEvent decode a user data event payload
+func newWsUserDataEvent(payload []byte) (*WsUserDataEvent, error) {
+	event := new(WsUserDataEvent)
+	err := json.Unmarshal(payload, event)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	switch event.Event {
+	case UserDataEventTypeOutboundAccountPosition:
+		v = &event.AccountUpdate
+	case UserDataEventTypeBalanceUpdate:
+		v = &event.BalanceUpdate
+	case UserDataEventTypeExecutionReport:
+		v = &event.OrderUpdate
+	case UserDataEventTypeListStatus:
+		v = &event.OCOUpdate
+	case UserDataEventTypeExternalLockUpdate:
+		v = &event.ExternalLockUpdate
+	case UserDataEventTypeListenKeyExpired:
+		v = &event.ListenKeyExpired
+	case UserDataEventTypeUserLiabilityChange:
+		v = &event.LiabilityUpdate
+	case UserDataEventTypeMarginLevelStatusChange:
+		v = &event.MarginLevelStatusChange
+	case UserDataEventTypeEventStreamTerminated:
+		// no payload
+	default:
+		if WsUserDataRawUnknownEvents {
+			event.Raw = append([]byte(nil), payload...)
+		}
+	}
+	if v != nil {
+		err = json.Unmarshal(payload, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
 }
 
 type WsAccountUpdateList struct {
@@ -649,47 +732,11 @@ func WsUserDataServe(listenKey string, handler WsUserDataHandler, errHandler Err
 	endpoint := fmt.Sprintf("%s/%s", getWsEndpoint(), listenKey)
 	cfg := newWsConfig(endpoint)
 	wsHandler := func(message []byte) {
-		j, err := newJSON(message)
+		event, err := newWsUserDataEvent(message)
 		if err != nil {
 			errHandler(err)
 			return
 		}
-
-		event := new(WsUserDataEvent)
-
-		err = json.Unmarshal(message, event)
-		if err != nil {
-			errHandler(err)
-			return
-		}
-
-		switch UserDataEventType(j.Get("e").MustString()) {
-		case UserDataEventTypeOutboundAccountPosition:
-			err = json.Unmarshal(message, &event.AccountUpdate)
-			if err != nil {
-				errHandler(err)
-				return
-			}
-		case UserDataEventTypeBalanceUpdate:
-			err = json.Unmarshal(message, &event.BalanceUpdate)
-			if err != nil {
-				errHandler(err)
-				return
-			}
-		case UserDataEventTypeExecutionReport:
-			err = json.Unmarshal(message, &event.OrderUpdate)
-			if err != nil {
-				errHandler(err)
-				return
-			}
-		case UserDataEventTypeListStatus:
-			err = json.Unmarshal(message, &event.OCOUpdate)
-			if err != nil {
-				errHandler(err)
-				return
-			}
-		}
-
 		handler(event)
 	}
 	return wsServe(cfg, wsHandler, errHandler)
@@ -798,8 +845,8 @@ func WsUserDataServeSignature(apiKey, secretKey string, keyType string, timeOffs
 			}
 
 			// Parse user data event from the resolved payload
-			event := new(WsUserDataEvent)
-			if err = json.Unmarshal(payload, event); err != nil {
+			event, err := newWsUserDataEvent(payload)
+			if err != nil {
 				select {
 				case <-stopC:
 					continue
@@ -808,58 +855,6 @@ func WsUserDataServeSignature(apiKey, secretKey string, keyType string, timeOffs
 					continue
 				}
 			}
-
-			// Determine event type from payload
-			jj, _ := newJSON(payload)
-			evtType := UserDataEventType(jj.Get("e").MustString())
-			// ensure top-level fields are populated even if struct sub-unmarshal doesn't set them
-			event.Event = evtType
-			event.Time = jj.Get("E").MustInt64()
-
-			switch evtType {
-			case UserDataEventTypeOutboundAccountPosition:
-				if err = json.Unmarshal(payload, &event.AccountUpdate); err != nil {
-					select {
-					case <-stopC:
-						continue
-					default:
-						errHandler(err)
-						continue
-					}
-				}
-			case UserDataEventTypeBalanceUpdate:
-				if err = json.Unmarshal(payload, &event.BalanceUpdate); err != nil {
-					select {
-					case <-stopC:
-						continue
-					default:
-						errHandler(err)
-						continue
-					}
-				}
-			case UserDataEventTypeExecutionReport:
-				if err = json.Unmarshal(payload, &event.OrderUpdate); err != nil {
-					select {
-					case <-stopC:
-						continue
-					default:
-						errHandler(err)
-						continue
-					}
-				}
-			case UserDataEventTypeListStatus:
-				if err = json.Unmarshal(payload, &event.OCOUpdate); err != nil {
-					select {
-					case <-stopC:
-						continue
-					default:
-						errHandler(err)
-						continue
-					}
-				}
-			default:
-				// Unknown event; still forward to handler so callers can log/inspect
-			}
 			handler(event)
 		}
 	}()
@@ -882,6 +882,11 @@ func (s *websocketServiceTestSuite) assertUserDataEvent(e, a *WsUserDataEvent) {
 	}
 	s.assertOrderUpdate(&e.OrderUpdate, &a.OrderUpdate)
 	s.assertBalanceUpdate(&e.BalanceUpdate, &a.BalanceUpdate)
+	r.Equal(e.ExternalLockUpdate, a.ExternalLockUpdate, "ExternalLockUpdate")
+	r.Equal(e.ListenKeyExpired, a.ListenKeyExpired, "ListenKeyExpired")
+	r.Equal(e.LiabilityUpdate, a.LiabilityUpdate, "LiabilityUpdate")
+	r.Equal(e.MarginLevelStatusChange, a.MarginLevelStatusChange, "MarginLevelStatusChange")
+	r.Equal(string(e.Raw), string(a.Raw), "Raw")
 }
 
 func (s *websocketServiceTestSuite) testWsUserDataServe(data []byte, expectedEvent *WsUserDataEvent) {
@@ -1200,6 +1205,89 @@ func (s *websocketServiceTestSuite) TestWsUserDataServeOrderUpdateWithTradeOrder
 	s.testWsUserDataServe(data, expectedEvent)
 }
 
+func (s *websocketServiceTestSuite) TestWsUserDataServeExternalLockUpdate() {
+	data := []byte(`{"e":"externalLockUpdate","E":1581557507324,"a":"NEO","d":"10.00000000","T":1581557507268}`)
+	expectedEvent := &WsUserDataEvent{
+		Event: UserDataEventTypeExternalLockUpdate,
+		Time:  1581557507324,
+		ExternalLockUpdate: WsExternalLockUpdate{
+			Asset:           "NEO",
+			Delta:           "10.00000000",
+			TransactionTime: 1581557507268,
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeListenKeyExpired() {
+	data := []byte(`{"e":"listenKeyExpired","E":1699596037418,"listenKey":"OfYGbUzi3PraNagEkdKuFwUHn48brFsItTdsuiIXrucEvD0rhRXZ7I6URWfE8YE8"}`)
+	expectedEvent := &WsUserDataEvent{
+		Event: UserDataEventTypeListenKeyExpired,
+		Time:  1699596037418,
+		ListenKeyExpired: WsListenKeyExpired{
+			ListenKey: "OfYGbUzi3PraNagEkdKuFwUHn48brFsItTdsuiIXrucEvD0rhRXZ7I6URWfE8YE8",
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeEventStreamTerminated() {
+	data := []byte(`{"e":"eventStreamTerminated","E":1728973001334}`)
+	expectedEvent := &WsUserDataEvent{
+		Event: UserDataEventTypeEventStreamTerminated,
+		Time:  1728973001334,
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeLiabilityUpdate() {
+	data := []byte(`{"e":"USER_LIABILITY_CHANGE","E":1701411452839,"a":"BTC","t":"BORROW","tx":1352286576452864727,"p":"1.03453430","i":"0"}`)
+	expectedEvent := &WsUserDataEvent{
+		Event: UserDataEventTypeUserLiabilityChange,
+		Time:  1701411452839,
+		LiabilityUpdate: WsLiabilityUpdate{
+			Asset:         "BTC",
+			Type:          "BORROW",
+			TransactionID: 1352286576452864727,
+			Principal:     "1.03453430",
+			Interest:      "0",
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeMarginLevelStatusChange() {
+	data := []byte(`{"e":"MARGIN_LEVEL_STATUS_CHANGE","E":1701411452839,"l":"1.10","s":"MARGIN_CALL"}`)
+	expectedEvent := &WsUserDataEvent{
+		Event: UserDataEventTypeMarginLevelStatusChange,
+		Time:  1701411452839,
+		MarginLevelStatusChange: WsMarginLevelStatusChange{
+			MarginLevel: "1.10",
+			Status:      "MARGIN_CALL",
+		},
+	}
+	s.testWsUserDataServe(data, expectedEvent)
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeUnknownEvent() {
+	data := []byte(`{"e":"newEvent","E":1701411452839,"x":"1"}`)
+	s.testWsUserDataServe(data, &WsUserDataEvent{
+		Event: "newEvent",
+		Time:  1701411452839,
+	})
+}
+
+func (s *websocketServiceTestSuite) TestWsUserDataServeUnknownEventRaw() {
+	data := []byte(`{"e":"newEvent","E":1701411452839,"x":"1"}`)
+	WsUserDataRawUnknownEvents = true
+	defer func() { WsUserDataRawUnknownEvents = false }()
+	s.testWsUserDataServe(data, &WsUserDataEvent{
+		Event: "newEvent",
+		Time:  1701411452839,
+		Raw:   data,
+	})
+}
+
 func (s *websocketServiceTestSuite) TestWsMarketStatServe() {
 	data := []byte(`{
   		"e": "24hrTicker",
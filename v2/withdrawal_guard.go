@@ -0,0 +1,539 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/adshao/go-binance/v2/alpha"
+)
+
+var (
+	// ErrWithdrawalNotAllowed is returned when the destination of a withdrawal isn't in the allowlist
+	ErrWithdrawalNotAllowed = errors.New("withdrawal destination is not in the allowlist")
+	// ErrWithdrawalCapExceeded is returned when a withdrawal exceeds the daily cap of its coin
+	ErrWithdrawalCapExceeded = errors.New("withdrawal exceeds the daily cap")
+	// ErrWithdrawalInvalid is returned when a withdrawal breaks the rules of its network
+	ErrWithdrawalInvalid = errors.New("withdrawal breaks the network rules")
+	// ErrWithdrawalNotApproved is returned when the approval callback rejects a withdrawal
+	ErrWithdrawalNotApproved = errors.New("withdrawal not approved")
+	// ErrWithdrawalNotFound is returned when a tracked withdrawal doesn't show up in the history
+	ErrWithdrawalNotFound = errors.New("withdrawal not found")
+)
+
+// Withdrawal statuses of the withdraw history
+const (
+	WithdrawStatusEmailSent        = 0
+	WithdrawStatusCancelled        = 1
+	WithdrawStatusAwaitingApproval = 2
+	WithdrawStatusRejected         = 3
+	WithdrawStatusProcessing       = 4
+	WithdrawStatusFailure          = 5
+	WithdrawStatusCompleted        = 6
+)
+
+// withdrawalCapWindow is the window of the daily caps
+const withdrawalCapWindow = 24 * time.Hour
+
+// WithdrawalRequest define a withdrawal to check and submit
+type WithdrawalRequest struct {
+	// Coin is the coin, or the alpha id of an alpha token
+	Coin       string
+	Network    string
+	Address    string
+	AddressTag string
+	Amount     string
+	// ContractAddress is the contract of an alpha token
+	ContractAddress string
+	WithdrawOrderID string
+	// MaxFee reject the withdrawal when the network fee is higher, ignored when empty
+	MaxFee string
+}
+
+// WithdrawalAllowance define an allowed destination, an empty AddressTag
+// only allows withdrawals without tag
+type WithdrawalAllowance struct {
+	Coin       string
+	Network    string
+	Address    string
+	AddressTag string
+}
+
+// WithdrawalNetwork define the withdrawal rules of a coin on a network
+type WithdrawalNetwork struct {
+	Coin           string
+	Network        string
+	WithdrawEnable bool
+	WithdrawDesc   string
+	WithdrawFee    string
+	WithdrawMin    string
+	WithdrawMax    string
+	// WithdrawIntegerMultiple is the step of the amounts, ignored when empty
+	WithdrawIntegerMultiple string
+	AddressRegex            string
+	MemoRegex               string
+	// MemoRequired is set when the deposit addresses are shared and need a tag
+	MemoRequired bool
+}
+
+// WithdrawalStatus define a withdrawal of the withdraw history
+type WithdrawalStatus struct {
+	ID             string
+	Coin           string
+	Network        string
+	Address        string
+	Amount         string
+	TransactionFee string
+	Status         int
+	TxID           string
+	Info           string
+	ApplyTime      string
+	CompleteTime   string
+}
+
+// IsFinal return whether the withdrawal is completed or failed
+func (s *WithdrawalStatus) IsFinal() bool {
+	switch s.Status {
+	case WithdrawStatusCancelled, WithdrawStatusRejected, WithdrawStatusFailure, WithdrawStatusCompleted:
+		return true
+	}
+	return false
+}
+
+// isFailed return whether the withdrawal didn't and won't send funds
+func (s *WithdrawalStatus) isFailed() bool {
+	return s.IsFinal() && s.Status != WithdrawStatusCompleted
+}
+
+// WithdrawalVenue query the network rules and the history of withdrawals and submit them
+type WithdrawalVenue interface {
+	Networks(ctx context.Context, coin string) ([]*WithdrawalNetwork, error)
+	Withdraw(ctx context.Context, request *WithdrawalRequest) (id string, err error)
+	// Withdrawals return the withdrawals of a coin applied since startTime, in milliseconds
+	Withdrawals(ctx context.Context, coin string, startTime int64) ([]*WithdrawalStatus, error)
+}
+
+type spotWithdrawalVenue struct {
+	c *Client
+}
+
+// SpotWithdrawalVenue return the venue of the withdrawals of the spot wallets
+func SpotWithdrawalVenue(c *Client) WithdrawalVenue {
+	return &spotWithdrawalVenue{c: c}
+}
+
+func (v *spotWithdrawalVenue) Networks(ctx context.Context, coin string) ([]*WithdrawalNetwork, error) {
+	coins, err := v.c.NewGetAllCoinsInfoService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []*WithdrawalNetwork
+	for _, c := range coins {
+		if c.Coin != coin {
+			continue
+		}
+		for _, n := range c.NetworkList {
+			res = append(res, &WithdrawalNetwork{
+				Coin:                    c.Coin,
+				Network:                 n.Network,
+				WithdrawEnable:          n.WithdrawEnable && c.WithdrawAllEnable,
+				WithdrawDesc:            n.WithdrawDesc,
+				WithdrawFee:             n.WithdrawFee,
+				WithdrawMin:             n.WithdrawMin,
+				WithdrawMax:             n.WithdrawMax,
+				WithdrawIntegerMultiple: n.WithdrawIntegerMultiple,
+				AddressRegex:            n.AddressRegex,
+				MemoRegex:               n.MemoRegex,
+				MemoRequired:            n.SameAddress,
+			})
+		}
+	}
+	return res, nil
+}
+
+func (v *spotWithdrawalVenue) Withdraw(ctx context.Context, request *WithdrawalRequest) (string, error) {
+	s := v.c.NewCreateWithdrawService().Coin(request.Coin).Network(request.Network).
+		Address(request.Address).Amount(request.Amount)
+	if request.AddressTag != "" {
+		s.AddressTag(request.AddressTag)
+	}
+	if request.WithdrawOrderID != "" {
+		s.WithdrawOrderID(request.WithdrawOrderID)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+func (v *spotWithdrawalVenue) Withdrawals(ctx context.Context, coin string, startTime int64) ([]*WithdrawalStatus, error) {
+	withdraws, err := v.c.NewListWithdrawsService().Coin(coin).StartTime(startTime).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*WithdrawalStatus, len(withdraws))
+	for i, w := range withdraws {
+		res[i] = &WithdrawalStatus{
+			ID:             w.ID,
+			Coin:           w.Coin,
+			Network:        w.Network,
+			Address:        w.Address,
+			Amount:         w.Amount,
+			TransactionFee: w.TransactionFee,
+			Status:         w.Status,
+			TxID:           w.TxID,
+			Info:           w.Info,
+			ApplyTime:      w.ApplyTime,
+			CompleteTime:   w.CompleteTime,
+		}
+	}
+	return res, nil
+}
+
+type alphaWithdrawalVenue struct {
+	c *alpha.Client
+}
+
+// AlphaWithdrawalVenue return the venue of the withdrawals of alpha tokens,
+// the coins are alpha ids
+func AlphaWithdrawalVenue(c *alpha.Client) WithdrawalVenue {
+	return &alphaWithdrawalVenue{c: c}
+}
+
+func (v *alphaWithdrawalVenue) Networks(ctx context.Context, coin string) ([]*WithdrawalNetwork, error) {
+	tokens, err := v.c.NewGetTokenInfoService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []*WithdrawalNetwork
+	for _, t := range tokens {
+		if t.Coin != coin {
+			continue
+		}
+		res = append(res, &WithdrawalNetwork{
+			Coin:           t.Coin,
+			Network:        t.Network,
+			WithdrawEnable: t.WithdrawEnable,
+			WithdrawDesc:   t.WithdrawDesc,
+			WithdrawFee:    t.WithdrawFee,
+			WithdrawMin:    t.WithdrawMin,
+			WithdrawMax:    t.WithdrawMax,
+			AddressRegex:   t.AddressRegex,
+			MemoRegex:      t.MemoRegex,
+			MemoRequired:   t.SameAddress,
+		})
+	}
+	return res, nil
+}
+
+func (v *alphaWithdrawalVenue) Withdraw(ctx context.Context, request *WithdrawalRequest) (string, error) {
+	s := v.c.NewAlphaWithdrawService().Network(request.Network).AlphaID(request.Coin).
+		ContractAddress(request.ContractAddress).Address(request.Address).Amount(request.Amount)
+	if request.AddressTag != "" {
+		s.AddressTag(request.AddressTag)
+	}
+	if request.WithdrawOrderID != "" {
+		s.ClientOrderID(request.WithdrawOrderID)
+	}
+	res, err := s.Do(ctx)
+	if err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+func (v *alphaWithdrawalVenue) Withdrawals(ctx context.Context, coin string, startTime int64) ([]*WithdrawalStatus, error) {
+	withdraws, err := v.c.NewGetAlphaWithdrawHistoryService().AlphaID(coin).StartTime(startTime).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*WithdrawalStatus, len(withdraws))
+	for i, w := range withdraws {
+		res[i] = &WithdrawalStatus{
+			ID:             w.ID,
+			Coin:           w.AlphaID,
+			Network:        w.Network,
+			Address:        w.Address,
+			Amount:         w.Amount,
+			TransactionFee: w.TransactionFee,
+			Status:         w.Status,
+			TxID:           w.TxID,
+			Info:           w.Info,
+			ApplyTime:      w.ApplyTime,
+			CompleteTime:   w.CompleteTime,
+		}
+	}
+	return res, nil
+}
+
+// withdrawalSpend define an amount counted in a daily cap
+type withdrawalSpend struct {
+	coin   string
+	id     string
+	amount decimal.Decimal
+	time   time.Time
+}
+
+// WithdrawalGuard check withdrawals before submitting them: the destination
+// must be in the allowlist, the amount within the daily cap of the coin and
+// the rules of the network, and the approval callback must accept it. The
+// daily caps count the withdrawals of the last 24 hours of the history, so
+// they survive restarts, and those submitted by the guard not listed yet.
+type WithdrawalGuard struct {
+	// Approve is called before a withdrawal is submitted, it's rejected
+	// when an error is returned
+	Approve func(ctx context.Context, request *WithdrawalRequest, network *WithdrawalNetwork) error
+	// OnStatus is called by Track every time the status of a withdrawal changes
+	OnStatus func(status *WithdrawalStatus)
+
+	venue        WithdrawalVenue
+	pollInterval time.Duration
+	now          func() time.Time
+	mu           sync.Mutex
+	allowlist    map[WithdrawalAllowance]bool
+	caps         map[string]decimal.Decimal
+	spends       []*withdrawalSpend
+}
+
+// NewWithdrawalGuard init a guard of the withdrawals of venue, nothing is
+// allowed until destinations are added to the allowlist
+func NewWithdrawalGuard(venue WithdrawalVenue) *WithdrawalGuard {
+	return &WithdrawalGuard{
+		venue:        venue,
+		pollInterval: 30 * time.Second,
+		now:          time.Now,
+		allowlist:    make(map[WithdrawalAllowance]bool),
+		caps:         make(map[string]decimal.Decimal),
+	}
+}
+
+// Allow add destinations to the allowlist
+func (g *WithdrawalGuard) Allow(allowances ...WithdrawalAllowance) *WithdrawalGuard {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, a := range allowances {
+		g.allowlist[a] = true
+	}
+	return g
+}
+
+// DailyCap set the max amount of a coin withdrawn over 24 hours, coins without cap are not limited
+func (g *WithdrawalGuard) DailyCap(coin, amount string) *WithdrawalGuard {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.caps[coin] = decimalOrZero(amount)
+	return g
+}
+
+// PollInterval set the interval between the checks of Track
+func (g *WithdrawalGuard) PollInterval(d time.Duration) *WithdrawalGuard {
+	g.pollInterval = d
+	return g
+}
+
+// Check return an error when a withdrawal isn't allowed, and the rules of its network
+func (g *WithdrawalGuard) Check(ctx context.Context, request *WithdrawalRequest) (*WithdrawalNetwork, error) {
+	g.mu.Lock()
+	allowed := g.allowlist[WithdrawalAllowance{
+		Coin: request.Coin, Network: request.Network, Address: request.Address, AddressTag: request.AddressTag,
+	}]
+	g.mu.Unlock()
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s %s %s", ErrWithdrawalNotAllowed, request.Coin, request.Network, request.Address)
+	}
+	networks, err := g.venue.Networks(ctx, request.Coin)
+	if err != nil {
+		return nil, err
+	}
+	var network *WithdrawalNetwork
+	for _, n := range networks {
+		if n.Network == request.Network {
+			network = n
+		}
+	}
+	if network == nil {
+		return nil, fmt.Errorf("%w: unknown network %s of %s", ErrWithdrawalInvalid, request.Network, request.Coin)
+	}
+	if err = checkWithdrawalNetwork(request, network); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// checkWithdrawalNetwork check a withdrawal against the rules of its network
+func checkWithdrawalNetwork(request *WithdrawalRequest, network *WithdrawalNetwork) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrWithdrawalInvalid, fmt.Sprintf(format, args...))
+	}
+	if !network.WithdrawEnable {
+		return invalid("withdrawals of %s on %s are disabled %s", request.Coin, request.Network, network.WithdrawDesc)
+	}
+	amount, err := decimal.NewFromString(request.Amount)
+	if err != nil || !amount.IsPositive() {
+		return invalid("invalid amount %s", request.Amount)
+	}
+	if min := decimalOrZero(network.WithdrawMin); amount.LessThan(min) {
+		return invalid("amount %s is below the min %s", request.Amount, network.WithdrawMin)
+	}
+	if max := decimalOrZero(network.WithdrawMax); max.IsPositive() && amount.GreaterThan(max) {
+		return invalid("amount %s is above the max %s", request.Amount, network.WithdrawMax)
+	}
+	if step := decimalOrZero(network.WithdrawIntegerMultiple); step.IsPositive() && !amount.Mod(step).IsZero() {
+		return invalid("amount %s is not a multiple of %s", request.Amount, network.WithdrawIntegerMultiple)
+	}
+	fee := decimalOrZero(network.WithdrawFee)
+	if !amount.GreaterThan(fee) {
+		return invalid("amount %s doesn't cover the fee %s", request.Amount, network.WithdrawFee)
+	}
+	if request.MaxFee != "" && fee.GreaterThan(decimalOrZero(request.MaxFee)) {
+		return invalid("fee %s is above the max fee %s", network.WithdrawFee, request.MaxFee)
+	}
+	if network.AddressRegex != "" {
+		re, err := regexp.Compile(network.AddressRegex)
+		if err != nil {
+			return invalid("invalid address regex %s: %v", network.AddressRegex, err)
+		}
+		if !re.MatchString(request.Address) {
+			return invalid("address %s doesn't match %s", request.Address, network.AddressRegex)
+		}
+	}
+	if network.MemoRequired && request.AddressTag == "" {
+		return invalid("a tag is required on %s", request.Network)
+	}
+	if request.AddressTag != "" && network.MemoRegex != "" {
+		re, err := regexp.Compile(network.MemoRegex)
+		if err != nil {
+			return invalid("invalid tag regex %s: %v", network.MemoRegex, err)
+		}
+		if !re.MatchString(request.AddressTag) {
+			return invalid("tag %s doesn't match %s", request.AddressTag, network.MemoRegex)
+		}
+	}
+	return nil
+}
+
+// Withdraw check, approve and submit a withdrawal, and return its id
+func (g *WithdrawalGuard) Withdraw(ctx context.Context, request *WithdrawalRequest) (string, error) {
+	network, err := g.Check(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	spend, err := g.reserve(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	if g.Approve != nil {
+		if err = g.Approve(ctx, request, network); err != nil {
+			g.release(spend)
+			return "", fmt.Errorf("%w: %v", ErrWithdrawalNotApproved, err)
+		}
+	}
+	id, err := g.venue.Withdraw(ctx, request)
+	if err != nil {
+		g.release(spend)
+		return "", err
+	}
+	g.mu.Lock()
+	spend.id = id
+	g.mu.Unlock()
+	return id, nil
+}
+
+// reserve count a withdrawal in the daily cap of its coin
+func (g *WithdrawalGuard) reserve(ctx context.Context, request *WithdrawalRequest) (*withdrawalSpend, error) {
+	amount := decimalOrZero(request.Amount)
+	g.mu.Lock()
+	limit, capped := g.caps[request.Coin]
+	g.mu.Unlock()
+	spend := &withdrawalSpend{coin: request.Coin, amount: amount, time: g.now()}
+	if !capped {
+		return spend, nil
+	}
+	since := spend.time.Add(-withdrawalCapWindow)
+	history, err := g.venue.Withdrawals(ctx, request.Coin, since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	spent := decimal.Zero
+	listed := make(map[string]bool, len(history))
+	for _, w := range history {
+		listed[w.ID] = true
+		if !w.isFailed() {
+			spent = spent.Add(decimalOrZero(w.Amount))
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	spends := g.spends[:0]
+	for _, s := range g.spends {
+		if s.time.Before(since) {
+			continue
+		}
+		spends = append(spends, s)
+		if s.coin == request.Coin && (s.id == "" || !listed[s.id]) {
+			spent = spent.Add(s.amount)
+		}
+	}
+	g.spends = spends
+	if spent.Add(amount).GreaterThan(limit) {
+		return nil, fmt.Errorf("%w: %s %s withdrawn, cap %s", ErrWithdrawalCapExceeded, spent, request.Coin, limit)
+	}
+	g.spends = append(g.spends, spend)
+	return spend, nil
+}
+
+// release drop a withdrawal that wasn't submitted from the daily caps
+func (g *WithdrawalGuard) release(spend *withdrawalSpend) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, s := range g.spends {
+		if s == spend {
+			g.spends = append(g.spends[:i], g.spends[i+1:]...)
+			return
+		}
+	}
+}
+
+// Track poll the history until a withdrawal is completed or failed and
+// return its final status. The history is searched from 24 hours before the
+// call, ErrWithdrawalNotFound is returned if the withdrawal doesn't show up
+// within 24 hours
+func (g *WithdrawalGuard) Track(ctx context.Context, coin, id string) (*WithdrawalStatus, error) {
+	start := g.now()
+	since := start.Add(-withdrawalCapWindow).UnixMilli()
+	var last *WithdrawalStatus
+	for {
+		history, err := g.venue.Withdrawals(ctx, coin, since)
+		if err != nil {
+			return last, err
+		}
+		for _, w := range history {
+			if w.ID != id {
+				continue
+			}
+			if last == nil || last.Status != w.Status || last.TxID != w.TxID {
+				last = w
+				if g.OnStatus != nil {
+					g.OnStatus(w)
+				}
+			}
+		}
+		if last != nil && last.IsFinal() {
+			return last, nil
+		}
+		if last == nil && g.now().Sub(start) > withdrawalCapWindow {
+			return nil, fmt.Errorf("%w: %s", ErrWithdrawalNotFound, id)
+		}
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-time.After(g.pollInterval):
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type withdrawalGuardTestSuite struct {
+	baseTestSuite
+}
+
+func TestWithdrawalGuard(t *testing.T) {
+	suite.Run(t, new(withdrawalGuardTestSuite))
+}
+
+func (s *withdrawalGuardTestSuite) SetupTest() {
+	s.baseTestSuite.SetupTest()
+	s.mockRoutes(routedResponses{
+		"/sapi/v1/capital/config/getall": `[
+			{
+				"coin": "BTC",
+				"withdrawAllEnable": true,
+				"networkList": [
+					{
+						"network": "BTC",
+						"addressRegex": "^(bc1|[13])[a-zA-HJ-NP-Z0-9]{25,39}$",
+						"withdrawEnable": true,
+						"withdrawFee": "0.0005",
+						"withdrawIntegerMultiple": "0.00000001",
+						"withdrawMax": "750",
+						"withdrawMin": "0.001"
+					},
+					{
+						"network": "BSC",
+						"withdrawEnable": false,
+						"withdrawDesc": "wallet maintenance",
+						"withdrawFee": "0.0000029",
+						"withdrawMin": "0.0000058"
+					}
+				]
+			},
+			{
+				"coin": "XRP",
+				"withdrawAllEnable": true,
+				"networkList": [
+					{
+						"network": "XRP",
+						"memoRegex": "^[0-9A-Za-z\\-_]{1,120}$",
+						"sameAddress": true,
+						"withdrawEnable": true,
+						"withdrawFee": "0.2",
+						"withdrawIntegerMultiple": "0.1",
+						"withdrawMin": "1"
+					}
+				]
+			}
+		]`,
+		"POST /sapi/v1/capital/withdraw/apply": `{"id": "new"}`,
+		"/sapi/v1/capital/withdraw/history": `[
+			{"id": "w1", "coin": "BTC", "network": "BTC", "amount": "0.5", "status": 6, "txId": "tx1"},
+			{"id": "w2", "coin": "BTC", "network": "BTC", "amount": "1", "status": 5, "info": "failed"}
+		]`,
+	})
+}
+
+const withdrawalTestAddress = "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh"
+
+func (s *withdrawalGuardTestSuite) newGuard() *WithdrawalGuard {
+	return NewWithdrawalGuard(SpotWithdrawalVenue(s.client.Client)).Allow(
+		WithdrawalAllowance{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress},
+		WithdrawalAllowance{Coin: "BTC", Network: "BSC", Address: "0x0000000000000000000000000000000000000001"},
+		WithdrawalAllowance{Coin: "BTC", Network: "BTC", Address: "invalid"},
+		WithdrawalAllowance{Coin: "XRP", Network: "XRP", Address: "rAddress"},
+		WithdrawalAllowance{Coin: "XRP", Network: "XRP", Address: "rAddress", AddressTag: "12345"},
+	)
+}
+
+func (s *withdrawalGuardTestSuite) TestCheck() {
+	g := s.newGuard()
+	r := s.r()
+	network, err := g.Check(newContext(), &WithdrawalRequest{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.01"})
+	r.NoError(err)
+	r.Equal("0.0005", network.WithdrawFee)
+
+	for _, request := range []*WithdrawalRequest{
+		{Coin: "BTC", Network: "BTC", Address: "elsewhere", Amount: "0.01"},
+		{Coin: "XRP", Network: "XRP", Address: "rAddress", AddressTag: "999", Amount: "10"},
+	} {
+		_, err = g.Check(newContext(), request)
+		r.ErrorIs(err, ErrWithdrawalNotAllowed, request.Address)
+	}
+	for _, request := range []*WithdrawalRequest{
+		{Coin: "BTC", Network: "BSC", Address: "0x0000000000000000000000000000000000000001", Amount: "0.01"},
+		{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.0001"},
+		{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "1000"},
+		{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.010000001"},
+		{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.01", MaxFee: "0.0001"},
+		{Coin: "BTC", Network: "BTC", Address: "invalid", Amount: "0.01"},
+		{Coin: "XRP", Network: "XRP", Address: "rAddress", Amount: "10"},
+		{Coin: "XRP", Network: "XRP", Address: "rAddress", AddressTag: "12345", Amount: "10.05"},
+	} {
+		_, err = g.Check(newContext(), request)
+		r.ErrorIs(err, ErrWithdrawalInvalid, request.Amount)
+	}
+	_, err = g.Check(newContext(), &WithdrawalRequest{Coin: "XRP", Network: "XRP", Address: "rAddress", AddressTag: "12345", Amount: "10"})
+	r.NoError(err)
+}
+
+func (s *withdrawalGuardTestSuite) TestWithdraw() {
+	g := s.newGuard().DailyCap("BTC", "1")
+	var approved []*WithdrawalRequest
+	g.Approve = func(ctx context.Context, request *WithdrawalRequest, network *WithdrawalNetwork) error {
+		approved = append(approved, request)
+		return nil
+	}
+	r := s.r()
+	id, err := g.Withdraw(newContext(), &WithdrawalRequest{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.4"})
+	r.NoError(err)
+	r.Equal("new", id)
+	r.Len(approved, 1)
+
+	// 0.5 completed and 0.4 submitted, the failed withdrawal isn't counted
+	_, err = g.Withdraw(newContext(), &WithdrawalRequest{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.2"})
+	r.ErrorIs(err, ErrWithdrawalCapExceeded)
+	r.Len(approved, 1)
+	_, err = g.Withdraw(newContext(), &WithdrawalRequest{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.1"})
+	r.NoError(err)
+
+	// the caps count 24 hours
+	g.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	_, err = g.Withdraw(newContext(), &WithdrawalRequest{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.4"})
+	r.NoError(err)
+}
+
+func (s *withdrawalGuardTestSuite) TestWithdrawNotApproved() {
+	g := s.newGuard().DailyCap("BTC", "1")
+	g.Approve = func(ctx context.Context, request *WithdrawalRequest, network *WithdrawalNetwork) error {
+		return errors.New("denied")
+	}
+	r := s.r()
+	_, err := g.Withdraw(newContext(), &WithdrawalRequest{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.4"})
+	r.ErrorIs(err, ErrWithdrawalNotApproved)
+
+	// the rejected withdrawal doesn't count in the cap
+	g.Approve = nil
+	_, err = g.Withdraw(newContext(), &WithdrawalRequest{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.5"})
+	r.NoError(err)
+}
+
+func (s *withdrawalGuardTestSuite) TestTrack() {
+	g := s.newGuard().PollInterval(time.Millisecond)
+	var statuses []*WithdrawalStatus
+	g.OnStatus = func(status *WithdrawalStatus) {
+		statuses = append(statuses, status)
+	}
+	r := s.r()
+	status, err := g.Track(newContext(), "BTC", "w1")
+	r.NoError(err)
+	r.Equal(WithdrawStatusCompleted, status.Status)
+	r.Equal("tx1", status.TxID)
+	r.Len(statuses, 1)
+
+	status, err = g.Track(newContext(), "BTC", "w2")
+	r.NoError(err)
+	r.Equal(WithdrawStatusFailure, status.Status)
+
+	ctx, cancel := context.WithTimeout(newContext(), 10*time.Millisecond)
+	defer cancel()
+	status, err = g.Track(ctx, "BTC", "unknown")
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.Nil(status)
+
+	// the unknown withdrawal isn't polled beyond the window
+	start := time.Now()
+	polls := 0
+	g.now = func() time.Time {
+		polls++
+		return start.Add(time.Duration(polls) * time.Hour)
+	}
+	status, err = g.Track(newContext(), "BTC", "unknown")
+	r.ErrorIs(err, ErrWithdrawalNotFound)
+	r.Nil(status)
+}
+
+func (s *withdrawalGuardTestSuite) TestCheckInvalidRegex() {
+	s.mockRoutes(routedResponses{
+		"/sapi/v1/capital/config/getall": `[
+			{
+				"coin": "BTC",
+				"withdrawAllEnable": true,
+				"networkList": [
+					{
+						"network": "BTC",
+						"addressRegex": "[",
+						"withdrawEnable": true,
+						"withdrawFee": "0.0005",
+						"withdrawMin": "0.001"
+					}
+				]
+			}
+		]`,
+	})
+	_, err := s.newGuard().Check(newContext(), &WithdrawalRequest{Coin: "BTC", Network: "BTC", Address: withdrawalTestAddress, Amount: "0.01"})
+	s.r().ErrorIs(err, ErrWithdrawalInvalid)
+}